@@ -18,7 +18,10 @@ func TestAgentExitCodes_JSON(t *testing.T) {
 	})
 
 	var doc struct {
-		ExitCodes map[string]int `json:"exit_codes"`
+		ExitCodes   map[string]int `json:"exit_codes"`
+		ErrorSchema struct {
+			Fields map[string]string `json:"fields"`
+		} `json:"error_schema"`
 	}
 	if err := json.Unmarshal([]byte(out), &doc); err != nil {
 		t.Fatalf("unmarshal: %v (out=%q)", err, out)
@@ -29,4 +32,7 @@ func TestAgentExitCodes_JSON(t *testing.T) {
 	if doc.ExitCodes["auth_required"] != exitCodeAuthRequired {
 		t.Fatalf("expected auth_required=%d, got %d", exitCodeAuthRequired, doc.ExitCodes["auth_required"])
 	}
+	if doc.ErrorSchema.Fields["class"] == "" || doc.ErrorSchema.Fields["remediation"] == "" {
+		t.Fatalf("expected error_schema fields, got %#v", doc.ErrorSchema)
+	}
 }
@@ -15,22 +15,13 @@ func (c *AgentExitCodesCmd) Run(ctx context.Context) error {
 	// Always emit untransformed JSON, even if the caller enabled global JSON transforms.
 	ctx = outfmt.WithJSONTransform(ctx, outfmt.JSONTransform{})
 
-	codes := map[string]int{
-		"ok":                0,
-		"error":             1,
-		"usage":             2,
-		"empty_results":     emptyResultsExitCode,
-		"auth_required":     exitCodeAuthRequired,
-		"not_found":         exitCodeNotFound,
-		"permission_denied": exitCodePermissionDenied,
-		"rate_limited":      exitCodeRateLimited,
-		"retryable":         exitCodeRetryable,
-		"config":            exitCodeConfig,
-		"cancelled":         exitCodeCancelled,
-	}
+	codes := exitCodeNames()
 
 	if outfmt.IsJSON(ctx) {
-		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"exit_codes": codes})
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"exit_codes":   codes,
+			"error_schema": errorSchema(),
+		})
 	}
 
 	// Plain output is TSV so it's easily machine-parsed.
@@ -0,0 +1,530 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"golang.org/x/term"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/input"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	approvalStatusPending  = "pending"
+	approvalStatusApproved = "approved"
+	approvalStatusRejected = "rejected"
+	approvalStatusFailed   = "failed"
+)
+
+// ApprovalsCmd reviews destructive requests queued by --require-approval.
+type ApprovalsCmd struct {
+	List    ApprovalsListCmd    `cmd:"" name:"list" aliases:"ls" help:"List pending approvals"`
+	Show    ApprovalsShowCmd    `cmd:"" name:"show" aliases:"get,info" help:"Show a stored approval request"`
+	Approve ApprovalsApproveCmd `cmd:"" name:"approve" aliases:"accept,run" help:"Approve and run the exact stored request"`
+	Reject  ApprovalsRejectCmd  `cmd:"" name:"reject" aliases:"deny,decline" help:"Reject a pending approval"`
+}
+
+type pendingApproval struct {
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	CreatedAt string   `json:"created_at"`
+	DecidedAt string   `json:"decided_at,omitempty"`
+	Account   string   `json:"account,omitempty"`
+	Client    string   `json:"client,omitempty"`
+	Op        string   `json:"op"`
+	Request   any      `json:"request,omitempty"`
+	Args      []string `json:"args"`
+	Reason    string   `json:"reason,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// invocationState tracks the current command line and the last planned request
+// (as passed to dryRunExit) so destructive commands can be queued and replayed.
+type invocationState struct {
+	args           []string
+	target         map[string][]string // the selected command's own argument values
+	plannedRequest any
+	approved       *pendingApproval
+	raw            *rawMessage // --raw message already parsed for the policy check
+}
+
+type invocationKey struct{}
+
+func withInvocation(ctx context.Context, st *invocationState) context.Context {
+	return context.WithValue(ctx, invocationKey{}, st)
+}
+
+func invocationFromContext(ctx context.Context) *invocationState {
+	if ctx == nil {
+		return nil
+	}
+	st, _ := ctx.Value(invocationKey{}).(*invocationState)
+	return st
+}
+
+// commandTargetArgs collects the positional and explicitly changed flag values
+// of the selected command, so requests gated without a planned payload still
+// show reviewers exactly what they act on.
+func commandTargetArgs(kctx *kong.Context) map[string][]string {
+	node := kctx.Selected()
+	if node == nil {
+		return nil
+	}
+	out := map[string][]string{}
+	for _, pos := range node.Positional {
+		if vals := policyValueStrings(pos.Target); len(vals) > 0 {
+			out[pos.Name] = vals
+		}
+	}
+	for _, flag := range node.Flags {
+		if flag == nil || flag.Value == nil || !flag.Target.IsValid() || flag.Target.IsZero() {
+			continue
+		}
+		vals := policyValueStrings(flag.Target)
+		if len(vals) == 0 || (flag.HasDefault && strings.Join(vals, ",") == flag.Default) {
+			continue
+		}
+		out[flag.Name] = vals
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// approvalIdentity resolves the account and OAuth client a request runs as,
+// including GOG_ACCOUNT/GOG_CLIENT and account aliases.
+func approvalIdentity(flags *RootFlags) (string, string) {
+	account, err := requireAccount(flags)
+	if err != nil {
+		account = strings.TrimSpace(flags.Account)
+	}
+	client, err := config.NormalizeClientNameOrDefault(flags.Client)
+	if err != nil {
+		client = strings.TrimSpace(flags.Client)
+	}
+	return account, client
+}
+
+func recordPlannedRequest(ctx context.Context, request any) {
+	if request == nil {
+		return
+	}
+	if st := invocationFromContext(ctx); st != nil {
+		st.plannedRequest = request
+	}
+}
+
+// requireApproval queues the destructive action when --require-approval is set.
+// It returns true when the current run is the replay of an approved request.
+func requireApproval(ctx context.Context, flags *RootFlags, op string) (bool, error) {
+	st := invocationFromContext(ctx)
+	if st != nil && st.approved != nil {
+		if err := st.approved.matches(op, st.plannedRequest); err != nil {
+			return false, err
+		}
+		if flags != nil {
+			if err := st.approved.matchesIdentity(approvalIdentity(flags)); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if flags == nil || !flags.RequireApproval {
+		return false, nil
+	}
+
+	account, client := approvalIdentity(flags)
+	rec := pendingApproval{
+		Status:    approvalStatusPending,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Account:   account,
+		Client:    client,
+		Op:        op,
+	}
+	if st != nil {
+		rec.Request = st.plannedRequest
+		rec.Args = append([]string(nil), st.args...)
+	}
+	if len(rec.Args) == 0 {
+		return false, errors.New("approval mode requires the original command line")
+	}
+	id, err := newApprovalID()
+	if err != nil {
+		return false, err
+	}
+	rec.ID = id
+	if err := saveApproval(rec); err != nil {
+		return false, err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		jsonCtx := outfmt.WithJSONTransform(ctx, outfmt.JSONTransform{})
+		_ = outfmt.WriteJSON(jsonCtx, os.Stdout, map[string]any{
+			"approval_required": true,
+			"id":                rec.ID,
+			"op":                rec.Op,
+			"request":           rec.Request,
+		})
+	} else if u := ui.FromContext(ctx); u != nil {
+		u.Out().Printf("approval_required\ttrue")
+		u.Out().Printf("id\t%s", rec.ID)
+		u.Out().Printf("op\t%s", rec.Op)
+	}
+
+	return false, &ExitError{
+		Code: exitCodeApprovalRequired,
+		Err:  fmt.Errorf("approval required to %s (review with: gog approvals show %s)", op, rec.ID),
+	}
+}
+
+func (p *pendingApproval) matches(op string, request any) error {
+	if p.Op != op {
+		return fmt.Errorf("approved request %s no longer matches: op %q, now %q", p.ID, p.Op, op)
+	}
+	stored, err := canonicalJSON(p.Request)
+	if err != nil {
+		return err
+	}
+	current, err := canonicalJSON(request)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stored, current) {
+		return fmt.Errorf("approved request %s no longer matches the planned request", p.ID)
+	}
+	return nil
+}
+
+// matchesIdentity refuses a replay that resolved a different mailbox or OAuth
+// client than the one the request was queued for.
+func (p *pendingApproval) matchesIdentity(account, client string) error {
+	if p.Account != "" && !strings.EqualFold(p.Account, account) {
+		return fmt.Errorf("approved request %s was queued for account %s, not %s", p.ID, p.Account, account)
+	}
+	if p.Client != "" && p.Client != client {
+		return fmt.Errorf("approved request %s was queued for client %s, not %s", p.ID, p.Client, client)
+	}
+	return nil
+}
+
+// replayArgs pins the stored account and client ahead of the original command
+// line, so the approver's GOG_ACCOUNT/GOG_CLIENT can't redirect the request.
+func (p *pendingApproval) replayArgs() []string {
+	var args []string
+	if p.Account != "" {
+		args = append(args, "--account", p.Account)
+	}
+	if p.Client != "" {
+		args = append(args, "--client", p.Client)
+	}
+	return append(args, p.Args...)
+}
+
+// canonicalJSON round-trips through a generic value so typed structs and
+// previously-decoded maps compare equal.
+func canonicalJSON(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, fmt.Errorf("decode request: %w", err)
+	}
+	return json.Marshal(generic)
+}
+
+func newApprovalID() (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate approval id: %w", err)
+	}
+	return "ap_" + hex.EncodeToString(b[:]), nil
+}
+
+func approvalPath(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", usagef("invalid approval id %q", id)
+	}
+	dir, err := config.EnsureApprovalsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+func saveApproval(rec pendingApproval) error {
+	path, err := approvalPath(rec.ID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(payload, '\n'), 0o600)
+}
+
+func loadApproval(id string) (pendingApproval, error) {
+	path, err := approvalPath(id)
+	if err != nil {
+		return pendingApproval{}, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // approval id is validated
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return pendingApproval{}, &ExitError{Code: exitCodeNotFound, Err: fmt.Errorf("approval %s not found", id)}
+		}
+		return pendingApproval{}, err
+	}
+	var rec pendingApproval
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return pendingApproval{}, fmt.Errorf("parse approval %s: %w", id, err)
+	}
+	return rec, nil
+}
+
+func listApprovals() ([]pendingApproval, error) {
+	dir, err := config.EnsureApprovalsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]pendingApproval, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		rec, err := loadApproval(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// decideApproval moves a pending approval to status under an exclusive lock
+// file, so two concurrent approve or reject calls can't both act on it.
+func decideApproval(id, status, reason string) (pendingApproval, error) {
+	path, err := approvalPath(id)
+	if err != nil {
+		return pendingApproval{}, err
+	}
+	lockPath := path + ".lock"
+	lock, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // approval id is validated
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return pendingApproval{}, usagef("approval %s is being decided by another command (remove %s if none is running)", id, lockPath)
+		}
+		return pendingApproval{}, err
+	}
+	_ = lock.Close()
+	defer func() { _ = os.Remove(lockPath) }()
+
+	rec, err := loadPendingApproval(id)
+	if err != nil {
+		return pendingApproval{}, err
+	}
+	rec.Status = status
+	rec.DecidedAt = time.Now().UTC().Format(time.RFC3339)
+	rec.Reason = reason
+	if err := saveApproval(rec); err != nil {
+		return pendingApproval{}, err
+	}
+	return rec, nil
+}
+
+func loadPendingApproval(id string) (pendingApproval, error) {
+	rec, err := loadApproval(id)
+	if err != nil {
+		return pendingApproval{}, err
+	}
+	if rec.Status != approvalStatusPending {
+		return pendingApproval{}, usagef("approval %s is already %s", rec.ID, rec.Status)
+	}
+	return rec, nil
+}
+
+type ApprovalsListCmd struct {
+	All bool `name:"all" help:"Include approved, rejected and failed requests"`
+}
+
+func (c *ApprovalsListCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	all, err := listApprovals()
+	if err != nil {
+		return err
+	}
+	items := make([]pendingApproval, 0, len(all))
+	for _, rec := range all {
+		if c.All || rec.Status == approvalStatusPending {
+			items = append(items, rec)
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"approvals": items})
+	}
+	if len(items) == 0 {
+		u.Err().Println("No pending approvals")
+		return nil
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "ID\tSTATUS\tCREATED\tACCOUNT\tOP")
+	for _, rec := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", rec.ID, rec.Status, rec.CreatedAt, rec.Account, rec.Op)
+	}
+	return nil
+}
+
+type ApprovalsShowCmd struct {
+	ID string `arg:"" name:"approvalId" help:"Approval ID"`
+}
+
+func (c *ApprovalsShowCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	rec, err := loadApproval(c.ID)
+	if err != nil {
+		return err
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"approval": rec})
+	}
+
+	u.Out().Printf("id\t%s", rec.ID)
+	u.Out().Printf("status\t%s", rec.Status)
+	u.Out().Printf("created_at\t%s", rec.CreatedAt)
+	if rec.DecidedAt != "" {
+		u.Out().Printf("decided_at\t%s", rec.DecidedAt)
+	}
+	if rec.Account != "" {
+		u.Out().Printf("account\t%s", rec.Account)
+	}
+	if rec.Client != "" {
+		u.Out().Printf("client\t%s", rec.Client)
+	}
+	u.Out().Printf("op\t%s", rec.Op)
+	u.Out().Printf("command\tgog %s", strings.Join(rec.Args, " "))
+	if rec.Request != nil {
+		if b, err := json.Marshal(rec.Request); err == nil {
+			u.Out().Printf("request_json\t%s", string(b))
+		}
+	}
+	if rec.Reason != "" {
+		u.Out().Printf("reason\t%s", rec.Reason)
+	}
+	if rec.Error != "" {
+		u.Out().Printf("error\t%s", rec.Error)
+	}
+	return nil
+}
+
+type ApprovalsApproveCmd struct {
+	ID string `arg:"" name:"approvalId" help:"Approval ID"`
+}
+
+func (c *ApprovalsApproveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	if flags != nil && flags.RequireApproval {
+		return usage("approvals cannot be approved while --require-approval is set")
+	}
+	rec, err := loadPendingApproval(c.ID)
+	if err != nil {
+		return err
+	}
+	if err := dryRunExit(ctx, flags, "approvals.approve", rec); err != nil {
+		return err
+	}
+	if err := confirmApproval(ctx, flags, rec); err != nil {
+		return err
+	}
+
+	if rec, err = decideApproval(rec.ID, approvalStatusApproved, ""); err != nil {
+		return err
+	}
+
+	replay := rec
+	runErr := execute(withInvocation(context.Background(), &invocationState{approved: &replay}), rec.replayArgs())
+	if runErr != nil && ExitCode(runErr) != 0 {
+		rec.Status = approvalStatusFailed
+		rec.Error = runErr.Error()
+		if err := saveApproval(rec); err != nil {
+			return err
+		}
+		return &ExitError{Code: ExitCode(runErr), Err: fmt.Errorf("approved request %s failed", rec.ID)}
+	}
+	return nil
+}
+
+// confirmApproval asks the person at the terminal before a held request runs.
+// There is no --force: an agent that was told to wait for approval must not be
+// able to approve its own request from a pipe.
+var confirmApproval = func(ctx context.Context, flags *RootFlags, rec pendingApproval) error {
+	if (flags != nil && flags.NoInput) || !term.IsTerminal(int(os.Stdin.Fd())) { //nolint:gosec // os file descriptor fits int on supported targets
+		return usage("approvals approve needs an interactive terminal; approving is a human step")
+	}
+	prompt := fmt.Sprintf("Approve and run %s (gog %s)? [y/N]: ", rec.Op, strings.Join(rec.Args, " "))
+	line, err := input.PromptLine(ctx, prompt)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read confirmation: %w", err)
+	}
+	if ans := strings.TrimSpace(strings.ToLower(line)); ans == "y" || ans == "yes" {
+		return nil
+	}
+	return &ExitError{Code: 1, Err: errors.New("cancelled")}
+}
+
+type ApprovalsRejectCmd struct {
+	ID     string `arg:"" name:"approvalId" help:"Approval ID"`
+	Reason string `name:"reason" help:"Optional note recorded with the rejection"`
+}
+
+func (c *ApprovalsRejectCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	rec, err := loadPendingApproval(c.ID)
+	if err != nil {
+		return err
+	}
+	if err := dryRunExit(ctx, flags, "approvals.reject", map[string]any{
+		"id":     rec.ID,
+		"reason": strings.TrimSpace(c.Reason),
+	}); err != nil {
+		return err
+	}
+
+	if rec, err = decideApproval(rec.ID, approvalStatusRejected, strings.TrimSpace(c.Reason)); err != nil {
+		return err
+	}
+	return writeResult(ctx, u,
+		kv("id", rec.ID),
+		kv("status", rec.Status),
+	)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApprovals_QueueApproveReject(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	origNew := newDriveService
+	t.Cleanup(func() { newDriveService = origNew })
+
+	var patchCount int
+	svc, closeSrv := newDriveTestService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/files/id1") {
+			http.NotFound(w, r)
+			return
+		}
+		patchCount++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "id1", "trashed": true})
+	}))
+	defer closeSrv()
+	newDriveService = stubDriveService(svc)
+
+	queue := func() string {
+		t.Helper()
+		var execErr error
+		out := captureStdout(t, func() {
+			_ = captureStderr(t, func() {
+				execErr = Execute([]string{"--json", "--force", "--require-approval", "--account", "a@b.com", "drive", "delete", "id1"})
+			})
+		})
+		if got := ExitCode(execErr); got != exitCodeApprovalRequired {
+			t.Fatalf("expected exit code %d, got %d (%v)", exitCodeApprovalRequired, got, execErr)
+		}
+		var queued struct {
+			ApprovalRequired bool   `json:"approval_required"`
+			ID               string `json:"id"`
+			Op               string `json:"op"`
+		}
+		if err := json.Unmarshal([]byte(out), &queued); err != nil {
+			t.Fatalf("unmarshal: %v (out=%q)", err, out)
+		}
+		if !queued.ApprovalRequired || queued.ID == "" || queued.Op != "trash drive file id1" {
+			t.Fatalf("unexpected queue output: %#v", queued)
+		}
+		return queued.ID
+	}
+
+	approveID := queue()
+	rejectID := queue()
+	if patchCount != 0 {
+		t.Fatalf("expected no API calls while queued, got %d", patchCount)
+	}
+
+	listOut := captureStdout(t, func() {
+		if err := Execute([]string{"--json", "approvals", "list"}); err != nil {
+			t.Fatalf("list: %v", err)
+		}
+	})
+	var listed struct {
+		Approvals []pendingApproval `json:"approvals"`
+	}
+	if err := json.Unmarshal([]byte(listOut), &listed); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if len(listed.Approvals) != 2 {
+		t.Fatalf("expected 2 pending approvals, got %#v", listed.Approvals)
+	}
+
+	if err := Execute([]string{"--require-approval", "approvals", "approve", approveID}); ExitCode(err) != 2 {
+		t.Fatalf("expected approve to be refused in approval mode, got %v", err)
+	}
+	// Without a terminal (as for an agent) approval is refused, even with --force.
+	if err := Execute([]string{"--force", "approvals", "approve", approveID}); ExitCode(err) != 2 {
+		t.Fatalf("expected non-interactive approve to be refused, got %v", err)
+	}
+	if patchCount != 0 {
+		t.Fatalf("refused approvals must not run, got %d calls", patchCount)
+	}
+
+	origConfirm := confirmApproval
+	t.Cleanup(func() { confirmApproval = origConfirm })
+	confirmApproval = func(context.Context, *RootFlags, pendingApproval) error { return nil }
+
+	_ = captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			if err := Execute([]string{"approvals", "approve", approveID}); err != nil {
+				t.Fatalf("approve: %v", err)
+			}
+		})
+	})
+	if patchCount != 1 {
+		t.Fatalf("expected stored request to run once, got %d", patchCount)
+	}
+
+	if err := Execute([]string{"approvals", "approve", approveID}); ExitCode(err) != 2 {
+		t.Fatalf("expected second approve to fail, got %v", err)
+	}
+
+	_ = captureStdout(t, func() {
+		if err := Execute([]string{"approvals", "reject", rejectID, "--reason", "nope"}); err != nil {
+			t.Fatalf("reject: %v", err)
+		}
+	})
+	rec, err := loadApproval(rejectID)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if rec.Status != approvalStatusRejected || rec.Reason != "nope" {
+		t.Fatalf("unexpected rejected record: %#v", rec)
+	}
+	if patchCount != 1 {
+		t.Fatalf("rejected request must not run, got %d calls", patchCount)
+	}
+}
+
+func TestPendingApprovalMatches(t *testing.T) {
+	rec := pendingApproval{ID: "ap_1", Op: "delete x", Request: map[string]any{"ids": []any{"a"}}}
+	if err := rec.matches("delete x", map[string]any{"ids": []string{"a"}}); err != nil {
+		t.Fatalf("expected match: %v", err)
+	}
+	if err := rec.matches("delete x", map[string]any{"ids": []string{"b"}}); err == nil {
+		t.Fatalf("expected request mismatch")
+	}
+	if err := rec.matches("delete y", map[string]any{"ids": []string{"a"}}); err == nil {
+		t.Fatalf("expected op mismatch")
+	}
+}
+
+func TestPendingApprovalIdentity(t *testing.T) {
+	rec := pendingApproval{ID: "ap_1", Account: "a@b.com", Client: "work", Args: []string{"drive", "delete", "id1"}}
+	if err := rec.matchesIdentity("A@B.com", "work"); err != nil {
+		t.Fatalf("expected identity match: %v", err)
+	}
+	if err := rec.matchesIdentity("c@d.com", "work"); err == nil {
+		t.Fatalf("expected account mismatch")
+	}
+	if err := rec.matchesIdentity("a@b.com", "default"); err == nil {
+		t.Fatalf("expected client mismatch")
+	}
+	got := strings.Join(rec.replayArgs(), " ")
+	if got != "--account a@b.com --client work drive delete id1" {
+		t.Fatalf("unexpected replay args: %q", got)
+	}
+}
@@ -14,10 +14,21 @@ import (
 )
 
 func confirmDestructive(ctx context.Context, flags *RootFlags, action string) error {
-	if err := dryRunExit(ctx, flags, action, nil); err != nil {
+	// Without a planned payload, the command's own arguments are the request,
+	// so dry-run output and queued approvals show what would be deleted.
+	var request any
+	if st := invocationFromContext(ctx); st != nil && st.plannedRequest == nil && len(st.target) > 0 {
+		request = st.target
+	}
+	if err := dryRunExit(ctx, flags, action, request); err != nil {
+		return err
+	}
+	// Approval mode wins over --force so agents cannot bypass review.
+	approved, err := requireApproval(ctx, flags, action)
+	if err != nil {
 		return err
 	}
-	if flags == nil || flags.Force {
+	if approved || flags == nil || flags.Force {
 		return nil
 	}
 
@@ -12,8 +12,10 @@ import (
 
 // dryRunExit prints the intended operation and exits successfully (exit code 0).
 // Call this from mutating commands early to avoid touching auth/keyring or making API calls.
+// Outside dry-run mode the request is remembered so --require-approval can store it.
 func dryRunExit(ctx context.Context, flags *RootFlags, op string, request any) error {
 	if flags == nil || !flags.DryRun {
+		recordPlannedRequest(ctx, request)
 		return nil
 	}
 
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/keyring"
+	ggoogleapi "google.golang.org/api/googleapi"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/errfmt"
+	gogapi "github.com/steipete/gogcli/internal/googleapi"
+	"github.com/steipete/gogcli/internal/outfmt"
+)
+
+// Error classes reported in the JSON error envelope.
+const (
+	errorClassUsage       = "usage"
+	errorClassAuth        = "auth"
+	errorClassConfig      = "config"
+	errorClassNotFound    = "notFound"
+	errorClassPermission  = "permission"
+	errorClassRateLimit   = "rateLimit"
+	errorClassConflict    = "conflict"
+	errorClassCircuitOpen = "circuitOpen"
+	errorClassRetryable   = "retryable"
+	errorClassPolicy      = "policy"
+	errorClassApproval    = "approval"
+	errorClassCancelled   = "cancelled"
+	errorClassError       = "error"
+)
+
+const errorInfoType = "type.googleapis.com/google.rpc.ErrorInfo"
+
+// exitCodeNames maps stable exit-code names to their numeric values.
+func exitCodeNames() map[string]int {
+	return map[string]int{
+		"ok":                0,
+		"error":             1,
+		"usage":             2,
+		"empty_results":     emptyResultsExitCode,
+		"auth_required":     exitCodeAuthRequired,
+		"not_found":         exitCodeNotFound,
+		"permission_denied": exitCodePermissionDenied,
+		"rate_limited":      exitCodeRateLimited,
+		"retryable":         exitCodeRetryable,
+		"config":            exitCodeConfig,
+		"policy_denied":     exitCodePolicyDenied,
+		"approval_required": exitCodeApprovalRequired,
+		"cancelled":         exitCodeCancelled,
+	}
+}
+
+// errorSchema documents the stderr error envelope for `gog agent exit-codes`.
+func errorSchema() map[string]any {
+	return map[string]any{
+		"stream": "stderr",
+		"fields": map[string]string{
+			"code":                "exit code name (see exit_codes)",
+			"exit_code":           "numeric process exit code",
+			"class":               "usage|auth|config|notFound|permission|rateLimit|conflict|circuitOpen|retryable|policy|approval|cancelled|error",
+			"message":             "human-readable message",
+			"reason":              "Google API error reason (optional)",
+			"domain":              "Google API error domain (optional)",
+			"http_status":         "HTTP status of the failed API call (optional)",
+			"retryable":           "true when retrying the same command may succeed",
+			"retry_after_seconds": "suggested wait before retrying (optional)",
+			"remediation":         "command that likely fixes the error (optional)",
+			"command":             "policy command path that denied the request (class policy)",
+			"rule":                "policy rule or pattern that matched (class policy)",
+			"arg":                 "denied argument name (class policy)",
+			"value":               "denied argument value (class policy)",
+			"policy_reason":       "why the policy denied the request (class policy)",
+		},
+		"example": map[string]any{
+			"error": map[string]any{
+				"code":        "auth_required",
+				"exit_code":   exitCodeAuthRequired,
+				"class":       errorClassAuth,
+				"message":     "auth required for drive you@example.com",
+				"retryable":   false,
+				"remediation": "gog auth add you@example.com --services drive",
+			},
+		},
+	}
+}
+
+// errorEnvelope is the machine-readable form of a failed command.
+type errorEnvelope struct {
+	Code        string `json:"code"`
+	ExitCode    int    `json:"exit_code"`
+	Class       string `json:"class"`
+	Message     string `json:"message"`
+	Reason      string `json:"reason,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	HTTPStatus  int    `json:"http_status,omitempty"`
+	Retryable   bool   `json:"retryable"`
+	RetryAfter  int    `json:"retry_after_seconds,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+
+	// Policy denials carry the matched rule.
+	Command      string `json:"command,omitempty"`
+	Rule         string `json:"rule,omitempty"`
+	Arg          string `json:"arg,omitempty"`
+	Value        string `json:"value,omitempty"`
+	PolicyReason string `json:"policy_reason,omitempty"`
+}
+
+// writeError prints err to w: a JSON envelope in JSON mode, errfmt text otherwise.
+// A non-nil redactor (--redact) masks PII in the message and policy values.
+func writeError(w io.Writer, jsonMode bool, account string, redactor *outfmt.Redactor, err error) {
+	if err == nil {
+		return
+	}
+	if !jsonMode {
+		if msg := strings.TrimSpace(errfmt.Format(err)); msg != "" {
+			_, _ = fmt.Fprintln(w, redactor.String(msg))
+		}
+		return
+	}
+
+	env := buildErrorEnvelope(err, account)
+	env.redact(redactor)
+	b, mErr := json.Marshal(map[string]any{"error": env})
+	if mErr != nil {
+		_, _ = fmt.Fprintln(w, redactor.String(errfmt.Format(err)))
+		return
+	}
+	_, _ = fmt.Fprintln(w, string(b))
+}
+
+// redact masks the free-text fields that may carry addresses or other PII.
+func (e *errorEnvelope) redact(r *outfmt.Redactor) {
+	if r == nil {
+		return
+	}
+	e.Message = r.String(e.Message)
+	e.Remediation = r.String(e.Remediation)
+	e.Rule = r.String(e.Rule)
+	e.Value = r.String(e.Value)
+	e.PolicyReason = r.String(e.PolicyReason)
+}
+
+// argsRequestJSON detects --json on a command line that failed to parse (flags are not applied then).
+func argsRequestJSON(args []string) bool {
+	for _, a := range args {
+		switch a {
+		case "--":
+			return false
+		case "--json", "--machine", "-j", "--json=true", "--machine=true":
+			return true
+		}
+	}
+
+	return false
+}
+
+func buildErrorEnvelope(err error, account string) errorEnvelope {
+	err = stableExitCode(err)
+	code := ExitCode(err)
+
+	env := errorEnvelope{
+		Code:     exitCodeName(code),
+		ExitCode: code,
+		Class:    errorClassError,
+		Message:  strings.TrimSpace(errfmt.Format(err)),
+	}
+
+	switch code {
+	case 2:
+		env.Class = errorClassUsage
+	case exitCodeAuthRequired:
+		env.Class = errorClassAuth
+	case exitCodeConfig:
+		env.Class = errorClassConfig
+	case exitCodeNotFound:
+		env.Class = errorClassNotFound
+	case exitCodePermissionDenied:
+		env.Class = errorClassPermission
+	case exitCodeRateLimited:
+		env.Class = errorClassRateLimit
+		env.Retryable = true
+	case exitCodeRetryable:
+		env.Class = errorClassRetryable
+		env.Retryable = true
+	case exitCodePolicyDenied:
+		env.Class = errorClassPolicy
+	case exitCodeApprovalRequired:
+		env.Class = errorClassApproval
+	case exitCodeCancelled:
+		env.Class = errorClassCancelled
+	}
+
+	var gerr *ggoogleapi.Error
+	if errors.As(err, &gerr) {
+		applyGoogleAPIError(&env, gerr, account)
+	}
+
+	var rlErr *gogapi.RateLimitError
+	if errors.As(err, &rlErr) {
+		env.Class = errorClassRateLimit
+		env.Retryable = true
+		if rlErr.RetryAfter > 0 {
+			env.RetryAfter = ceilSeconds(rlErr.RetryAfter)
+		}
+	}
+
+	var cbErr *gogapi.CircuitBreakerError
+	if errors.As(err, &cbErr) {
+		env.Class = errorClassCircuitOpen
+		env.Retryable = true
+		env.RetryAfter = ceilSeconds(gogapi.CircuitBreakerResetTime)
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		env.Retryable = true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		env.Retryable = true
+	}
+
+	var authErr *gogapi.AuthRequiredError
+	var credErr *config.CredentialsMissingError
+	switch {
+	case errors.As(err, &authErr):
+		env.Remediation = authAddCommand(firstNonEmpty(authErr.Email, account), authErr.Service, false)
+	case errors.As(err, &credErr):
+		env.Remediation = "gog auth credentials <credentials.json>"
+	case errors.Is(err, keyring.ErrKeyNotFound):
+		env.Remediation = authAddCommand(account, "", false)
+	}
+
+	var perr *PolicyError
+	if errors.As(err, &perr) {
+		env.Message = perr.Error()
+		env.Command = perr.Command
+		env.Rule = perr.Rule
+		env.Arg = perr.Arg
+		env.Value = perr.Value
+		env.PolicyReason = perr.Reason
+	}
+
+	return env
+}
+
+func applyGoogleAPIError(env *errorEnvelope, gerr *ggoogleapi.Error, account string) {
+	env.HTTPStatus = gerr.Code
+	if len(gerr.Errors) > 0 {
+		env.Reason = gerr.Errors[0].Reason
+	}
+
+	// Newer APIs put the canonical reason and domain in a google.rpc.ErrorInfo detail.
+	info := googleErrorInfo(gerr)
+	env.Reason = firstNonEmpty(env.Reason, info.reason)
+	env.Domain = info.domain
+
+	switch gerr.Code {
+	case 409, 412:
+		env.Class = errorClassConflict
+	case 401:
+		env.Remediation = authAddCommand(account, info.service, false)
+	case 403:
+		if isScopeReason(env.Reason) || isScopeReason(info.reason) {
+			env.Remediation = authAddCommand(account, info.service, true)
+		}
+	}
+
+	if gerr.Header != nil {
+		if secs := parseRetryAfter(gerr.Header.Get("Retry-After")); secs > 0 {
+			env.RetryAfter = secs
+		}
+	}
+}
+
+type googleErrorDetail struct {
+	reason  string
+	domain  string
+	service string
+}
+
+func googleErrorInfo(gerr *ggoogleapi.Error) googleErrorDetail {
+	var out googleErrorDetail
+	for _, d := range gerr.Details {
+		m, ok := d.(map[string]any)
+		if !ok || m["@type"] != errorInfoType {
+			continue
+		}
+		out.reason, _ = m["reason"].(string)
+		out.domain, _ = m["domain"].(string)
+		if md, ok := m["metadata"].(map[string]any); ok {
+			svc, _ := md["service"].(string)
+			out.service = strings.TrimSuffix(svc, ".googleapis.com")
+		}
+		break
+	}
+
+	return out
+}
+
+func isScopeReason(reason string) bool {
+	switch strings.ToLower(strings.TrimSpace(reason)) {
+	case "access_token_scope_insufficient", "insufficientpermissions":
+		return true
+	default:
+		return false
+	}
+}
+
+// authAddCommand renders a `gog auth add` suggestion, using placeholders for unknown parts.
+func authAddCommand(email, service string, forceConsent bool) string {
+	email = firstNonEmpty(strings.TrimSpace(email), "<email>")
+	cmd := "gog auth add " + email
+	if service = strings.TrimSpace(service); service != "" {
+		cmd += " --services " + service
+	}
+	if forceConsent {
+		cmd += " --force-consent"
+	}
+
+	return cmd
+}
+
+// parseRetryAfter accepts delta-seconds or an HTTP date.
+func parseRetryAfter(v string) int {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return max(n, 0)
+	}
+	if t, err := time.Parse(time.RFC1123, v); err == nil {
+		return ceilSeconds(time.Until(t))
+	}
+
+	return 0
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
+
+func exitCodeName(code int) string {
+	for name, c := range exitCodeNames() {
+		if c == code {
+			return name
+		}
+	}
+
+	return "error"
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	ggoogleapi "google.golang.org/api/googleapi"
+
+	"github.com/steipete/gogcli/internal/config"
+	gogapi "github.com/steipete/gogcli/internal/googleapi"
+)
+
+func TestBuildErrorEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errorEnvelope
+	}{
+		{
+			name: "usage",
+			err:  usage("missing id"),
+			want: errorEnvelope{Code: "usage", ExitCode: 2, Class: errorClassUsage},
+		},
+		{
+			name: "auth",
+			err:  fmt.Errorf("wrap: %w", &gogapi.AuthRequiredError{Service: "drive", Email: "a@b.com"}),
+			want: errorEnvelope{Code: "auth_required", ExitCode: exitCodeAuthRequired, Class: errorClassAuth, Remediation: "gog auth add a@b.com --services drive"},
+		},
+		{
+			name: "credentials",
+			err:  &config.CredentialsMissingError{Path: "/x/credentials.json"},
+			want: errorEnvelope{Code: "config", ExitCode: exitCodeConfig, Class: errorClassConfig, Remediation: "gog auth credentials <credentials.json>"},
+		},
+		{
+			name: "rate limit",
+			err: &ggoogleapi.Error{
+				Code:   429,
+				Errors: []ggoogleapi.ErrorItem{{Reason: "rateLimitExceeded"}},
+				Header: http.Header{"Retry-After": []string{"12"}},
+			},
+			want: errorEnvelope{Code: "rate_limited", ExitCode: exitCodeRateLimited, Class: errorClassRateLimit, Reason: "rateLimitExceeded", HTTPStatus: 429, Retryable: true, RetryAfter: 12},
+		},
+		{
+			name: "scope",
+			err: &ggoogleapi.Error{
+				Code: 403,
+				Details: []any{map[string]any{
+					"@type":    errorInfoType,
+					"reason":   "ACCESS_TOKEN_SCOPE_INSUFFICIENT",
+					"domain":   "googleapis.com",
+					"metadata": map[string]any{"service": "gmail.googleapis.com"},
+				}},
+			},
+			want: errorEnvelope{
+				Code: "permission_denied", ExitCode: exitCodePermissionDenied, Class: errorClassPermission,
+				Reason: "ACCESS_TOKEN_SCOPE_INSUFFICIENT", Domain: "googleapis.com", HTTPStatus: 403,
+				Remediation: "gog auth add a@b.com --services gmail --force-consent",
+			},
+		},
+		{
+			name: "conflict",
+			err:  &ggoogleapi.Error{Code: 409, Errors: []ggoogleapi.ErrorItem{{Reason: "conflict"}}},
+			want: errorEnvelope{Code: "error", ExitCode: 1, Class: errorClassConflict, Reason: "conflict", HTTPStatus: 409},
+		},
+		{
+			name: "circuit open",
+			err:  &gogapi.CircuitBreakerError{},
+			want: errorEnvelope{Code: "retryable", ExitCode: exitCodeRetryable, Class: errorClassCircuitOpen, Retryable: true, RetryAfter: 30},
+		},
+		{
+			name: "policy",
+			err:  &ExitError{Code: exitCodePolicyDenied, Err: &PolicyError{Command: "gmail send", Rule: "*@corp.com", Arg: "to", Value: "bob@example.com", Reason: "value does not match any allow pattern"}},
+			want: errorEnvelope{
+				Code: "policy_denied", ExitCode: exitCodePolicyDenied, Class: errorClassPolicy,
+				Command: "gmail send", Rule: "*@corp.com", Arg: "to", Value: "bob@example.com",
+				PolicyReason: "value does not match any allow pattern",
+			},
+		},
+		{
+			name: "generic",
+			err:  errors.New("boom"),
+			want: errorEnvelope{Code: "error", ExitCode: 1, Class: errorClassError},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := buildErrorEnvelope(tc.err, "a@b.com")
+			if got.Message == "" {
+				t.Fatalf("expected message")
+			}
+			got.Message = ""
+			if got != tc.want {
+				t.Fatalf("unexpected envelope:\n got %#v\nwant %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7 {
+		t.Fatalf("expected 7, got %d", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Fatalf("expected 0, got %d", got)
+	}
+}
+
+func TestExecute_JSONErrorEnvelope(t *testing.T) {
+	var err error
+	errOut := captureStderr(t, func() {
+		err = Execute([]string{"--json", "drive", "nope-not-a-command"})
+	})
+	if ExitCode(err) != 2 {
+		t.Fatalf("expected usage exit code, got %v", err)
+	}
+
+	var doc struct {
+		Error errorEnvelope `json:"error"`
+	}
+	if uErr := json.Unmarshal([]byte(errOut), &doc); uErr != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", uErr, errOut)
+	}
+	if doc.Error.Class != errorClassUsage || doc.Error.Code != "usage" || doc.Error.Message == "" {
+		t.Fatalf("unexpected envelope: %#v", doc.Error)
+	}
+}
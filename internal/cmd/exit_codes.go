@@ -25,6 +25,8 @@ const (
 	exitCodeRateLimited      = 7
 	exitCodeRetryable        = 8
 	exitCodeConfig           = 10
+	exitCodePolicyDenied     = 11
+	exitCodeApprovalRequired = 12
 
 	// 130 is the conventional "interrupted" exit code (SIGINT / Ctrl-C).
 	exitCodeCancelled = 130
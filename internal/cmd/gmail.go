@@ -19,13 +19,19 @@ import (
 var newGmailService = googleapi.NewGmail
 
 type GmailCmd struct {
-	Search     GmailSearchCmd     `cmd:"" name:"search" aliases:"find,query,ls,list" group:"Read" help:"Search threads using Gmail query syntax"`
-	Messages   GmailMessagesCmd   `cmd:"" name:"messages" aliases:"message,msg,msgs" group:"Read" help:"Message operations"`
-	Thread     GmailThreadCmd     `cmd:"" name:"thread" aliases:"threads,read" group:"Organize" help:"Thread operations (get, modify)"`
-	Get        GmailGetCmd        `cmd:"" name:"get" aliases:"info,show" group:"Read" help:"Get a message (full|metadata|raw)"`
-	Attachment GmailAttachmentCmd `cmd:"" name:"attachment" group:"Read" help:"Download a single attachment"`
-	URL        GmailURLCmd        `cmd:"" name:"url" group:"Read" help:"Print Gmail web URLs for threads"`
-	History    GmailHistoryCmd    `cmd:"" name:"history" group:"Read" help:"Gmail history"`
+	Search      GmailSearchCmd      `cmd:"" name:"search" aliases:"find,query,ls,list" group:"Read" help:"Search threads using Gmail query syntax"`
+	Messages    GmailMessagesCmd    `cmd:"" name:"messages" aliases:"message,msg,msgs" group:"Read" help:"Message operations"`
+	Thread      GmailThreadCmd      `cmd:"" name:"thread" aliases:"threads,read" group:"Organize" help:"Thread operations (get, modify)"`
+	Get         GmailGetCmd         `cmd:"" name:"get" aliases:"info,show" group:"Read" help:"Get a message (full|metadata|raw)"`
+	Export      GmailExportCmd      `cmd:"" name:"export" group:"Read" help:"Export messages as .eml files or an mbox"`
+	Import      GmailImportCmd      `cmd:"" name:"import" group:"Write" help:"Import an mbox file or Maildir into Gmail"`
+	Sync        GmailSyncCmd        `cmd:"" name:"sync" group:"Read" help:"Mirror the mailbox into a local Maildir (incremental via history)"`
+	LocalSearch GmailLocalSearchCmd `cmd:"" name:"local-search" aliases:"lsearch" group:"Read" help:"Search a gmail sync mirror offline (subset of Gmail query syntax)"`
+	Attachment  GmailAttachmentCmd  `cmd:"" name:"attachment" group:"Read" help:"Download a single attachment"`
+	Attachments GmailAttachmentsCmd `cmd:"" name:"attachments" group:"Read" help:"Bulk attachment downloads"`
+	URL         GmailURLCmd         `cmd:"" name:"url" group:"Read" help:"Print Gmail web URLs for threads"`
+	History     GmailHistoryCmd     `cmd:"" name:"history" group:"Read" help:"Gmail history"`
+	Stats       GmailStatsCmd       `cmd:"" name:"stats" group:"Read" help:"Mailbox analytics: senders, volume, thread lengths, response times"`
 
 	Labels  GmailLabelsCmd   `cmd:"" name:"labels" aliases:"label" group:"Organize" help:"Label operations"`
 	Batch   GmailBatchCmd    `cmd:"" name:"batch" group:"Organize" help:"Batch operations"`
@@ -34,11 +40,16 @@ type GmailCmd struct {
 	Unread  GmailUnreadCmd   `cmd:"" name:"unread" aliases:"mark-unread" group:"Organize" help:"Mark messages as unread"`
 	Trash   GmailTrashMsgCmd `cmd:"" name:"trash" group:"Organize" help:"Move messages to trash"`
 
+	Unsubscribe GmailUnsubscribeCmd `cmd:"" name:"unsubscribe" group:"Organize" help:"Unsubscribe from mailing lists (List-Unsubscribe one-click or mailto)"`
+	Rules       GmailRulesCmd       `cmd:"" name:"rules" group:"Organize" help:"Client-side mail rules run by gmail watch (test against a message)"`
+
 	Send   GmailSendCmd   `cmd:"" name:"send" group:"Write" help:"Send an email"`
+	Merge  GmailMergeCmd  `cmd:"" name:"merge" group:"Write" help:"Send personalized emails from a CSV or Sheet (mail merge)"`
 	Track  GmailTrackCmd  `cmd:"" name:"track" group:"Write" help:"Email open tracking"`
 	Drafts GmailDraftsCmd `cmd:"" name:"drafts" aliases:"draft" group:"Write" help:"Draft operations"`
 
 	Settings GmailSettingsCmd `cmd:"" name:"settings" group:"Admin" help:"Settings and admin"`
+	Config   GmailConfigCmd   `cmd:"" name:"config" group:"Admin" help:"Declarative labels, filters, send-as, forwarding and vacation (plan/apply)"`
 
 	// Kept for backwards-compatibility; hidden from default help.
 	Watch       GmailWatchCmd       `cmd:"" name:"watch" hidden:"" help:"Manage Gmail watch"`
@@ -0,0 +1,612 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type GmailAttachmentsCmd struct {
+	Download GmailAttachmentsDownloadCmd `cmd:"" name:"download" aliases:"dl,harvest" help:"Download attachments from every message matching a query"`
+}
+
+const attachmentManifestName = ".gog-attachments.jsonl"
+
+type GmailAttachmentsDownloadCmd struct {
+	Query         string   `name:"query" short:"q" required:"" help:"Gmail search query (has:attachment is added)"`
+	Out           string   `name:"out" aliases:"output,dir" required:"" help:"Directory to save attachments into"`
+	Max           int64    `name:"max" aliases:"limit" help:"Max messages to scan" default:"500"`
+	All           bool     `name:"all" help:"Scan every matching message (ignores --max)"`
+	MimeType      []string `name:"mime-type" aliases:"type" sep:"," help:"Only these MIME types; image/* style wildcards allowed (comma-separated)"`
+	Ext           []string `name:"ext" sep:"," help:"Only these file extensions, e.g. pdf,xlsx (comma-separated)"`
+	MinSize       string   `name:"min-size" help:"Skip attachments smaller than this (e.g. 10KB)"`
+	MaxSize       string   `name:"max-size" help:"Skip attachments larger than this (e.g. 25MB)"`
+	IncludeInline bool     `name:"include-inline" help:"Also save inline images (signature logos and the like)"`
+	NameTemplate  string   `name:"name-template" aliases:"template" help:"File name template: {date} {datetime} {from} {domain} {subject} {filename} {basename} {ext} {messageId} {threadId}; / makes subfolders" default:"{date}_{from}_{filename}"`
+	Concurrency   int      `name:"concurrency" help:"Parallel message downloads" default:"4"`
+	Timezone      string   `name:"timezone" short:"z" help:"Timezone for {date}/{datetime} (IANA name). Default: local"`
+	Local         bool     `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+}
+
+var attachmentTemplateFieldRe = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+var attachmentTemplateFields = map[string]bool{
+	"date": true, "datetime": true, "from": true, "domain": true, "subject": true,
+	"filename": true, "basename": true, "ext": true, "messageId": true, "threadId": true,
+}
+
+type attachmentHarvestFilter struct {
+	mimeTypes []string
+	exts      map[string]bool
+	minSize   int64
+	maxSize   int64
+	inline    bool
+}
+
+// harvestPart is a file part of a message. Small attachments come inline in
+// the message body instead of by attachment ID.
+type harvestPart struct {
+	attachmentInfo
+	PartID string
+	Data   string
+	Inline bool
+}
+
+type harvestedFile struct {
+	MessageID string `json:"messageId"`
+	Filename  string `json:"filename"`
+	MimeType  string `json:"mimeType,omitempty"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+}
+
+const (
+	harvestSaved     = "saved"
+	harvestDuplicate = "duplicate"
+)
+
+func (c *GmailAttachmentsDownloadCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	query := strings.TrimSpace(c.Query)
+	if query == "" {
+		return usage("empty --query")
+	}
+	if !strings.Contains(strings.ToLower(query), "has:attachment") {
+		query += " has:attachment"
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be at least 1")
+	}
+	filter, err := c.filter()
+	if err != nil {
+		return err
+	}
+	template := strings.TrimSpace(c.NameTemplate)
+	for _, m := range attachmentTemplateFieldRe.FindAllStringSubmatch(template, -1) {
+		if !attachmentTemplateFields[m[1]] {
+			return usagef("unknown placeholder {%s} in --name-template", m[1])
+		}
+	}
+	if !strings.Contains(template, "{filename}") && !strings.Contains(template, "{ext}") {
+		return usage("--name-template must include {filename} or {ext}")
+	}
+	loc, err := resolveOutputLocation(c.Timezone, c.Local)
+	if err != nil {
+		return err
+	}
+	outDir, err := config.ExpandPath(strings.TrimSpace(c.Out))
+	if err != nil {
+		return err
+	}
+	limit := c.Max
+	if c.All || limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	if err := dryRunExit(ctx, flags, "gmail.attachments.download", map[string]any{
+		"query":    query,
+		"out":      outDir,
+		"max":      c.Max,
+		"all":      c.All,
+		"template": template,
+		"mimeType": filter.mimeTypes,
+		"ext":      c.Ext,
+		"minSize":  filter.minSize,
+		"maxSize":  filter.maxSize,
+	}); err != nil {
+		return err
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	ids, err := searchMessageIDs(ctx, svc, query, limit)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		return err
+	}
+	manifest, err := openAttachmentManifest(filepath.Join(outDir, attachmentManifestName))
+	if err != nil {
+		return err
+	}
+	defer manifest.Close()
+
+	h := &attachmentHarvester{
+		svc:      svc,
+		outDir:   outDir,
+		template: template,
+		filter:   filter,
+		loc:      loc,
+		manifest: manifest,
+		// Filters are part of the message key, so rerunning with wider
+		// filters revisits messages finished under narrower ones.
+		filterKey: c.filterSignature(template),
+	}
+	files, resumed, err := h.run(ctx, u, ids, c.Concurrency)
+
+	saved, duplicates := 0, 0
+	var totalBytes int64
+	for _, f := range files {
+		if f.Status == harvestSaved {
+			saved++
+			totalBytes += f.Size
+		} else {
+			duplicates++
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w (%d saved so far; rerun to resume)", err, saved)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if files == nil {
+			files = []harvestedFile{}
+		}
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"query":      query,
+			"dir":        outDir,
+			"messages":   len(ids),
+			"resumed":    resumed,
+			"saved":      saved,
+			"duplicates": duplicates,
+			"bytes":      totalBytes,
+			"files":      files,
+			"manifest":   manifest.path,
+		})
+	}
+	u.Out().Printf("messages\t%d", len(ids))
+	u.Out().Printf("resumed\t%d", resumed)
+	u.Out().Printf("saved\t%d", saved)
+	u.Out().Printf("duplicates\t%d", duplicates)
+	u.Out().Printf("bytes\t%s", formatBytes(totalBytes))
+	u.Out().Printf("dir\t%s", outDir)
+	return nil
+}
+
+func (c *GmailAttachmentsDownloadCmd) filter() (attachmentHarvestFilter, error) {
+	f := attachmentHarvestFilter{inline: c.IncludeInline, maxSize: -1}
+	for _, m := range c.MimeType {
+		if m = strings.ToLower(strings.TrimSpace(m)); m != "" {
+			f.mimeTypes = append(f.mimeTypes, m)
+		}
+	}
+	for _, e := range c.Ext {
+		if e = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(e), ".")); e != "" {
+			if f.exts == nil {
+				f.exts = map[string]bool{}
+			}
+			f.exts[e] = true
+		}
+	}
+	var err error
+	if strings.TrimSpace(c.MinSize) != "" {
+		if f.minSize, err = parseByteSize(c.MinSize); err != nil {
+			return f, usagef("--min-size: %v", err)
+		}
+	}
+	if strings.TrimSpace(c.MaxSize) != "" {
+		if f.maxSize, err = parseByteSize(c.MaxSize); err != nil {
+			return f, usagef("--max-size: %v", err)
+		}
+		if f.maxSize < f.minSize {
+			return f, usage("--max-size is smaller than --min-size")
+		}
+	}
+	return f, nil
+}
+
+func (c *GmailAttachmentsDownloadCmd) filterSignature(template string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.Join(c.MimeType, ","), strings.Join(c.Ext, ","), c.MinSize, c.MaxSize,
+		strconv.FormatBool(c.IncludeInline), template,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (f attachmentHarvestFilter) match(p harvestPart) bool {
+	if p.Inline && !f.inline {
+		return false
+	}
+	if len(f.mimeTypes) > 0 {
+		mimeType := normalizeMimeType(p.MimeType)
+		ok := false
+		for _, want := range f.mimeTypes {
+			if prefix, wildcard := strings.CutSuffix(want, "/*"); wildcard {
+				ok = strings.HasPrefix(mimeType, prefix+"/")
+			} else {
+				ok = mimeType == want
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.exts != nil && !f.exts[strings.ToLower(strings.TrimPrefix(filepath.Ext(p.Filename), "."))] {
+		return false
+	}
+	if p.Size < f.minSize {
+		return false
+	}
+	return f.maxSize < 0 || p.Size <= f.maxSize
+}
+
+// parseByteSize reads sizes like "512", "10KB", "1.5 MB" or "2g" (binary
+// units, as Gmail reports sizes).
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	num := strings.TrimRight(s, "KMGTBI ")
+	unit := strings.TrimSpace(s[len(num):])
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	mult := map[string]float64{"": 1, "B": 1, "K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10, "M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20, "G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30}[unit]
+	if mult == 0 {
+		return 0, fmt.Errorf("invalid size unit %q", unit)
+	}
+	return int64(n * mult), nil
+}
+
+func collectHarvestParts(p *gmail.MessagePart) []harvestPart {
+	if p == nil {
+		return nil
+	}
+	var out []harvestPart
+	isFile := p.Filename != "" || (p.Body != nil && p.Body.AttachmentId != "")
+	if isFile && len(p.Parts) == 0 && p.Body != nil && (p.Body.AttachmentId != "" || p.Body.Data != "") {
+		cid := strings.TrimSpace(headerValue(p, "Content-ID"))
+		disposition := strings.ToLower(strings.TrimSpace(headerValue(p, "Content-Disposition")))
+		part := harvestPart{
+			attachmentInfo: attachmentInfo{
+				Filename:     firstNonEmpty(strings.TrimSpace(p.Filename), "attachment"),
+				Size:         p.Body.Size,
+				MimeType:     p.MimeType,
+				AttachmentID: p.Body.AttachmentId,
+			},
+			PartID: p.PartId,
+			Data:   p.Body.Data,
+			Inline: cid != "" && !strings.HasPrefix(disposition, "attachment"),
+		}
+		out = append(out, part)
+	}
+	for _, child := range p.Parts {
+		out = append(out, collectHarvestParts(child)...)
+	}
+	return out
+}
+
+type attachmentHarvester struct {
+	svc       *gmail.Service
+	outDir    string
+	template  string
+	filter    attachmentHarvestFilter
+	loc       *time.Location
+	manifest  *attachmentManifest
+	filterKey string
+
+	mu    sync.Mutex
+	files []harvestedFile
+}
+
+func (h *attachmentHarvester) run(ctx context.Context, u *ui.UI, ids []string, concurrency int) ([]harvestedFile, int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		firstErr error
+		resumed  int
+		done     int
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, concurrency)
+	)
+	fail := func(err error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	for _, id := range ids {
+		if h.manifest.Done(h.messageKey(id)) {
+			resumed++
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			n, err := h.harvestMessage(ctx, id)
+			if err != nil {
+				fail(fmt.Errorf("message %s: %w", id, err))
+				return
+			}
+			h.mu.Lock()
+			done++
+			progress := done
+			h.mu.Unlock()
+			if n > 0 && !outfmt.IsJSON(ctx) {
+				u.Err().Printf("[%d/%d] %s: %d attachment(s)", progress, len(ids)-resumed, id, n)
+			}
+		}(id)
+	}
+	wg.Wait()
+	return h.files, resumed, firstErr
+}
+
+func (h *attachmentHarvester) messageKey(id string) string {
+	return "msg:" + id + ":" + h.filterKey
+}
+
+func (h *attachmentHarvester) harvestMessage(ctx context.Context, id string) (int, error) {
+	msg, err := h.svc.Users.Messages.Get("me", id).Format("full").
+		Fields("id,threadId,internalDate,payload").Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, part := range collectHarvestParts(msg.Payload) {
+		if !h.filter.match(part) {
+			continue
+		}
+		key := id + "/" + part.PartID
+		if h.manifest.Done(key) {
+			continue
+		}
+		var data []byte
+		if part.AttachmentID != "" {
+			data, err = fetchAttachmentBytes(ctx, h.svc, id, part.AttachmentID)
+		} else {
+			data, err = decodeBase64URLBytes(part.Data)
+		}
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", part.Filename, err)
+		}
+		if err := h.save(msg, part, key, data); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, h.manifest.Record(attachmentManifestEntry{Key: h.messageKey(id)})
+}
+
+// save writes data under the template name unless identical content was
+// already saved, in which case the file is recorded as a duplicate.
+func (h *attachmentHarvester) save(msg *gmail.Message, part harvestPart, key string, data []byte) error {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file := harvestedFile{
+		MessageID: msg.Id,
+		Filename:  part.Filename,
+		MimeType:  part.MimeType,
+		Size:      int64(len(data)),
+		SHA256:    hash,
+	}
+	if existing := h.manifest.PathFor(hash); existing != "" {
+		if _, err := os.Stat(filepath.Join(h.outDir, existing)); err == nil {
+			file.Path = filepath.Join(h.outDir, existing)
+			file.Status = harvestDuplicate
+			h.files = append(h.files, file)
+			return h.manifest.Record(attachmentManifestEntry{Key: key, SHA256: hash, Path: existing})
+		}
+	}
+
+	rel := h.uniquePath(renderAttachmentName(h.template, msg, part, h.loc))
+	if err := writeFileAtomic(filepath.Join(h.outDir, rel), data); err != nil {
+		return err
+	}
+	file.Path = filepath.Join(h.outDir, rel)
+	file.Status = harvestSaved
+	h.files = append(h.files, file)
+	return h.manifest.Record(attachmentManifestEntry{Key: key, SHA256: hash, Path: rel})
+}
+
+// uniquePath appends " (2)", " (3)", ... when a different file already has
+// the name.
+func (h *attachmentHarvester) uniquePath(rel string) string {
+	ext := path.Ext(rel)
+	base := strings.TrimSuffix(rel, ext)
+	candidate := rel
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(h.outDir, filepath.FromSlash(candidate))); errors.Is(err, os.ErrNotExist) {
+			return filepath.FromSlash(candidate)
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
+
+var attachmentNameUnsafeRe = regexp.MustCompile(`[\x00-\x1f/\\:*?"<>|]+`)
+
+func sanitizeAttachmentNamePart(s string) string {
+	s = attachmentNameUnsafeRe.ReplaceAllString(strings.TrimSpace(s), "_")
+	s = strings.Trim(s, " .")
+	if len(s) > 100 {
+		s = s[:100]
+	}
+	return s
+}
+
+// renderAttachmentName fills the template. Values are sanitized, so only "/"
+// written in the template itself creates subfolders.
+func renderAttachmentName(template string, msg *gmail.Message, part harvestPart, loc *time.Location) string {
+	when := time.UnixMilli(msg.InternalDate).In(loc)
+	from := headerValue(msg.Payload, "From")
+	sender, domain := from, ""
+	if addr, err := mail.ParseAddress(from); err == nil {
+		sender = addr.Address
+	}
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at+1:]
+	}
+	filename := sanitizeAttachmentFilename(part.Filename, "attachment")
+	ext := strings.TrimPrefix(path.Ext(filename), ".")
+	values := map[string]string{
+		"date":      when.Format("2006-01-02"),
+		"datetime":  when.Format("2006-01-02_150405"),
+		"from":      sender,
+		"domain":    domain,
+		"subject":   headerValue(msg.Payload, "Subject"),
+		"filename":  filename,
+		"basename":  strings.TrimSuffix(filename, path.Ext(filename)),
+		"ext":       ext,
+		"messageId": msg.Id,
+		"threadId":  msg.ThreadId,
+	}
+	var segments []string
+	for _, segment := range strings.Split(template, "/") {
+		rendered := attachmentTemplateFieldRe.ReplaceAllStringFunc(segment, func(m string) string {
+			return sanitizeAttachmentNamePart(values[m[1:len(m)-1]])
+		})
+		rendered = strings.Trim(rendered, " .")
+		if rendered != "" {
+			segments = append(segments, rendered)
+		}
+	}
+	if len(segments) == 0 {
+		return filename
+	}
+	return strings.Join(segments, "/")
+}
+
+// attachmentManifest is an append-only JSONL log in the output directory. It
+// records finished messages and attachments for resuming and the content hash
+// of every saved file for deduplication.
+type attachmentManifest struct {
+	path   string
+	mu     sync.Mutex
+	f      *os.File
+	done   map[string]bool
+	hashes map[string]string
+}
+
+type attachmentManifestEntry struct {
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256,omitempty"`
+	Path   string `json:"path,omitempty"`
+}
+
+func openAttachmentManifest(path string) (*attachmentManifest, error) {
+	m := &attachmentManifest{path: path, done: map[string]bool{}, hashes: map[string]string{}}
+	if b, err := os.ReadFile(path); err == nil { //nolint:gosec // user-provided output dir
+		for _, line := range bytes.Split(b, []byte("\n")) {
+			var e attachmentManifestEntry
+			if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &e) != nil || e.Key == "" {
+				// A torn last line from an interrupted run is ignored.
+				continue
+			}
+			m.done[e.Key] = true
+			if e.SHA256 != "" && e.Path != "" && m.hashes[e.SHA256] == "" {
+				m.hashes[e.SHA256] = e.Path
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read attachment manifest: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // user-provided output dir
+	if err != nil {
+		return nil, fmt.Errorf("open attachment manifest: %w", err)
+	}
+	m.f = f
+	return m, nil
+}
+
+func (m *attachmentManifest) Done(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.done[key]
+}
+
+func (m *attachmentManifest) PathFor(hash string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hashes[hash]
+}
+
+func (m *attachmentManifest) Record(e attachmentManifestEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.done[e.Key] = true
+	if e.SHA256 != "" && e.Path != "" && m.hashes[e.SHA256] == "" {
+		m.hashes[e.SHA256] = e.Path
+	}
+	if _, err := m.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("write attachment manifest: %w", err)
+	}
+	return nil
+}
+
+func (m *attachmentManifest) Close() error {
+	return m.f.Close()
+}
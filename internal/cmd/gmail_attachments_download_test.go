@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"512":    512,
+		"10KB":   10 << 10,
+		"1.5 MB": 3 << 19,
+		"2g":     2 << 30,
+		"3MiB":   3 << 20,
+	}
+	for in, want := range cases {
+		got, err := parseByteSize(in)
+		if err != nil || got != want {
+			t.Fatalf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1", "10XB"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
+
+func TestGmailAttachmentsDownload(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	invoice := []byte("%PDF invoice 42")
+	message := func(id, from string, parts ...map[string]any) map[string]any {
+		return map[string]any{
+			"id":           id,
+			"threadId":     "t" + id,
+			"internalDate": "1767268800000", // 2026-01-01T12:00:00Z
+			"payload": map[string]any{
+				"mimeType": "multipart/mixed",
+				"headers":  []map[string]any{{"name": "From", "value": from}, {"name": "Subject", "value": "Invoice"}},
+				"parts":    parts,
+			},
+		}
+	}
+	pdf := func(partID, name, attID string) map[string]any {
+		return map[string]any{
+			"partId": partID, "mimeType": "application/pdf", "filename": name,
+			"body": map[string]any{"attachmentId": attID, "size": len(invoice)},
+		}
+	}
+	messages := map[string]map[string]any{
+		"m1": message("m1", "Billing <billing@vendor.com>",
+			map[string]any{"partId": "0", "mimeType": "text/plain", "body": map[string]any{"data": "aGk", "size": 2}},
+			pdf("1", "invoice.pdf", "a1"),
+			map[string]any{
+				"partId": "2", "mimeType": "image/png", "filename": "logo.png",
+				"headers": []map[string]any{{"name": "Content-ID", "value": "<logo>"}},
+				"body":    map[string]any{"data": base64.RawURLEncoding.EncodeToString([]byte("png")), "size": 3},
+			},
+			map[string]any{
+				"partId": "3", "mimeType": "text/csv", "filename": "usage.csv",
+				"body": map[string]any{"data": base64.RawURLEncoding.EncodeToString([]byte("a,b\n")), "size": 4},
+			},
+		),
+		// Same invoice forwarded again: identical content, different message.
+		"m2": message("m2", "billing@vendor.com", pdf("1", "invoice copy.pdf", "a2")),
+	}
+
+	var (
+		mu     sync.Mutex
+		gets   []string
+		attGet []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case path == "/messages":
+			if !strings.Contains(r.URL.Query().Get("q"), "has:attachment") {
+				t.Errorf("query missing has:attachment: %q", r.URL.Query().Get("q"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}, {"id": "m2"}}})
+		case strings.Contains(path, "/attachments/"):
+			attGet = append(attGet, path)
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": base64.URLEncoding.EncodeToString(invoice), "size": len(invoice)})
+		case strings.HasPrefix(path, "/messages/"):
+			id := strings.TrimPrefix(path, "/messages/")
+			gets = append(gets, id)
+			if msg, ok := messages[id]; ok {
+				_ = json.NewEncoder(w).Encode(msg)
+				return
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	outDir := filepath.Join(t.TempDir(), "invoices")
+	args := []string{
+		"--json", "--account", "me@example.com", "gmail", "attachments", "download",
+		"--query", "from:billing@vendor.com", "--out", outDir,
+		"--ext", "pdf,csv", "--min-size", "4", "--timezone", "UTC", "--concurrency", "1",
+	}
+
+	out := captureStdout(t, func() {
+		if execErr := Execute(args); execErr != nil {
+			t.Fatalf("download: %v", execErr)
+		}
+	})
+	var parsed struct {
+		Saved      int             `json:"saved"`
+		Duplicates int             `json:"duplicates"`
+		Resumed    int             `json:"resumed"`
+		Files      []harvestedFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	if parsed.Saved != 2 || parsed.Duplicates != 1 {
+		t.Fatalf("unexpected counts: %+v", parsed)
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatalf("read out dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	want := []string{".gog-attachments.jsonl", "2026-01-01_billing@vendor.com_invoice.pdf", "2026-01-01_billing@vendor.com_usage.csv"}
+	if strings.Join(names, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected files:\n%s", strings.Join(names, "\n"))
+	}
+	if b, _ := os.ReadFile(filepath.Join(outDir, want[1])); string(b) != string(invoice) {
+		t.Fatalf("unexpected invoice content: %q", b)
+	}
+
+	// A rerun resumes from the manifest and fetches nothing.
+	gets, attGet = nil, nil
+	out = captureStdout(t, func() {
+		if execErr := Execute(args); execErr != nil {
+			t.Fatalf("rerun: %v", execErr)
+		}
+	})
+	parsed.Files = nil
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("unmarshal rerun: %v", err)
+	}
+	if parsed.Resumed != 2 || parsed.Saved != 0 || len(gets) != 0 || len(attGet) != 0 {
+		t.Fatalf("rerun was not resumed: %+v gets=%v attachments=%v", parsed, gets, attGet)
+	}
+
+	if err := Execute([]string{"--account", "me@example.com", "gmail", "attachments", "download", "-q", "x", "--out", outDir, "--name-template", "{bogus}_{filename}"}); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for unknown placeholder, got %v", err)
+	}
+}
+
+func TestRenderAttachmentNameSubdirs(t *testing.T) {
+	msg := &gmail.Message{
+		Id:           "m1",
+		InternalDate: 1767268800000,
+		Payload: &gmail.MessagePart{Headers: []*gmail.MessagePartHeader{
+			{Name: "From", Value: "Acme <ar@acme.com>"},
+			{Name: "Subject", Value: "Re: Q1/Q2 invoice"},
+		}},
+	}
+	part := harvestPart{attachmentInfo: attachmentInfo{Filename: "../../etc/passwd.pdf"}}
+	got := renderAttachmentName("{domain}/{subject}/{basename}.{ext}", msg, part, time.UTC)
+	if got != "acme.com/Re_ Q1_Q2 invoice/passwd.pdf" {
+		t.Fatalf("unexpected name: %q", got)
+	}
+}
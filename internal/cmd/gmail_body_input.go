@@ -35,3 +35,28 @@ func resolveBodyInput(body, bodyFile string) (string, error) {
 	}
 	return string(b), nil
 }
+
+// resolveComposeBodies returns the plain-text and HTML bodies. A Markdown body
+// (--body-markdown or --body-md-file) renders both and excludes the other body flags.
+func resolveComposeBodies(body, bodyFile, bodyHTML, markdown, markdownFile string) (string, string, error) {
+	hasMarkdown := strings.TrimSpace(markdown) != "" || strings.TrimSpace(markdownFile) != ""
+	if !hasMarkdown {
+		plain, err := resolveBodyInput(body, bodyFile)
+		return plain, bodyHTML, err
+	}
+
+	if strings.TrimSpace(body) != "" || strings.TrimSpace(bodyFile) != "" || strings.TrimSpace(bodyHTML) != "" {
+		return "", "", usage("use --body-markdown/--body-md-file instead of --body, --body-file, or --body-html")
+	}
+	if strings.TrimSpace(markdown) != "" && strings.TrimSpace(markdownFile) != "" {
+		return "", "", usage("use only one of --body-markdown or --body-md-file")
+	}
+
+	src, err := resolveBodyInput(markdown, markdownFile)
+	if err != nil {
+		return "", "", err
+	}
+	plain, htmlBody := renderMarkdownEmail(src)
+
+	return plain, htmlBody, nil
+}
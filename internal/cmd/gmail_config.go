@@ -0,0 +1,919 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type GmailConfigCmd struct {
+	Plan  GmailConfigPlanCmd  `cmd:"" name:"plan" aliases:"diff" help:"Show the changes needed to match a config file"`
+	Apply GmailConfigApplyCmd `cmd:"" name:"apply" help:"Apply a config file to the mailbox"`
+}
+
+// gmailConfigFile is the declarative mailbox config read from YAML. Sections
+// left out of the file are not managed; resources missing from a section are
+// only deleted with --prune.
+type gmailConfigFile struct {
+	Labels     []gmailConfigLabel     `yaml:"labels"`
+	Filters    []gmailConfigFilter    `yaml:"filters"`
+	SendAs     []gmailConfigSendAs    `yaml:"sendAs"`
+	Forwarding *gmailConfigForwarding `yaml:"forwarding"`
+	Vacation   *gmailConfigVacation   `yaml:"vacation"`
+
+	hasLabels, hasFilters, hasSendAs bool
+}
+
+type gmailConfigLabel struct {
+	Name                  string             `yaml:"name"`
+	Color                 *gmailConfigColor  `yaml:"color"`
+	LabelListVisibility   string             `yaml:"labelListVisibility"`
+	MessageListVisibility string             `yaml:"messageListVisibility"`
+	Children              []gmailConfigLabel `yaml:"children"`
+}
+
+type gmailConfigColor struct {
+	Background string `yaml:"background"`
+	Text       string `yaml:"text"`
+}
+
+type gmailConfigFilter struct {
+	From           string   `yaml:"from"`
+	To             string   `yaml:"to"`
+	Subject        string   `yaml:"subject"`
+	Query          string   `yaml:"query"`
+	NegatedQuery   string   `yaml:"negatedQuery"`
+	HasAttachment  bool     `yaml:"hasAttachment"`
+	ExcludeChats   bool     `yaml:"excludeChats"`
+	Size           int64    `yaml:"size"`
+	SizeComparison string   `yaml:"sizeComparison"`
+	AddLabels      []string `yaml:"addLabels"`
+	RemoveLabels   []string `yaml:"removeLabels"`
+	Forward        string   `yaml:"forward"`
+	// Shorthands matching `gmail filters create`.
+	Archive   bool `yaml:"archive"`
+	MarkRead  bool `yaml:"markRead"`
+	Star      bool `yaml:"star"`
+	Trash     bool `yaml:"trash"`
+	NeverSpam bool `yaml:"neverSpam"`
+	Important bool `yaml:"important"`
+}
+
+type gmailConfigSendAs struct {
+	Email         string  `yaml:"email"`
+	DisplayName   *string `yaml:"displayName"`
+	ReplyTo       *string `yaml:"replyTo"`
+	Signature     *string `yaml:"signature"`
+	SignatureFile string  `yaml:"signatureFile"`
+	TreatAsAlias  *bool   `yaml:"treatAsAlias"`
+	Default       *bool   `yaml:"default"`
+}
+
+type gmailConfigForwarding struct {
+	Addresses   []string `yaml:"addresses"`
+	Enabled     *bool    `yaml:"enabled"`
+	Email       *string  `yaml:"email"`
+	Disposition *string  `yaml:"disposition"`
+}
+
+type gmailConfigVacation struct {
+	Enabled      *bool   `yaml:"enabled"`
+	Subject      *string `yaml:"subject"`
+	Body         *string `yaml:"body"`
+	Start        *string `yaml:"start"`
+	End          *string `yaml:"end"`
+	ContactsOnly *bool   `yaml:"contactsOnly"`
+	DomainOnly   *bool   `yaml:"domainOnly"`
+}
+
+func loadGmailConfigFile(path string) (*gmailConfigFile, error) {
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, err
+	}
+	var cfg gmailConfigFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, usagef("parse %s: %v", path, err)
+	}
+
+	// Tell an empty list (manage, and prune everything) from an absent key.
+	var keys map[string]any
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, usagef("parse %s: %v", path, err)
+	}
+	_, cfg.hasLabels = keys["labels"]
+	_, cfg.hasFilters = keys["filters"]
+	_, cfg.hasSendAs = keys["sendAs"]
+	if !cfg.hasLabels && !cfg.hasFilters && !cfg.hasSendAs && cfg.Forwarding == nil && cfg.Vacation == nil {
+		return nil, usagef("%s declares nothing to manage (expected labels, filters, sendAs, forwarding or vacation)", path)
+	}
+
+	dir := filepath.Dir(path)
+	for i := range cfg.SendAs {
+		s := &cfg.SendAs[i]
+		if strings.TrimSpace(s.Email) == "" {
+			return nil, usagef("sendAs[%d]: email is required", i)
+		}
+		if s.SignatureFile == "" {
+			continue
+		}
+		if s.Signature != nil {
+			return nil, usagef("sendAs %s: use signature or signatureFile, not both", s.Email)
+		}
+		sigPath := s.SignatureFile
+		if !filepath.IsAbs(sigPath) && !strings.HasPrefix(sigPath, "~") {
+			sigPath = filepath.Join(dir, sigPath)
+		}
+		if sigPath, err = config.ExpandPath(sigPath); err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(sigPath) //nolint:gosec // user-provided path
+		if err != nil {
+			return nil, err
+		}
+		sig := strings.TrimSpace(string(b))
+		s.Signature = &sig
+	}
+	if f := cfg.Forwarding; f != nil && f.Disposition != nil {
+		switch *f.Disposition {
+		case "leaveInInbox", "archive", "trash", "markRead":
+		default:
+			return nil, usage("forwarding.disposition must be one of: leaveInInbox, archive, trash, markRead")
+		}
+	}
+	if err := validateConfigLabelColors(cfg.Labels); err != nil {
+		return nil, err
+	}
+	for i, f := range cfg.Filters {
+		p := f.portable()
+		if describeFilterCriteria(p.Criteria) == "" {
+			return nil, usagef("filters[%d]: at least one criteria is required", i)
+		}
+		if len(p.AddLabels) == 0 && len(p.RemoveLabels) == 0 && p.Forward == "" {
+			return nil, usagef("filters[%d]: at least one action is required", i)
+		}
+	}
+	return &cfg, nil
+}
+
+// flattenLabels expands children into "Parent/Child" names and adds any
+// missing ancestors, so the hierarchy shows up nested in Gmail.
+func flattenLabels(labels []gmailConfigLabel, prefix string, out map[string]gmailConfigLabel) error {
+	for _, l := range labels {
+		name := strings.Trim(strings.TrimSpace(l.Name), "/")
+		if name == "" {
+			return usage("label name is required")
+		}
+		if prefix != "" {
+			name = prefix + "/" + name
+		}
+		existing, dup := out[name]
+		switch {
+		case !dup || isImplicitLabel(existing):
+			entry := l
+			entry.Name = name
+			entry.Children = nil
+			out[name] = entry
+		case !isImplicitLabel(l):
+			return usagef("label %q declared twice", name)
+		}
+		parts := strings.Split(name, "/")
+		for i := 1; i < len(parts); i++ {
+			parent := strings.Join(parts[:i], "/")
+			if _, ok := out[parent]; !ok {
+				out[parent] = gmailConfigLabel{Name: parent}
+			}
+		}
+		if err := flattenLabels(l.Children, name, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateConfigLabelColors(labels []gmailConfigLabel) error {
+	for _, l := range labels {
+		if l.Color != nil {
+			if err := validateLabelColor(l.Color.Background, l.Color.Text); err != nil {
+				return usagef("label %q: %v", l.Name, err)
+			}
+		}
+		if err := validateConfigLabelColors(l.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isImplicitLabel(l gmailConfigLabel) bool {
+	return l.Color == nil && l.LabelListVisibility == "" && l.MessageListVisibility == ""
+}
+
+func (f gmailConfigFilter) portable() portableFilter {
+	p := portableFilter{
+		Criteria: &gmail.FilterCriteria{
+			From:           strings.TrimSpace(f.From),
+			To:             strings.TrimSpace(f.To),
+			Subject:        strings.TrimSpace(f.Subject),
+			Query:          strings.TrimSpace(f.Query),
+			NegatedQuery:   strings.TrimSpace(f.NegatedQuery),
+			HasAttachment:  f.HasAttachment,
+			ExcludeChats:   f.ExcludeChats,
+			Size:           f.Size,
+			SizeComparison: f.SizeComparison,
+		},
+		AddLabels:    append([]string(nil), f.AddLabels...),
+		RemoveLabels: append([]string(nil), f.RemoveLabels...),
+		Forward:      strings.TrimSpace(f.Forward),
+	}
+	if p.Criteria.Size > 0 && p.Criteria.SizeComparison == "" {
+		p.Criteria.SizeComparison = "larger"
+	}
+	flag := func(set bool, list *[]string, label string) {
+		if set {
+			*list = append(*list, label)
+		}
+	}
+	flag(f.Archive, &p.RemoveLabels, "INBOX")
+	flag(f.MarkRead, &p.RemoveLabels, "UNREAD")
+	flag(f.NeverSpam, &p.RemoveLabels, "SPAM")
+	flag(f.Star, &p.AddLabels, "STARRED")
+	flag(f.Trash, &p.AddLabels, "TRASH")
+	flag(f.Important, &p.AddLabels, "IMPORTANT")
+	return p
+}
+
+// portableFilterKey compares filters by label name, case-insensitively, so
+// desired filters can be matched before their labels exist.
+func portableFilterKey(p portableFilter) string {
+	lower := func(in []string) []string {
+		out := make([]string, 0, len(in))
+		for _, s := range in {
+			out = append(out, strings.ToLower(strings.TrimSpace(s)))
+		}
+		return out
+	}
+	return filterKey(&gmail.Filter{
+		Criteria: p.Criteria,
+		Action:   &gmail.FilterAction{AddLabelIds: lower(p.AddLabels), RemoveLabelIds: lower(p.RemoveLabels), Forward: p.Forward},
+	})
+}
+
+const (
+	configActionCreate = "create"
+	configActionUpdate = "update"
+	configActionDelete = "delete"
+)
+
+// gmailConfigChange is one step of a plan. Steps apply in plan order, which
+// creates labels before the filters that use them and deletes filters before
+// labels.
+type gmailConfigChange struct {
+	Action  string   `json:"action"`
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Details []string `json:"details,omitempty"`
+
+	apply func(context.Context, *gmailConfigApplier) error
+}
+
+type gmailConfigApplier struct {
+	svc      *gmail.Service
+	nameToID map[string]string
+}
+
+type gmailConfigPlanner struct {
+	cfg     *gmailConfigFile
+	prune   bool
+	changes []gmailConfigChange
+	deletes []gmailConfigChange
+}
+
+func (p *gmailConfigPlanner) add(ch gmailConfigChange) {
+	if ch.Action == configActionDelete {
+		p.deletes = append(p.deletes, ch)
+		return
+	}
+	p.changes = append(p.changes, ch)
+}
+
+func diffField(details *[]string, name string, from, to any) {
+	if fmt.Sprint(from) != fmt.Sprint(to) {
+		*details = append(*details, fmt.Sprintf("%s: %q -> %q", name, fmt.Sprint(from), fmt.Sprint(to)))
+	}
+}
+
+func planGmailConfig(ctx context.Context, svc *gmail.Service, cfg *gmailConfigFile, prune bool) ([]gmailConfigChange, error) {
+	p := &gmailConfigPlanner{cfg: cfg, prune: prune}
+	if err := p.planForwardingAddresses(ctx, svc); err != nil {
+		return nil, err
+	}
+	if err := p.planLabels(ctx, svc); err != nil {
+		return nil, err
+	}
+	if err := p.planFilters(ctx, svc); err != nil {
+		return nil, err
+	}
+	if err := p.planSendAs(ctx, svc); err != nil {
+		return nil, err
+	}
+	if err := p.planAutoForwarding(ctx, svc); err != nil {
+		return nil, err
+	}
+	if err := p.planVacation(ctx, svc); err != nil {
+		return nil, err
+	}
+	return append(p.changes, p.deletes...), nil
+}
+
+func (p *gmailConfigPlanner) planForwardingAddresses(ctx context.Context, svc *gmail.Service) error {
+	if p.cfg.Forwarding == nil || p.cfg.Forwarding.Addresses == nil {
+		return nil
+	}
+	resp, err := svc.Users.Settings.ForwardingAddresses.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	live := map[string]bool{}
+	for _, a := range resp.ForwardingAddresses {
+		live[strings.ToLower(a.ForwardingEmail)] = true
+	}
+	want := map[string]bool{}
+	for _, addr := range p.cfg.Forwarding.Addresses {
+		addr = strings.TrimSpace(addr)
+		want[strings.ToLower(addr)] = true
+		if live[strings.ToLower(addr)] {
+			continue
+		}
+		p.add(gmailConfigChange{
+			Action: configActionCreate, Kind: "forwardingAddress", Name: addr,
+			Details: []string{"sends a verification email to the address"},
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				_, err := a.svc.Users.Settings.ForwardingAddresses.Create("me", &gmail.ForwardingAddress{ForwardingEmail: addr}).Context(ctx).Do()
+				return err
+			},
+		})
+	}
+	if !p.prune {
+		return nil
+	}
+	for _, a := range resp.ForwardingAddresses {
+		addr := a.ForwardingEmail
+		if want[strings.ToLower(addr)] {
+			continue
+		}
+		p.add(gmailConfigChange{
+			Action: configActionDelete, Kind: "forwardingAddress", Name: addr,
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				return a.svc.Users.Settings.ForwardingAddresses.Delete("me", addr).Context(ctx).Do()
+			},
+		})
+	}
+	return nil
+}
+
+func (p *gmailConfigPlanner) planLabels(ctx context.Context, svc *gmail.Service) error {
+	if !p.cfg.hasLabels {
+		return nil
+	}
+	want := map[string]gmailConfigLabel{}
+	if err := flattenLabels(p.cfg.Labels, "", want); err != nil {
+		return err
+	}
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	live := map[string]*gmail.Label{}
+	for _, l := range resp.Labels {
+		if l.Type == "user" {
+			live[strings.ToLower(l.Name)] = l
+		}
+	}
+
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	// Sorted, so parents are created before their children.
+	sort.Strings(names)
+	for _, name := range names {
+		desired := want[name]
+		current, exists := live[strings.ToLower(name)]
+		if !exists {
+			var details []string
+			if desired.Color != nil {
+				details = append(details, fmt.Sprintf("color: %s on %s", desired.Color.Text, desired.Color.Background))
+			}
+			p.add(gmailConfigChange{
+				Action: configActionCreate, Kind: "label", Name: name, Details: details,
+				apply: func(ctx context.Context, a *gmailConfigApplier) error {
+					label := &gmail.Label{
+						Name:                  desired.Name,
+						LabelListVisibility:   firstNonEmpty(desired.LabelListVisibility, "labelShow"),
+						MessageListVisibility: firstNonEmpty(desired.MessageListVisibility, "show"),
+					}
+					if desired.Color != nil {
+						label.Color = &gmail.LabelColor{BackgroundColor: desired.Color.Background, TextColor: desired.Color.Text}
+					}
+					created, err := a.svc.Users.Labels.Create("me", label).Context(ctx).Do()
+					if err != nil {
+						return mapLabelCreateError(err, desired.Name)
+					}
+					a.nameToID[strings.ToLower(created.Name)] = created.Id
+					return nil
+				},
+			})
+			continue
+		}
+
+		var details []string
+		if desired.LabelListVisibility != "" {
+			diffField(&details, "labelListVisibility", current.LabelListVisibility, desired.LabelListVisibility)
+		}
+		if desired.MessageListVisibility != "" {
+			diffField(&details, "messageListVisibility", current.MessageListVisibility, desired.MessageListVisibility)
+		}
+		if desired.Color != nil {
+			bg, fg := "", ""
+			if current.Color != nil {
+				bg, fg = current.Color.BackgroundColor, current.Color.TextColor
+			}
+			diffField(&details, "color.background", bg, desired.Color.Background)
+			diffField(&details, "color.text", fg, desired.Color.Text)
+		}
+		if current.Name != name {
+			diffField(&details, "name", current.Name, name)
+		}
+		if len(details) == 0 {
+			continue
+		}
+		id := current.Id
+		p.add(gmailConfigChange{
+			Action: configActionUpdate, Kind: "label", Name: name, Details: details,
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				patch := &gmail.Label{
+					Name:                  desired.Name,
+					LabelListVisibility:   desired.LabelListVisibility,
+					MessageListVisibility: desired.MessageListVisibility,
+				}
+				if desired.Color != nil {
+					patch.Color = &gmail.LabelColor{BackgroundColor: desired.Color.Background, TextColor: desired.Color.Text}
+				}
+				_, err := a.svc.Users.Labels.Patch("me", id, patch).Context(ctx).Do()
+				return err
+			},
+		})
+	}
+
+	if !p.prune {
+		return nil
+	}
+	wanted := make(map[string]bool, len(want))
+	for name := range want {
+		wanted[strings.ToLower(name)] = true
+	}
+	var extra []*gmail.Label
+	for key, l := range live {
+		if !wanted[key] {
+			extra = append(extra, l)
+		}
+	}
+	// Children before parents.
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Name > extra[j].Name })
+	for _, l := range extra {
+		id := l.Id
+		p.add(gmailConfigChange{
+			Action: configActionDelete, Kind: "label", Name: l.Name,
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				return a.svc.Users.Labels.Delete("me", id).Context(ctx).Do()
+			},
+		})
+	}
+	return nil
+}
+
+func (p *gmailConfigPlanner) planFilters(ctx context.Context, svc *gmail.Service) error {
+	if !p.cfg.hasFilters {
+		return nil
+	}
+	resp, err := svc.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+	live := map[string]*gmail.Filter{}
+	for _, f := range resp.Filter {
+		live[portableFilterKey(toPortableFilter(f, idToName))] = f
+	}
+
+	want := map[string]bool{}
+	for _, f := range p.cfg.Filters {
+		desired := f.portable()
+		key := portableFilterKey(desired)
+		if want[key] {
+			continue
+		}
+		want[key] = true
+		if live[key] != nil {
+			continue
+		}
+		p.add(gmailConfigChange{
+			Action: configActionCreate, Kind: "filter", Name: describeFilterCriteria(desired.Criteria),
+			Details: filterActionDetails(desired),
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				_, err := a.svc.Users.Settings.Filters.Create("me", &gmail.Filter{
+					Criteria: desired.Criteria,
+					Action: &gmail.FilterAction{
+						AddLabelIds:    resolveLabelIDs(desired.AddLabels, a.nameToID),
+						RemoveLabelIds: resolveLabelIDs(desired.RemoveLabels, a.nameToID),
+						Forward:        desired.Forward,
+					},
+				}).Context(ctx).Do()
+				return err
+			},
+		})
+	}
+
+	if !p.prune {
+		return nil
+	}
+	for _, f := range resp.Filter {
+		current := toPortableFilter(f, idToName)
+		if want[portableFilterKey(current)] {
+			continue
+		}
+		id := f.Id
+		p.add(gmailConfigChange{
+			Action: configActionDelete, Kind: "filter", Name: describeFilterCriteria(current.Criteria),
+			Details: filterActionDetails(current),
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				return a.svc.Users.Settings.Filters.Delete("me", id).Context(ctx).Do()
+			},
+		})
+	}
+	return nil
+}
+
+func filterActionDetails(f portableFilter) []string {
+	var details []string
+	if len(f.AddLabels) > 0 {
+		details = append(details, "add: "+strings.Join(f.AddLabels, ", "))
+	}
+	if len(f.RemoveLabels) > 0 {
+		details = append(details, "remove: "+strings.Join(f.RemoveLabels, ", "))
+	}
+	if f.Forward != "" {
+		details = append(details, "forward: "+f.Forward)
+	}
+	return details
+}
+
+func (p *gmailConfigPlanner) planSendAs(ctx context.Context, svc *gmail.Service) error {
+	if !p.cfg.hasSendAs {
+		return nil
+	}
+	resp, err := svc.Users.Settings.SendAs.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	live := map[string]*gmail.SendAs{}
+	for _, s := range resp.SendAs {
+		live[strings.ToLower(s.SendAsEmail)] = s
+	}
+
+	want := map[string]bool{}
+	for _, desired := range p.cfg.SendAs {
+		email := strings.TrimSpace(desired.Email)
+		want[strings.ToLower(email)] = true
+		current := live[strings.ToLower(email)]
+		if current == nil {
+			sendAs := &gmail.SendAs{SendAsEmail: email, TreatAsAlias: true}
+			applySendAsConfig(sendAs, desired)
+			p.add(gmailConfigChange{
+				Action: configActionCreate, Kind: "sendAs", Name: email,
+				Details: []string{"sends a verification email unless the address is in your domain"},
+				apply: func(ctx context.Context, a *gmailConfigApplier) error {
+					_, err := a.svc.Users.Settings.SendAs.Create("me", sendAs).Context(ctx).Do()
+					return err
+				},
+			})
+			continue
+		}
+
+		updated := *current
+		applySendAsConfig(&updated, desired)
+		var details []string
+		diffField(&details, "displayName", current.DisplayName, updated.DisplayName)
+		diffField(&details, "replyTo", current.ReplyToAddress, updated.ReplyToAddress)
+		if current.Signature != updated.Signature {
+			details = append(details, "signature: changed")
+		}
+		diffField(&details, "treatAsAlias", current.TreatAsAlias, updated.TreatAsAlias)
+		diffField(&details, "default", current.IsDefault, updated.IsDefault)
+		if len(details) == 0 {
+			continue
+		}
+		p.add(gmailConfigChange{
+			Action: configActionUpdate, Kind: "sendAs", Name: email, Details: details,
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				_, err := a.svc.Users.Settings.SendAs.Update("me", updated.SendAsEmail, &updated).Context(ctx).Do()
+				return err
+			},
+		})
+	}
+
+	if !p.prune {
+		return nil
+	}
+	for _, s := range resp.SendAs {
+		if s.IsPrimary || want[strings.ToLower(s.SendAsEmail)] {
+			continue
+		}
+		email := s.SendAsEmail
+		p.add(gmailConfigChange{
+			Action: configActionDelete, Kind: "sendAs", Name: email,
+			apply: func(ctx context.Context, a *gmailConfigApplier) error {
+				return a.svc.Users.Settings.SendAs.Delete("me", email).Context(ctx).Do()
+			},
+		})
+	}
+	return nil
+}
+
+func applySendAsConfig(s *gmail.SendAs, c gmailConfigSendAs) {
+	if c.DisplayName != nil {
+		s.DisplayName = *c.DisplayName
+	}
+	if c.ReplyTo != nil {
+		s.ReplyToAddress = *c.ReplyTo
+	}
+	if c.Signature != nil {
+		s.Signature = *c.Signature
+	}
+	if c.TreatAsAlias != nil {
+		s.TreatAsAlias = *c.TreatAsAlias
+	}
+	if c.Default != nil {
+		s.IsDefault = *c.Default
+	}
+}
+
+func (p *gmailConfigPlanner) planAutoForwarding(ctx context.Context, svc *gmail.Service) error {
+	f := p.cfg.Forwarding
+	if f == nil || (f.Enabled == nil && f.Email == nil && f.Disposition == nil) {
+		return nil
+	}
+	current, err := svc.Users.Settings.GetAutoForwarding("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	desired := &gmail.AutoForwarding{
+		Enabled:      current.Enabled,
+		EmailAddress: current.EmailAddress,
+		Disposition:  current.Disposition,
+	}
+	if f.Enabled != nil {
+		desired.Enabled = *f.Enabled
+	}
+	if f.Email != nil {
+		desired.EmailAddress = *f.Email
+	}
+	if f.Disposition != nil {
+		desired.Disposition = *f.Disposition
+	}
+	var details []string
+	diffField(&details, "enabled", current.Enabled, desired.Enabled)
+	diffField(&details, "email", current.EmailAddress, desired.EmailAddress)
+	diffField(&details, "disposition", current.Disposition, desired.Disposition)
+	if len(details) == 0 {
+		return nil
+	}
+	p.add(gmailConfigChange{
+		Action: configActionUpdate, Kind: "autoForwarding", Name: "auto-forwarding", Details: details,
+		apply: func(ctx context.Context, a *gmailConfigApplier) error {
+			_, err := a.svc.Users.Settings.UpdateAutoForwarding("me", desired).Context(ctx).Do()
+			return err
+		},
+	})
+	return nil
+}
+
+func (p *gmailConfigPlanner) planVacation(ctx context.Context, svc *gmail.Service) error {
+	v := p.cfg.Vacation
+	if v == nil {
+		return nil
+	}
+	current, err := svc.Users.Settings.GetVacation("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	desired := &gmail.VacationSettings{
+		EnableAutoReply:       current.EnableAutoReply,
+		ResponseSubject:       current.ResponseSubject,
+		ResponseBodyHtml:      current.ResponseBodyHtml,
+		ResponseBodyPlainText: current.ResponseBodyPlainText,
+		StartTime:             current.StartTime,
+		EndTime:               current.EndTime,
+		RestrictToContacts:    current.RestrictToContacts,
+		RestrictToDomain:      current.RestrictToDomain,
+	}
+	if v.Enabled != nil {
+		desired.EnableAutoReply = *v.Enabled
+	}
+	if v.Subject != nil {
+		desired.ResponseSubject = *v.Subject
+	}
+	if v.Body != nil {
+		desired.ResponseBodyHtml = *v.Body
+		desired.ResponseBodyPlainText = stripHTML(*v.Body)
+	}
+	if v.Start != nil {
+		if desired.StartTime, err = parseRFC3339ToMillis(*v.Start); err != nil {
+			return usagef("vacation.start: %v", err)
+		}
+	}
+	if v.End != nil {
+		if desired.EndTime, err = parseRFC3339ToMillis(*v.End); err != nil {
+			return usagef("vacation.end: %v", err)
+		}
+	}
+	if v.ContactsOnly != nil {
+		desired.RestrictToContacts = *v.ContactsOnly
+	}
+	if v.DomainOnly != nil {
+		desired.RestrictToDomain = *v.DomainOnly
+	}
+
+	var details []string
+	diffField(&details, "enabled", current.EnableAutoReply, desired.EnableAutoReply)
+	diffField(&details, "subject", current.ResponseSubject, desired.ResponseSubject)
+	if current.ResponseBodyHtml != desired.ResponseBodyHtml {
+		details = append(details, "body: changed")
+	}
+	diffField(&details, "start", current.StartTime, desired.StartTime)
+	diffField(&details, "end", current.EndTime, desired.EndTime)
+	diffField(&details, "contactsOnly", current.RestrictToContacts, desired.RestrictToContacts)
+	diffField(&details, "domainOnly", current.RestrictToDomain, desired.RestrictToDomain)
+	if len(details) == 0 {
+		return nil
+	}
+	p.add(gmailConfigChange{
+		Action: configActionUpdate, Kind: "vacation", Name: "vacation responder", Details: details,
+		apply: func(ctx context.Context, a *gmailConfigApplier) error {
+			_, err := a.svc.Users.Settings.UpdateVacation("me", desired).Context(ctx).Do()
+			return err
+		},
+	})
+	return nil
+}
+
+func gmailConfigSummary(changes []gmailConfigChange) map[string]int {
+	counts := map[string]int{configActionCreate: 0, configActionUpdate: 0, configActionDelete: 0}
+	for _, ch := range changes {
+		counts[ch.Action]++
+	}
+	return counts
+}
+
+func printGmailConfigPlan(u *ui.UI, changes []gmailConfigChange) {
+	if len(changes) == 0 {
+		u.Out().Println("No changes. The mailbox matches the config.")
+		return
+	}
+	symbols := map[string]string{configActionCreate: "+", configActionUpdate: "~", configActionDelete: "-"}
+	for _, ch := range changes {
+		u.Out().Printf("  %s %s %s", symbols[ch.Action], ch.Kind, ch.Name)
+		for _, d := range ch.Details {
+			u.Out().Printf("      %s", d)
+		}
+	}
+	counts := gmailConfigSummary(changes)
+	u.Out().Println("")
+	u.Out().Printf("Plan: %d to create, %d to update, %d to delete.",
+		counts[configActionCreate], counts[configActionUpdate], counts[configActionDelete])
+}
+
+type GmailConfigPlanCmd struct {
+	File  string `name:"file" short:"f" required:"" help:"Config file (YAML)"`
+	Prune bool   `name:"prune" help:"Also plan deletion of labels, filters, send-as aliases and forwarding addresses missing from the file"`
+}
+
+func (c *GmailConfigPlanCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	cfg, err := loadGmailConfigFile(c.File)
+	if err != nil {
+		return err
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	changes, err := planGmailConfig(ctx, svc, cfg, c.Prune)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"changes": changes,
+			"summary": gmailConfigSummary(changes),
+		})
+	}
+	printGmailConfigPlan(u, changes)
+	return nil
+}
+
+type GmailConfigApplyCmd struct {
+	File  string `name:"file" short:"f" required:"" help:"Config file (YAML)"`
+	Prune bool   `name:"prune" help:"Also delete labels, filters, send-as aliases and forwarding addresses missing from the file"`
+}
+
+func (c *GmailConfigApplyCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	cfg, err := loadGmailConfigFile(c.File)
+	if err != nil {
+		return err
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	changes, err := planGmailConfig(ctx, svc, cfg, c.Prune)
+	if err != nil {
+		return err
+	}
+	counts := gmailConfigSummary(changes)
+
+	if err := dryRunExit(ctx, flags, "gmail.config.apply", map[string]any{
+		"changes": changes,
+		"summary": counts,
+	}); err != nil {
+		return err
+	}
+	if !outfmt.IsJSON(ctx) {
+		printGmailConfigPlan(u, changes)
+	}
+	if len(changes) == 0 {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"applied": []gmailConfigChange{}, "summary": counts})
+		}
+		return nil
+	}
+	if counts[configActionDelete] > 0 {
+		if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete %d gmail settings resources", counts[configActionDelete])); err != nil {
+			return err
+		}
+	}
+
+	nameToID, err := fetchLabelNameToID(svc)
+	if err != nil {
+		return err
+	}
+	applier := &gmailConfigApplier{svc: svc, nameToID: nameToID}
+	applied := make([]gmailConfigChange, 0, len(changes))
+	for _, ch := range changes {
+		if err := ch.apply(ctx, applier); err != nil {
+			if outfmt.IsJSON(ctx) {
+				_ = outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"applied": applied, "summary": gmailConfigSummary(applied)})
+			}
+			return fmt.Errorf("%s %s %s: %w (%d of %d changes applied)", ch.Action, ch.Kind, ch.Name, err, len(applied), len(changes))
+		}
+		applied = append(applied, ch)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"applied": applied, "summary": counts})
+	}
+	u.Out().Printf("Apply complete: %d created, %d updated, %d deleted.",
+		counts[configActionCreate], counts[configActionUpdate], counts[configActionDelete])
+	return nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+const testGmailConfig = `
+labels:
+  - name: Clients
+    color: {background: "#16a766", text: "#ffffff"}
+    children:
+      - name: Acme
+  - name: Receipts
+    labelListVisibility: labelHide
+filters:
+  - from: billing@acme.com
+    addLabels: [Clients/Acme]
+    archive: true
+  - from: shop@example.com
+    addLabels: [Receipts]
+    removeLabels: [INBOX]
+sendAs:
+  - email: support@acme.com
+    displayName: Acme Support
+    signatureFile: sig.html
+forwarding:
+  enabled: false
+vacation:
+  enabled: true
+  subject: Away
+  body: "<p>Back <b>Monday</b></p>"
+`
+
+func TestGmailConfigPlanApply(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "gmail.yaml")
+	if err := os.WriteFile(cfgPath, []byte(testGmailConfig), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sig.html"), []byte("<b>Acme</b>\n"), 0o600); err != nil {
+		t.Fatalf("write signature: %v", err)
+	}
+
+	var (
+		mu     sync.Mutex
+		calls  []string
+		labels = []*gmail.Label{
+			{Id: "INBOX", Name: "INBOX", Type: "system"},
+			{Id: "Label_1", Name: "Receipts", Type: "user", LabelListVisibility: "labelShow"},
+			{Id: "Label_2", Name: "Old", Type: "user"},
+		}
+		filters = []*gmail.Filter{
+			{Id: "f1", Criteria: &gmail.FilterCriteria{From: "shop@example.com"}, Action: &gmail.FilterAction{AddLabelIds: []string{"Label_1"}, RemoveLabelIds: []string{"INBOX"}}},
+			{Id: "f2", Criteria: &gmail.FilterCriteria{From: "old@example.com"}, Action: &gmail.FilterAction{AddLabelIds: []string{"Label_2"}}},
+		}
+		created []*gmail.Filter
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodGet {
+			calls = append(calls, r.Method+" "+path)
+		}
+		switch {
+		case r.Method == http.MethodGet && path == "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": labels})
+		case r.Method == http.MethodPost && path == "/labels":
+			var l gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			l.Id = "Label_" + l.Name
+			l.Type = "user"
+			labels = append(labels, &l)
+			_ = json.NewEncoder(w).Encode(l)
+		case r.Method == http.MethodPatch && strings.HasPrefix(path, "/labels/"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": strings.TrimPrefix(path, "/labels/")})
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && path == "/settings/filters":
+			_ = json.NewEncoder(w).Encode(map[string]any{"filter": filters})
+		case r.Method == http.MethodPost && path == "/settings/filters":
+			var f gmail.Filter
+			_ = json.NewDecoder(r.Body).Decode(&f)
+			created = append(created, &f)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "new"})
+		case r.Method == http.MethodGet && path == "/settings/sendAs":
+			_ = json.NewEncoder(w).Encode(map[string]any{"sendAs": []map[string]any{{"sendAsEmail": "me@example.com", "isPrimary": true, "isDefault": true}}})
+		case r.Method == http.MethodPost && path == "/settings/sendAs":
+			var s gmail.SendAs
+			_ = json.NewDecoder(r.Body).Decode(&s)
+			if s.DisplayName != "Acme Support" || s.Signature != "<b>Acme</b>" {
+				t.Errorf("unexpected send-as: %+v", s)
+			}
+			_ = json.NewEncoder(w).Encode(s)
+		case r.Method == http.MethodGet && path == "/settings/autoForwarding":
+			_ = json.NewEncoder(w).Encode(map[string]any{"enabled": false})
+		case r.Method == http.MethodGet && path == "/settings/vacation":
+			_ = json.NewEncoder(w).Encode(map[string]any{"enableAutoReply": false})
+		case r.Method == http.MethodPut && path == "/settings/vacation":
+			var v gmail.VacationSettings
+			_ = json.NewDecoder(r.Body).Decode(&v)
+			if !v.EnableAutoReply || v.ResponseBodyPlainText != "Back Monday" {
+				t.Errorf("unexpected vacation: %+v", v)
+			}
+			_ = json.NewEncoder(w).Encode(v)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "config", "plan", "-f", cfgPath, "--prune"}); execErr != nil {
+			t.Fatalf("plan: %v", execErr)
+		}
+	})
+	var plan struct {
+		Changes []gmailConfigChange `json:"changes"`
+		Summary map[string]int      `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(out), &plan); err != nil {
+		t.Fatalf("unmarshal plan: %v (out=%q)", err, out)
+	}
+	var steps []string
+	for _, ch := range plan.Changes {
+		steps = append(steps, ch.Action+" "+ch.Kind+" "+ch.Name)
+	}
+	want := []string{
+		"create label Clients",
+		"create label Clients/Acme",
+		"update label Receipts",
+		"create filter from:billing@acme.com",
+		"create sendAs support@acme.com",
+		"update vacation vacation responder",
+		"delete label Old",
+		"delete filter from:old@example.com",
+	}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected plan:\n%s", strings.Join(steps, "\n"))
+	}
+	if len(calls) != 0 {
+		t.Fatalf("plan made changes: %v", calls)
+	}
+
+	// Without --prune nothing is deleted.
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "config", "apply", "-f", cfgPath}); execErr != nil {
+			t.Fatalf("apply: %v", execErr)
+		}
+	})
+	wantCalls := []string{
+		"POST /labels",
+		"POST /labels",
+		"PATCH /labels/Label_1",
+		"POST /settings/filters",
+		"POST /settings/sendAs",
+		"PUT /settings/vacation",
+	}
+	if strings.Join(calls, "\n") != strings.Join(wantCalls, "\n") {
+		t.Fatalf("unexpected calls:\n%s", strings.Join(calls, "\n"))
+	}
+	if len(created) != 1 || strings.Join(created[0].Action.AddLabelIds, ",") != "Label_Clients/Acme" || strings.Join(created[0].Action.RemoveLabelIds, ",") != "INBOX" {
+		t.Fatalf("filter not wired to the new label: %+v", created)
+	}
+}
@@ -291,11 +291,16 @@ type GmailDraftsCreateCmd struct {
 	Body             string   `name:"body" help:"Body (plain text; required unless --body-html is set)"`
 	BodyFile         string   `name:"body-file" help:"Body file path (plain text; '-' for stdin)"`
 	BodyHTML         string   `name:"body-html" help:"Body (HTML; optional)"`
+	BodyMarkdown     string   `name:"body-markdown" aliases:"body-md" help:"Body (Markdown; sent as HTML plus a plain-text alternative)"`
+	BodyMarkdownFile string   `name:"body-md-file" aliases:"body-markdown-file" help:"Markdown body file path ('-' for stdin)"`
 	ReplyToMessageID string   `name:"reply-to-message-id" help:"Reply to Gmail message ID (sets In-Reply-To/References and thread)"`
 	ReplyTo          string   `name:"reply-to" help:"Reply-To header address"`
 	Quote            bool     `name:"quote" help:"Include quoted original message in reply (requires --reply-to-message-id)"`
 	Attach           []string `name:"attach" help:"Attachment file path (repeatable)"`
+	Inline           []string `name:"inline" help:"Inline image as name=path, referenced from HTML as cid:name (repeatable)"`
+	EmbedImages      bool     `name:"embed-images" help:"Embed local <img src> image files as inline parts (relative paths resolve against the Markdown file)"`
 	From             string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
+	Raw              string   `name:"raw" help:"Save a pre-built RFC 822 message (.eml path; '-' for stdin) as a draft"`
 }
 
 type draftComposeInput struct {
@@ -310,6 +315,7 @@ type draftComposeInput struct {
 	ReplyTo          string
 	Quote            bool
 	Attach           []string
+	Inline           []mailAttachment
 	From             string
 }
 
@@ -318,7 +324,7 @@ func (c draftComposeInput) validate() error {
 		return usage("required: --subject")
 	}
 	if strings.TrimSpace(c.Body) == "" && strings.TrimSpace(c.BodyHTML) == "" {
-		return usage("required: --body, --body-file, or --body-html")
+		return usage("required: --body, --body-file, --body-html, or --body-markdown")
 	}
 	return nil
 }
@@ -369,6 +375,7 @@ func buildDraftMessage(ctx context.Context, svc *gmail.Service, account string,
 		InReplyTo:   inReplyTo,
 		References:  references,
 		Attachments: atts,
+		Inline:      input.Inline,
 	}, &rfc822Config{allowMissingTo: true})
 	if err != nil {
 		return nil, "", err
@@ -495,11 +502,19 @@ func messageFromMatchesAccount(msg *gmail.Message, account string) bool {
 func (c *GmailDraftsCreateCmd) Run(ctx context.Context, flags *RootFlags) error {
 	u := ui.FromContext(ctx)
 
-	body, err := resolveBodyInput(c.Body, c.BodyFile)
+	if strings.TrimSpace(c.Raw) != "" {
+		return c.runRaw(ctx, flags)
+	}
+
+	body, bodyHTML, err := resolveComposeBodies(c.Body, c.BodyFile, c.BodyHTML, c.BodyMarkdown, c.BodyMarkdownFile)
 	if err != nil {
 		return err
 	}
 	replyToMessageID := normalizeGmailMessageID(c.ReplyToMessageID)
+	bodyHTML, inline, err := embedInlineImages(bodyHTML, c.Inline, inlineBaseDir(c.BodyMarkdownFile), c.EmbedImages)
+	if err != nil {
+		return err
+	}
 	if c.Quote && replyToMessageID == "" {
 		return usage("--quote requires --reply-to-message-id")
 	}
@@ -519,12 +534,13 @@ func (c *GmailDraftsCreateCmd) Run(ctx context.Context, flags *RootFlags) error
 		Bcc:              c.Bcc,
 		Subject:          c.Subject,
 		Body:             body,
-		BodyHTML:         c.BodyHTML,
+		BodyHTML:         bodyHTML,
 		ReplyToMessageID: replyToMessageID,
 		ReplyToThreadID:  "",
 		ReplyTo:          c.ReplyTo,
 		Quote:            c.Quote,
 		Attach:           attachPaths,
+		Inline:           inline,
 		From:             c.From,
 	}
 	if validateErr := input.validate(); validateErr != nil {
@@ -543,6 +559,7 @@ func (c *GmailDraftsCreateCmd) Run(ctx context.Context, flags *RootFlags) error
 		"quote":               input.Quote,
 		"from":                strings.TrimSpace(input.From),
 		"attachments":         attachPaths,
+		"inline":              inlinePaths(inline),
 	}); dryRunErr != nil {
 		return dryRunErr
 	}
@@ -569,6 +586,64 @@ func (c *GmailDraftsCreateCmd) Run(ctx context.Context, flags *RootFlags) error
 	return writeDraftResult(ctx, u, draft, threadID)
 }
 
+func (c *GmailDraftsCreateCmd) runRaw(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	if err := rejectWithRaw(map[string]bool{
+		"to":                  strings.TrimSpace(c.To) != "",
+		"cc":                  strings.TrimSpace(c.Cc) != "",
+		"bcc":                 strings.TrimSpace(c.Bcc) != "",
+		"subject":             strings.TrimSpace(c.Subject) != "",
+		"body":                strings.TrimSpace(c.Body) != "",
+		"body-file":           strings.TrimSpace(c.BodyFile) != "",
+		"body-html":           strings.TrimSpace(c.BodyHTML) != "",
+		"body-markdown":       strings.TrimSpace(c.BodyMarkdown) != "",
+		"body-md-file":        strings.TrimSpace(c.BodyMarkdownFile) != "",
+		"reply-to-message-id": strings.TrimSpace(c.ReplyToMessageID) != "",
+		"reply-to":            strings.TrimSpace(c.ReplyTo) != "",
+		"quote":               c.Quote,
+		"attach":              len(c.Attach) > 0,
+		"inline":              len(c.Inline) > 0,
+		"from":                strings.TrimSpace(c.From) != "",
+	}); err != nil {
+		return err
+	}
+
+	raw, err := rawMessageFor(ctx, c.Raw, false)
+	if err != nil {
+		return err
+	}
+
+	if dryRunErr := dryRunExit(ctx, flags, "gmail.drafts.create", map[string]any{
+		"raw":     raw.Path,
+		"size":    len(raw.Data),
+		"to":      raw.To,
+		"cc":      raw.Cc,
+		"bcc":     raw.Bcc,
+		"subject": raw.Subject,
+	}); dryRunErr != nil {
+		return dryRunErr
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	draft, err := svc.Users.Drafts.Create("me", &gmail.Draft{
+		Message: &gmail.Message{Raw: base64.RawURLEncoding.EncodeToString(raw.Data)},
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	return writeDraftResult(ctx, u, draft, "")
+}
+
 type GmailDraftsUpdateCmd struct {
 	DraftID          string   `arg:"" name:"draftId" help:"Draft ID"`
 	To               *string  `name:"to" help:"Recipients (comma-separated; omit to keep existing)"`
@@ -578,10 +653,14 @@ type GmailDraftsUpdateCmd struct {
 	Body             string   `name:"body" help:"Body (plain text; required unless --body-html is set)"`
 	BodyFile         string   `name:"body-file" help:"Body file path (plain text; '-' for stdin)"`
 	BodyHTML         string   `name:"body-html" help:"Body (HTML; optional)"`
+	BodyMarkdown     string   `name:"body-markdown" aliases:"body-md" help:"Body (Markdown; sent as HTML plus a plain-text alternative)"`
+	BodyMarkdownFile string   `name:"body-md-file" aliases:"body-markdown-file" help:"Markdown body file path ('-' for stdin)"`
 	ReplyToMessageID string   `name:"reply-to-message-id" help:"Reply to Gmail message ID (sets In-Reply-To/References and thread)"`
 	ReplyTo          string   `name:"reply-to" help:"Reply-To header address"`
 	Quote            bool     `name:"quote" help:"Include quoted original message in reply"`
 	Attach           []string `name:"attach" help:"Attachment file path (repeatable)"`
+	Inline           []string `name:"inline" help:"Inline image as name=path, referenced from HTML as cid:name (repeatable)"`
+	EmbedImages      bool     `name:"embed-images" help:"Embed local <img src> image files as inline parts (relative paths resolve against the Markdown file)"`
 	From             string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
 }
 
@@ -599,11 +678,15 @@ func (c *GmailDraftsUpdateCmd) Run(ctx context.Context, flags *RootFlags) error
 		to = *c.To
 	}
 
-	body, err := resolveBodyInput(c.Body, c.BodyFile)
+	body, bodyHTML, err := resolveComposeBodies(c.Body, c.BodyFile, c.BodyHTML, c.BodyMarkdown, c.BodyMarkdownFile)
 	if err != nil {
 		return err
 	}
 	replyToMessageID := normalizeGmailMessageID(c.ReplyToMessageID)
+	bodyHTML, inline, err := embedInlineImages(bodyHTML, c.Inline, inlineBaseDir(c.BodyMarkdownFile), c.EmbedImages)
+	if err != nil {
+		return err
+	}
 
 	attachPaths := make([]string, 0, len(c.Attach))
 	for _, p := range c.Attach {
@@ -620,12 +703,13 @@ func (c *GmailDraftsUpdateCmd) Run(ctx context.Context, flags *RootFlags) error
 		Bcc:              c.Bcc,
 		Subject:          c.Subject,
 		Body:             body,
-		BodyHTML:         c.BodyHTML,
+		BodyHTML:         bodyHTML,
 		ReplyToMessageID: replyToMessageID,
 		ReplyToThreadID:  "",
 		ReplyTo:          c.ReplyTo,
 		Quote:            c.Quote,
 		Attach:           attachPaths,
+		Inline:           inline,
 		From:             c.From,
 	}
 	if validateErr := input.validate(); validateErr != nil {
@@ -646,6 +730,7 @@ func (c *GmailDraftsUpdateCmd) Run(ctx context.Context, flags *RootFlags) error
 		"quote":               input.Quote,
 		"from":                strings.TrimSpace(input.From),
 		"attachments":         attachPaths,
+		"inline":              inlinePaths(inline),
 	}); dryRunErr != nil {
 		return dryRunErr
 	}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/mbox"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	gmailExportEML  = "eml"
+	gmailExportMbox = "mbox"
+)
+
+// GmailExportCmd downloads messages in raw RFC 822 form for other mail tools.
+type GmailExportCmd struct {
+	MessageIDs []string `arg:"" optional:"" name:"messageId" help:"Message IDs to export"`
+	Query      string   `name:"query" short:"q" help:"Export all messages matching this Gmail search query"`
+	Max        int64    `name:"max" aliases:"limit" help:"Max messages to export (with --query)" default:"100"`
+	Format     string   `name:"format" help:"Output format: eml|mbox" enum:"eml,mbox" default:"eml"`
+	Out        string   `name:"out" aliases:"output" help:"Output directory (eml: one <id>.eml per message; mbox: gmail-export.mbox, or a path ending in .mbox)" default:"."`
+}
+
+type gmailExportItem struct {
+	ID       string `json:"id"`
+	ThreadID string `json:"thread_id,omitempty"`
+	Path     string `json:"path"`
+	Size     int    `json:"size"`
+}
+
+func (c *GmailExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	ids := make([]string, 0, len(c.MessageIDs))
+	for _, id := range c.MessageIDs {
+		if id = normalizeGmailMessageID(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	query := strings.TrimSpace(c.Query)
+	if len(ids) == 0 && query == "" {
+		return usage("provide message IDs or --query")
+	}
+
+	out, err := config.ExpandPath(strings.TrimSpace(c.Out))
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = "."
+	}
+	// Catch --out paths whose extension names the other format instead of
+	// silently creating a directory called x.mbox or x.eml.
+	switch ext := strings.ToLower(filepath.Ext(out)); {
+	case c.Format != gmailExportMbox && ext == ".mbox":
+		return usagef("--out %s is an mbox file; use --format mbox or pass a directory for eml", out)
+	case ext == ".eml":
+		return usagef("--out %s is a single .eml file; pass a directory (eml) or a .mbox path (mbox)", out)
+	}
+	mboxPath := ""
+	if c.Format == gmailExportMbox {
+		mboxPath = filepath.Join(out, "gmail-export.mbox")
+		if strings.HasSuffix(strings.ToLower(out), ".mbox") {
+			mboxPath = out
+		}
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	if query != "" {
+		found, searchErr := searchMessageIDs(ctx, svc, query, c.Max)
+		if searchErr != nil {
+			return searchErr
+		}
+		ids = append(ids, found...)
+	}
+	if len(ids) == 0 {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"format": c.Format, "count": 0, "messages": []gmailExportItem{}})
+		}
+		u.Err().Println("No messages")
+		return nil
+	}
+
+	// The mbox is written to a temp file next to the target and renamed into
+	// place once complete, so a failed export never leaves a truncated file.
+	var (
+		mw      *mbox.Writer
+		mboxTmp *os.File
+	)
+	if mboxPath != "" {
+		if mkErr := os.MkdirAll(filepath.Dir(mboxPath), 0o700); mkErr != nil {
+			return mkErr
+		}
+		f, createErr := os.CreateTemp(filepath.Dir(mboxPath), ".gog-export-*.mbox")
+		if createErr != nil {
+			return createErr
+		}
+		mboxTmp = f
+		defer func() {
+			_ = mboxTmp.Close()
+			_ = os.Remove(mboxTmp.Name())
+		}()
+		mw = mbox.NewWriter(f)
+	} else if mkErr := os.MkdirAll(out, 0o700); mkErr != nil {
+		return mkErr
+	}
+
+	items := make([]gmailExportItem, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		msg, getErr := svc.Users.Messages.Get("me", id).Format(gmailFormatRaw).Context(ctx).Do()
+		if getErr != nil {
+			return fmt.Errorf("get message %s: %w", id, getErr)
+		}
+		raw, decodeErr := base64.RawURLEncoding.DecodeString(strings.TrimRight(msg.Raw, "="))
+		if decodeErr != nil {
+			return fmt.Errorf("decode message %s: %w", id, decodeErr)
+		}
+
+		item := gmailExportItem{ID: msg.Id, ThreadID: msg.ThreadId, Size: len(raw)}
+		if mw != nil {
+			if writeErr := mw.WriteMessage(raw, time.UnixMilli(msg.InternalDate)); writeErr != nil {
+				return writeErr
+			}
+			item.Path = mboxPath
+		} else {
+			item.Path = filepath.Join(out, msg.Id+".eml")
+			if writeErr := os.WriteFile(item.Path, raw, 0o600); writeErr != nil {
+				return writeErr
+			}
+		}
+		items = append(items, item)
+	}
+	if mboxTmp != nil {
+		if closeErr := mboxTmp.Close(); closeErr != nil {
+			return closeErr
+		}
+		if renameErr := os.Rename(mboxTmp.Name(), mboxPath); renameErr != nil {
+			return renameErr
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		payload := map[string]any{"format": c.Format, "count": len(items), "messages": items}
+		if mboxPath != "" {
+			payload["path"] = mboxPath
+		}
+		return outfmt.WriteJSON(ctx, os.Stdout, payload)
+	}
+
+	if mboxPath != "" {
+		u.Out().Printf("path\t%s", mboxPath)
+		u.Out().Printf("count\t%d", len(items))
+		return nil
+	}
+	w, done := tableWriter(ctx)
+	defer done()
+	_, _ = fmt.Fprintln(w, "ID\tTHREAD\tSIZE\tPATH")
+	for _, it := range items {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", it.ID, it.ThreadID, it.Size, it.Path)
+	}
+	return nil
+}
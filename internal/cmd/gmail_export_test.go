@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestGmailExport_EMLAndMbox(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	raws := map[string]string{
+		"m1": "From: ann@example.com\r\nSubject: one\r\n\r\nFrom the top\r\n",
+		"m2": "From: bob@example.com\r\nSubject: two\r\n\r\nsecond\r\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case path == "/users/me/messages" && r.Method == http.MethodGet:
+			if r.URL.Query().Get("q") != "label:reports" {
+				t.Errorf("unexpected query %q", r.URL.Query().Get("q"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}, {"id": "m2"}}})
+		case strings.HasPrefix(path, "/users/me/messages/"):
+			id := strings.TrimPrefix(path, "/users/me/messages/")
+			if _, ok := raws[id]; !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if r.URL.Query().Get("format") != "raw" {
+				t.Errorf("expected format=raw, got %q", r.URL.Query().Get("format"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":           id,
+				"threadId":     "t-" + id,
+				"internalDate": "1700000000000",
+				"raw":          base64.URLEncoding.EncodeToString([]byte(raws[id])),
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	emlDir := filepath.Join(t.TempDir(), "eml")
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "export", "m1", "--query", "label:reports", "--out", emlDir}); execErr != nil {
+			t.Fatalf("export eml: %v", execErr)
+		}
+	})
+	for id, want := range raws {
+		got, readErr := os.ReadFile(filepath.Join(emlDir, id+".eml"))
+		if readErr != nil || string(got) != want {
+			t.Fatalf("%s.eml: %q (%v)", id, got, readErr)
+		}
+	}
+
+	mboxPath := filepath.Join(t.TempDir(), "archive.mbox")
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "export", "--query", "label:reports", "--format", "mbox", "--out", mboxPath}); execErr != nil {
+			t.Fatalf("export mbox: %v", execErr)
+		}
+	})
+	var doc map[string]any
+	if uErr := json.Unmarshal([]byte(out), &doc); uErr != nil || doc["count"] != float64(2) || doc["path"] != mboxPath {
+		t.Fatalf("unexpected output %q (%v)", out, uErr)
+	}
+	b, err := os.ReadFile(mboxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "\nFrom ") != 1 || !strings.HasPrefix(string(b), "From ann@example.com ") || !strings.Contains(string(b), "\n>From the top\n") {
+		t.Fatalf("unexpected mbox:\n%s", b)
+	}
+
+	// A failed export leaves the previous mbox in place and no temp file behind.
+	if execErr := Execute([]string{"--account", "me@example.com", "gmail", "export", "m1", "gone", "--format", "mbox", "--out", mboxPath}); execErr == nil {
+		t.Fatalf("expected export of a missing message to fail")
+	}
+	if after, _ := os.ReadFile(mboxPath); string(after) != string(b) {
+		t.Fatalf("mbox changed by a failed export:\n%s", after)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(mboxPath)); len(entries) != 1 {
+		t.Fatalf("expected only the mbox, got %v", entries)
+	}
+}
+
+func TestGmailExport_RejectsMismatchedOut(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"--format", "eml", "--out", filepath.Join(dir, "x.mbox")},
+		{"--format", "mbox", "--out", filepath.Join(dir, "x.eml")},
+	} {
+		var err error
+		_ = captureStderr(t, func() {
+			err = Execute(append([]string{"--account", "me@example.com", "gmail", "export", "m1"}, args...))
+		})
+		if ExitCode(err) != 2 {
+			t.Fatalf("%v: expected usage error, got %v", args, err)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected nothing created, got %v", entries)
+	}
+}
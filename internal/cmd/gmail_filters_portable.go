@@ -0,0 +1,717 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	filtersFormatXML  = "xml"
+	filtersFormatJSON = "json"
+
+	mailFiltersAtomNS = "http://www.w3.org/2005/Atom"
+	mailFiltersAppsNS = "http://schemas.google.com/apps/2006"
+)
+
+// portableFilter is a filter with label names instead of IDs, so it can be
+// moved between accounts. System labels (INBOX, STARRED, ...) keep their IDs,
+// which are also their names.
+type portableFilter struct {
+	Criteria     *gmail.FilterCriteria `json:"criteria"`
+	AddLabels    []string              `json:"addLabels,omitempty"`
+	RemoveLabels []string              `json:"removeLabels,omitempty"`
+	Forward      string                `json:"forward,omitempty"`
+}
+
+// Gmail's web UI expresses system-label actions as boolean properties in
+// mailFilters.xml.
+var (
+	mailFiltersAddFlags = map[string]string{
+		"STARRED":   "shouldStar",
+		"TRASH":     "shouldTrash",
+		"IMPORTANT": "shouldAlwaysMarkAsImportant",
+	}
+	mailFiltersRemoveFlags = map[string]string{
+		"INBOX":     "shouldArchive",
+		"UNREAD":    "shouldMarkAsRead",
+		"SPAM":      "shouldNeverSpam",
+		"IMPORTANT": "shouldNeverMarkAsImportant",
+	}
+	mailFiltersSmartLabels = map[string]string{
+		"CATEGORY_PERSONAL":   "^smartlabel_personal",
+		"CATEGORY_SOCIAL":     "^smartlabel_social",
+		"CATEGORY_PROMOTIONS": "^smartlabel_promo",
+		"CATEGORY_UPDATES":    "^smartlabel_notification",
+		"CATEGORY_FORUMS":     "^smartlabel_group",
+	}
+	mailFiltersSizeUnits = []struct {
+		name  string
+		bytes int64
+	}{
+		{"s_smb", 1 << 20},
+		{"s_skb", 1 << 10},
+		{"s_sb", 1},
+	}
+)
+
+// Encoding and decoding use separate types: encoding/xml writes prefixed
+// names verbatim but matches elements by local name when reading.
+type mailFiltersFeedOut struct {
+	XMLName   xml.Name              `xml:"feed"`
+	Xmlns     string                `xml:"xmlns,attr"`
+	XmlnsApps string                `xml:"xmlns:apps,attr"`
+	Title     string                `xml:"title"`
+	ID        string                `xml:"id"`
+	Updated   string                `xml:"updated"`
+	Author    mailFiltersAuthor     `xml:"author"`
+	Entries   []mailFiltersEntryOut `xml:"entry"`
+}
+
+type mailFiltersAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type mailFiltersCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type mailFiltersEntryOut struct {
+	Category   mailFiltersCategory      `xml:"category"`
+	Title      string                   `xml:"title"`
+	ID         string                   `xml:"id"`
+	Updated    string                   `xml:"updated"`
+	Content    string                   `xml:"content"`
+	Properties []mailFiltersPropertyOut `xml:"apps:property"`
+}
+
+type mailFiltersPropertyOut struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type mailFiltersFeedIn struct {
+	Entries []struct {
+		Properties []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"property"`
+	} `xml:"entry"`
+}
+
+func toPortableFilter(f *gmail.Filter, idToName map[string]string) portableFilter {
+	name := func(ids []string) []string {
+		if len(ids) == 0 {
+			return nil
+		}
+		out := make([]string, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, firstNonEmpty(idToName[id], id))
+		}
+		return out
+	}
+	p := portableFilter{Criteria: f.Criteria}
+	if p.Criteria == nil {
+		p.Criteria = &gmail.FilterCriteria{}
+	}
+	if f.Action != nil {
+		p.AddLabels = name(f.Action.AddLabelIds)
+		p.RemoveLabels = name(f.Action.RemoveLabelIds)
+		p.Forward = f.Action.Forward
+	}
+	return p
+}
+
+// encodeMailFiltersXML renders filters as the Atom feed that Gmail's
+// Settings > Filters page exports and imports. The format holds one user
+// label per filter, so filters with several get one entry per label; actions
+// that have no XML property are returned as warnings.
+func encodeMailFiltersXML(filters []portableFilter, account string, now time.Time) ([]byte, []string, error) {
+	stamp := now.UTC().Format(time.RFC3339)
+	tag := fmt.Sprintf("tag:mail.google.com,2008:filter:z%013d", now.UnixMilli())
+	feed := mailFiltersFeedOut{
+		Xmlns:     mailFiltersAtomNS,
+		XmlnsApps: mailFiltersAppsNS,
+		Title:     "Mail Filters",
+		ID:        strings.Replace(tag, ":filter:", ":filters:", 1),
+		Updated:   stamp,
+		Author:    mailFiltersAuthor{Name: account, Email: account},
+	}
+	var warnings []string
+	for i, f := range filters {
+		props, labels, skipped := mailFiltersProperties(f)
+		for _, s := range skipped {
+			warnings = append(warnings, fmt.Sprintf("filter %d: %s", i+1, s))
+		}
+		if len(labels) == 0 {
+			labels = []string{""}
+		}
+		for j, label := range labels {
+			entryProps := props
+			if j > 0 {
+				// Extra labels repeat the criteria only.
+				entryProps = mailFiltersCriteriaProperties(f.Criteria)
+			}
+			if label != "" {
+				entryProps = append(entryProps, mailFiltersPropertyOut{Name: "label", Value: label})
+			}
+			feed.Entries = append(feed.Entries, mailFiltersEntryOut{
+				Category:   mailFiltersCategory{Term: "filter"},
+				Title:      "Mail Filter",
+				ID:         fmt.Sprintf("%s*%d", tag, len(feed.Entries)),
+				Updated:    stamp,
+				Properties: entryProps,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+	if err := enc.Encode(feed); err != nil {
+		return nil, nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), warnings, nil
+}
+
+func mailFiltersCriteriaProperties(c *gmail.FilterCriteria) []mailFiltersPropertyOut {
+	var props []mailFiltersPropertyOut
+	add := func(name, value string) {
+		if value != "" {
+			props = append(props, mailFiltersPropertyOut{Name: name, Value: value})
+		}
+	}
+	if c == nil {
+		return nil
+	}
+	add("from", c.From)
+	add("to", c.To)
+	add("subject", c.Subject)
+	add("hasTheWord", c.Query)
+	add("doesNotHaveTheWord", c.NegatedQuery)
+	if c.HasAttachment {
+		add("hasAttachment", "true")
+	}
+	if c.ExcludeChats {
+		add("excludeChats", "true")
+	}
+	if c.Size > 0 {
+		op := "s_sl"
+		if c.SizeComparison == "smaller" {
+			op = "s_ss"
+		}
+		for _, unit := range mailFiltersSizeUnits {
+			if c.Size%unit.bytes == 0 {
+				add("size", strconv.FormatInt(c.Size/unit.bytes, 10))
+				add("sizeOperator", op)
+				add("sizeUnit", unit.name)
+				break
+			}
+		}
+	}
+	return props
+}
+
+// mailFiltersProperties returns the criteria and action properties of f, the
+// user labels to apply, and descriptions of actions the XML cannot express.
+func mailFiltersProperties(f portableFilter) ([]mailFiltersPropertyOut, []string, []string) {
+	props := mailFiltersCriteriaProperties(f.Criteria)
+	var labels, skipped []string
+	for _, label := range f.AddLabels {
+		switch {
+		case mailFiltersAddFlags[label] != "":
+			props = append(props, mailFiltersPropertyOut{Name: mailFiltersAddFlags[label], Value: "true"})
+		case mailFiltersSmartLabels[label] != "":
+			props = append(props, mailFiltersPropertyOut{Name: "smartLabelToApply", Value: mailFiltersSmartLabels[label]})
+		case isSystemLabelID(label):
+			skipped = append(skipped, "cannot express adding "+label)
+		default:
+			labels = append(labels, label)
+		}
+	}
+	for _, label := range f.RemoveLabels {
+		if flag := mailFiltersRemoveFlags[label]; flag != "" {
+			props = append(props, mailFiltersPropertyOut{Name: flag, Value: "true"})
+			continue
+		}
+		skipped = append(skipped, "cannot express removing "+label)
+	}
+	if f.Forward != "" {
+		props = append(props, mailFiltersPropertyOut{Name: "forwardTo", Value: f.Forward})
+	}
+	return props, labels, skipped
+}
+
+// decodeMailFiltersXML parses a mailFilters.xml feed. Unknown properties are
+// returned as warnings rather than failing the whole import.
+func decodeMailFiltersXML(data []byte) ([]portableFilter, []string, error) {
+	var feed mailFiltersFeedIn
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, nil, fmt.Errorf("parse filters XML: %w", err)
+	}
+	addFlags := invertStringMap(mailFiltersAddFlags)
+	removeFlags := invertStringMap(mailFiltersRemoveFlags)
+	smartLabels := invertStringMap(mailFiltersSmartLabels)
+
+	var (
+		filters  []portableFilter
+		warnings []string
+	)
+	for i, entry := range feed.Entries {
+		f := portableFilter{Criteria: &gmail.FilterCriteria{}}
+		var size int64
+		unit := int64(1)
+		for _, prop := range entry.Properties {
+			value := prop.Value
+			switch prop.Name {
+			case "from":
+				f.Criteria.From = value
+			case "to":
+				f.Criteria.To = value
+			case "subject":
+				f.Criteria.Subject = value
+			case "hasTheWord":
+				f.Criteria.Query = value
+			case "doesNotHaveTheWord":
+				f.Criteria.NegatedQuery = value
+			case "hasAttachment":
+				f.Criteria.HasAttachment = value == "true"
+			case "excludeChats":
+				f.Criteria.ExcludeChats = value == "true"
+			case "size":
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, nil, fmt.Errorf("filter %d: invalid size %q", i+1, value)
+				}
+				size = n
+			case "sizeOperator":
+				f.Criteria.SizeComparison = "larger"
+				if value == "s_ss" {
+					f.Criteria.SizeComparison = "smaller"
+				}
+			case "sizeUnit":
+				for _, u := range mailFiltersSizeUnits {
+					if u.name == value {
+						unit = u.bytes
+					}
+				}
+			case "label":
+				f.AddLabels = append(f.AddLabels, value)
+			case "smartLabelToApply":
+				if label := smartLabels[value]; label != "" {
+					f.AddLabels = append(f.AddLabels, label)
+				} else {
+					warnings = append(warnings, fmt.Sprintf("filter %d: unknown smart label %q", i+1, value))
+				}
+			case "forwardTo":
+				f.Forward = value
+			default:
+				switch {
+				case addFlags[prop.Name] != "":
+					if value == "true" {
+						f.AddLabels = append(f.AddLabels, addFlags[prop.Name])
+					}
+				case removeFlags[prop.Name] != "":
+					if value == "true" {
+						f.RemoveLabels = append(f.RemoveLabels, removeFlags[prop.Name])
+					}
+				default:
+					warnings = append(warnings, fmt.Sprintf("filter %d: ignored property %q", i+1, prop.Name))
+				}
+			}
+		}
+		if size > 0 {
+			f.Criteria.Size = size * unit
+			if f.Criteria.SizeComparison == "" {
+				f.Criteria.SizeComparison = "larger"
+			}
+		} else {
+			f.Criteria.SizeComparison = ""
+		}
+		filters = append(filters, f)
+	}
+	return filters, warnings, nil
+}
+
+// decodePortableFilters reads either mailFilters.xml or the JSON written by
+// `gmail filters export --format json` (a bare array is accepted too).
+func decodePortableFilters(data []byte) ([]portableFilter, []string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return decodeMailFiltersXML(trimmed)
+	}
+	var filters []portableFilter
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &filters); err != nil {
+			return nil, nil, fmt.Errorf("parse filters JSON: %w", err)
+		}
+	} else {
+		var doc struct {
+			Filters []portableFilter `json:"filters"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, nil, fmt.Errorf("parse filters JSON: %w", err)
+		}
+		filters = doc.Filters
+	}
+	for i := range filters {
+		if filters[i].Criteria == nil {
+			filters[i].Criteria = &gmail.FilterCriteria{}
+		}
+	}
+	return filters, nil, nil
+}
+
+func invertStringMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[v] = k
+	}
+	return out
+}
+
+// filterKey identifies a filter by what it does, so an import can skip
+// filters the account already has.
+func filterKey(f *gmail.Filter) string {
+	criteria := f.Criteria
+	if criteria == nil {
+		criteria = &gmail.FilterCriteria{}
+	}
+	action := gmail.FilterAction{}
+	if f.Action != nil {
+		action.Forward = f.Action.Forward
+		action.AddLabelIds = append([]string(nil), f.Action.AddLabelIds...)
+		action.RemoveLabelIds = append([]string(nil), f.Action.RemoveLabelIds...)
+	}
+	sort.Strings(action.AddLabelIds)
+	sort.Strings(action.RemoveLabelIds)
+	b, _ := json.Marshal(struct {
+		Criteria *gmail.FilterCriteria `json:"c"`
+		Action   gmail.FilterAction    `json:"a"`
+	}{criteria, action})
+	return string(b)
+}
+
+func describeFilterCriteria(c *gmail.FilterCriteria) string {
+	if c == nil {
+		return ""
+	}
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+":"+value)
+		}
+	}
+	add("from", c.From)
+	add("to", c.To)
+	add("subject", c.Subject)
+	add("query", c.Query)
+	add("-query", c.NegatedQuery)
+	if c.HasAttachment {
+		parts = append(parts, "has:attachment")
+	}
+	if c.Size > 0 {
+		parts = append(parts, fmt.Sprintf("%s:%d", firstNonEmpty(c.SizeComparison, "larger"), c.Size))
+	}
+	return strings.Join(parts, " ")
+}
+
+type GmailFiltersExportCmd struct {
+	Format string `name:"format" help:"Export format: xml|json (default: xml, or json with --json)"`
+	Out    string `name:"out" aliases:"output" help:"Write the export to a file instead of stdout"`
+}
+
+func (c *GmailFiltersExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	format := strings.ToLower(strings.TrimSpace(c.Format))
+	switch format {
+	case "":
+		format = filtersFormatXML
+		if outfmt.IsJSON(ctx) {
+			format = filtersFormatJSON
+		}
+	case filtersFormatXML, filtersFormatJSON:
+	default:
+		return usagef("invalid --format %q (expected xml or json)", c.Format)
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+	filters := make([]portableFilter, 0, len(resp.Filter))
+	for _, f := range resp.Filter {
+		filters = append(filters, toPortableFilter(f, idToName))
+	}
+
+	out := strings.TrimSpace(c.Out)
+	if out == "" && format == filtersFormatJSON {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"filters": filters})
+	}
+
+	var (
+		rendered []byte
+		warnings []string
+	)
+	if format == filtersFormatXML {
+		rendered, warnings, err = encodeMailFiltersXML(filters, account, time.Now())
+	} else {
+		rendered, err = json.MarshalIndent(map[string]any{"filters": filters}, "", "  ")
+		rendered = append(rendered, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		u.Err().Printf("warning: %s", w)
+	}
+
+	if out == "" {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+				"format":   format,
+				"count":    len(filters),
+				"content":  string(rendered),
+				"warnings": warnings,
+			})
+		}
+		_, err = os.Stdout.Write(rendered)
+		return err
+	}
+
+	path, err := config.ExpandPath(out)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, rendered, 0o600); err != nil { //nolint:gosec // user-provided path
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"format":   format,
+			"count":    len(filters),
+			"path":     path,
+			"warnings": warnings,
+		})
+	}
+	u.Out().Printf("format\t%s", format)
+	u.Out().Printf("filters\t%d", len(filters))
+	u.Out().Printf("path\t%s", path)
+	return nil
+}
+
+type GmailFiltersImportCmd struct {
+	File           string `arg:"" name:"file" help:"mailFilters.xml or JSON export to import (- for stdin)"`
+	NoCreateLabels bool   `name:"no-create-labels" help:"Fail instead of creating labels that do not exist"`
+}
+
+type filterImportResult struct {
+	Index    int    `json:"index"`
+	Criteria string `json:"criteria"`
+	Status   string `json:"status"`
+	FilterID string `json:"filterId,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+const (
+	filterImportCreated = "created"
+	filterImportExists  = "exists"
+	filterImportFailed  = "failed"
+)
+
+func (c *GmailFiltersImportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	inPath := strings.TrimSpace(c.File)
+	if inPath == "" {
+		return usage("empty file")
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if inPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		inPath, err = config.ExpandPath(inPath)
+		if err != nil {
+			return err
+		}
+		data, err = os.ReadFile(inPath) //nolint:gosec // user-provided path
+	}
+	if err != nil {
+		return err
+	}
+	filters, warnings, err := decodePortableFilters(data)
+	if err != nil {
+		return usage(err.Error())
+	}
+	if len(filters) == 0 {
+		return usage("no filters found in " + c.File)
+	}
+	for _, w := range warnings {
+		u.Err().Printf("warning: %s", w)
+	}
+
+	if err := dryRunExit(ctx, flags, "gmail.filters.import", map[string]any{
+		"file":     inPath,
+		"count":    len(filters),
+		"filters":  filters,
+		"warnings": warnings,
+	}); err != nil {
+		return err
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	nameToID, err := fetchLabelNameToID(svc)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	seen := map[string]bool{}
+	for _, f := range filters {
+		for _, label := range append(append([]string(nil), f.AddLabels...), f.RemoveLabels...) {
+			key := strings.ToLower(label)
+			if isSystemLabelID(label) || nameToID[key] != "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			missing = append(missing, label)
+		}
+	}
+	if len(missing) > 0 && c.NoCreateLabels {
+		return usagef("labels not found: %s", strings.Join(missing, ", "))
+	}
+	var createdLabels []string
+	for _, name := range missing {
+		label, createErr := createLabel(ctx, svc, name)
+		if createErr != nil {
+			if isDuplicateLabelError(createErr) {
+				continue
+			}
+			return fmt.Errorf("create label %q: %w", name, createErr)
+		}
+		nameToID[strings.ToLower(name)] = label.Id
+		createdLabels = append(createdLabels, name)
+	}
+	if len(createdLabels) < len(missing) {
+		// Another client created some of them meanwhile; pick up their IDs.
+		if nameToID, err = fetchLabelNameToID(svc); err != nil {
+			return err
+		}
+	}
+
+	existing, err := svc.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(existing.Filter))
+	for _, f := range existing.Filter {
+		have[filterKey(f)] = true
+	}
+
+	results := make([]filterImportResult, 0, len(filters))
+	failed := 0
+	for i, p := range filters {
+		filter := &gmail.Filter{
+			Criteria: p.Criteria,
+			Action: &gmail.FilterAction{
+				AddLabelIds:    resolveLabelIDs(p.AddLabels, nameToID),
+				RemoveLabelIds: resolveLabelIDs(p.RemoveLabels, nameToID),
+				Forward:        p.Forward,
+			},
+		}
+		result := filterImportResult{Index: i + 1, Criteria: describeFilterCriteria(p.Criteria)}
+		key := filterKey(filter)
+		if have[key] {
+			result.Status = filterImportExists
+			results = append(results, result)
+			continue
+		}
+		created, createErr := svc.Users.Settings.Filters.Create("me", filter).Context(ctx).Do()
+		if createErr != nil {
+			result.Status = filterImportFailed
+			result.Error = createErr.Error()
+			failed++
+		} else {
+			result.Status = filterImportCreated
+			result.FilterID = created.Id
+			have[key] = true
+		}
+		results = append(results, result)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"results":       results,
+			"count":         len(results),
+			"failed":        failed,
+			"labelsCreated": createdLabels,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, name := range createdLabels {
+			u.Err().Printf("Created label %s", name)
+		}
+		w, flush := tableWriter(ctx)
+		fmt.Fprintln(w, "#\tSTATUS\tID\tCRITERIA")
+		for _, r := range results {
+			detail := r.FilterID
+			if r.Error != "" {
+				detail = r.Error
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", r.Index, r.Status, sanitizeTab(detail), sanitizeTab(r.Criteria))
+		}
+		flush()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d filters failed to import", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestMailFiltersXMLRoundTrip(t *testing.T) {
+	filters := []portableFilter{
+		{
+			Criteria:     &gmail.FilterCriteria{From: "news@example.com", Size: 2 << 20, SizeComparison: "larger"},
+			AddLabels:    []string{"Newsletters", "Reading/Later", "STARRED", "CATEGORY_PROMOTIONS"},
+			RemoveLabels: []string{"INBOX", "UNREAD"},
+		},
+		{
+			Criteria:     &gmail.FilterCriteria{Query: "invoice", NegatedQuery: "draft", HasAttachment: true},
+			RemoveLabels: []string{"SPAM", "Work"},
+			Forward:      "books@example.com",
+		},
+	}
+	data, warnings, err := encodeMailFiltersXML(filters, "me@example.com", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	xmlText := string(data)
+	for _, want := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:apps="http://schemas.google.com/apps/2006">`,
+		`<apps:property name="shouldArchive" value="true"></apps:property>`,
+		`<apps:property name="smartLabelToApply" value="^smartlabel_promo"></apps:property>`,
+		`<apps:property name="size" value="2"></apps:property>`,
+		`<apps:property name="sizeUnit" value="s_smb"></apps:property>`,
+	} {
+		if !strings.Contains(xmlText, want) {
+			t.Fatalf("xml missing %q:\n%s", want, xmlText)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "removing Work") {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+
+	decoded, warnings, err := decodePortableFilters(data)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("decode: %v %v", err, warnings)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("expected one entry per user label plus one, got %d", len(decoded))
+	}
+	first := decoded[0]
+	if first.Criteria.From != "news@example.com" || first.Criteria.Size != 2<<20 || first.Criteria.SizeComparison != "larger" ||
+		strings.Join(first.AddLabels, ",") != "STARRED,CATEGORY_PROMOTIONS,Newsletters" || strings.Join(first.RemoveLabels, ",") != "INBOX,UNREAD" {
+		t.Fatalf("unexpected first filter: %+v %+v", first, first.Criteria)
+	}
+	if second := decoded[1]; second.Criteria.From != "news@example.com" || strings.Join(second.AddLabels, ",") != "Reading/Later" || len(second.RemoveLabels) != 0 {
+		t.Fatalf("unexpected extra label entry: %+v", second)
+	}
+	if third := decoded[2]; third.Criteria.Query != "invoice" || third.Criteria.NegatedQuery != "draft" || !third.Criteria.HasAttachment ||
+		third.Forward != "books@example.com" || strings.Join(third.RemoveLabels, ",") != "SPAM" {
+		t.Fatalf("unexpected third filter: %+v", third)
+	}
+}
+
+func TestGmailFiltersExportImport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var (
+		mu            sync.Mutex
+		labels        = []map[string]any{{"id": "INBOX", "name": "INBOX"}, {"id": "Label_1", "name": "Receipts"}}
+		createdLabels []string
+		filters       = []*gmail.Filter{{
+			Id:       "f1",
+			Criteria: &gmail.FilterCriteria{From: "shop@example.com"},
+			Action:   &gmail.FilterAction{AddLabelIds: []string{"Label_1"}, RemoveLabelIds: []string{"INBOX"}},
+		}}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && path == "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": labels})
+		case r.Method == http.MethodPost && path == "/labels":
+			var l gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			id := "Label_" + l.Name
+			createdLabels = append(createdLabels, l.Name)
+			labels = append(labels, map[string]any{"id": id, "name": l.Name})
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "name": l.Name})
+		case r.Method == http.MethodGet && path == "/settings/filters":
+			_ = json.NewEncoder(w).Encode(map[string]any{"filter": filters})
+		case r.Method == http.MethodPost && path == "/settings/filters":
+			var f gmail.Filter
+			_ = json.NewDecoder(r.Body).Decode(&f)
+			f.Id = "new" + f.Criteria.From
+			filters = append(filters, &f)
+			_ = json.NewEncoder(w).Encode(f)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "filters", "export"}); execErr != nil {
+			t.Fatalf("export: %v", execErr)
+		}
+	})
+	if !strings.Contains(out, `name="label" value="Receipts"`) || !strings.Contains(out, `name="shouldArchive" value="true"`) {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+
+	// The exported filter already exists; the new ones need a label created.
+	out = strings.Replace(out, "</feed>", `<entry><category term="filter"></category><title>Mail Filter</title>`+
+		`<apps:property name="from" value="bank@example.com"/><apps:property name="label" value="Finance/Bank"/>`+
+		`<apps:property name="shouldMarkAsRead" value="true"/></entry></feed>`, 1)
+	file := filepath.Join(t.TempDir(), "mailFilters.xml")
+	if err := os.WriteFile(file, []byte(out), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	out = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "filters", "import", file}); execErr != nil {
+			t.Fatalf("import: %v", execErr)
+		}
+	})
+	var result struct {
+		Results       []filterImportResult `json:"results"`
+		LabelsCreated []string             `json:"labelsCreated"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	if len(result.Results) != 2 || result.Results[0].Status != filterImportExists || result.Results[1].Status != filterImportCreated {
+		t.Fatalf("unexpected results: %+v", result.Results)
+	}
+	if len(createdLabels) != 1 || createdLabels[0] != "Finance/Bank" {
+		t.Fatalf("unexpected created labels: %v", createdLabels)
+	}
+	created := filters[len(filters)-1]
+	if created.Criteria.From != "bank@example.com" || strings.Join(created.Action.AddLabelIds, ",") != "Label_Finance/Bank" ||
+		strings.Join(created.Action.RemoveLabelIds, ",") != "UNREAD" {
+		t.Fatalf("unexpected created filter: %+v %+v", created.Criteria, created.Action)
+	}
+
+	// Re-importing is a no-op.
+	out = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "filters", "import", file}); execErr != nil {
+			t.Fatalf("re-import: %v", execErr)
+		}
+	})
+	if strings.Contains(out, `"created"`) {
+		t.Fatalf("expected no new filters on re-import: %s", out)
+	}
+}
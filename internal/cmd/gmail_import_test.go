@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestGmailImport_MaildirLabelsDedupeAndResume(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	root := t.TempDir()
+	files := map[string]string{
+		"cur/1.host:2,S":               "Message-ID: <a@x>\r\nSubject: read\r\n\r\na\r\n",
+		"new/2.host":                   "Message-ID: <b@x>\r\nSubject: unread\r\n\r\nb\r\n",
+		".Sent/cur/3.host:2,S":         "Message-ID: <a@x>\r\nSubject: read\r\n\r\na\r\n",
+		".Projects.Q1/cur/4.host:2,FS": "Subject: no id\r\n\r\nc\r\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		uploads = map[string][]string{} // subject -> label IDs
+		created []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/users/me/labels"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{{"id": "INBOX", "name": "INBOX"}, {"id": "Label_old", "name": "Old"}}})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/users/me/labels"):
+			var l gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			mu.Lock()
+			created = append(created, l.Name)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "Label_" + l.Name, "name": l.Name})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/users/me/messages/import"):
+			if r.URL.Query().Get("internalDateSource") != "dateHeader" {
+				t.Errorf("expected internalDateSource=dateHeader, got %q", r.URL.RawQuery)
+			}
+			meta, raw := readImportUpload(t, r)
+			msg, parseErr := mail.ReadMessage(strings.NewReader(raw))
+			if parseErr != nil {
+				t.Errorf("parse upload: %v", parseErr)
+				return
+			}
+			subject := msg.Header.Get("Subject")
+			labels := append([]string{}, meta.LabelIds...)
+			sort.Strings(labels)
+			mu.Lock()
+			uploads[subject] = labels
+			n := len(uploads)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": fmt.Sprintf("m%d", n)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	run := func(extra ...string) map[string]any {
+		t.Helper()
+		args := append([]string{"--json", "--account", "me@example.com", "gmail", "import", root, "--label", "Old", "--label-prefix", "Imported", "--concurrency", "2"}, extra...)
+		out := captureStdout(t, func() {
+			if execErr := Execute(args); execErr != nil {
+				t.Fatalf("import: %v", execErr)
+			}
+		})
+		var doc map[string]any
+		if uErr := json.Unmarshal([]byte(out), &doc); uErr != nil {
+			t.Fatalf("unmarshal: %v (out=%q)", uErr, out)
+		}
+		return doc
+	}
+
+	first := run("--limit", "2")
+	if first["imported"] != float64(2) || first["messages"] != float64(3) || first["duplicates"] != float64(1) {
+		t.Fatalf("unexpected first run: %v", first)
+	}
+	second := run()
+	if second["imported"] != float64(1) || second["skipped"] != float64(2) || second["remaining"] != float64(0) {
+		t.Fatalf("unexpected second run: %v", second)
+	}
+
+	want := map[string]string{
+		"read":   "INBOX,Label_old,SENT",
+		"unread": "INBOX,Label_old,UNREAD",
+		"no id":  "Label_Imported/Projects/Q1,Label_old,STARRED",
+	}
+	if len(uploads) != len(want) {
+		t.Fatalf("uploads = %v", uploads)
+	}
+	for subject, labels := range want {
+		if got := strings.Join(uploads[subject], ","); got != labels {
+			t.Fatalf("%s: labels %s, want %s", subject, got, labels)
+		}
+	}
+	if len(created) != 1 || created[0] != "Imported/Projects/Q1" {
+		t.Fatalf("created labels = %v", created)
+	}
+}
+
+func TestGmailImport_MboxTakeoutLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "All mail.mbox")
+	content := "From 1@xxx Mon Jan  1 00:00:00 2024\n" +
+		"X-Gmail-Labels: Inbox,Unread,Category Promotions,Work/Clients\n" +
+		"Message-ID: <t1@x>\nSubject: one\n\n>From the archive\n\n" +
+		"From 2@xxx Mon Jan  1 00:00:00 2024\n" +
+		"Status: RO\nMessage-ID: <t2@x>\nSubject: two\n\nbody\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &GmailImportCmd{LabelPrefix: "Old"}
+	scan, err := cmd.scan(path)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if scan.Format != importFormatMbox || len(scan.Items) != 2 {
+		t.Fatalf("unexpected scan: %#v", scan)
+	}
+	first, second := scan.Items[0], scan.Items[1]
+	if strings.Join(first.Labels, ",") != "INBOX,CATEGORY_PROMOTIONS,Old/Work/Clients" || !first.Unread {
+		t.Fatalf("unexpected takeout labels: %#v", first)
+	}
+	// "All mail" maps to archived: no folder label.
+	if len(second.Labels) != 0 || second.Unread {
+		t.Fatalf("unexpected folder labels: %#v", second)
+	}
+
+	raw, err := readImportItem(second)
+	if err != nil || !strings.HasPrefix(string(raw), "Status: RO\n") {
+		t.Fatalf("readImportItem: %q (%v)", raw, err)
+	}
+}
+
+func readImportUpload(t *testing.T, r *http.Request) (gmail.Message, string) {
+	t.Helper()
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("content type: %v", err)
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	var meta gmail.Message
+	metaPart, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("meta part: %v", err)
+	}
+	_ = json.NewDecoder(metaPart).Decode(&meta)
+	mediaPart, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("media part: %v", err)
+	}
+	raw, _ := io.ReadAll(mediaPart)
+	return meta, string(raw)
+}
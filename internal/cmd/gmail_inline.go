@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+// imgSrcRe matches the src attribute of an <img> tag. Group 1 is the quoted
+// value including quotes, or the bare value.
+var imgSrcRe = regexp.MustCompile(`(?i)<img\b[^>]*?\ssrc\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+
+var contentIDUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// embedInlineImages turns image references in htmlBody into inline
+// multipart/related parts. Explicit --inline name=path specs are always
+// attached as cid:name, and any <img src> that names one of them is rewritten.
+// With embedLocal, an <img src> pointing at an existing local image file
+// (relative paths resolve against baseDir) is embedded too; sources that are
+// missing or not image/* files are left alone, as are remote, data: and cid:.
+func embedInlineImages(htmlBody string, specs []string, baseDir string, embedLocal bool) (string, []mailAttachment, error) {
+	inline := make([]mailAttachment, 0, len(specs))
+	byName := map[string]string{}
+	byPath := map[string]string{}
+
+	for _, spec := range specs {
+		name, path, ok := strings.Cut(spec, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || name == "" || path == "" {
+			return "", nil, usagef("invalid --inline %q (expected name=path)", spec)
+		}
+		if contentIDUnsafeRe.MatchString(name) {
+			return "", nil, usagef("invalid --inline name %q (use letters, digits, '.', '_' or '-')", name)
+		}
+		if _, dup := byName[name]; dup {
+			return "", nil, usagef("duplicate --inline name %q", name)
+		}
+		expanded, err := inlineImagePath(path, "")
+		if err != nil {
+			return "", nil, err
+		}
+		mimeType := imageMIMEType(expanded)
+		if mimeType == "" {
+			return "", nil, usagef("--inline %s is not an image", path)
+		}
+		byName[name] = name
+		byPath[expanded] = name
+		inline = append(inline, mailAttachment{Path: expanded, ContentID: name, MIMEType: mimeType})
+	}
+
+	if strings.TrimSpace(htmlBody) == "" {
+		if len(inline) > 0 {
+			return "", nil, usage("--inline requires --body-html or --body-markdown")
+		}
+		return htmlBody, nil, nil
+	}
+
+	var (
+		out  strings.Builder
+		last int
+	)
+	for _, m := range imgSrcRe.FindAllStringSubmatchIndex(htmlBody, -1) {
+		start, end := m[2], m[3]
+		raw := htmlBody[start:end]
+		quote := ""
+		if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') {
+			quote = raw[:1]
+			raw = raw[1 : len(raw)-1]
+		}
+		src := strings.TrimSpace(html.UnescapeString(raw))
+
+		cid, ok := byName[src]
+		if !ok {
+			if !embedLocal {
+				continue
+			}
+			path, local := localImageSource(src)
+			if !local {
+				continue
+			}
+			expanded, mimeType, found := localImageFile(path, baseDir)
+			if !found {
+				continue
+			}
+			if cid, ok = byPath[expanded]; !ok {
+				cid = fmt.Sprintf("img%d-%s", len(inline)+1, contentIDUnsafeRe.ReplaceAllString(filepath.Base(expanded), "_"))
+				byPath[expanded] = cid
+				inline = append(inline, mailAttachment{Path: expanded, ContentID: cid, MIMEType: mimeType})
+			}
+		}
+
+		if quote == "" {
+			quote = `"`
+		}
+		out.WriteString(htmlBody[last:start])
+		out.WriteString(quote + "cid:" + cid + quote)
+		last = end
+	}
+	out.WriteString(htmlBody[last:])
+
+	return out.String(), inline, nil
+}
+
+// localImageFile resolves an <img src> path to an existing image file. Anything
+// else (missing, a directory, not image/*) reports false.
+func localImageFile(path, baseDir string) (string, string, bool) {
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return "", "", false
+	}
+	if !filepath.IsAbs(expanded) && baseDir != "" {
+		expanded = filepath.Join(baseDir, expanded)
+	}
+	expanded = filepath.Clean(expanded)
+	if st, statErr := os.Stat(expanded); statErr != nil || !st.Mode().IsRegular() {
+		return "", "", false
+	}
+	mimeType := imageMIMEType(expanded)
+	return expanded, mimeType, mimeType != ""
+}
+
+// imageMIMEType returns the image/* type of path by extension, falling back
+// to content sniffing, or "" when the file is not an image.
+func imageMIMEType(path string) string {
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		f, err := os.Open(path) //nolint:gosec // user-referenced image path
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		mimeType = http.DetectContentType(head[:n])
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil && strings.HasPrefix(mediaType, "image/") {
+		return mediaType
+	}
+	return ""
+}
+
+// localImageSource reports whether src refers to a file on disk and returns
+// its path. file:// URLs count; any other scheme (http, data, cid, ...) does not.
+func localImageSource(src string) (string, bool) {
+	if src == "" || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "#") {
+		return "", false
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return src, true
+	}
+	switch {
+	case strings.EqualFold(u.Scheme, "file"):
+		return u.Path, u.Path != ""
+	case len(u.Scheme) > 1:
+		return "", false
+	default:
+		return src, true
+	}
+}
+
+func inlineImagePath(path, baseDir string) (string, error) {
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expanded) && baseDir != "" {
+		expanded = filepath.Join(baseDir, expanded)
+	}
+	expanded = filepath.Clean(expanded)
+
+	st, err := os.Stat(expanded)
+	if err != nil {
+		return "", fmt.Errorf("inline image %s: %w", path, err)
+	}
+	if st.IsDir() {
+		return "", usagef("inline image %s is a directory", path)
+	}
+	return expanded, nil
+}
+
+// inlineBaseDir is the directory relative image paths resolve against: the
+// Markdown file's directory when the body came from one, else the working dir.
+func inlineBaseDir(markdownFile string) string {
+	markdownFile = strings.TrimSpace(markdownFile)
+	if markdownFile == "" || markdownFile == "-" {
+		return ""
+	}
+	expanded, err := config.ExpandPath(markdownFile)
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(expanded)
+}
+
+// inlinePaths maps cid -> path for dry-run and JSON output.
+func inlinePaths(inline []mailAttachment) map[string]string {
+	out := make(map[string]string, len(inline))
+	for _, a := range inline {
+		out[a.ContentID] = a.Path
+	}
+	return out
+}
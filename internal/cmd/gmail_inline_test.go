@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbedInlineImages(t *testing.T) {
+	dir := t.TempDir()
+	chart := filepath.Join(dir, "chart.png")
+	logo := filepath.Join(dir, "logo.png")
+	for _, p := range []string{chart, logo} {
+		if err := os.WriteFile(p, []byte("png"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	in := `<p><img src="./chart.png" alt="c"> <img alt=x src='chart.png'>` +
+		`<img src="https://example.com/a.png"><img src="cid:keep"><img src=logo></p>`
+	out, inline, err := embedInlineImages(in, []string{"logo=" + logo}, dir, true)
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+
+	want := `<p><img src="cid:img2-chart.png" alt="c"> <img alt=x src='cid:img2-chart.png'>` +
+		`<img src="https://example.com/a.png"><img src="cid:keep"><img src="cid:logo"></p>`
+	if out != want {
+		t.Fatalf("html:\n got %s\nwant %s", out, want)
+	}
+	if len(inline) != 2 || inline[0].ContentID != "logo" || inline[1].ContentID != "img2-chart.png" || inline[1].Path != chart {
+		t.Fatalf("unexpected inline parts: %#v", inline)
+	}
+
+	// Without embedLocal only --inline names are rewritten.
+	out, inline, err = embedInlineImages(in, []string{"logo=" + logo}, dir, false)
+	if err != nil || len(inline) != 1 || !strings.Contains(out, `<img src="./chart.png" alt="c">`) || !strings.Contains(out, `src="cid:logo"`) {
+		t.Fatalf("unexpected opt-out result: %s %#v (%v)", out, inline, err)
+	}
+
+	// Missing files and non-images are left alone instead of failing the send.
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("secret"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	keep := `<img src="missing.png"><img src="notes.txt"><img src="` + dir + `">`
+	if out, inline, err := embedInlineImages(keep, nil, dir, true); err != nil || out != keep || len(inline) != 0 {
+		t.Fatalf("expected sources to be left alone, got %s %#v (%v)", out, inline, err)
+	}
+	if _, _, err := embedInlineImages(`<p>x</p>`, []string{"n=" + notes}, dir, false); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for non-image --inline, got %v", err)
+	}
+	if _, _, err := embedInlineImages(`<p>x</p>`, []string{"bad"}, dir, false); err == nil || ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for bad spec, got %v", err)
+	}
+	if _, _, err := embedInlineImages("", []string{"logo=" + logo}, dir, false); err == nil || ExitCode(err) != 2 {
+		t.Fatalf("expected usage error without HTML body, got %v", err)
+	}
+}
+
+func TestBuildRFC822InlineRelated(t *testing.T) {
+	dir := t.TempDir()
+	attach := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(attach, []byte("%PDF"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	raw, err := buildRFC822(mailOptions{
+		From:        "a@b.com",
+		To:          []string{"c@d.com"},
+		Subject:     "Weekly",
+		Body:        "See chart",
+		BodyHTML:    `<img src="cid:chart">`,
+		Attachments: []mailAttachment{{Path: attach}},
+		Inline:      []mailAttachment{{Filename: "chart.png", Data: []byte("png"), ContentID: "chart"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	parts := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0].ct, "multipart/alternative") || parts[1].ct != "application/pdf" {
+		t.Fatalf("unexpected mixed parts: %#v", parts)
+	}
+
+	alt := readParts(t, parts[0].ct, bytes.NewReader(parts[0].body))
+	if len(alt) != 2 || !strings.HasPrefix(alt[0].ct, "text/plain") || !strings.HasPrefix(alt[1].ct, "multipart/related") {
+		t.Fatalf("unexpected alternative parts: %#v", alt)
+	}
+
+	rel := readParts(t, alt[1].ct, bytes.NewReader(alt[1].body))
+	if len(rel) != 2 || !strings.HasPrefix(rel[0].ct, "text/html") || rel[1].ct != "image/png" {
+		t.Fatalf("unexpected related parts: %#v", rel)
+	}
+	if rel[1].header.Get("Content-Id") != "<chart>" || !strings.HasPrefix(rel[1].header.Get("Content-Disposition"), "inline") {
+		t.Fatalf("unexpected inline headers: %v", rel[1].header)
+	}
+}
+
+type mimePart struct {
+	ct     string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func readParts(t *testing.T, contentType string, r io.Reader) []mimePart {
+	t.Helper()
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("media type %q: %v", contentType, err)
+	}
+	mr := multipart.NewReader(r, params["boundary"])
+	var out []mimePart
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		out = append(out, mimePart{ct: p.Header.Get("Content-Type"), header: p.Header, body: body})
+	}
+}
@@ -18,6 +18,10 @@ type GmailLabelsCmd struct {
 	Create GmailLabelsCreateCmd `cmd:"" name:"create" aliases:"add,new" help:"Create a new label"`
 	Modify GmailLabelsModifyCmd `cmd:"" name:"modify" aliases:"update,edit,set" help:"Modify labels on threads"`
 	Delete GmailLabelsDeleteCmd `cmd:"" name:"delete" aliases:"rm,del" help:"Delete a label"`
+	Rename GmailLabelsRenameCmd `cmd:"" name:"rename" aliases:"mv-name" help:"Rename a label and all nested labels"`
+	Move   GmailLabelsMoveCmd   `cmd:"" name:"move" aliases:"mv" help:"Move a label subtree under another parent"`
+	Tree   GmailLabelsTreeCmd   `cmd:"" name:"tree" help:"Show nested labels as a tree with message counts"`
+	Color  GmailLabelsColorCmd  `cmd:"" name:"color" aliases:"colour" help:"Set or clear a label color from Gmail's palette"`
 }
 
 type GmailLabelsGetCmd struct {
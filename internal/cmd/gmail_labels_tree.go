@@ -0,0 +1,517 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// gmailLabelColors are the only colors the Gmail API accepts, for both the
+// background and the text of a label.
+var gmailLabelColors = stringSet(strings.Fields(`
+	#000000 #434343 #666666 #999999 #cccccc #efefef #f3f3f3 #ffffff
+	#fb4c2f #ffad47 #fad165 #16a766 #43d692 #4a86e8 #a479e2 #f691b3
+	#f6c5be #ffe6c7 #fef1d1 #b9e4d0 #c6f3de #c9daf8 #e4d7f5 #fcdee8
+	#efa093 #ffd6a2 #fce8b3 #89d3b2 #a0eac9 #a4c2f4 #d0bcf1 #fbc8d9
+	#e66550 #ffbc6b #fcda83 #44b984 #68dfa9 #6d9eeb #b694e8 #f7a7c0
+	#cc3a21 #eaa041 #f2c960 #149e60 #3dc789 #3c78d8 #8e63ce #e07798
+	#ac2b16 #cf8933 #d5ae49 #0b804b #2a9c68 #285bac #653e9b #b65775
+	#822111 #a46a21 #aa8831 #076239 #1a764d #1c4587 #41236d #83334c
+	#464646 #e7e7e7 #0d3472 #b6cff5 #0d3b44 #98d7e4 #3d188e #e3d7ff
+	#711a36 #fbd3e0 #8a1c0a #f2b2a8 #7a2e0b #ffc8af #7a4706 #ffdeb5
+	#594c05 #fbe983 #684e07 #fdedc1 #0b4f30 #b3efd3 #04502e #a2dcc1
+	#c2c2c2 #4986e7 #2da2bb #b99aff #994a64 #f691b2 #ff7537 #ffad46
+	#662e37 #ebdbde #cca6ac #094228 #42d692 #16a765`))
+
+type labelPaletteColor struct {
+	Name       string `json:"name"`
+	Background string `json:"background"`
+	Text       string `json:"text"`
+}
+
+// gmailLabelPalette names readable background/text pairs from the allowed
+// colors, mirroring the swatches in the Gmail web UI.
+var gmailLabelPalette = []labelPaletteColor{
+	{"red", "#fb4c2f", "#ffffff"},
+	{"orange", "#ffad47", "#ffffff"},
+	{"yellow", "#fad165", "#000000"},
+	{"green", "#16a766", "#ffffff"},
+	{"teal", "#43d692", "#ffffff"},
+	{"blue", "#4a86e8", "#ffffff"},
+	{"purple", "#a479e2", "#ffffff"},
+	{"pink", "#f691b3", "#ffffff"},
+	{"gray", "#999999", "#ffffff"},
+	{"black", "#000000", "#ffffff"},
+	{"light-red", "#f6c5be", "#ac2b16"},
+	{"light-orange", "#ffe6c7", "#a46a21"},
+	{"light-yellow", "#fef1d1", "#aa8831"},
+	{"light-green", "#b9e4d0", "#076239"},
+	{"light-teal", "#c6f3de", "#1a764d"},
+	{"light-blue", "#c9daf8", "#1c4587"},
+	{"light-purple", "#e4d7f5", "#41236d"},
+	{"light-pink", "#fcdee8", "#83334c"},
+	{"light-gray", "#efefef", "#464646"},
+	{"dark-red", "#ac2b16", "#ffffff"},
+	{"dark-orange", "#cf8933", "#ffffff"},
+	{"dark-yellow", "#d5ae49", "#ffffff"},
+	{"dark-green", "#0b804b", "#ffffff"},
+	{"dark-teal", "#2a9c68", "#ffffff"},
+	{"dark-blue", "#285bac", "#ffffff"},
+	{"dark-purple", "#653e9b", "#ffffff"},
+	{"dark-pink", "#b65775", "#ffffff"},
+	{"dark-gray", "#434343", "#ffffff"},
+}
+
+func lookupLabelPalette(name string) (labelPaletteColor, bool) {
+	key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+	key = strings.ReplaceAll(key, "grey", "gray")
+	for _, c := range gmailLabelPalette {
+		if c.Name == key {
+			return c, true
+		}
+	}
+	return labelPaletteColor{}, false
+}
+
+// validateLabelColor checks a background/text pair against the colors the
+// API accepts, so a typo fails before any request is made.
+func validateLabelColor(background, text string) error {
+	for _, c := range []struct{ field, value string }{{"background", background}, {"text", text}} {
+		if c.value == "" {
+			return usagef("label color needs both a background and a text color")
+		}
+		if _, ok := gmailLabelColors[strings.ToLower(c.value)]; !ok {
+			return usagef("%s color %s is not in Gmail's label palette (see `gog gmail labels color --list`)", c.field, c.value)
+		}
+	}
+	return nil
+}
+
+// normalizeLabelPath trims each "/"-separated segment of a nested label name.
+func normalizeLabelPath(name string) string {
+	parts := strings.Split(name, "/")
+	out := parts[:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+// findUserLabel resolves an ID or (case-insensitive) name among labels and
+// rejects system labels, which cannot be renamed or recolored.
+func findUserLabel(labels []*gmail.Label, raw string) (*gmail.Label, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, usage("empty label")
+	}
+	var found *gmail.Label
+	for _, l := range labels {
+		if l.Id == raw {
+			found = l
+			break
+		}
+	}
+	if found == nil {
+		name := normalizeLabelPath(raw)
+		for _, l := range labels {
+			if strings.EqualFold(l.Name, name) {
+				found = l
+				break
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("label not found: %s", raw)
+	}
+	if found.Type == "system" {
+		return nil, usagef("cannot change system label %q", found.Name)
+	}
+	return found, nil
+}
+
+type labelRename struct {
+	ID   string `json:"id,omitempty"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+}
+
+// planLabelRename renames label and every descendant ("Parent/Child") so the
+// subtree stays intact. Missing ancestors of the new name are returned
+// separately so they can be created first.
+func planLabelRename(labels []*gmail.Label, label *gmail.Label, newName string) ([]labelRename, []string, error) {
+	newName = normalizeLabelPath(newName)
+	if newName == "" {
+		return nil, nil, usage("new label name is required")
+	}
+	oldName := label.Name
+	if newName == oldName {
+		return nil, nil, usagef("label is already named %q", oldName)
+	}
+	if strings.HasPrefix(strings.ToLower(newName), strings.ToLower(oldName)+"/") {
+		return nil, nil, usagef("cannot move %q into its own subtree", oldName)
+	}
+
+	moving := map[string]bool{}
+	var renames []labelRename
+	for _, l := range labels {
+		if l.Type == "system" {
+			continue
+		}
+		if l.Id == label.Id || strings.HasPrefix(l.Name, oldName+"/") {
+			moving[l.Id] = true
+			renames = append(renames, labelRename{ID: l.Id, From: l.Name, To: newName + strings.TrimPrefix(l.Name, oldName)})
+		}
+	}
+	// Parents first, so Gmail shows the subtree nested throughout.
+	sort.Slice(renames, func(i, j int) bool { return renames[i].From < renames[j].From })
+
+	taken := map[string]bool{}
+	for _, l := range labels {
+		if !moving[l.Id] {
+			taken[strings.ToLower(l.Name)] = true
+		}
+	}
+	for _, r := range renames {
+		if taken[strings.ToLower(r.To)] {
+			return nil, nil, usagef("label already exists: %s", r.To)
+		}
+	}
+
+	var parents []string
+	parts := strings.Split(newName, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if !taken[strings.ToLower(parent)] {
+			parents = append(parents, parent)
+		}
+	}
+	return renames, parents, nil
+}
+
+// runLabelRename resolves raw, asks newName for the label's new full name and
+// renames its subtree.
+func runLabelRename(ctx context.Context, flags *RootFlags, op, raw string, newName func(*gmail.Label) string) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	label, err := findUserLabel(resp.Labels, raw)
+	if err != nil {
+		return err
+	}
+	renames, parents, err := planLabelRename(resp.Labels, label, newName(label))
+	if err != nil {
+		return err
+	}
+
+	if err := dryRunExit(ctx, flags, op, map[string]any{
+		"renames":        renames,
+		"createdParents": parents,
+	}); err != nil {
+		return err
+	}
+
+	for _, parent := range parents {
+		if _, err := createLabel(ctx, svc, parent); err != nil && !isDuplicateLabelError(err) {
+			return fmt.Errorf("create parent label %q: %w", parent, err)
+		}
+	}
+	for i, r := range renames {
+		if _, err := svc.Users.Labels.Patch("me", r.ID, &gmail.Label{Name: r.To}).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("rename %q to %q: %w (%d of %d labels renamed)", r.From, r.To, err, i, len(renames))
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"renames":        renames,
+			"createdParents": parents,
+		})
+	}
+	for _, parent := range parents {
+		u.Err().Printf("Created parent label %s", parent)
+	}
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "ID\tFROM\tTO")
+	for _, r := range renames {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.ID, r.From, r.To)
+	}
+	return nil
+}
+
+type GmailLabelsRenameCmd struct {
+	Label   string `arg:"" name:"labelIdOrName" help:"Label ID or name"`
+	NewName string `arg:"" name:"newName" help:"New full name (use / for nesting)"`
+}
+
+func (c *GmailLabelsRenameCmd) Run(ctx context.Context, flags *RootFlags) error {
+	return runLabelRename(ctx, flags, "gmail.labels.rename", c.Label, func(*gmail.Label) string { return c.NewName })
+}
+
+type GmailLabelsMoveCmd struct {
+	Label  string `arg:"" name:"labelIdOrName" help:"Label ID or name"`
+	Parent string `arg:"" name:"newParent" help:"New parent label name (/ for top level)"`
+}
+
+func (c *GmailLabelsMoveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	return runLabelRename(ctx, flags, "gmail.labels.move", c.Label, func(label *gmail.Label) string {
+		leaf := label.Name
+		if i := strings.LastIndex(leaf, "/"); i >= 0 {
+			leaf = leaf[i+1:]
+		}
+		if parent := normalizeLabelPath(c.Parent); parent != "" {
+			return parent + "/" + leaf
+		}
+		return leaf
+	})
+}
+
+type labelTreeNode struct {
+	ID             string            `json:"id,omitempty"`
+	Name           string            `json:"name"`
+	Path           string            `json:"path"`
+	Type           string            `json:"type,omitempty"`
+	MessagesTotal  int64             `json:"messagesTotal"`
+	MessagesUnread int64             `json:"messagesUnread"`
+	ThreadsTotal   int64             `json:"threadsTotal"`
+	ThreadsUnread  int64             `json:"threadsUnread"`
+	Color          *gmail.LabelColor `json:"color,omitempty"`
+	Children       []*labelTreeNode  `json:"children,omitempty"`
+}
+
+// buildLabelTree nests labels by their "/"-separated names. Ancestors that
+// do not exist as labels appear as nodes without an ID.
+func buildLabelTree(labels []*gmail.Label) []*labelTreeNode {
+	byPath := map[string]*labelTreeNode{}
+	var roots []*labelTreeNode
+	var node func(path string) *labelTreeNode
+	node = func(path string) *labelTreeNode {
+		if n := byPath[path]; n != nil {
+			return n
+		}
+		n := &labelTreeNode{Name: path, Path: path}
+		byPath[path] = n
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			n.Name = path[i+1:]
+			parent := node(path[:i])
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+		return n
+	}
+	for _, l := range labels {
+		n := node(l.Name)
+		n.ID = l.Id
+		n.Type = l.Type
+		n.MessagesTotal = l.MessagesTotal
+		n.MessagesUnread = l.MessagesUnread
+		n.ThreadsTotal = l.ThreadsTotal
+		n.ThreadsUnread = l.ThreadsUnread
+		n.Color = l.Color
+	}
+	var sortNodes func([]*labelTreeNode)
+	sortNodes = func(nodes []*labelTreeNode) {
+		sort.Slice(nodes, func(i, j int) bool { return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name) })
+		for _, n := range nodes {
+			sortNodes(n.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+type GmailLabelsTreeCmd struct {
+	System   bool `name:"system" help:"Include system labels (INBOX, SENT, categories, ...)"`
+	NoCounts bool `name:"no-counts" help:"Skip per-label message counts (one request per label)"`
+}
+
+func (c *GmailLabelsTreeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	labels := make([]*gmail.Label, 0, len(resp.Labels))
+	for _, l := range resp.Labels {
+		if l.Type == "system" && !c.System {
+			continue
+		}
+		if !c.NoCounts {
+			// labels.list omits counts; labels.get has them.
+			full, getErr := svc.Users.Labels.Get("me", l.Id).Context(ctx).Do()
+			if getErr != nil {
+				return getErr
+			}
+			l = full
+		}
+		labels = append(labels, l)
+	}
+	tree := buildLabelTree(labels)
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"tree": tree})
+	}
+	if len(tree) == 0 {
+		u.Err().Println("No labels")
+		return nil
+	}
+	var printNodes func(nodes []*labelTreeNode, depth int)
+	printNodes = func(nodes []*labelTreeNode, depth int) {
+		for _, n := range nodes {
+			line := strings.Repeat("  ", depth) + n.Name
+			if !c.NoCounts && n.ID != "" {
+				line += fmt.Sprintf(" (%d", n.MessagesTotal)
+				if n.MessagesUnread > 0 {
+					line += fmt.Sprintf(", %d unread", n.MessagesUnread)
+				}
+				line += ")"
+			}
+			u.Out().Println(line)
+			printNodes(n.Children, depth+1)
+		}
+	}
+	printNodes(tree, 0)
+	return nil
+}
+
+type GmailLabelsColorCmd struct {
+	Label      string `arg:"" optional:"" name:"labelIdOrName" help:"Label ID or name"`
+	Color      string `arg:"" optional:"" name:"color" help:"Named palette color (see --list)"`
+	Background string `name:"background" aliases:"bg" help:"Background color (hex from Gmail's palette)"`
+	Text       string `name:"text" aliases:"fg" help:"Text color (hex from Gmail's palette)"`
+	Clear      bool   `name:"clear" help:"Remove the label color"`
+	Recursive  bool   `name:"recursive" aliases:"r" help:"Also color all nested labels"`
+	List       bool   `name:"list" help:"List the named palette"`
+}
+
+func (c *GmailLabelsColorCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	if c.List {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"palette": gmailLabelPalette})
+		}
+		w, flush := tableWriter(ctx)
+		defer flush()
+		fmt.Fprintln(w, "NAME\tBACKGROUND\tTEXT")
+		for _, p := range gmailLabelPalette {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Background, p.Text)
+		}
+		return nil
+	}
+	if strings.TrimSpace(c.Label) == "" {
+		return usage("missing label (or use --list)")
+	}
+
+	var color *gmail.LabelColor
+	switch {
+	case c.Clear:
+		if c.Color != "" || c.Background != "" || c.Text != "" {
+			return usage("--clear cannot be combined with a color")
+		}
+	case c.Color != "":
+		if c.Background != "" || c.Text != "" {
+			return usage("use a palette name or --background/--text, not both")
+		}
+		p, ok := lookupLabelPalette(c.Color)
+		if !ok {
+			return usagef("unknown color %q (see `gog gmail labels color --list`)", c.Color)
+		}
+		color = &gmail.LabelColor{BackgroundColor: p.Background, TextColor: p.Text}
+	default:
+		if err := validateLabelColor(c.Background, c.Text); err != nil {
+			return err
+		}
+		color = &gmail.LabelColor{BackgroundColor: strings.ToLower(c.Background), TextColor: strings.ToLower(c.Text)}
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	label, err := findUserLabel(resp.Labels, c.Label)
+	if err != nil {
+		return err
+	}
+	targets := []*gmail.Label{label}
+	if c.Recursive {
+		for _, l := range resp.Labels {
+			if l.Type != "system" && strings.HasPrefix(l.Name, label.Name+"/") {
+				targets = append(targets, l)
+			}
+		}
+	}
+	names := make([]string, 0, len(targets))
+	for _, l := range targets {
+		names = append(names, l.Name)
+	}
+
+	if err := dryRunExit(ctx, flags, "gmail.labels.color", map[string]any{
+		"labels": names,
+		"color":  color,
+	}); err != nil {
+		return err
+	}
+
+	patch := &gmail.Label{Color: color}
+	if color == nil {
+		// An empty color object is how the API removes it.
+		patch.Color = &gmail.LabelColor{}
+		patch.ForceSendFields = []string{"Color"}
+	}
+	for _, l := range targets {
+		if _, err := svc.Users.Labels.Patch("me", l.Id, patch).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("color %q: %w", l.Name, err)
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"labels": names,
+			"color":  color,
+		})
+	}
+	for _, name := range names {
+		if color == nil {
+			u.Out().Printf("%s\tno color", name)
+			continue
+		}
+		u.Out().Printf("%s\t%s on %s", name, color.TextColor, color.BackgroundColor)
+	}
+	return nil
+}
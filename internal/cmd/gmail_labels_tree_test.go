@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestPlanLabelRename(t *testing.T) {
+	labels := []*gmail.Label{
+		{Id: "INBOX", Name: "INBOX", Type: "system"},
+		{Id: "L1", Name: "Clients", Type: "user"},
+		{Id: "L2", Name: "Clients/Acme", Type: "user"},
+		{Id: "L3", Name: "Clients/Acme/Invoices", Type: "user"},
+		{Id: "L4", Name: "Clientsold", Type: "user"},
+		{Id: "L5", Name: "Archive", Type: "user"},
+	}
+
+	renames, parents, err := planLabelRename(labels, labels[1], "Archive/2024/Customers")
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	var got []string
+	for _, r := range renames {
+		got = append(got, r.From+" -> "+r.To)
+	}
+	want := []string{
+		"Clients -> Archive/2024/Customers",
+		"Clients/Acme -> Archive/2024/Customers/Acme",
+		"Clients/Acme/Invoices -> Archive/2024/Customers/Acme/Invoices",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected renames:\n%s", strings.Join(got, "\n"))
+	}
+	if strings.Join(parents, ",") != "Archive/2024" {
+		t.Fatalf("unexpected parents: %v", parents)
+	}
+
+	if _, _, err := planLabelRename(labels, labels[1], "Clients/Acme/Sub"); err == nil {
+		t.Fatalf("expected error moving into own subtree")
+	}
+	if _, _, err := planLabelRename(labels, labels[2], "Clientsold"); err == nil {
+		t.Fatalf("expected conflict error")
+	}
+	if _, _, err := planLabelRename(labels, labels[1], "clients"); err != nil {
+		t.Fatalf("case-only rename: %v", err)
+	}
+}
+
+func TestLabelColorValidation(t *testing.T) {
+	if err := validateLabelColor("#16A766", "#ffffff"); err != nil {
+		t.Fatalf("valid color rejected: %v", err)
+	}
+	if err := validateLabelColor("#123456", "#ffffff"); err == nil {
+		t.Fatalf("expected off-palette color to fail")
+	}
+	if err := validateLabelColor("#16a766", ""); err == nil {
+		t.Fatalf("expected missing text color to fail")
+	}
+	for _, p := range gmailLabelPalette {
+		if err := validateLabelColor(p.Background, p.Text); err != nil {
+			t.Fatalf("palette %s: %v", p.Name, err)
+		}
+	}
+	if p, ok := lookupLabelPalette("Light_Grey"); !ok || p.Name != "light-gray" {
+		t.Fatalf("lookup alias failed: %+v", p)
+	}
+}
+
+func TestGmailLabelsTreeMoveColor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var (
+		mu      sync.Mutex
+		patches []string
+		labels  = []*gmail.Label{
+			{Id: "INBOX", Name: "INBOX", Type: "system"},
+			{Id: "L1", Name: "Work", Type: "user"},
+			{Id: "L2", Name: "Work/Projects", Type: "user"},
+			{Id: "L3", Name: "Work/Projects/Alpha", Type: "user"},
+			{Id: "L4", Name: "Home/Bills", Type: "user"},
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && path == "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": labels})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/labels/"):
+			id := strings.TrimPrefix(path, "/labels/")
+			for _, l := range labels {
+				if l.Id == id {
+					full := *l
+					full.MessagesTotal = int64(len(id)) * 10
+					full.MessagesUnread = 1
+					_ = json.NewEncoder(w).Encode(full)
+					return
+				}
+			}
+			http.NotFound(w, r)
+		case r.Method == http.MethodPatch && strings.HasPrefix(path, "/labels/"):
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			b, _ := json.Marshal(body)
+			patches = append(patches, strings.TrimPrefix(path, "/labels/")+" "+string(b))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": strings.TrimPrefix(path, "/labels/")})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "labels", "tree"}); execErr != nil {
+			t.Fatalf("tree: %v", execErr)
+		}
+	})
+	want := "Home\n  Bills (20, 1 unread)\nWork (20, 1 unread)\n  Projects (20, 1 unread)\n    Alpha (20, 1 unread)\n"
+	if out != want {
+		t.Fatalf("unexpected tree:\n%s", out)
+	}
+
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "labels", "move", "Work/Projects", "/"}); execErr != nil {
+			t.Fatalf("move: %v", execErr)
+		}
+	})
+	if strings.Join(patches, "\n") != `L2 {"name":"Projects"}`+"\n"+`L3 {"name":"Projects/Alpha"}` {
+		t.Fatalf("unexpected move patches:\n%s", strings.Join(patches, "\n"))
+	}
+
+	patches = nil
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "labels", "color", "Work", "dark-blue", "--recursive"}); execErr != nil {
+			t.Fatalf("color: %v", execErr)
+		}
+	})
+	if len(patches) != 3 || !strings.Contains(patches[0], `"backgroundColor":"#285bac"`) {
+		t.Fatalf("unexpected color patches: %v", patches)
+	}
+	if err := Execute([]string{"--account", "me@example.com", "gmail", "labels", "color", "Work", "--background", "#123456", "--text", "#ffffff"}); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for off-palette color, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/mailindex"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// GmailLocalSearchCmd searches a `gmail sync` mirror without calling the API.
+type GmailLocalSearchCmd struct {
+	Query     []string `arg:"" name:"query" help:"Search query (from:, to:, subject:, label:, in:, is:, after:, before:, has:attachment, free text)"`
+	Maildir   string   `name:"maildir" help:"Mirror to search (default: the account's only gmail sync mirror)"`
+	Max       int64    `name:"max" aliases:"limit" help:"Max results" default:"10"`
+	Page      string   `name:"page" aliases:"cursor" help:"Page token"`
+	All       bool     `name:"all" aliases:"all-pages,allpages" help:"Return all results"`
+	FailEmpty bool     `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+	Oldest    bool     `name:"oldest" help:"Show first message date instead of last"`
+	Timezone  string   `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
+	Local     bool     `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+}
+
+func (c *GmailLocalSearchCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	query := strings.TrimSpace(strings.Join(c.Query, " "))
+	if query == "" {
+		return usage("missing query")
+	}
+	if c.Max <= 0 {
+		return usage("--max must be > 0")
+	}
+	q, err := mailindex.Parse(query, time.Now())
+	if err != nil {
+		var unsupported *mailindex.UnsupportedError
+		if errors.As(err, &unsupported) {
+			return usagef("%v (use `gog gmail search` for the full syntax)", err)
+		}
+		return usage(err.Error())
+	}
+	loc, err := resolveOutputLocation(c.Timezone, c.Local)
+	if err != nil {
+		return err
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	root, err := c.mirrorRoot(account)
+	if err != nil {
+		return err
+	}
+	store, err := loadGmailSyncStore(account, root)
+	if err != nil {
+		return err
+	}
+	idx, err := mailindex.Open(gmailSyncIndexPath(store.path))
+	if err != nil {
+		return err
+	}
+	// Catch up on anything a sync with --no-index (or an interrupted one) left.
+	if _, err := updateGmailSyncIndex(idx, &store.state, root); err != nil {
+		return err
+	}
+	if err := idx.Save(); err != nil {
+		return err
+	}
+
+	all := localSearchThreads(idx, idx.Search(q), c.Oldest, loc)
+	fetch := func(pageToken string) ([]threadItem, string, error) {
+		offset := 0
+		if strings.TrimSpace(pageToken) != "" {
+			n, convErr := strconv.Atoi(pageToken)
+			if convErr != nil || n < 0 {
+				return nil, "", usagef("invalid --page %q", pageToken)
+			}
+			offset = n
+		}
+		if offset >= len(all) {
+			return nil, "", nil
+		}
+		end := min(offset+int(c.Max), len(all))
+		next := ""
+		if end < len(all) {
+			next = strconv.Itoa(end)
+		}
+		return all[offset:end], next, nil
+	}
+
+	var items []threadItem
+	nextPageToken := ""
+	if c.All {
+		if items, err = collectAllPages(c.Page, fetch); err != nil {
+			return err
+		}
+	} else if items, nextPageToken, err = fetch(c.Page); err != nil {
+		return err
+	}
+	if items == nil {
+		items = []threadItem{}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"threads":       items,
+			"nextPageToken": nextPageToken,
+		}); err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(items) == 0 {
+		u.Err().Println("No results")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+
+	fmt.Fprintln(w, "ID\tDATE\tFROM\tSUBJECT\tLABELS\tTHREAD")
+	for _, it := range items {
+		threadInfo := "-"
+		if it.MessageCount > 1 {
+			threadInfo = fmt.Sprintf("[%d msgs]", it.MessageCount)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", it.ID, it.Date, it.From, it.Subject, strings.Join(it.Labels, ","), threadInfo)
+	}
+	printNextPageHint(u, nextPageToken)
+	return nil
+}
+
+// mirrorRoot picks the Maildir to search: --maildir, or the single mirror
+// recorded for the account.
+func (c *GmailLocalSearchCmd) mirrorRoot(account string) (string, error) {
+	if strings.TrimSpace(c.Maildir) != "" {
+		root, err := config.ExpandPath(strings.TrimSpace(c.Maildir))
+		if err != nil {
+			return "", err
+		}
+		if root, err = filepath.Abs(root); err != nil {
+			return "", err
+		}
+		path, err := gmailSyncStatePath(account, root)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", usagef("no gmail sync mirror of %s at %s; run `gog gmail sync --maildir %s` first", account, root, root)
+		}
+		return root, nil
+	}
+
+	dir, err := config.GmailSyncDir()
+	if err != nil {
+		return "", err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, sanitizeAccountForPath(account)+"-"+strings.Repeat("[0-9a-f]", 12)+".json"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", usagef("no gmail sync mirror for %s; run `gog gmail sync --maildir DIR` first", account)
+	case 1:
+	default:
+		return "", usagef("%s has %d gmail sync mirrors; pass --maildir", account, len(matches))
+	}
+
+	data, err := os.ReadFile(matches[0]) //nolint:gosec // state path under the gog config dir
+	if err != nil {
+		return "", err
+	}
+	var st gmailSyncState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return "", fmt.Errorf("parse sync state %s: %w", matches[0], err)
+	}
+	if strings.TrimSpace(st.Maildir) == "" {
+		return "", fmt.Errorf("sync state %s has no maildir", matches[0])
+	}
+	return st.Maildir, nil
+}
+
+// gmailSyncIndexPath keeps the search index next to the sync state it mirrors.
+func gmailSyncIndexPath(statePath string) string {
+	return strings.TrimSuffix(statePath, ".json") + ".index"
+}
+
+type gmailIndexStats struct {
+	Added   int
+	Updated int
+	Removed int
+	Skipped int // unreadable or unparsable message files
+}
+
+// updateGmailSyncIndex brings the index in line with the sync state: messages
+// the last sync fetched are read from the Maildir, deleted ones are dropped and
+// label moves are copied over. Only the changes are touched, so it is cheap
+// after an incremental sync.
+func updateGmailSyncIndex(idx *mailindex.Index, st *gmailSyncState, root string) (gmailIndexStats, error) {
+	var stats gmailIndexStats
+	if !maps.Equal(idx.LabelNames, st.LabelNames) {
+		idx.SetLabelNames(st.LabelNames)
+	}
+	for _, id := range idx.IDs() {
+		if _, ok := st.Messages[id]; !ok {
+			idx.Remove(id)
+			stats.Removed++
+		}
+	}
+
+	ids := make([]string, 0, len(st.Messages))
+	for id := range st.Messages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		m := st.Messages[id]
+		meta := mailindex.Meta{ThreadID: m.ThreadID, Path: m.Path, Time: m.InternalDate, Labels: m.Labels}
+		if doc, ok := idx.Doc(id); ok {
+			if doc.Path != m.Path || doc.ThreadID != m.ThreadID || !sameLabels(doc.Labels, m.Labels) {
+				idx.Update(id, meta)
+				stats.Updated++
+			}
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(root, m.Path)) //nolint:gosec // file inside the user's mirror
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				stats.Skipped++
+				continue
+			}
+			return stats, err
+		}
+		if err := idx.Add(id, meta, raw); err != nil {
+			stats.Skipped++
+			continue
+		}
+		stats.Added++
+	}
+	return stats, nil
+}
+
+// localSearchThreads groups matching messages into threads, ordered by their
+// newest match, in the shape `gmail search` prints.
+func localSearchThreads(idx *mailindex.Index, ids []string, oldest bool, loc *time.Location) []threadItem {
+	byThread := map[string][]*mailindex.Doc{}
+	threadOf := func(id string, d *mailindex.Doc) string {
+		if d.ThreadID != "" {
+			return d.ThreadID
+		}
+		return id
+	}
+	for _, id := range idx.IDs() {
+		d, _ := idx.Doc(id)
+		t := threadOf(id, d)
+		byThread[t] = append(byThread[t], d)
+	}
+
+	seen := map[string]bool{}
+	items := make([]threadItem, 0, len(ids))
+	for _, id := range ids {
+		d, _ := idx.Doc(id)
+		t := threadOf(id, d)
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+
+		msgs := byThread[t]
+		sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Time < msgs[j].Time })
+		first := msgs[0]
+		item := threadItem{
+			ID:           t,
+			MessageCount: len(msgs),
+			From:         sanitizeTab(first.From),
+			Subject:      sanitizeTab(first.Subject),
+		}
+		for _, lid := range first.Labels {
+			if name, ok := idx.LabelNames[lid]; ok {
+				item.Labels = append(item.Labels, name)
+			} else {
+				item.Labels = append(item.Labels, lid)
+			}
+		}
+		dateMsg := msgs[len(msgs)-1]
+		if oldest {
+			dateMsg = first
+		}
+		item.Date = formatGmailDateInLocation(dateMsg.Date, loc)
+		if item.Date == "" && dateMsg.Time > 0 {
+			item.Date = time.UnixMilli(dateMsg.Time).In(loc).Format("2006-01-02 15:04")
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGmailLocalSearch(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	root := filepath.Join(t.TempDir(), "Mail")
+	files := map[string]string{
+		"cur/1700000000.m1.gog:2,":        "From: Ann <ann@example.com>\r\nSubject: Budget\r\nDate: Tue, 14 Nov 2023 22:13:20 +0000\r\n\r\nPlease review the budget.\r\n",
+		"cur/1700000100.m2.gog:2,S":       "From: Bob <bob@example.com>\r\nSubject: Re: Budget\r\nDate: Tue, 14 Nov 2023 22:15:00 +0000\r\n\r\nApproved.\r\n",
+		".Sent/cur/1700000200.m3.gog:2,S": "From: me@example.com\r\nSubject: Lunch\r\n\r\nbudget-free lunch?\r\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := loadGmailSyncStore("me@example.com", root)
+	if err != nil {
+		t.Fatalf("load store: %v", err)
+	}
+	store.state.HistoryID = "100"
+	store.state.LabelNames = map[string]string{"INBOX": "INBOX", "Label_1": "Finance"}
+	store.Put("m1", gmailSyncMessage{Path: "cur/1700000000.m1.gog:2,", ThreadID: "t1", Labels: []string{"INBOX", "UNREAD", "Label_1"}, InternalDate: 1700000000000})
+	store.Put("m2", gmailSyncMessage{Path: "cur/1700000100.m2.gog:2,S", ThreadID: "t1", Labels: []string{"INBOX"}, InternalDate: 1700000100000})
+	store.Put("m3", gmailSyncMessage{Path: ".Sent/cur/1700000200.m3.gog:2,S", ThreadID: "t3", Labels: []string{"SENT"}, InternalDate: 1700000200000})
+	if err := store.Save(); err != nil {
+		t.Fatalf("save store: %v", err)
+	}
+
+	search := func(args ...string) map[string]any {
+		t.Helper()
+		out := captureStdout(t, func() {
+			if execErr := Execute(append([]string{"--json", "--account", "me@example.com", "gmail", "local-search", "--timezone", "UTC"}, args...)); execErr != nil {
+				t.Fatalf("local-search: %v", execErr)
+			}
+		})
+		var doc map[string]any
+		if uErr := json.Unmarshal([]byte(out), &doc); uErr != nil {
+			t.Fatalf("unmarshal: %v (out=%q)", uErr, out)
+		}
+		return doc
+	}
+	threads := func(doc map[string]any) []map[string]any {
+		raw, _ := doc["threads"].([]any)
+		out := make([]map[string]any, 0, len(raw))
+		for _, r := range raw {
+			out = append(out, r.(map[string]any))
+		}
+		return out
+	}
+
+	first := threads(search("budget"))
+	if len(first) != 2 || first[0]["id"] != "t3" || first[1]["id"] != "t1" {
+		t.Fatalf("unexpected threads: %v", first)
+	}
+	t1 := first[1]
+	if t1["messageCount"] != float64(2) || t1["from"] != "Ann <ann@example.com>" || t1["subject"] != "Budget" || t1["date"] != "2023-11-14 22:15" {
+		t.Fatalf("unexpected thread item: %v", t1)
+	}
+	if labels, _ := t1["labels"].([]any); len(labels) != 3 || labels[2] != "Finance" {
+		t.Fatalf("unexpected labels: %v", t1["labels"])
+	}
+
+	page := search("budget", "--max", "1")
+	if len(threads(page)) != 1 || page["nextPageToken"] != "1" {
+		t.Fatalf("unexpected first page: %v", page)
+	}
+	page = search("budget", "--max", "1", "--page", "1")
+	if got := threads(page); len(got) != 1 || got[0]["id"] != "t1" || page["nextPageToken"] != "" {
+		t.Fatalf("unexpected second page: %v", page)
+	}
+
+	if got := threads(search("label:finance is:unread")); len(got) != 1 || got[0]["id"] != "t1" {
+		t.Fatalf("unexpected label search: %v", got)
+	}
+
+	// A sync that dropped m3 is picked up on the next search.
+	store.Delete("m3")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := threads(search("lunch", "--maildir", root)); len(got) != 0 {
+		t.Fatalf("deleted message still found: %v", got)
+	}
+
+	err = Execute([]string{"--json", "--account", "me@example.com", "gmail", "local-search", "cc:ann"})
+	if ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for unsupported operator, got %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// Markdown email bodies: the source is parsed into blocks once and rendered
+// twice, as sanitized HTML and as the matching text/plain alternative.
+// Inline spans reuse ParseInlineFormatting (shared with `docs` Markdown import);
+// raw HTML in the source is always escaped.
+
+type mdBlockKind int
+
+const (
+	mdBlockParagraph mdBlockKind = iota
+	mdBlockHeading
+	mdBlockCode
+	mdBlockQuote
+	mdBlockList
+	mdBlockRule
+	mdBlockTable
+)
+
+type mdBlock struct {
+	kind     mdBlockKind
+	text     string
+	level    int
+	ordered  bool
+	start    int
+	items    [][]mdBlock
+	children []mdBlock
+	rows     [][]string
+}
+
+var (
+	mdBulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrderedRe = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+(.*)$`)
+)
+
+// renderMarkdownEmail returns the plain-text body and an HTML fragment for a
+// Markdown source. The fragment has no <html>/<body> wrapper so --quote and
+// tracking pixels can be appended as with --body-html.
+func renderMarkdownEmail(src string) (string, string) {
+	blocks := parseMarkdownBlocks(strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"))
+
+	var h strings.Builder
+	writeMarkdownHTML(&h, blocks)
+
+	var t strings.Builder
+	writeMarkdownText(&t, blocks, "")
+
+	return strings.TrimRight(t.String(), "\n") + "\n", h.String()
+}
+
+func parseMarkdownBlocks(lines []string) []mdBlock {
+	var (
+		blocks []mdBlock
+		para   []string
+	)
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, mdBlock{kind: mdBlockParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, mdBlock{kind: mdBlockCode, text: strings.Join(code, "\n")})
+		case isHorizontalRule(line):
+			flush()
+			blocks = append(blocks, mdBlock{kind: mdBlockRule})
+		case strings.HasPrefix(trimmed, "#"):
+			level, content := parseHeading(trimmed)
+			if level == 0 {
+				para = append(para, line)
+				continue
+			}
+			flush()
+			blocks = append(blocks, mdBlock{kind: mdBlockHeading, level: level, text: strings.TrimRight(content, " #")})
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			i--
+			blocks = append(blocks, mdBlock{kind: mdBlockQuote, children: parseMarkdownBlocks(quoted)})
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && isTableSeparator(lines[i+1]):
+			flush()
+			rows := parseMarkdownTable(lines[i:])
+			i += len(rows)
+			blocks = append(blocks, mdBlock{kind: mdBlockTable, rows: rows})
+		case startsMarkdownList(line, len(para) > 0):
+			flush()
+			var list mdBlock
+			list, i = parseMarkdownList(lines, i)
+			blocks = append(blocks, list)
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// startsMarkdownList reports whether line opens a list. Inside a paragraph only
+// bullets and "1." may interrupt it, so prose like "2019. was fine" stays text.
+func startsMarkdownList(line string, inParagraph bool) bool {
+	if mdBulletRe.MatchString(line) {
+		return true
+	}
+	m := mdOrderedRe.FindStringSubmatch(line)
+	return m != nil && (!inParagraph || m[2] == "1")
+}
+
+// parseMarkdownList consumes a list starting at lines[i] and returns the index of its last line.
+func parseMarkdownList(lines []string, i int) (mdBlock, int) {
+	indentOf := func(s string) int { return len(s) - len(strings.TrimLeft(s, " \t")) }
+	marker := func(s string) (bool, bool, int, string) {
+		if m := mdOrderedRe.FindStringSubmatch(s); m != nil {
+			n := 0
+			_, _ = fmt.Sscanf(m[2], "%d", &n)
+			return true, true, n, m[3]
+		}
+		if m := mdBulletRe.FindStringSubmatch(s); m != nil && !isHorizontalRule(s) {
+			return true, false, 0, m[2]
+		}
+		return false, false, 0, ""
+	}
+
+	_, ordered, start, _ := marker(lines[i])
+	list := mdBlock{kind: mdBlockList, ordered: ordered, start: start}
+	baseIndent := indentOf(lines[i])
+
+	var item []string
+	flushItem := func() {
+		if item != nil {
+			list.items = append(list.items, parseMarkdownBlocks(item))
+			item = nil
+		}
+	}
+
+	j := i
+	for ; j < len(lines); j++ {
+		line := lines[j]
+		if strings.TrimSpace(line) == "" {
+			// A blank line ends the list unless the next line continues it.
+			if j+1 < len(lines) && indentOf(lines[j+1]) > baseIndent {
+				item = append(item, "")
+				continue
+			}
+			if j+1 < len(lines) && indentOf(lines[j+1]) == baseIndent {
+				if ok, ord, _, _ := marker(lines[j+1]); ok && ord == ordered {
+					continue
+				}
+			}
+			break
+		}
+
+		ok, ord, _, content := marker(line)
+		indent := indentOf(line)
+		switch {
+		case ok && indent == baseIndent && ord == ordered:
+			flushItem()
+			item = []string{content}
+		case ok && indent == baseIndent:
+			// A different list type at the same level starts a new list.
+			flushItem()
+			return list, j - 1
+		case indent > baseIndent:
+			item = append(item, line[min(indent, baseIndent+4):])
+		case ok || strings.HasPrefix(strings.TrimSpace(line), "#") || strings.HasPrefix(strings.TrimSpace(line), ">"):
+			flushItem()
+			return list, j - 1
+		default:
+			// Lazy continuation of the item's paragraph.
+			item = append(item, strings.TrimSpace(line))
+		}
+	}
+	flushItem()
+
+	return list, j - 1
+}
+
+func writeMarkdownHTML(b *strings.Builder, blocks []mdBlock) {
+	for _, blk := range blocks {
+		switch blk.kind {
+		case mdBlockParagraph:
+			b.WriteString("<p>")
+			b.WriteString(markdownInlineHTML(blk.text))
+			b.WriteString("</p>\n")
+		case mdBlockHeading:
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", blk.level, markdownInlineHTML(blk.text), blk.level)
+		case mdBlockCode:
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(blk.text))
+			b.WriteString("</code></pre>\n")
+		case mdBlockQuote:
+			b.WriteString("<blockquote>\n")
+			writeMarkdownHTML(b, blk.children)
+			b.WriteString("</blockquote>\n")
+		case mdBlockRule:
+			b.WriteString("<hr>\n")
+		case mdBlockList:
+			tag := "ul"
+			if blk.ordered {
+				tag = "ol"
+			}
+			if blk.ordered && blk.start > 1 {
+				fmt.Fprintf(b, "<%s start=\"%d\">\n", tag, blk.start)
+			} else {
+				fmt.Fprintf(b, "<%s>\n", tag)
+			}
+			for _, item := range blk.items {
+				b.WriteString("<li>")
+				// Tight items render their single paragraph inline.
+				if len(item) == 1 && item[0].kind == mdBlockParagraph {
+					b.WriteString(markdownInlineHTML(item[0].text))
+				} else {
+					b.WriteString("\n")
+					writeMarkdownHTML(b, item)
+				}
+				b.WriteString("</li>\n")
+			}
+			fmt.Fprintf(b, "</%s>\n", tag)
+		case mdBlockTable:
+			b.WriteString("<table>\n")
+			for i, row := range blk.rows {
+				cell := "td"
+				if i == 0 {
+					cell = "th"
+				}
+				b.WriteString("<tr>")
+				for _, c := range row {
+					fmt.Fprintf(b, "<%s>%s</%s>", cell, markdownInlineHTML(c), cell)
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</table>\n")
+		}
+	}
+}
+
+func writeMarkdownText(b *strings.Builder, blocks []mdBlock, prefix string) {
+	for i, blk := range blocks {
+		if i > 0 {
+			b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+		}
+		switch blk.kind {
+		case mdBlockParagraph:
+			writePrefixed(b, prefix, markdownInlineText(blk.text))
+		case mdBlockHeading:
+			text := markdownInlineText(blk.text)
+			writePrefixed(b, prefix, text)
+			switch blk.level {
+			case 1:
+				writePrefixed(b, prefix, strings.Repeat("=", len([]rune(text))))
+			case 2:
+				writePrefixed(b, prefix, strings.Repeat("-", len([]rune(text))))
+			}
+		case mdBlockCode:
+			writePrefixed(b, prefix+"    ", blk.text)
+		case mdBlockQuote:
+			writeMarkdownText(b, blk.children, prefix+"> ")
+		case mdBlockRule:
+			writePrefixed(b, prefix, "----")
+		case mdBlockList:
+			for n, item := range blk.items {
+				bullet := "- "
+				if blk.ordered {
+					bullet = fmt.Sprintf("%d. ", max(blk.start, 1)+n)
+				}
+				var inner strings.Builder
+				writeMarkdownText(&inner, item, "")
+				lines := strings.Split(strings.TrimRight(inner.String(), "\n"), "\n")
+				for k, l := range lines {
+					lead := bullet
+					if k > 0 {
+						lead = strings.Repeat(" ", len(bullet))
+					}
+					writePrefixed(b, prefix, lead+l)
+				}
+			}
+		case mdBlockTable:
+			for _, row := range blk.rows {
+				cells := make([]string, len(row))
+				for k, c := range row {
+					cells[k] = markdownInlineText(c)
+				}
+				writePrefixed(b, prefix, strings.Join(cells, " | "))
+			}
+		}
+	}
+}
+
+func writePrefixed(b *strings.Builder, prefix, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(strings.TrimRight(prefix+line, " "))
+		b.WriteString("\n")
+	}
+}
+
+// markdownInlineHTML renders images, links, code and emphasis; everything else is escaped.
+func markdownInlineHTML(text string) string {
+	var b strings.Builder
+	forEachMarkdownImage(text, func(seg string) {
+		lines := strings.Split(seg, "\n")
+		for i, line := range lines {
+			hard := strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")
+			if i < len(lines)-1 {
+				line = strings.TrimRight(strings.TrimSuffix(line, "\\"), " ")
+			}
+			writeInlineSpansHTML(&b, line)
+			if i < len(lines)-1 {
+				if hard {
+					b.WriteString("<br>")
+				}
+				b.WriteString("\n")
+			}
+		}
+	}, func(alt, src string) {
+		if !safeMarkdownURL(src, true) {
+			b.WriteString(html.EscapeString(alt))
+			return
+		}
+		fmt.Fprintf(&b, "<img src=\"%s\" alt=\"%s\">", html.EscapeString(src), html.EscapeString(alt))
+	})
+
+	return b.String()
+}
+
+func markdownInlineText(text string) string {
+	var b strings.Builder
+	forEachMarkdownImage(text, func(seg string) {
+		lines := strings.Split(seg, "\n")
+		for i, line := range lines {
+			if i < len(lines)-1 {
+				line = strings.TrimRight(strings.TrimSuffix(line, "\\"), " ")
+			}
+			styles, plain := ParseInlineFormatting(line)
+			units := utf16.Encode([]rune(plain))
+			// Append link targets after their text: "docs (https://...)".
+			var out strings.Builder
+			last := int64(0)
+			for _, st := range styles {
+				if st.Link == "" || st.End <= last {
+					continue
+				}
+				out.WriteString(string(utf16.Decode(units[last:st.End])))
+				if label := string(utf16.Decode(units[st.Start:st.End])); label != st.Link {
+					fmt.Fprintf(&out, " (%s)", st.Link)
+				}
+				last = st.End
+			}
+			out.WriteString(string(utf16.Decode(units[last:])))
+			b.WriteString(out.String())
+			if i < len(lines)-1 {
+				b.WriteString("\n")
+			}
+		}
+	}, func(alt, src string) {
+		if alt == "" {
+			alt = src
+		}
+		fmt.Fprintf(&b, "[%s]", alt)
+	})
+
+	return b.String()
+}
+
+func forEachMarkdownImage(text string, seg func(string), img func(alt, src string)) {
+	last := 0
+	for _, m := range mdImageRe.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > last {
+			seg(text[last:m[0]])
+		}
+		src := ""
+		if m[4] >= 0 {
+			src = text[m[4]:m[5]]
+		} else if m[6] >= 0 {
+			src = text[m[6]:m[7]]
+		}
+		img(text[m[2]:m[3]], src)
+		last = m[1]
+	}
+	if last < len(text) {
+		seg(text[last:])
+	}
+}
+
+type inlineState struct {
+	bold, italic, code bool
+	link               string
+}
+
+func writeInlineSpansHTML(b *strings.Builder, line string) {
+	styles, plain := ParseInlineFormatting(line)
+	units := utf16.Encode([]rune(plain))
+
+	stateAt := func(pos int64) inlineState {
+		var st inlineState
+		for _, s := range styles {
+			if pos < s.Start || pos >= s.End {
+				continue
+			}
+			st.bold = st.bold || s.Bold
+			st.italic = st.italic || s.Italic
+			st.code = st.code || s.Code
+			if s.Link != "" && safeMarkdownURL(s.Link, false) {
+				st.link = s.Link
+			}
+		}
+		return st
+	}
+	open := func(st inlineState) {
+		if st.link != "" {
+			fmt.Fprintf(b, "<a href=\"%s\">", html.EscapeString(st.link))
+		}
+		if st.bold {
+			b.WriteString("<strong>")
+		}
+		if st.italic {
+			b.WriteString("<em>")
+		}
+		if st.code {
+			b.WriteString("<code>")
+		}
+	}
+	closeTags := func(st inlineState) {
+		if st.code {
+			b.WriteString("</code>")
+		}
+		if st.italic {
+			b.WriteString("</em>")
+		}
+		if st.bold {
+			b.WriteString("</strong>")
+		}
+		if st.link != "" {
+			b.WriteString("</a>")
+		}
+	}
+
+	var cur inlineState
+	for pos := 0; pos < len(units); {
+		size := 1
+		if utf16.IsSurrogate(rune(units[pos])) && pos+1 < len(units) {
+			size = 2
+		}
+		st := stateAt(int64(pos))
+		if st != cur {
+			closeTags(cur)
+			open(st)
+			cur = st
+		}
+		b.WriteString(html.EscapeString(string(utf16.Decode(units[pos : pos+size]))))
+		pos += size
+	}
+	closeTags(cur)
+}
+
+// safeMarkdownURL drops javascript:/data: and other active schemes.
+// Relative paths are allowed for images so local files can be embedded.
+func safeMarkdownURL(raw string, image bool) bool {
+	u := strings.ToLower(strings.TrimSpace(raw))
+	for _, scheme := range []string{"http://", "https://", "mailto:", "cid:"} {
+		if strings.HasPrefix(u, scheme) {
+			return true
+		}
+	}
+	if strings.HasPrefix(u, "#") {
+		return !image
+	}
+
+	return image && !strings.Contains(u, ":")
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestRenderMarkdownEmail(t *testing.T) {
+	src := strings.Join([]string{
+		"# Weekly report",
+		"",
+		"Hello **team**, see [the dashboard](https://example.com/d)",
+		"and the *notes*.",
+		"",
+		"- first",
+		"- second with `code`",
+		"  continued",
+		"",
+		"1. one",
+		"2. two",
+		"",
+		"> quoted <script>alert(1)</script>",
+		"",
+		"```",
+		"x := 1 < 2",
+		"```",
+		"",
+		"| Metric | Value |",
+		"|---|---|",
+		"| Opens | 42 |",
+		"",
+		"[bad](javascript:alert(1)) ![chart](./chart.png)",
+	}, "\n")
+
+	plain, htmlBody := renderMarkdownEmail(src)
+
+	for _, want := range []string{
+		"<h1>Weekly report</h1>",
+		`<p>Hello <strong>team</strong>, see <a href="https://example.com/d">the dashboard</a>` + "\nand the <em>notes</em>.</p>",
+		"<ul>\n<li>first</li>\n<li>second with <code>code</code>\ncontinued</li>\n</ul>",
+		"<ol>\n<li>one</li>\n<li>two</li>\n</ol>",
+		"<blockquote>\n<p>quoted &lt;script&gt;alert(1)&lt;/script&gt;</p>\n</blockquote>",
+		"<pre><code>x := 1 &lt; 2</code></pre>",
+		"<tr><th>Metric</th><th>Value</th></tr>",
+		`<img src="./chart.png" alt="chart">`,
+	} {
+		if !strings.Contains(htmlBody, want) {
+			t.Fatalf("html missing %q:\n%s", want, htmlBody)
+		}
+	}
+	if strings.Contains(htmlBody, "javascript:") || strings.Contains(htmlBody, "<script>") {
+		t.Fatalf("html not sanitized:\n%s", htmlBody)
+	}
+
+	for _, want := range []string{
+		"Weekly report\n=============",
+		"Hello team, see the dashboard (https://example.com/d)\nand the notes.",
+		"- first\n- second with code\n  continued",
+		"1. one\n2. two",
+		"> quoted <script>alert(1)</script>",
+		"    x := 1 < 2",
+		"Metric | Value\nOpens | 42",
+		"[chart]",
+	} {
+		if !strings.Contains(plain, want) {
+			t.Fatalf("plain missing %q:\n%s", want, plain)
+		}
+	}
+	if strings.Contains(plain, "**") || strings.Contains(plain, "<strong>") {
+		t.Fatalf("plain text still has markup:\n%s", plain)
+	}
+}
+
+func TestRenderMarkdownEmail_NestedList(t *testing.T) {
+	_, htmlBody := renderMarkdownEmail("- a\n  - b\n- c\n")
+	want := "<ul>\n<li>\n<p>a</p>\n<ul>\n<li>b</li>\n</ul>\n</li>\n<li>c</li>\n</ul>\n"
+	if htmlBody != want {
+		t.Fatalf("unexpected nested list:\n%s", htmlBody)
+	}
+}
+
+func TestResolveComposeBodies(t *testing.T) {
+	plain, htmlBody, err := resolveComposeBodies("", "", "", "**hi**", "")
+	if err != nil {
+		t.Fatalf("resolveComposeBodies: %v", err)
+	}
+	if plain != "hi\n" || htmlBody != "<p><strong>hi</strong></p>\n" {
+		t.Fatalf("unexpected bodies: %q %q", plain, htmlBody)
+	}
+
+	if _, _, err := resolveComposeBodies("x", "", "", "**hi**", ""); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error mixing --body and --body-markdown, got %v", err)
+	}
+
+	plain, htmlBody, err = resolveComposeBodies("plain", "", "<p>x</p>", "", "")
+	if err != nil || plain != "plain" || htmlBody != "<p>x</p>" {
+		t.Fatalf("expected passthrough, got %q %q %v", plain, htmlBody, err)
+	}
+}
+
+func TestGmailSend_BodyMarkdown(t *testing.T) {
+	var raw string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/users/me/messages/send") {
+			http.NotFound(w, r)
+			return
+		}
+		var msg gmail.Message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		b, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+		raw = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1"})
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "send", "--to", "a@b.com", "--subject", "Hi", "--body-markdown", "Hello **there**"}); execErr != nil {
+			t.Fatalf("send: %v", execErr)
+		}
+	})
+
+	for _, want := range []string{"multipart/alternative", "text/plain", "Hello there", "text/html", "<strong>there</strong>"} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("raw message missing %q:\n%s", want, raw)
+		}
+	}
+}
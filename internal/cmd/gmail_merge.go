@@ -0,0 +1,713 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/mailmerge"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/tracking"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	mergeStatusSent    = "sent"
+	mergeStatusDrafted = "drafted"
+	mergeStatusSkipped = "skipped"
+
+	defaultMergeToTemplate = "{{email}}"
+)
+
+type GmailMergeCmd struct {
+	Template     string        `name:"template" required:"" help:"Template file: optional --- front matter (subject/to/cc/bcc/reply-to/attach), then the plain-text body"`
+	TemplateHTML string        `name:"template-html" help:"HTML body template file (optional; required for --track)"`
+	Data         string        `name:"data" help:"CSV data file with a header row ('-' for stdin)"`
+	Sheet        string        `name:"sheet" help:"Read rows from this spreadsheet ID/URL instead of --data (first row is the header)"`
+	Range        string        `name:"range" help:"Sheet range for --sheet (eg. Recipients!A:F)" default:"A:Z"`
+	To           string        `name:"to" help:"Recipient template (overrides front matter; default {{email}})"`
+	Cc           string        `name:"cc" help:"CC template (comma-separated)"`
+	Bcc          string        `name:"bcc" help:"BCC template (comma-separated)"`
+	Subject      string        `name:"subject" help:"Subject template (overrides front matter)"`
+	ReplyTo      string        `name:"reply-to" help:"Reply-To template"`
+	Attach       []string      `name:"attach" help:"Attachment path template, eg. 'invoices/{{id}}.pdf' (repeatable; empty renders are skipped)"`
+	AttachDir    string        `name:"attach-dir" help:"Directory that attachment paths rendered from row data must stay in; relative paths resolve against it (default: the template's directory prefix, eg. invoices/)"`
+	From         string        `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
+	Drafts       bool          `name:"drafts" aliases:"draft" help:"Create drafts instead of sending"`
+	Track        bool          `name:"track" help:"Enable open tracking (requires --template-html and one recipient per row)"`
+	Delay        time.Duration `name:"delay" help:"Pause between messages (throttling)" default:"1s"`
+	Limit        int           `name:"limit" help:"Process at most N pending rows this run (0 = all)"`
+	Progress     string        `name:"progress" help:"Progress file for resumable runs (default: per account and data source under the state dir)"`
+	AllowMissing bool          `name:"allow-missing" help:"Render unknown {{placeholders}} as empty instead of failing"`
+}
+
+// mergeTemplates holds the parsed per-field templates of one merge.
+type mergeTemplates struct {
+	subject  *mailmerge.Template
+	to       *mailmerge.Template
+	cc       *mailmerge.Template
+	bcc      *mailmerge.Template
+	replyTo  *mailmerge.Template
+	body     *mailmerge.Template
+	bodyHTML *mailmerge.Template
+	attach   []*mailmerge.Template
+	// attachRoots confines each attach template with placeholders ("" = static path).
+	attachRoots []string
+	attachDir   string
+	inline      []mailAttachment // images embedded from the HTML template, shared by every row
+}
+
+type mergeMessage struct {
+	Row      int
+	To       []string
+	Cc       []string
+	Bcc      []string
+	ReplyTo  string
+	Subject  string
+	Body     string
+	BodyHTML string
+	Attach   []string
+	Inline   []mailAttachment
+	Key      string
+}
+
+type mergeResult struct {
+	Row        int    `json:"row"`
+	To         string `json:"to"`
+	Subject    string `json:"subject"`
+	Status     string `json:"status"`
+	MessageID  string `json:"messageId,omitempty"`
+	ThreadID   string `json:"threadId,omitempty"`
+	DraftID    string `json:"draftId,omitempty"`
+	TrackingID string `json:"tracking_id,omitempty"`
+}
+
+func (c *GmailMergeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	dataPath := strings.TrimSpace(c.Data)
+	sheetID := normalizeGoogleID(strings.TrimSpace(c.Sheet))
+	if (dataPath == "") == (sheetID == "") {
+		return usage("use exactly one of --data or --sheet")
+	}
+	if c.Delay < 0 || c.Limit < 0 {
+		return usage("--delay and --limit must be >= 0")
+	}
+	if c.Track && c.Drafts {
+		return usage("--track cannot be combined with --drafts")
+	}
+
+	tmpl, err := c.loadTemplates()
+	if err != nil {
+		return err
+	}
+	if c.Track && tmpl.bodyHTML == nil {
+		return usage("--track requires --template-html (pixel must be in HTML)")
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	var (
+		rows   []mailmerge.Row
+		source string
+	)
+	if sheetID != "" {
+		rangeSpec := cleanRange(c.Range)
+		source = "sheet:" + sheetID + "!" + rangeSpec
+		rows, err = readMergeSheet(ctx, account, sheetID, rangeSpec)
+	} else {
+		source, rows, err = readMergeCSV(dataPath)
+	}
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return usage("no data rows")
+	}
+
+	messages, err := renderMergeMessages(tmpl, rows, c.AllowMissing, c.Track)
+	if err != nil {
+		return err
+	}
+	if err := c.enforcePolicy(flags, messages); err != nil {
+		return err
+	}
+
+	progressPath, err := c.progressPath(account, source)
+	if err != nil {
+		return err
+	}
+	progress, err := mailmerge.LoadProgress(progressPath, source)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]mergeMessage, 0, len(messages))
+	doneCount := 0
+	for _, m := range messages {
+		if _, done := progress.Lookup(m.Key); done {
+			doneCount++
+			continue
+		}
+		pending = append(pending, m)
+	}
+	if c.Limit > 0 && len(pending) > c.Limit {
+		pending = pending[:c.Limit]
+	}
+
+	op := "gmail.merge"
+	if c.Drafts {
+		op = "gmail.merge.drafts"
+	}
+	planned := make([]map[string]any, 0, len(pending))
+	for _, m := range pending {
+		planned = append(planned, map[string]any{
+			"row":         m.Row,
+			"to":          m.To,
+			"cc":          m.Cc,
+			"bcc":         m.Bcc,
+			"subject":     m.Subject,
+			"attachments": m.Attach,
+		})
+	}
+	if dryRunErr := dryRunExit(ctx, flags, op, map[string]any{
+		"source":   source,
+		"rows":     len(rows),
+		"done":     doneCount,
+		"progress": progressPath,
+		"track":    c.Track,
+		"messages": planned,
+	}); dryRunErr != nil {
+		return dryRunErr
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	fromAddr, _, err := resolveSendFrom(ctx, svc, account, c.From)
+	if err != nil {
+		return err
+	}
+
+	var trackingCfg *tracking.Config
+	if c.Track {
+		trackingCfg, err = tracking.LoadConfig(account)
+		if err != nil {
+			return fmt.Errorf("load tracking config: %w", err)
+		}
+		if !trackingCfg.IsConfigured() {
+			return fmt.Errorf("tracking not configured; run 'gog gmail track setup' first")
+		}
+	}
+
+	results := make([]mergeResult, 0, len(messages))
+	for _, m := range messages {
+		if entry, done := progress.Lookup(m.Key); done {
+			results = append(results, mergeResult{
+				Row:       m.Row,
+				To:        strings.Join(m.To, ", "),
+				Subject:   m.Subject,
+				Status:    mergeStatusSkipped,
+				MessageID: entry.MessageID,
+				ThreadID:  entry.ThreadID,
+				DraftID:   entry.DraftID,
+			})
+		}
+	}
+
+	for i, m := range pending {
+		if i > 0 && c.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.Delay):
+			}
+		}
+
+		res, sendErr := c.deliver(ctx, svc, fromAddr, trackingCfg, m)
+		if sendErr != nil {
+			return fmt.Errorf("row %d (%s): %w (rerun to resume; progress in %s)", m.Row, strings.Join(m.To, ", "), sendErr, progressPath)
+		}
+
+		if recErr := progress.Record(m.Key, mailmerge.ProgressEntry{
+			Row:       m.Row,
+			To:        res.To,
+			Subject:   m.Subject,
+			MessageID: res.MessageID,
+			ThreadID:  res.ThreadID,
+			DraftID:   res.DraftID,
+			At:        time.Now().UTC(),
+		}); recErr != nil {
+			return recErr
+		}
+		results = append(results, res)
+
+		if !outfmt.IsJSON(ctx) {
+			u.Err().Printf("[%d/%d] %s row %d -> %s", i+1, len(pending), res.Status, m.Row, res.To)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Row < results[j].Row })
+
+	return writeMergeResults(ctx, results, progressPath)
+}
+
+// enforcePolicy checks every rendered row against the policy rules for
+// `gmail send` (or `gmail drafts create` with --drafts). Recipients come from
+// the data source, so the command-line check only ever sees --data/--sheet.
+func (c *GmailMergeCmd) enforcePolicy(flags *RootFlags, messages []mergeMessage) error {
+	if flags == nil || strings.TrimSpace(flags.Policy) == "" {
+		return nil
+	}
+	policy, err := config.ReadPolicy(strings.TrimSpace(flags.Policy))
+	if err != nil {
+		return &ExitError{Code: exitCodeConfig, Err: err}
+	}
+	cmdPath := []string{"gmail", "send"}
+	if c.Drafts {
+		cmdPath = []string{"gmail", "drafts", "create"}
+	}
+	for _, m := range messages {
+		values := map[string][]string{
+			"to":      m.To,
+			"cc":      m.Cc,
+			"bcc":     m.Bcc,
+			"subject": {m.Subject},
+			"attach":  m.Attach,
+		}
+		if m.ReplyTo != "" {
+			values["reply-to"] = []string{m.ReplyTo}
+		}
+		if from := strings.TrimSpace(c.From); from != "" {
+			values["from"] = []string{from}
+		}
+		if c.Track {
+			values["track"] = []string{"true"}
+		}
+		if err := evaluatePolicy(policy, cmdPath, values); err != nil {
+			return &ExitError{Code: exitCodePolicyDenied, Err: fmt.Errorf("row %d: %w", m.Row, err)}
+		}
+	}
+	return nil
+}
+
+func (c *GmailMergeCmd) deliver(ctx context.Context, svc *gmail.Service, fromAddr string, trackingCfg *tracking.Config, m mergeMessage) (mergeResult, error) {
+	atts := make([]mailAttachment, 0, len(m.Attach))
+	for _, p := range m.Attach {
+		atts = append(atts, mailAttachment{Path: p})
+	}
+
+	res := mergeResult{Row: m.Row, To: strings.Join(m.To, ", "), Subject: m.Subject}
+
+	if c.Drafts {
+		raw, err := buildRFC822(mailOptions{
+			From:        fromAddr,
+			To:          m.To,
+			Cc:          m.Cc,
+			Bcc:         m.Bcc,
+			ReplyTo:     m.ReplyTo,
+			Subject:     m.Subject,
+			Body:        m.Body,
+			BodyHTML:    m.BodyHTML,
+			Attachments: atts,
+			Inline:      m.Inline,
+		}, nil)
+		if err != nil {
+			return res, err
+		}
+		draft, err := svc.Users.Drafts.Create("me", &gmail.Draft{
+			Message: &gmail.Message{Raw: base64.RawURLEncoding.EncodeToString(raw)},
+		}).Context(ctx).Do()
+		if err != nil {
+			return res, err
+		}
+		res.Status = mergeStatusDrafted
+		res.DraftID = draft.Id
+		if draft.Message != nil {
+			res.MessageID = draft.Message.Id
+			res.ThreadID = draft.Message.ThreadId
+		}
+		return res, nil
+	}
+
+	sent, err := sendGmailBatches(ctx, svc, sendMessageOptions{
+		FromAddr:    fromAddr,
+		ReplyTo:     m.ReplyTo,
+		Subject:     m.Subject,
+		Body:        m.Body,
+		BodyHTML:    m.BodyHTML,
+		Attachments: atts,
+		Inline:      m.Inline,
+		Track:       c.Track,
+		TrackingCfg: trackingCfg,
+	}, []sendBatch{{
+		To:                m.To,
+		Cc:                m.Cc,
+		Bcc:               m.Bcc,
+		TrackingRecipient: firstRecipient(m.To, m.Cc, m.Bcc),
+	}})
+	if err != nil {
+		return res, err
+	}
+	res.Status = mergeStatusSent
+	res.MessageID = sent[0].MessageID
+	res.ThreadID = sent[0].ThreadID
+	res.TrackingID = sent[0].TrackingID
+
+	return res, nil
+}
+
+func (c *GmailMergeCmd) loadTemplates() (*mergeTemplates, error) {
+	src, err := readMergeFile(c.Template)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := mailmerge.ParseDocument(src)
+	if err != nil {
+		return nil, usagef("%s: %v", c.Template, err)
+	}
+
+	pick := func(flag, key, fallback string) string {
+		if v := strings.TrimSpace(flag); v != "" {
+			return v
+		}
+		if v := strings.TrimSpace(doc.Headers[key]); v != "" {
+			return v
+		}
+		return fallback
+	}
+
+	t := &mergeTemplates{}
+	parse := func(name, src string) (*mailmerge.Template, error) {
+		if src == "" {
+			return nil, nil
+		}
+		parsed, parseErr := mailmerge.Parse(src)
+		if parseErr != nil {
+			return nil, usagef("%s template: %v", name, parseErr)
+		}
+		return parsed, nil
+	}
+
+	fields := []struct {
+		dst  **mailmerge.Template
+		name string
+		src  string
+	}{
+		{&t.subject, "subject", pick(c.Subject, "subject", "")},
+		{&t.to, "to", pick(c.To, "to", defaultMergeToTemplate)},
+		{&t.cc, "cc", pick(c.Cc, "cc", "")},
+		{&t.bcc, "bcc", pick(c.Bcc, "bcc", "")},
+		{&t.replyTo, "reply-to", pick(c.ReplyTo, "reply-to", "")},
+		{&t.body, "body", doc.Body},
+	}
+	for _, f := range fields {
+		if *f.dst, err = parse(f.name, f.src); err != nil {
+			return nil, err
+		}
+	}
+	if t.subject == nil {
+		return nil, usage("required: --subject (or subject: in the template front matter)")
+	}
+
+	if path := strings.TrimSpace(c.TemplateHTML); path != "" {
+		htmlSrc, readErr := readMergeFile(path)
+		if readErr != nil {
+			return nil, readErr
+		}
+		// Embed images from the template itself, before any row data is filled in,
+		// so a data value can never point <img src> at a local file.
+		if htmlSrc, t.inline, err = embedInlineImages(htmlSrc, nil, inlineBaseDir(path), true); err != nil {
+			return nil, err
+		}
+		if t.bodyHTML, err = parse("html", htmlSrc); err != nil {
+			return nil, err
+		}
+	}
+	if t.body == nil && t.bodyHTML == nil {
+		return nil, usage("template body is empty")
+	}
+
+	if dir := strings.TrimSpace(c.AttachDir); dir != "" {
+		if t.attachDir, err = resolveMergePath(dir); err != nil {
+			return nil, usagef("--attach-dir: %v", err)
+		}
+	}
+	attach := c.Attach
+	if len(attach) == 0 && doc.Headers["attach"] != "" {
+		attach = splitCSV(doc.Headers["attach"])
+	}
+	for _, a := range attach {
+		a = strings.TrimSpace(a)
+		parsed, parseErr := parse("attach", a)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if parsed == nil {
+			continue
+		}
+		root, rootErr := t.attachRoot(a, parsed)
+		if rootErr != nil {
+			return nil, rootErr
+		}
+		t.attach = append(t.attach, parsed)
+		t.attachRoots = append(t.attachRoots, root)
+	}
+
+	return t, nil
+}
+
+// renderMergeMessages renders and validates every row. Progress is keyed by
+// recipients and subject, so two rows rendering to the same pair are rejected
+// rather than one of them being skipped as already sent.
+func renderMergeMessages(t *mergeTemplates, rows []mailmerge.Row, allowMissing, track bool) ([]mergeMessage, error) {
+	out := make([]mergeMessage, 0, len(rows))
+	keyRows := map[string]int{}
+	for _, row := range rows {
+		render := func(tmpl *mailmerge.Template) (string, error) {
+			return tmpl.Render(row, allowMissing)
+		}
+
+		var (
+			m   = mergeMessage{Row: row.Number, Inline: t.inline}
+			err error
+			s   string
+		)
+		if s, err = render(t.to); err != nil {
+			return nil, usagef("%v", err)
+		}
+		m.To = splitCSV(s)
+		if s, err = render(t.cc); err != nil {
+			return nil, usagef("%v", err)
+		}
+		m.Cc = splitCSV(s)
+		if s, err = render(t.bcc); err != nil {
+			return nil, usagef("%v", err)
+		}
+		m.Bcc = splitCSV(s)
+		if m.ReplyTo, err = render(t.replyTo); err != nil {
+			return nil, usagef("%v", err)
+		}
+		if s, err = render(t.subject); err != nil {
+			return nil, usagef("%v", err)
+		}
+		m.Subject = strings.TrimSpace(s)
+		if m.Body, err = render(t.body); err != nil {
+			return nil, usagef("%v", err)
+		}
+		if m.BodyHTML, err = t.bodyHTML.RenderHTML(row, allowMissing); err != nil {
+			return nil, usagef("%v", err)
+		}
+
+		if len(m.To) == 0 {
+			return nil, usagef("row %d: no recipients", row.Number)
+		}
+		if m.Subject == "" {
+			return nil, usagef("row %d: empty subject", row.Number)
+		}
+		if track && len(m.To)+len(m.Cc)+len(m.Bcc) != 1 {
+			return nil, usagef("row %d: --track requires exactly 1 recipient per row", row.Number)
+		}
+
+		for i, at := range t.attach {
+			if s, err = render(at); err != nil {
+				return nil, usagef("%v", err)
+			}
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			path, expandErr := config.ExpandPath(s)
+			if expandErr != nil {
+				return nil, expandErr
+			}
+			if t.attachDir != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(t.attachDir, path)
+			}
+			if i < len(t.attachRoots) && t.attachRoots[i] != "" {
+				real, realErr := resolveMergePath(path)
+				if realErr != nil {
+					return nil, fmt.Errorf("row %d: attachment: %w", row.Number, realErr)
+				}
+				if !pathWithinDir(real, t.attachRoots[i]) {
+					return nil, usagef("row %d: attachment %s is outside %s (use --attach-dir to allow another directory)", row.Number, s, t.attachRoots[i])
+				}
+			}
+			st, statErr := os.Stat(path)
+			if statErr != nil {
+				return nil, fmt.Errorf("row %d: attachment: %w", row.Number, statErr)
+			}
+			if st.IsDir() {
+				return nil, usagef("row %d: attachment %s is a directory (use {{#if column}} for optional files)", row.Number, path)
+			}
+			m.Attach = append(m.Attach, path)
+		}
+
+		m.Key = mailmerge.MessageKey(m.To, m.Cc, m.Bcc, m.Subject)
+		if first, dup := keyRows[m.Key]; dup {
+			return nil, usagef("rows %d and %d render the same recipients and subject; remove the duplicate or make the subject differ", first, row.Number)
+		}
+		keyRows[m.Key] = row.Number
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+// progressPath defaults to one file per account, data source and mode so that
+// send and draft runs over the same data do not share state.
+func (c *GmailMergeCmd) progressPath(account, source string) (string, error) {
+	if p := strings.TrimSpace(c.Progress); p != "" {
+		return config.ExpandPath(p)
+	}
+
+	dir, err := config.MergeProgressDir()
+	if err != nil {
+		return "", err
+	}
+	mode := mergeStatusSent
+	if c.Drafts {
+		mode = mergeStatusDrafted
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(account) + "\x00" + source + "\x00" + mode))
+
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// attachRoot returns the directory an attachment template's rendered paths must
+// stay in, so a row value like ../../.ssh/id_rsa cannot pull in arbitrary files:
+// --attach-dir when set, else the static directory before the first placeholder,
+// else the working directory. Templates without placeholders are not confined.
+func (t *mergeTemplates) attachRoot(src string, tmpl *mailmerge.Template) (string, error) {
+	if len(tmpl.Fields()) == 0 {
+		return "", nil
+	}
+	if t.attachDir != "" {
+		return t.attachDir, nil
+	}
+	// Leading {{#if}}/{{#unless}} only guard the path; skip them to find the prefix.
+	prefix := src
+	for strings.HasPrefix(prefix, "{{#") {
+		end := strings.Index(prefix, "}}")
+		if end < 0 {
+			break
+		}
+		prefix = prefix[end+2:]
+	}
+	prefix, _, _ = strings.Cut(prefix, "{{")
+	dir := "."
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i+1]
+	}
+	root, err := resolveMergePath(dir)
+	if err != nil {
+		return "", usagef("attach template %q: %v", src, err)
+	}
+	return root, nil
+}
+
+// resolveMergePath returns path as an absolute path with symlinks resolved.
+func resolveMergePath(path string) (string, error) {
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+func readMergeFile(path string) (string, error) {
+	path, err := config.ExpandPath(strings.TrimSpace(path))
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path) //nolint:gosec // user-provided template path
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func readMergeCSV(path string) (string, []mailmerge.Row, error) {
+	if path == "-" {
+		rows, err := mailmerge.ReadCSV(os.Stdin)
+		return "csv:-", rows, err
+	}
+
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if abs, absErr := filepath.Abs(path); absErr == nil {
+		path = abs
+	}
+	f, err := os.Open(path) //nolint:gosec // user-provided data path
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	rows, err := mailmerge.ReadCSV(f)
+	if err != nil {
+		return "", nil, usagef("%s: %v", path, err)
+	}
+	return "csv:" + path, rows, nil
+}
+
+func readMergeSheet(ctx context.Context, account, spreadsheetID, rangeSpec string) ([]mailmerge.Row, error) {
+	svc, err := newSheetsService(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Spreadsheets.Values.Get(spreadsheetID, rangeSpec).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := mailmerge.RowsFromValues(resp.Values)
+	if err != nil {
+		return nil, usagef("sheet %s: %v", spreadsheetID, err)
+	}
+	return rows, nil
+}
+
+func writeMergeResults(ctx context.Context, results []mergeResult, progressPath string) error {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"results":  results,
+			"sent":     counts[mergeStatusSent],
+			"drafted":  counts[mergeStatusDrafted],
+			"skipped":  counts[mergeStatusSkipped],
+			"progress": progressPath,
+		})
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "ROW\tTO\tSTATUS\tMESSAGE_ID\tDRAFT_ID")
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.Row, r.To, r.Status, r.MessageID, r.DraftID)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/mailmerge"
+)
+
+func TestGmailMerge_SendsOncePerRow(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	dir := t.TempDir()
+	tmplPath := filepath.Join(dir, "tmpl.md")
+	dataPath := filepath.Join(dir, "people.csv")
+	ticket := filepath.Join(dir, "t1.txt")
+	if err := os.WriteFile(tmplPath, []byte("---\nsubject: Invite for {{name}}\nattach: {{#if ticket}}"+dir+"/{{ticket}}{{/if}}\n---\nHi {{name}}{{#if company}} from {{company}}{{/if}}!\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dataPath, []byte("email,name,company,ticket\nada@example.com,Ada,ACME,t1.txt\nbob@example.com,Bob,,\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ticket, []byte("ticket"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var raws []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1")
+		if r.Method != http.MethodPost || path != "/users/me/messages/send" {
+			http.NotFound(w, r)
+			return
+		}
+		var msg gmail.Message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		raw, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+		raws = append(raws, string(raw))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": fmt.Sprintf("m%d", len(raws)), "threadId": "t"})
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	run := func(extra ...string) map[string]any {
+		t.Helper()
+		args := append([]string{"--json", "--account", "me@example.com", "gmail", "merge", "--template", tmplPath, "--data", dataPath, "--delay", "0s"}, extra...)
+		out := captureStdout(t, func() {
+			if execErr := Execute(args); execErr != nil {
+				t.Fatalf("merge: %v", execErr)
+			}
+		})
+		var doc map[string]any
+		if uErr := json.Unmarshal([]byte(out), &doc); uErr != nil {
+			t.Fatalf("unmarshal: %v (out=%q)", uErr, out)
+		}
+		return doc
+	}
+
+	first := run("--limit", "1")
+	if first["sent"] != float64(1) || len(raws) != 1 {
+		t.Fatalf("expected 1 send with --limit 1, got %v (%d raw)", first, len(raws))
+	}
+	if !strings.Contains(raws[0], "Invite for Ada") || !strings.Contains(raws[0], "Hi Ada from ACME!") || !strings.Contains(raws[0], "t1.txt") {
+		t.Fatalf("unexpected first message:\n%s", raws[0])
+	}
+
+	second := run()
+	if second["sent"] != float64(1) || second["skipped"] != float64(1) || len(raws) != 2 {
+		t.Fatalf("expected resume to send only the remaining row, got %v", second)
+	}
+	if !strings.Contains(raws[1], "Hi Bob!") {
+		t.Fatalf("unexpected second message:\n%s", raws[1])
+	}
+
+	third := run()
+	if third["sent"] != float64(0) || third["skipped"] != float64(2) || len(raws) != 2 {
+		t.Fatalf("expected rerun to skip everything, got %v", third)
+	}
+}
+
+func TestGmailMerge_MissingColumnFailsBeforeSending(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	dir := t.TempDir()
+	tmplPath := filepath.Join(dir, "tmpl.md")
+	dataPath := filepath.Join(dir, "people.csv")
+	if err := os.WriteFile(tmplPath, []byte("Hi {{nickname}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dataPath, []byte("email\nada@example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) {
+		t.Fatalf("gmail service must not be created")
+		return nil, nil
+	}
+
+	var err error
+	_ = captureStderr(t, func() {
+		err = Execute([]string{"--account", "me@example.com", "gmail", "merge", "--template", tmplPath, "--data", dataPath, "--subject", "x"})
+	})
+	if ExitCode(err) != 2 || !strings.Contains(err.Error(), "nickname") {
+		t.Fatalf("expected usage error for missing column, got %v", err)
+	}
+}
+
+func TestGmailMerge_HTMLEscapesValuesAndEmbedsTemplateImagesOnly(t *testing.T) {
+	dir := t.TempDir()
+	tmplPath := filepath.Join(dir, "tmpl.md")
+	htmlPath := filepath.Join(dir, "tmpl.html")
+	if err := os.WriteFile(tmplPath, []byte("---\nsubject: Hi\n---\nHi {{company}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(htmlPath, []byte(`<img src="logo.png"><p title="{{company}}">{{company}}</p>{{note}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logo.png"), []byte("png"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "secret.png")
+	if err := os.WriteFile(secret, []byte("png"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := (&GmailMergeCmd{Template: tmplPath, TemplateHTML: htmlPath}).loadTemplates()
+	if err != nil {
+		t.Fatalf("loadTemplates: %v", err)
+	}
+	rows := []mailmerge.Row{{Number: 2, Values: map[string]string{
+		"email":   "ada@example.com",
+		"company": `AT&T "Labs"`,
+		"note":    `<img src="` + secret + `">`,
+	}}}
+	msgs, err := renderMergeMessages(tmpl, rows, false, false)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	m := msgs[0]
+	want := `<img src="cid:img1-logo.png"><p title="AT&amp;T &#34;Labs&#34;">AT&amp;T &#34;Labs&#34;</p>&lt;img src=&#34;` + secret + `&#34;&gt;`
+	if m.BodyHTML != want {
+		t.Fatalf("unexpected html:\n got: %s\nwant: %s", m.BodyHTML, want)
+	}
+	if m.Body != "Hi AT&T \"Labs\"\n" {
+		t.Fatalf("plain body must not be escaped: %q", m.Body)
+	}
+	if len(m.Inline) != 1 || m.Inline[0].Path != filepath.Join(dir, "logo.png") {
+		t.Fatalf("expected only the template image inline, got %#v", m.Inline)
+	}
+}
+
+func TestGmailMerge_DuplicateRowsFail(t *testing.T) {
+	tmpl := &mergeTemplates{
+		subject: mailmerge.MustParse("Hello"),
+		to:      mailmerge.MustParse("{{email}}"),
+		body:    mailmerge.MustParse("Hi {{name}}"),
+	}
+	rows := []mailmerge.Row{
+		{Number: 2, Values: map[string]string{"email": "ada@example.com", "name": "Ada"}},
+		{Number: 3, Values: map[string]string{"email": "bob@example.com", "name": "Bob"}},
+		{Number: 4, Values: map[string]string{"email": "ADA@example.com", "name": "Ada L."}},
+	}
+	_, err := renderMergeMessages(tmpl, rows, false, false)
+	if ExitCode(err) != 2 || !strings.Contains(err.Error(), "rows 2 and 4") {
+		t.Fatalf("expected duplicate rows error, got %v", err)
+	}
+}
+
+func TestGmailMerge_PolicyChecksEveryRow(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	dir := t.TempDir()
+	tmplPath := filepath.Join(dir, "tmpl.md")
+	dataPath := filepath.Join(dir, "people.csv")
+	if err := os.WriteFile(tmplPath, []byte("---\nsubject: Hi {{name}}\n---\nHello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dataPath, []byte("email,name\nada@corp.com,Ada\nbob@example.com,Bob\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policyPath := writeTestPolicy(t, `{rules: [{command: "gmail send", args: {to: {allow: ["*@corp.com"]}}}]}`)
+
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) {
+		t.Fatalf("no message may be sent when a row is denied")
+		return nil, nil
+	}
+
+	var execErr error
+	errOut := captureStderr(t, func() {
+		execErr = Execute([]string{"--account", "me@example.com", "--policy", policyPath, "gmail", "merge", "--template", tmplPath, "--data", dataPath, "--delay", "0s"})
+	})
+	if ExitCode(execErr) != exitCodePolicyDenied {
+		t.Fatalf("expected policy denial, got %v", execErr)
+	}
+	if !strings.Contains(errOut, "row 2") || !strings.Contains(errOut, "bob@example.com") {
+		t.Fatalf("expected denial for row 2, got %q", errOut)
+	}
+}
+
+func TestGmailMerge_AttachmentsConfined(t *testing.T) {
+	dir := t.TempDir()
+	files := filepath.Join(dir, "files")
+	if err := os.MkdirAll(files, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{filepath.Join(files, "a.pdf"), filepath.Join(dir, "secret.txt")} {
+		if err := os.WriteFile(p, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmplPath := filepath.Join(dir, "tmpl.txt")
+	if err := os.WriteFile(tmplPath, []byte("---\nsubject: Hi\n---\nHi\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	row := func(f string) []mailmerge.Row {
+		return []mailmerge.Row{{Number: 2, Values: map[string]string{"email": "ada@example.com", "f": f}}}
+	}
+
+	tmpl, err := (&GmailMergeCmd{Template: tmplPath, Attach: []string{files + "/{{f}}"}}).loadTemplates()
+	if err != nil {
+		t.Fatalf("loadTemplates: %v", err)
+	}
+	msgs, err := renderMergeMessages(tmpl, row("a.pdf"), false, false)
+	if err != nil || len(msgs[0].Attach) != 1 {
+		t.Fatalf("expected the attachment inside the prefix, got %v (%v)", msgs, err)
+	}
+	if _, err := renderMergeMessages(tmpl, row("../secret.txt"), false, false); ExitCode(err) != 2 || !strings.Contains(err.Error(), "outside") {
+		t.Fatalf("expected an escape to be rejected, got %v", err)
+	}
+
+	// --attach-dir roots relative values and still rejects escapes.
+	tmpl, err = (&GmailMergeCmd{Template: tmplPath, Attach: []string{"{{f}}"}, AttachDir: files}).loadTemplates()
+	if err != nil {
+		t.Fatalf("loadTemplates: %v", err)
+	}
+	if msgs, err = renderMergeMessages(tmpl, row("a.pdf"), false, false); err != nil || msgs[0].Attach[0] != filepath.Join(files, "a.pdf") {
+		t.Fatalf("expected a.pdf under --attach-dir, got %v (%v)", msgs, err)
+	}
+	if _, err := renderMergeMessages(tmpl, row(filepath.Join(dir, "secret.txt")), false, false); ExitCode(err) != 2 {
+		t.Fatalf("expected an absolute escape to be rejected, got %v", err)
+	}
+}
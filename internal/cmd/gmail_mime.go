@@ -16,10 +16,11 @@ import (
 )
 
 type mailAttachment struct {
-	Path     string
-	Filename string
-	MIMEType string
-	Data     []byte
+	Path      string
+	Filename  string
+	MIMEType  string
+	Data      []byte
+	ContentID string // inline parts only; referenced from HTML as cid:<ContentID>
 }
 
 type rfc822Config struct {
@@ -39,6 +40,7 @@ type mailOptions struct {
 	References        string
 	AdditionalHeaders map[string]string
 	Attachments       []mailAttachment
+	Inline            []mailAttachment
 }
 
 func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
@@ -119,9 +121,15 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 	htmlBody := normalizeCRLF(opts.BodyHTML)
 	hasPlain := strings.TrimSpace(plainBody) != ""
 	hasHTML := strings.TrimSpace(htmlBody) != ""
+	related := hasHTML && len(opts.Inline) > 0
 
 	if len(opts.Attachments) == 0 {
 		switch {
+		case related:
+			if err := writeRelatedBody(&b, plainBody, htmlBody, opts.Inline); err != nil {
+				return nil, err
+			}
+			return b.Bytes(), nil
 		case hasPlain && hasHTML:
 			altBoundary, err := randomBoundary()
 			if err != nil {
@@ -160,6 +168,10 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 	// Body part
 	fmt.Fprintf(&b, "--%s\r\n", mixedBoundary)
 	switch {
+	case related:
+		if err := writeRelatedBody(&b, plainBody, htmlBody, opts.Inline); err != nil {
+			return nil, err
+		}
 	case hasPlain && hasHTML:
 		altBoundary, err := randomBoundary()
 		if err != nil {
@@ -181,21 +193,9 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 
 	// Attachments
 	for _, a := range opts.Attachments {
-		if a.Filename == "" {
-			a.Filename = filepath.Base(a.Path)
-		}
-		if a.MIMEType == "" {
-			a.MIMEType = mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Filename)))
-			if a.MIMEType == "" {
-				a.MIMEType = "application/octet-stream"
-			}
-		}
-		if len(a.Data) == 0 {
-			data, err := os.ReadFile(a.Path)
-			if err != nil {
-				return nil, err
-			}
-			a.Data = data
+		a, err := loadMailAttachment(a)
+		if err != nil {
+			return nil, err
 		}
 
 		fmt.Fprintf(&b, "\r\n--%s\r\n", mixedBoundary)
@@ -210,6 +210,78 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// loadMailAttachment fills in the filename, MIME type, and data of a part.
+func loadMailAttachment(a mailAttachment) (mailAttachment, error) {
+	if a.Filename == "" {
+		a.Filename = filepath.Base(a.Path)
+	}
+	if a.MIMEType == "" {
+		a.MIMEType = mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Filename)))
+		if a.MIMEType == "" {
+			a.MIMEType = "application/octet-stream"
+		}
+	}
+	if len(a.Data) == 0 {
+		data, err := os.ReadFile(a.Path)
+		if err != nil {
+			return a, err
+		}
+		a.Data = data
+	}
+	return a, nil
+}
+
+// writeRelatedBody writes an HTML body and its inline parts as
+// multipart/related, nested in multipart/alternative when there is also a
+// plain-text body so text-only clients never see the images.
+func writeRelatedBody(b *bytes.Buffer, plainBody, htmlBody string, inline []mailAttachment) error {
+	if strings.TrimSpace(plainBody) == "" {
+		return writeRelatedPart(b, htmlBody, inline)
+	}
+
+	altBoundary, err := randomBoundary()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", altBoundary)
+	writeTextPart(b, altBoundary, "text/plain; charset=\"utf-8\"", plainBody)
+	fmt.Fprintf(b, "--%s\r\n", altBoundary)
+	if err := writeRelatedPart(b, htmlBody, inline); err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "--%s--\r\n", altBoundary)
+	return nil
+}
+
+func writeRelatedPart(b *bytes.Buffer, htmlBody string, inline []mailAttachment) error {
+	relBoundary, err := randomBoundary()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "Content-Type: multipart/related; boundary=%q; type=\"text/html\"\r\n\r\n", relBoundary)
+	writeTextPart(b, relBoundary, "text/html; charset=\"utf-8\"", htmlBody)
+
+	for _, a := range inline {
+		if err := validateHeaderValue(a.ContentID); err != nil || strings.TrimSpace(a.ContentID) == "" {
+			return fmt.Errorf("invalid inline Content-ID %q", a.ContentID)
+		}
+		a, err := loadMailAttachment(a)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "--%s\r\n", relBoundary)
+		fmt.Fprintf(b, "Content-Type: %s\r\n", a.MIMEType)
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(b, "Content-ID: <%s>\r\n", a.ContentID)
+		fmt.Fprintf(b, "Content-Disposition: inline; %s\r\n\r\n", contentDispositionFilename(a.Filename))
+		b.WriteString(wrapBase64(a.Data))
+		b.WriteString("\r\n")
+	}
+
+	fmt.Fprintf(b, "--%s--\r\n", relBoundary)
+	return nil
+}
+
 func writeHeader(b *bytes.Buffer, name, value string) {
 	b.WriteString(name)
 	b.WriteString(": ")
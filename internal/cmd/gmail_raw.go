@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// rawMessage is a pre-built RFC 822 message loaded for --raw.
+type rawMessage struct {
+	Path    string
+	Data    []byte
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	Subject string
+}
+
+// loadRawMessage reads a .eml file (or '-' for stdin) and validates its
+// headers. The bytes are uploaded unchanged.
+func loadRawMessage(path string, requireRecipients bool) (rawMessage, error) {
+	data, err := resolveBodyInput("", path)
+	if err != nil {
+		return rawMessage{}, err
+	}
+	raw := rawMessage{Path: path, Data: []byte(data)}
+	if len(bytes.TrimSpace(raw.Data)) == 0 {
+		return rawMessage{}, usagef("raw message %s is empty", path)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw.Data))
+	if err != nil {
+		return rawMessage{}, usagef("invalid raw message %s: %v", path, err)
+	}
+
+	for _, h := range []struct {
+		name string
+		dst  *[]string
+	}{{"To", &raw.To}, {"Cc", &raw.Cc}, {"Bcc", &raw.Bcc}} {
+		v := strings.TrimSpace(msg.Header.Get(h.name))
+		if v == "" {
+			continue
+		}
+		addrs, parseErr := mail.ParseAddressList(v)
+		if parseErr != nil {
+			return rawMessage{}, usagef("invalid raw message %s: %s header: %v", path, h.name, parseErr)
+		}
+		for _, a := range addrs {
+			*h.dst = append(*h.dst, a.Address)
+		}
+	}
+	if from := strings.TrimSpace(msg.Header.Get("From")); from != "" {
+		addr, parseErr := mail.ParseAddress(from)
+		if parseErr != nil {
+			return rawMessage{}, usagef("invalid raw message %s: From header: %v", path, parseErr)
+		}
+		raw.From = addr.Address
+	}
+	if requireRecipients && len(raw.To)+len(raw.Cc)+len(raw.Bcc) == 0 {
+		return rawMessage{}, usagef("invalid raw message %s: no To, Cc, or Bcc header", path)
+	}
+	if ct := strings.TrimSpace(msg.Header.Get("Content-Type")); ct != "" {
+		mediaType, params, parseErr := mime.ParseMediaType(ct)
+		if parseErr != nil {
+			return rawMessage{}, usagef("invalid raw message %s: Content-Type: %v", path, parseErr)
+		}
+		if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] == "" {
+			return rawMessage{}, usagef("invalid raw message %s: %s without boundary", path, mediaType)
+		}
+	}
+	if subject, decodeErr := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); decodeErr == nil {
+		raw.Subject = subject
+	} else {
+		raw.Subject = msg.Header.Get("Subject")
+	}
+
+	return raw, nil
+}
+
+// rawMessageFor returns the --raw message parsed by the policy check, so the
+// bytes that were checked are the bytes sent, and loads it otherwise.
+func rawMessageFor(ctx context.Context, path string, requireRecipients bool) (rawMessage, error) {
+	st := invocationFromContext(ctx)
+	if st == nil || st.raw == nil || st.raw.Path != strings.TrimSpace(path) {
+		return loadRawMessage(path, requireRecipients)
+	}
+	raw := *st.raw
+	if requireRecipients && len(raw.To)+len(raw.Cc)+len(raw.Bcc) == 0 {
+		return rawMessage{}, usagef("invalid raw message %s: no To, Cc, or Bcc header", path)
+	}
+	return raw, nil
+}
+
+// rejectWithRaw returns a usage error naming any compose flags set alongside --raw.
+func rejectWithRaw(set map[string]bool) error {
+	var names []string
+	for name, ok := range set {
+		if ok {
+			names = append(names, "--"+name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return usagef("--raw sends the message as-is; remove %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestLoadRawMessage(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	ok := write("ok.eml", "From: Ann <ann@example.com>\r\nTo: bob@example.com, Cy <cy@example.com>\r\nSubject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n\r\nhi\r\n")
+	raw, err := loadRawMessage(ok, true)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if raw.From != "ann@example.com" || strings.Join(raw.To, ",") != "bob@example.com,cy@example.com" || raw.Subject != "Grüße" {
+		t.Fatalf("unexpected parse: %#v", raw)
+	}
+
+	for name, content := range map[string]string{
+		"norcpt.eml":    "From: ann@example.com\r\nSubject: x\r\n\r\nhi\r\n",
+		"badto.eml":     "To: not an address\r\n\r\nhi\r\n",
+		"noheaders.eml": "just text without headers",
+		"boundary.eml":  "To: bob@example.com\r\nContent-Type: multipart/mixed\r\n\r\nhi\r\n",
+	} {
+		if _, err := loadRawMessage(write(name, content), true); err == nil || ExitCode(err) != 2 {
+			t.Fatalf("%s: expected usage error, got %v", name, err)
+		}
+	}
+	if _, err := loadRawMessage(write("draft.eml", "Subject: draft\r\n\r\nhi\r\n"), false); err != nil {
+		t.Fatalf("draft without recipients: %v", err)
+	}
+}
+
+func TestGmailSendRaw_UploadsUnchanged(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	content := "From: me@example.com\r\nTo: bob@example.com\r\nSubject: Raw\r\nX-Custom: keep\r\n\r\nbody\r\n"
+	emlPath := filepath.Join(t.TempDir(), "msg.eml")
+	if err := os.WriteFile(emlPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got gmail.Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || strings.TrimPrefix(r.URL.Path, "/gmail/v1") != "/users/me/messages/send" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t9"})
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "send", "--raw", emlPath, "--thread-id", "t9"}); execErr != nil {
+			t.Fatalf("send: %v", execErr)
+		}
+	})
+	sent, _ := base64.RawURLEncoding.DecodeString(got.Raw)
+	if string(sent) != content || got.ThreadId != "t9" {
+		t.Fatalf("raw not uploaded unchanged: %q thread=%q", sent, got.ThreadId)
+	}
+	if !strings.Contains(out, `"messageId": "m1"`) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	err = Execute([]string{"--account", "me@example.com", "gmail", "send", "--raw", emlPath, "--subject", "x"})
+	if ExitCode(err) != 2 || !strings.Contains(err.Error(), "--subject") {
+		t.Fatalf("expected usage error naming --subject, got %v", err)
+	}
+
+}
+
+func TestRawMessageFor_ReusesPolicyCheckedMessage(t *testing.T) {
+	emlPath := filepath.Join(t.TempDir(), "msg.eml")
+	if err := os.WriteFile(emlPath, []byte("To: bob@example.com\r\nSubject: Checked\r\n\r\nbody\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checked, err := loadRawMessage(emlPath, false)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// The file is swapped after the policy check; the checked bytes are still sent.
+	if err = os.WriteFile(emlPath, []byte("To: x@evil.com\r\nSubject: Swapped\r\n\r\nbody\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := withInvocation(context.Background(), &invocationState{raw: &checked})
+	raw, err := rawMessageFor(ctx, emlPath, true)
+	if err != nil {
+		t.Fatalf("rawMessageFor: %v", err)
+	}
+	if raw.Subject != "Checked" || !strings.Contains(string(raw.Data), "bob@example.com") {
+		t.Fatalf("expected the checked message, got %#v", raw)
+	}
+	if raw, err = rawMessageFor(context.Background(), emlPath, true); err != nil || raw.Subject != "Swapped" {
+		t.Fatalf("expected a fresh load without a policy check, got %#v (%v)", raw, err)
+	}
+}
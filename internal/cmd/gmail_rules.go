@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/mailmerge"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type GmailRulesCmd struct {
+	Test GmailRulesTestCmd `cmd:"" name:"test" aliases:"dry-run" help:"Show which rules match a message and what they would do"`
+}
+
+// gmailRulesFile is the rules file read from YAML. Rules run in order on
+// each new message; every condition of a rule must match.
+type gmailRulesFile struct {
+	Rules []gmailRule `yaml:"rules"`
+}
+
+type gmailRule struct {
+	Name    string           `yaml:"name"`
+	Match   gmailRuleMatch   `yaml:"match"`
+	Actions gmailRuleActions `yaml:"actions"`
+	Stop    bool             `yaml:"stop"`
+
+	patterns  []gmailRulePattern
+	reply     *mailmerge.Template
+	taskTitle *mailmerge.Template
+	taskNotes *mailmerge.Template
+}
+
+// gmailRuleMatch holds case-insensitive regular expressions. Labels must all
+// be present on the message (names or IDs).
+type gmailRuleMatch struct {
+	From       string   `yaml:"from"`
+	To         string   `yaml:"to"`
+	Subject    string   `yaml:"subject"`
+	Body       string   `yaml:"body"`
+	Attachment string   `yaml:"attachment"`
+	Labels     []string `yaml:"labels"`
+}
+
+type gmailRuleActions struct {
+	Label     []string       `yaml:"label"`
+	Archive   bool           `yaml:"archive"`
+	MarkRead  bool           `yaml:"markRead"`
+	Star      bool           `yaml:"star"`
+	Forward   []string       `yaml:"forward"`
+	Reply     string         `yaml:"reply"`
+	ReplyFile string         `yaml:"replyFile"`
+	Task      *gmailRuleTask `yaml:"task"`
+	Run       string         `yaml:"run"`
+}
+
+type gmailRuleTask struct {
+	Title string `yaml:"title"`
+	Notes string `yaml:"notes"`
+	List  string `yaml:"list"`
+	Due   string `yaml:"due"`
+}
+
+type gmailRulePattern struct {
+	field string
+	re    *regexp.Regexp
+}
+
+func loadGmailRules(path string) ([]*gmailRule, error) {
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, err
+	}
+	var file gmailRulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, usagef("parse %s: %v", path, err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, usagef("%s declares no rules", path)
+	}
+	rules := make([]*gmailRule, 0, len(file.Rules))
+	seen := map[string]bool{}
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		if strings.TrimSpace(r.Name) == "" {
+			return nil, usagef("rules[%d]: name is required", i)
+		}
+		if seen[r.Name] {
+			return nil, usagef("rule %s: duplicate name", r.Name)
+		}
+		seen[r.Name] = true
+		if err := r.compile(filepath.Dir(path)); err != nil {
+			return nil, usagef("rule %s: %v", r.Name, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r *gmailRule) compile(dir string) error {
+	for _, p := range []struct{ field, expr string }{
+		{"from", r.Match.From},
+		{"to", r.Match.To},
+		{"subject", r.Match.Subject},
+		{"body", r.Match.Body},
+		{"attachment", r.Match.Attachment},
+	} {
+		if p.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + p.expr)
+		if err != nil {
+			return fmt.Errorf("match.%s: %w", p.field, err)
+		}
+		r.patterns = append(r.patterns, gmailRulePattern{field: p.field, re: re})
+	}
+	if len(r.patterns) == 0 && len(r.Match.Labels) == 0 {
+		return fmt.Errorf("match needs at least one condition")
+	}
+
+	a := &r.Actions
+	if len(a.Label) == 0 && !a.Archive && !a.MarkRead && !a.Star && len(a.Forward) == 0 &&
+		a.Reply == "" && a.ReplyFile == "" && a.Task == nil && strings.TrimSpace(a.Run) == "" {
+		return fmt.Errorf("actions are empty")
+	}
+	reply := a.Reply
+	if a.ReplyFile != "" {
+		if reply != "" {
+			return fmt.Errorf("use reply or replyFile, not both")
+		}
+		replyPath := a.ReplyFile
+		if !filepath.IsAbs(replyPath) && !strings.HasPrefix(replyPath, "~") {
+			replyPath = filepath.Join(dir, replyPath)
+		}
+		replyPath, err := config.ExpandPath(replyPath)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(replyPath) //nolint:gosec // user-provided path
+		if err != nil {
+			return err
+		}
+		reply = string(b)
+	}
+	var err error
+	if reply != "" {
+		if r.reply, err = mailmerge.Parse(reply); err != nil {
+			return fmt.Errorf("reply template: %w", err)
+		}
+	}
+	if a.Task != nil {
+		if strings.TrimSpace(a.Task.Title) == "" {
+			return fmt.Errorf("task.title is required")
+		}
+		if r.taskTitle, err = mailmerge.Parse(a.Task.Title); err != nil {
+			return fmt.Errorf("task.title: %w", err)
+		}
+		if r.taskNotes, err = mailmerge.Parse(a.Task.Notes); err != nil {
+			return fmt.Errorf("task.notes: %w", err)
+		}
+	}
+	for _, addr := range a.Forward {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("forward %q: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// gmailRuleMessage is the view of a message that rules match against and
+// templates render from.
+type gmailRuleMessage struct {
+	ID          string   `json:"id"`
+	ThreadID    string   `json:"threadId"`
+	From        string   `json:"from"`
+	To          string   `json:"to,omitempty"`
+	Cc          string   `json:"cc,omitempty"`
+	Subject     string   `json:"subject"`
+	Date        string   `json:"date,omitempty"`
+	Snippet     string   `json:"snippet,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+
+	labelIDs []string
+	msg      *gmail.Message
+}
+
+// newGmailRuleMessage builds the view from a message fetched in full format.
+// Label IDs are shown by name when idToName knows them.
+func newGmailRuleMessage(msg *gmail.Message, idToName map[string]string) gmailRuleMessage {
+	m := gmailRuleMessage{
+		ID:       msg.Id,
+		ThreadID: msg.ThreadId,
+		From:     headerValue(msg.Payload, "From"),
+		To:       headerValue(msg.Payload, "To"),
+		Cc:       headerValue(msg.Payload, "Cc"),
+		Subject:  headerValue(msg.Payload, "Subject"),
+		Date:     headerValue(msg.Payload, "Date"),
+		Snippet:  msg.Snippet,
+		labelIDs: msg.LabelIds,
+		msg:      msg,
+	}
+	if m.Body = bestBodyText(msg.Payload); looksLikeHTML(m.Body) {
+		m.Body = stripHTMLTags(m.Body)
+	}
+	for _, id := range msg.LabelIds {
+		m.Labels = append(m.Labels, firstNonEmpty(idToName[id], id))
+	}
+	for _, a := range collectAttachments(msg.Payload) {
+		m.Attachments = append(m.Attachments, a.Filename)
+	}
+	return m
+}
+
+func (m gmailRuleMessage) fieldValues(field string) []string {
+	switch field {
+	case "from":
+		return []string{m.From}
+	case "to":
+		return []string{strings.Trim(m.To+", "+m.Cc, ", ")}
+	case "subject":
+		return []string{m.Subject}
+	case "body":
+		return []string{m.Body}
+	case "attachment":
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m gmailRuleMessage) hasLabel(label string) bool {
+	for i, id := range m.labelIDs {
+		if strings.EqualFold(id, label) || (i < len(m.Labels) && strings.EqualFold(m.Labels[i], label)) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether every condition holds. On a miss it returns the
+// first failing condition; on a hit it returns named regex groups, which
+// templates can use like message fields.
+func (r *gmailRule) match(m gmailRuleMessage) (bool, string, map[string]string) {
+	captures := map[string]string{}
+	for _, label := range r.Match.Labels {
+		if !m.hasLabel(label) {
+			return false, "labels", nil
+		}
+	}
+	for _, p := range r.patterns {
+		matched := false
+		for _, v := range m.fieldValues(p.field) {
+			sub := p.re.FindStringSubmatch(v)
+			if sub == nil {
+				continue
+			}
+			matched = true
+			for i, name := range p.re.SubexpNames() {
+				if name != "" && i < len(sub) {
+					captures[name] = sub[i]
+				}
+			}
+			break
+		}
+		if !matched {
+			return false, p.field, nil
+		}
+	}
+	return true, "", captures
+}
+
+// templateRow exposes message fields and regex captures to templates.
+func (m gmailRuleMessage) templateRow(captures map[string]string) mailmerge.Row {
+	values := map[string]string{
+		"id":          m.ID,
+		"threadId":    m.ThreadID,
+		"from":        m.From,
+		"fromName":    m.From,
+		"fromAddress": m.From,
+		"to":          m.To,
+		"cc":          m.Cc,
+		"subject":     m.Subject,
+		"date":        m.Date,
+		"snippet":     m.Snippet,
+		"body":        m.Body,
+		"labels":      strings.Join(m.Labels, ", "),
+		"attachments": strings.Join(m.Attachments, ", "),
+	}
+	if addr, err := mail.ParseAddress(m.From); err == nil {
+		values["fromName"] = firstNonEmpty(addr.Name, addr.Address)
+		values["fromAddress"] = addr.Address
+	}
+	for k, v := range captures {
+		values[k] = v
+	}
+	return mailmerge.Row{Values: values}
+}
+
+// gmailRuleAction is one planned action, rendered for the matched message.
+type gmailRuleAction struct {
+	Type    string   `json:"type"`
+	Labels  []string `json:"labels,omitempty"`
+	To      []string `json:"to,omitempty"`
+	Subject string   `json:"subject,omitempty"`
+	Body    string   `json:"body,omitempty"`
+	List    string   `json:"list,omitempty"`
+	Due     string   `json:"due,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Skipped string   `json:"skipped,omitempty"`
+}
+
+// plan renders the rule's actions for a matched message. Templates render
+// missing fields as empty.
+func (r *gmailRule) plan(m gmailRuleMessage, captures map[string]string, account string) []gmailRuleAction {
+	a := r.Actions
+	row := m.templateRow(captures)
+	var out []gmailRuleAction
+	if len(a.Label) > 0 {
+		out = append(out, gmailRuleAction{Type: "label", Labels: a.Label})
+	}
+	if a.Archive {
+		out = append(out, gmailRuleAction{Type: "archive"})
+	}
+	if a.MarkRead {
+		out = append(out, gmailRuleAction{Type: "markRead"})
+	}
+	if a.Star {
+		out = append(out, gmailRuleAction{Type: "star"})
+	}
+	if len(a.Forward) > 0 {
+		out = append(out, gmailRuleAction{Type: "forward", To: a.Forward, Subject: "Fwd: " + m.Subject})
+	}
+	if r.reply != nil {
+		body, _ := r.reply.Render(row, true)
+		action := gmailRuleAction{Type: "reply", Subject: replySubject(m.Subject), Body: body}
+		if reason := autoReplySkipReason(m.msg, account); reason != "" {
+			action.Skipped = reason
+		} else {
+			info := replyInfoFromMessage(m.msg, false)
+			action.To = []string{firstNonEmpty(info.ReplyToAddr, info.FromAddr)}
+		}
+		out = append(out, action)
+	}
+	if a.Task != nil {
+		title, _ := r.taskTitle.Render(row, true)
+		notes, _ := r.taskNotes.Render(row, true)
+		out = append(out, gmailRuleAction{Type: "task", Subject: title, Body: notes, List: firstNonEmpty(a.Task.List, defaultTaskListID), Due: a.Task.Due})
+	}
+	if cmd := strings.TrimSpace(a.Run); cmd != "" {
+		out = append(out, gmailRuleAction{Type: "run", Command: cmd})
+	}
+	return out
+}
+
+func replySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(subject)), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// autoReplySkipReason follows RFC 3834: never auto-reply to automated or
+// bulk mail, or to the account's own messages.
+func autoReplySkipReason(msg *gmail.Message, account string) string {
+	if msg == nil {
+		return ""
+	}
+	if addr, err := mail.ParseAddress(headerValue(msg.Payload, "From")); err == nil && strings.EqualFold(addr.Address, account) {
+		return "message is from " + account
+	}
+	if v := strings.ToLower(strings.TrimSpace(headerValue(msg.Payload, "Auto-Submitted"))); v != "" && v != "no" {
+		return "automated message (Auto-Submitted: " + v + ")"
+	}
+	switch v := strings.ToLower(strings.TrimSpace(headerValue(msg.Payload, "Precedence"))); v {
+	case "bulk", "junk", "list":
+		return "bulk message (Precedence: " + v + ")"
+	}
+	if headerValue(msg.Payload, "List-Id") != "" {
+		return "mailing list message"
+	}
+	return ""
+}
+
+type gmailRuleResult struct {
+	Rule    string            `json:"rule"`
+	Matched bool              `json:"matched"`
+	Missed  string            `json:"missed,omitempty"`
+	Actions []gmailRuleAction `json:"actions,omitempty"`
+}
+
+// evaluateGmailRules runs the rules in order until one with stop matches.
+func evaluateGmailRules(rules []*gmailRule, m gmailRuleMessage, account string) []gmailRuleResult {
+	out := make([]gmailRuleResult, 0, len(rules))
+	for _, r := range rules {
+		ok, missed, captures := r.match(m)
+		res := gmailRuleResult{Rule: r.Name, Matched: ok, Missed: missed}
+		if ok {
+			res.Actions = r.plan(m, captures, account)
+		}
+		out = append(out, res)
+		if ok && r.Stop {
+			break
+		}
+	}
+	return out
+}
+
+type GmailRulesTestCmd struct {
+	MessageID string `arg:"" name:"messageId" help:"Message ID"`
+	File      string `name:"file" short:"f" required:"" help:"Rules file (YAML)"`
+}
+
+func (c *GmailRulesTestCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	rules, err := loadGmailRules(c.File)
+	if err != nil {
+		return err
+	}
+	messageID := normalizeGmailMessageID(c.MessageID)
+	if messageID == "" {
+		return usage("empty messageId")
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	msg, err := svc.Users.Messages.Get("me", messageID).Format(gmailFormatFull).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+	m := newGmailRuleMessage(msg, idToName)
+	results := evaluateGmailRules(rules, m, account)
+
+	if outfmt.IsJSON(ctx) {
+		m.Body = ""
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"message": m, "rules": results})
+	}
+	u.Out().Printf("message\t%s", m.ID)
+	u.Out().Printf("from\t%s", sanitizeTab(m.From))
+	u.Out().Printf("subject\t%s", sanitizeTab(m.Subject))
+	for _, res := range results {
+		if !res.Matched {
+			u.Out().Printf("rule\t%s\tno match (%s)", res.Rule, res.Missed)
+			continue
+		}
+		u.Out().Printf("rule\t%s\tmatch", res.Rule)
+		for _, a := range res.Actions {
+			u.Out().Printf("  %s", describeGmailRuleAction(a))
+		}
+	}
+	return nil
+}
+
+func describeGmailRuleAction(a gmailRuleAction) string {
+	var parts []string
+	switch a.Type {
+	case "label":
+		parts = append(parts, strings.Join(a.Labels, ", "))
+	case "forward", "reply":
+		if len(a.To) > 0 {
+			parts = append(parts, "to="+strings.Join(a.To, ","))
+		}
+		parts = append(parts, fmt.Sprintf("subject=%q", a.Subject))
+	case "task":
+		parts = append(parts, fmt.Sprintf("title=%q", a.Subject), "list="+a.List)
+		if a.Due != "" {
+			parts = append(parts, "due="+a.Due)
+		}
+	case "run":
+		parts = append(parts, a.Command)
+	}
+	if a.Skipped != "" {
+		parts = append(parts, "(skipped: "+a.Skipped+")")
+	}
+	if len(parts) == 0 {
+		return a.Type
+	}
+	return a.Type + "\t" + sanitizeTab(strings.Join(parts, " "))
+}
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/tasks/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+const gmailRuleRunTimeout = time.Minute
+
+// queueRules hands newly added messages to the rules worker, so slow actions
+// never hold up a push. Without a worker (poll --once) the rules run inline.
+func (s *gmailWatchServer) queueRules(ctx context.Context, svc *gmail.Service, ids []string) {
+	if len(s.rules) == 0 || len(ids) == 0 {
+		return
+	}
+	if s.ruleWake == nil {
+		s.applyRules(ctx, svc, ids)
+		return
+	}
+	s.ruleMu.Lock()
+	s.rulePending = append(s.rulePending, ids...)
+	s.ruleMu.Unlock()
+	select {
+	case s.ruleWake <- struct{}{}:
+	default:
+	}
+}
+
+// startRules runs queued rules in the background until ctx is done.
+func (s *gmailWatchServer) startRules(ctx context.Context) {
+	if len(s.rules) == 0 {
+		return
+	}
+	s.ruleWake = make(chan struct{}, 1)
+	go s.runRules(ctx)
+}
+
+func (s *gmailWatchServer) runRules(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.ruleWake:
+		}
+		s.ruleMu.Lock()
+		ids := s.rulePending
+		s.rulePending = nil
+		s.ruleMu.Unlock()
+		svc, err := s.newService(ctx, s.cfg.Account)
+		if err != nil {
+			s.warnf("watch: rules: %v", err)
+			continue
+		}
+		s.applyRules(ctx, svc, ids)
+	}
+}
+
+// applyRules evaluates the rules on newly added messages and runs the actions
+// of every match. Failures are logged and never fail the push.
+func (s *gmailWatchServer) applyRules(ctx context.Context, svc *gmail.Service, ids []string) {
+	if len(s.rules) == 0 || len(ids) == 0 {
+		return
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		s.warnf("watch: rules: list labels: %v", err)
+		return
+	}
+	for _, id := range ids {
+		msg, err := svc.Users.Messages.Get("me", id).Format(gmailFormatFull).Context(ctx).Do()
+		if err != nil {
+			if !isNotFoundAPIError(err) {
+				s.warnf("watch: rules: get message %s: %v", id, err)
+			}
+			continue
+		}
+		// Mail the account sends or drafts itself is never a rule trigger.
+		if s.isExcludedLabel(msg.LabelIds) || hasLabelID(msg.LabelIds, "SENT") || hasLabelID(msg.LabelIds, "DRAFT") {
+			continue
+		}
+		m := newGmailRuleMessage(msg, idToName)
+		for _, res := range evaluateGmailRules(s.rules, m, s.cfg.Account) {
+			if !res.Matched {
+				continue
+			}
+			s.logf("watch: rule %s matched message %s", res.Rule, m.ID)
+			res.Actions = s.allowedRuleActions(res, m)
+			if err := runGmailRuleActions(ctx, svc, s.cfg.Account, res, m); err != nil {
+				s.warnf("watch: rule %s on message %s: %v", res.Rule, m.ID, err)
+			}
+		}
+	}
+}
+
+// allowedRuleActions drops the actions that the global flags forbid, logging
+// each one: everything under --dry-run, any action the --policy rejects for
+// the command that does the same thing (see ruleActionCommand), and anything
+// that sends mail or runs a command under --require-approval, since nobody can
+// approve it in time.
+func (s *gmailWatchServer) allowedRuleActions(res gmailRuleResult, m gmailRuleMessage) []gmailRuleAction {
+	out := make([]gmailRuleAction, 0, len(res.Actions))
+	for _, a := range res.Actions {
+		if a.Skipped != "" {
+			continue
+		}
+		if s.cfg.DryRun {
+			s.logf("watch: rule %s on message %s: dry-run, would %s", res.Rule, m.ID, strings.ReplaceAll(describeGmailRuleAction(a), "\t", " "))
+			continue
+		}
+		if s.cfg.RequireApproval && (a.Type == "forward" || a.Type == "reply" || a.Type == "run") {
+			s.warnf("watch: rule %s on message %s: %s held, --require-approval is set", res.Rule, m.ID, a.Type)
+			continue
+		}
+		if s.cfg.Policy != nil {
+			if err := checkRuleActionPolicy(*s.cfg.Policy, a, m); err != nil {
+				s.warnf("watch: rule %s on message %s: %s: %v", res.Rule, m.ID, a.Type, err)
+				continue
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// ruleRunCommand is the pseudo-command a policy names to allow run actions.
+var ruleRunCommand = []string{"gmail", "rules", "run"}
+
+// checkRuleActionPolicy evaluates an action against the policy as its
+// equivalent command. run has no real equivalent, so a policy must allow it
+// with an explicit "gmail rules run" rule (the command is checked as
+// "command"); a default, "*" or broader prefix rule doesn't count.
+func checkRuleActionPolicy(policy config.Policy, a gmailRuleAction, m gmailRuleMessage) error {
+	cmdPath, values := ruleActionCommand(a, m)
+	if cmdPath == nil {
+		return nil
+	}
+	if a.Type == "run" {
+		rule, ok := matchPolicyRule(policy.Rules, cmdPath)
+		if !ok || len(strings.Fields(rule.Command)) != len(cmdPath) {
+			return &PolicyError{Command: strings.Join(cmdPath, " "), Reason: `run actions need an explicit "gmail rules run" rule`}
+		}
+	}
+	return evaluatePolicy(policy, cmdPath, values)
+}
+
+// ruleActionCommand maps a rule action to the gog command that does the same
+// thing, with the argument values that command would see, so --policy rules
+// written for the commands also bind the rules engine. run maps to the
+// ruleRunCommand pseudo-command.
+func ruleActionCommand(a gmailRuleAction, m gmailRuleMessage) ([]string, map[string][]string) {
+	ids := []string{m.ID}
+	switch a.Type {
+	case "label":
+		return []string{"gmail", "batch", "modify"}, map[string][]string{"messageId": ids, "add": a.Labels}
+	case "star":
+		return []string{"gmail", "batch", "modify"}, map[string][]string{"messageId": ids, "add": {"STARRED"}}
+	case "archive":
+		return []string{"gmail", "archive"}, map[string][]string{"messageId": ids}
+	case "markRead":
+		return []string{"gmail", "mark-read"}, map[string][]string{"messageId": ids}
+	case "forward", "reply":
+		return []string{"gmail", "send"}, map[string][]string{"to": a.To, "subject": {a.Subject}}
+	case "task":
+		values := map[string][]string{"tasklistId": {a.List}, "title": {a.Subject}}
+		if a.Due != "" {
+			values["due"] = []string{a.Due}
+		}
+		return []string{"tasks", "add"}, values
+	case "run":
+		return ruleRunCommand, map[string][]string{"command": {a.Command}}
+	}
+	return nil, nil
+}
+
+func hasLabelID(labelIDs []string, want string) bool {
+	for _, id := range labelIDs {
+		if id == want {
+			return true
+		}
+	}
+	return false
+}
+
+// runGmailRuleActions runs every planned action, continuing past failures.
+// Label and flag changes go out as one modify call.
+func runGmailRuleActions(ctx context.Context, svc *gmail.Service, account string, res gmailRuleResult, m gmailRuleMessage) error {
+	var errs []error
+	modify := &gmail.ModifyMessageRequest{}
+	for _, a := range res.Actions {
+		switch a.Type {
+		case "label":
+			ids, err := ensureRuleLabelIDs(ctx, svc, a.Labels)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("label: %w", err))
+				continue
+			}
+			modify.AddLabelIds = append(modify.AddLabelIds, ids...)
+		case "archive":
+			modify.RemoveLabelIds = append(modify.RemoveLabelIds, "INBOX")
+		case "markRead":
+			modify.RemoveLabelIds = append(modify.RemoveLabelIds, "UNREAD")
+		case "star":
+			modify.AddLabelIds = append(modify.AddLabelIds, "STARRED")
+		}
+	}
+	if len(modify.AddLabelIds) > 0 || len(modify.RemoveLabelIds) > 0 {
+		if _, err := svc.Users.Messages.Modify("me", m.ID, modify).Context(ctx).Do(); err != nil {
+			errs = append(errs, fmt.Errorf("modify: %w", err))
+		}
+	}
+
+	for _, a := range res.Actions {
+		var err error
+		switch a.Type {
+		case "forward":
+			err = forwardRuleMessage(ctx, svc, account, a, m)
+		case "reply":
+			if a.Skipped != "" {
+				continue
+			}
+			err = replyRuleMessage(ctx, svc, account, a, m)
+		case "task":
+			err = createRuleTask(ctx, account, a)
+		case "run":
+			err = runRuleCommand(ctx, account, res.Rule, a.Command, m)
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ensureRuleLabelIDs resolves label names or IDs, creating missing labels.
+func ensureRuleLabelIDs(ctx context.Context, svc *gmail.Service, labels []string) ([]string, error) {
+	nameToID, err := fetchLabelNameToID(svc)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(labels))
+	for _, name := range labels {
+		if id, ok := nameToID[strings.ToLower(strings.TrimSpace(name))]; ok {
+			ids = append(ids, id)
+			continue
+		}
+		label, err := createLabel(ctx, svc, strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("create %q: %w", name, err)
+		}
+		ids = append(ids, label.Id)
+	}
+	return ids, nil
+}
+
+// forwardRuleMessage sends the original message as a message/rfc822
+// attachment, which keeps its headers and attachments intact.
+func forwardRuleMessage(ctx context.Context, svc *gmail.Service, account string, a gmailRuleAction, m gmailRuleMessage) error {
+	rawMsg, err := svc.Users.Messages.Get("me", m.ID).Format(gmailFormatRaw).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	original, err := decodeBase64URLBytes(rawMsg.Raw)
+	if err != nil {
+		return err
+	}
+	filename := sanitizeAttachmentFilename(m.Subject, m.ID)
+	raw, err := buildRFC822(mailOptions{
+		From:        account,
+		To:          a.To,
+		Subject:     a.Subject,
+		Body:        fmt.Sprintf("Forwarded message from %s.\n", m.From),
+		Attachments: []mailAttachment{{Filename: filename + ".eml", MIMEType: "message/rfc822", Data: original}},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = svc.Users.Messages.Send("me", &gmail.Message{Raw: base64.RawURLEncoding.EncodeToString(raw)}).Context(ctx).Do()
+	return err
+}
+
+// replyRuleMessage answers in the same thread. Auto-Submitted marks the reply
+// as automatic so other responders don't answer it (RFC 3834).
+func replyRuleMessage(ctx context.Context, svc *gmail.Service, account string, a gmailRuleAction, m gmailRuleMessage) error {
+	info := replyInfoFromMessage(m.msg, false)
+	raw, err := buildRFC822(mailOptions{
+		From:              account,
+		To:                a.To,
+		Subject:           a.Subject,
+		Body:              a.Body,
+		InReplyTo:         info.InReplyTo,
+		References:        info.References,
+		AdditionalHeaders: map[string]string{"Auto-Submitted": "auto-replied"},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = svc.Users.Messages.Send("me", &gmail.Message{
+		Raw:      base64.RawURLEncoding.EncodeToString(raw),
+		ThreadId: firstNonEmpty(info.ThreadID, m.ThreadID),
+	}).Context(ctx).Do()
+	return err
+}
+
+func createRuleTask(ctx context.Context, account string, a gmailRuleAction) error {
+	due, err := normalizeTaskDue(a.Due)
+	if err != nil {
+		return err
+	}
+	svc, err := newTasksService(ctx, account)
+	if err != nil {
+		return err
+	}
+	listID, err := resolveTasklistID(ctx, svc, a.List)
+	if err != nil {
+		return err
+	}
+	_, err = svc.Tasks.Insert(listID, &tasks.Task{Title: a.Subject, Notes: a.Body, Due: due}).Context(ctx).Do()
+	return err
+}
+
+// runRuleCommand runs the command through the shell with the message as JSON
+// on stdin. The command string is not templated, so message content never
+// reaches the shell; use the GOG_* variables or stdin instead.
+func runRuleCommand(ctx context.Context, account, rule, command string, m gmailRuleMessage) error {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, gmailRuleRunTimeout)
+	defer cancel()
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"GOG_ACCOUNT="+account,
+		"GOG_RULE="+rule,
+		"GOG_MESSAGE_ID="+m.ID,
+		"GOG_THREAD_ID="+m.ThreadID,
+		"GOG_FROM="+m.From,
+		"GOG_SUBJECT="+m.Subject,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, firstLine(msg))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+func writeRulesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	return path
+}
+
+func TestLoadGmailRulesValidation(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":         "rules: []\n",
+		"unknown field": "rules:\n  - name: a\n    match: {from: x}\n    actions: {archive: true}\n    when: always\n",
+		"no name":       "rules:\n  - match: {from: x}\n    actions: {archive: true}\n",
+		"no condition":  "rules:\n  - name: a\n    actions: {archive: true}\n",
+		"no action":     "rules:\n  - name: a\n    match: {from: x}\n",
+		"bad regex":     "rules:\n  - name: a\n    match: {subject: \"(\"}\n    actions: {archive: true}\n",
+		"duplicate":     "rules:\n  - name: a\n    match: {from: x}\n    actions: {star: true}\n  - name: a\n    match: {from: y}\n    actions: {star: true}\n",
+		"two replies":   "rules:\n  - name: a\n    match: {from: x}\n    actions: {reply: hi, replyFile: r.txt}\n",
+		"task no title": "rules:\n  - name: a\n    match: {from: x}\n    actions: {task: {notes: n}}\n",
+		"bad forward":   "rules:\n  - name: a\n    match: {from: x}\n    actions: {forward: [\"not an address\"]}\n",
+	} {
+		if _, err := loadGmailRules(writeRulesFile(t, content)); ExitCode(err) != 2 {
+			t.Errorf("%s: expected usage error, got %v", name, err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reply.txt"), []byte("Hi {{fromName}}"), 0o600); err != nil {
+		t.Fatalf("write reply: %v", err)
+	}
+	path := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - name: a\n    match: {from: x}\n    actions: {replyFile: reply.txt}\n"), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	rules, err := loadGmailRules(path)
+	if err != nil || len(rules) != 1 || rules[0].reply == nil {
+		t.Fatalf("expected replyFile relative to the rules file: %v", err)
+	}
+}
+
+func TestGmailRuleMatch(t *testing.T) {
+	rules, err := loadGmailRules(writeRulesFile(t, `
+rules:
+  - name: invoices
+    match:
+      from: "@billing\\.example\\.com"
+      subject: "invoice (?P<number>\\d+)"
+      attachment: "\\.pdf$"
+      labels: [inbox]
+    actions:
+      label: [Finance]
+      archive: true
+      task: {title: "Pay invoice {{number}}", notes: "{{fromAddress}}", due: "2026-11-01"}
+    stop: true
+  - name: never
+    match: {from: "x"}
+    actions: {star: true}
+`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	msg := &gmail.Message{
+		Id: "m1", ThreadId: "t1", LabelIds: []string{"INBOX", "UNREAD"},
+		Payload: &gmail.MessagePart{
+			Headers: []*gmail.MessagePartHeader{
+				{Name: "From", Value: "Billing <noreply@billing.example.com>"},
+				{Name: "To", Value: "me@example.com"},
+				{Name: "Subject", Value: "Your Invoice 4711"},
+			},
+			Parts: []*gmail.MessagePart{
+				{MimeType: "application/pdf", Filename: "invoice.pdf", Body: &gmail.MessagePartBody{AttachmentId: "a1"}},
+			},
+		},
+	}
+	results := evaluateGmailRules(rules, newGmailRuleMessage(msg, map[string]string{"INBOX": "INBOX"}), "me@example.com")
+	if len(results) != 1 || !results[0].Matched {
+		t.Fatalf("expected stop after the first match, got %+v", results)
+	}
+	task := results[0].Actions[2]
+	if task.Type != "task" || task.Subject != "Pay invoice 4711" || task.Body != "noreply@billing.example.com" || task.List != defaultTaskListID {
+		t.Fatalf("unexpected task action: %+v", task)
+	}
+
+	msg.Payload.Parts = nil
+	results = evaluateGmailRules(rules, newGmailRuleMessage(msg, nil), "me@example.com")
+	if results[0].Matched || results[0].Missed != "attachment" {
+		t.Fatalf("expected miss on attachment, got %+v", results[0])
+	}
+}
+
+func TestGmailRulesTestCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch path {
+		case "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{
+				{"id": "INBOX", "name": "INBOX"},
+				{"id": "Label_1", "name": "Customers"},
+			}})
+		case "/messages/m1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "m1", "threadId": "t1", "labelIds": []string{"INBOX", "Label_1"},
+				"payload": map[string]any{"headers": []map[string]any{
+					{"name": "From", "value": "Ada <ada@example.com>"},
+					{"name": "Subject", "value": "Question"},
+					{"name": "Message-ID", "value": "<q@example.com>"},
+					{"name": "Precedence", "value": "bulk"},
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	rulesPath := writeRulesFile(t, `
+rules:
+  - name: customers
+    match: {labels: [customers]}
+    actions:
+      reply: "Hi {{fromName}}, thanks."
+      run: ./notify.sh
+  - name: newsletters
+    match: {subject: newsletter}
+    actions: {archive: true}
+`)
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "rules", "test", "m1", "--file", rulesPath}); execErr != nil {
+			t.Fatalf("rules test: %v", execErr)
+		}
+	})
+	var parsed struct {
+		Message gmailRuleMessage  `json:"message"`
+		Rules   []gmailRuleResult `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	if parsed.Message.ID != "m1" || len(parsed.Rules) != 2 {
+		t.Fatalf("unexpected output: %+v", parsed)
+	}
+	customers := parsed.Rules[0]
+	if !customers.Matched || len(customers.Actions) != 2 {
+		t.Fatalf("unexpected customers result: %+v", customers)
+	}
+	reply := customers.Actions[0]
+	if reply.Body != "Hi Ada, thanks." || reply.Subject != "Re: Question" || !strings.Contains(reply.Skipped, "bulk") {
+		t.Fatalf("unexpected reply action: %+v", reply)
+	}
+	if parsed.Rules[1].Matched || parsed.Rules[1].Missed != "subject" {
+		t.Fatalf("unexpected newsletters result: %+v", parsed.Rules[1])
+	}
+
+	out = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "rules", "test", "m1", "-f", rulesPath}); execErr != nil {
+			t.Fatalf("rules test: %v", execErr)
+		}
+	})
+	if !strings.Contains(out, "rule\tcustomers\tmatch") || !strings.Contains(out, "rule\tnewsletters\tno match (subject)") || !strings.Contains(out, "run\t./notify.sh") {
+		t.Fatalf("unexpected text output: %q", out)
+	}
+}
+
+func TestGmailWatchApplyRules(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("run action uses sh")
+	}
+	var (
+		mu       sync.Mutex
+		modifies []gmail.ModifyMessageRequest
+		created  []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		headers := []map[string]any{
+			{"name": "From", "value": "alerts@example.com"},
+			{"name": "Subject", "value": "Disk full"},
+		}
+		switch {
+		case path == "/labels" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{{"id": "INBOX", "name": "INBOX"}}})
+		case path == "/labels" && r.Method == http.MethodPost:
+			var label gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&label)
+			mu.Lock()
+			created = append(created, label.Name)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "Label_9", "name": label.Name})
+		case path == "/messages/m1":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1", "labelIds": []string{"INBOX"}, "payload": map[string]any{"headers": headers}})
+		case path == "/messages/m2":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m2", "threadId": "t2", "labelIds": []string{"SENT"}, "payload": map[string]any{"headers": headers}})
+		case path == "/messages/m1/modify":
+			var req gmail.ModifyMessageRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			modifies = append(modifies, req)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	outPath := filepath.Join(t.TempDir(), "run.out")
+	rules, err := loadGmailRules(writeRulesFile(t, `
+rules:
+  - name: alerts
+    match: {from: "^alerts@"}
+    actions:
+      label: [Ops]
+      archive: true
+      star: true
+      run: 'printf "%s %s " "$GOG_RULE" "$GOG_MESSAGE_ID" > `+outPath+`; cat >> `+outPath+`'
+`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	var warnings []string
+	server := &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "me@example.com"},
+		rules: rules,
+		logf:  func(string, ...any) {},
+		warnf: func(format string, args ...any) {
+			warnings = append(warnings, format)
+		},
+	}
+	server.applyRules(context.Background(), svc, []string{"m1", "m2"})
+
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if len(created) != 1 || created[0] != "Ops" {
+		t.Fatalf("expected label Ops to be created, got %v", created)
+	}
+	if len(modifies) != 1 {
+		t.Fatalf("expected one modify for m1, got %+v", modifies)
+	}
+	add, remove := strings.Join(modifies[0].AddLabelIds, ","), strings.Join(modifies[0].RemoveLabelIds, ",")
+	if add != "Label_9,STARRED" || remove != "INBOX" {
+		t.Fatalf("unexpected modify: add=%s remove=%s", add, remove)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read run output: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "alerts m1 {") || !strings.Contains(string(data), `"subject":"Disk full"`) {
+		t.Fatalf("unexpected run output: %q", data)
+	}
+}
+
+func TestGmailWatchRuleActionGuards(t *testing.T) {
+	res := gmailRuleResult{Rule: "r", Matched: true, Actions: []gmailRuleAction{
+		{Type: "label", Labels: []string{"Ops"}},
+		{Type: "forward", To: []string{"x@evil.com"}},
+		{Type: "forward", To: []string{"a@ourco.com"}},
+		{Type: "reply", To: []string{"b@ourco.com"}},
+		{Type: "reply", Skipped: "bulk message (Precedence: bulk)"},
+		{Type: "run", Command: "true"},
+	}}
+	m := gmailRuleMessage{ID: "m1"}
+	types := func(actions []gmailRuleAction) string {
+		var out []string
+		for _, a := range actions {
+			out = append(out, a.Type+":"+strings.Join(a.To, ","))
+		}
+		return strings.Join(out, " ")
+	}
+
+	var logs, warnings []string
+	server := &gmailWatchServer{
+		logf:  func(format string, args ...any) { logs = append(logs, format) },
+		warnf: func(format string, args ...any) { warnings = append(warnings, format) },
+	}
+	server.cfg.Policy = &config.Policy{Rules: []config.PolicyRule{
+		{Command: "gmail send", Args: map[string]config.ArgumentPolicy{"to": {Allow: []string{"*@ourco.com"}}}},
+	}}
+	// run has no real command, so it needs an explicit "gmail rules run" rule.
+	if got := types(server.allowedRuleActions(res, m)); got != "label: forward:a@ourco.com reply:b@ourco.com" {
+		t.Fatalf("policy: got %q", got)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected forward and run policy warnings, got %v", warnings)
+	}
+	run := gmailRuleResult{Rule: "r", Matched: true, Actions: []gmailRuleAction{{Type: "run", Command: "true"}, {Type: "run", Command: "rm -rf ~"}}}
+	for _, policy := range []*config.Policy{
+		{Default: "deny"},
+		{Rules: []config.PolicyRule{{Command: "*"}}},
+		{Rules: []config.PolicyRule{{Command: "gmail rules"}}},
+	} {
+		server.cfg.Policy = policy
+		if got := types(server.allowedRuleActions(run, m)); got != "" {
+			t.Fatalf("policy %+v: expected run denied, got %q", policy, got)
+		}
+	}
+	server.cfg.Policy = &config.Policy{Default: "deny", Rules: []config.PolicyRule{
+		{Command: "gmail rules run", Args: map[string]config.ArgumentPolicy{"command": {Allow: []string{"true"}}}},
+	}}
+	if got := types(server.allowedRuleActions(run, m)); got != "run:" {
+		t.Fatalf("explicit run rule: got %q", got)
+	}
+	server.cfg.Policy = &config.Policy{Rules: []config.PolicyRule{
+		{Command: "gmail send", Args: map[string]config.ArgumentPolicy{"to": {Allow: []string{"*@ourco.com"}}}},
+	}}
+
+	// Every action type is checked against its equivalent command.
+	others := gmailRuleResult{Rule: "r", Matched: true, Actions: []gmailRuleAction{
+		{Type: "label", Labels: []string{"Secret"}},
+		{Type: "label", Labels: []string{"Ops"}},
+		{Type: "star"},
+		{Type: "archive"},
+		{Type: "markRead"},
+		{Type: "task", Subject: "Follow up", List: "@default"},
+	}}
+	warnings = nil
+	server.cfg.Policy = &config.Policy{Rules: []config.PolicyRule{
+		{Command: "tasks", Action: "deny"},
+		{Command: "gmail archive", Action: "deny"},
+		{Command: "gmail batch modify", Args: map[string]config.ArgumentPolicy{"add": {Deny: []string{"Secret"}}}},
+	}}
+	if got := types(server.allowedRuleActions(others, m)); got != "label: star: markRead:" {
+		t.Fatalf("per-action policy: got %q", got)
+	}
+	if len(warnings) != 3 {
+		t.Fatalf("expected label, archive and task denials, got %v", warnings)
+	}
+	server.cfg.Policy = &config.Policy{Rules: []config.PolicyRule{
+		{Command: "gmail send", Args: map[string]config.ArgumentPolicy{"to": {Allow: []string{"*@ourco.com"}}}},
+	}}
+
+	warnings = nil
+	server.cfg.RequireApproval = true
+	if got := types(server.allowedRuleActions(res, m)); got != "label:" {
+		t.Fatalf("require-approval: got %q", got)
+	}
+	if len(warnings) != 4 {
+		t.Fatalf("expected held actions to be reported, got %v", warnings)
+	}
+
+	logs = nil
+	server.cfg.DryRun = true
+	if got := server.allowedRuleActions(res, m); len(got) != 0 {
+		t.Fatalf("dry-run should run nothing, got %+v", got)
+	}
+	if len(logs) != 5 {
+		t.Fatalf("expected every planned action logged, got %v", logs)
+	}
+}
+
+func TestGmailWatchRulesRunOffThePushPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("run action uses sh")
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch path {
+		case "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{{"id": "INBOX", "name": "INBOX"}}})
+		case "/messages/m1":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1", "labelIds": []string{"INBOX"},
+				"payload": map[string]any{"headers": []map[string]any{{"name": "From", "value": "alerts@example.com"}}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	dir := t.TempDir()
+	release := filepath.Join(dir, "release")
+	done := filepath.Join(dir, "done")
+	rules, err := loadGmailRules(writeRulesFile(t, `
+rules:
+  - name: slow
+    match: {from: "^alerts@"}
+    actions:
+      run: 'while [ ! -e `+release+` ]; do sleep 0.01; done; touch `+done+`'
+`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "me@example.com"},
+		rules:      rules,
+		newService: func(context.Context, string) (*gmail.Service, error) { return svc, nil },
+		logf:       func(string, ...any) {},
+		warnf:      func(format string, args ...any) { t.Errorf(format, args...) },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.startRules(ctx)
+
+	// The command blocks until released, so this only returns if the rules
+	// run in the background.
+	server.queueRules(ctx, svc, []string{"m1"})
+	if err := os.WriteFile(release, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, statErr := os.Stat(done); statErr == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued rule never ran")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -25,15 +25,20 @@ type GmailSendCmd struct {
 	Body             string   `name:"body" help:"Body (plain text; required unless --body-html is set)"`
 	BodyFile         string   `name:"body-file" help:"Body file path (plain text; '-' for stdin)"`
 	BodyHTML         string   `name:"body-html" help:"Body (HTML; optional)"`
+	BodyMarkdown     string   `name:"body-markdown" aliases:"body-md" help:"Body (Markdown; sent as HTML plus a plain-text alternative)"`
+	BodyMarkdownFile string   `name:"body-md-file" aliases:"body-markdown-file" help:"Markdown body file path ('-' for stdin)"`
 	ReplyToMessageID string   `name:"reply-to-message-id" aliases:"in-reply-to" help:"Reply to Gmail message ID (sets In-Reply-To/References and thread)"`
 	ThreadID         string   `name:"thread-id" help:"Reply within a Gmail thread (uses latest message for headers)"`
 	ReplyAll         bool     `name:"reply-all" help:"Auto-populate recipients from original message (requires --reply-to-message-id or --thread-id)"`
 	ReplyTo          string   `name:"reply-to" help:"Reply-To header address"`
 	Attach           []string `name:"attach" help:"Attachment file path (repeatable)"`
+	Inline           []string `name:"inline" help:"Inline image as name=path, referenced from HTML as cid:name (repeatable)"`
+	EmbedImages      bool     `name:"embed-images" help:"Embed local <img src> image files as inline parts (relative paths resolve against the Markdown file)"`
 	From             string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
 	Track            bool     `name:"track" help:"Enable open tracking (requires tracking setup)"`
 	TrackSplit       bool     `name:"track-split" help:"Send tracked messages separately per recipient"`
 	Quote            bool     `name:"quote" help:"Include quoted original message in reply (requires --reply-to-message-id or --thread-id)"`
+	Raw              string   `name:"raw" help:"Send a pre-built RFC 822 message (.eml path; '-' for stdin) as-is"`
 }
 
 type sendBatch struct {
@@ -58,6 +63,7 @@ type sendMessageOptions struct {
 	BodyHTML    string
 	ReplyInfo   *replyInfo
 	Attachments []mailAttachment
+	Inline      []mailAttachment
 	Track       bool
 	TrackingCfg *tracking.Config
 }
@@ -65,10 +71,14 @@ type sendMessageOptions struct {
 func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 	u := ui.FromContext(ctx)
 
+	if strings.TrimSpace(c.Raw) != "" {
+		return c.runRaw(ctx, flags)
+	}
+
 	replyToMessageID := normalizeGmailMessageID(c.ReplyToMessageID)
 	threadID := normalizeGmailThreadID(c.ThreadID)
 
-	body, err := resolveBodyInput(c.Body, c.BodyFile)
+	body, bodyHTML, err := resolveComposeBodies(c.Body, c.BodyFile, c.BodyHTML, c.BodyMarkdown, c.BodyMarkdownFile)
 	if err != nil {
 		return err
 	}
@@ -94,14 +104,19 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 	if strings.TrimSpace(c.Subject) == "" {
 		return usage("required: --subject")
 	}
-	if strings.TrimSpace(body) == "" && strings.TrimSpace(c.BodyHTML) == "" {
-		return usage("required: --body, --body-file, or --body-html")
+	if strings.TrimSpace(body) == "" && strings.TrimSpace(bodyHTML) == "" {
+		return usage("required: --body, --body-file, --body-html, or --body-markdown")
 	}
 	if c.TrackSplit && !c.Track {
 		return usage("--track-split requires --track")
 	}
-	if c.Track && strings.TrimSpace(c.BodyHTML) == "" {
-		return fmt.Errorf("--track requires --body-html (pixel must be in HTML)")
+	if c.Track && strings.TrimSpace(bodyHTML) == "" {
+		return fmt.Errorf("--track requires --body-html or --body-markdown (pixel must be in HTML)")
+	}
+
+	bodyHTML, inline, err := embedInlineImages(bodyHTML, c.Inline, inlineBaseDir(c.BodyMarkdownFile), c.EmbedImages)
+	if err != nil {
+		return err
 	}
 
 	attachPaths := make([]string, 0, len(c.Attach))
@@ -124,8 +139,9 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 		"reply_to":            strings.TrimSpace(c.ReplyTo),
 		"from":                strings.TrimSpace(c.From),
 		"body_len":            len(strings.TrimSpace(body)),
-		"body_html_len":       len(strings.TrimSpace(c.BodyHTML)),
+		"body_html_len":       len(strings.TrimSpace(bodyHTML)),
 		"attachments":         attachPaths,
+		"inline":              inlinePaths(inline),
 		"track":               c.Track,
 		"track_split":         c.TrackSplit,
 	}); dryRunErr != nil {
@@ -142,48 +158,9 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return err
 	}
 
-	sendAsList, sendAsListErr := listSendAs(ctx, svc)
-
-	// Determine the From address
-	fromAddr := account
-	sendingEmail := account // The email we're sending from (without display name)
-	if fromEmail := strings.TrimSpace(c.From); fromEmail != "" {
-		// Validate that this is a configured and verified send-as alias.
-		var sa *gmail.SendAs
-		if sendAsListErr == nil {
-			sa = findSendAsByEmail(sendAsList, fromEmail)
-			if sa == nil {
-				return fmt.Errorf("invalid --from address %q: not found in send-as settings", fromEmail)
-			}
-		} else {
-			// Fallback: preserve legacy behavior if we cannot list settings.
-			var getErr error
-			sa, getErr = svc.Users.Settings.SendAs.Get("me", fromEmail).Context(ctx).Do()
-			if getErr != nil {
-				return fmt.Errorf("invalid --from address %q: %w", fromEmail, getErr)
-			}
-		}
-
-		if sa.VerificationStatus != gmailVerificationAccepted {
-			return fmt.Errorf("--from address %q is not verified (status: %s)", fromEmail, sa.VerificationStatus)
-		}
-
-		sendingEmail = fromEmail
-		fromAddr = fromEmail
-
-		if displayName := strings.TrimSpace(sa.DisplayName); displayName != "" {
-			fromAddr = displayName + " <" + fromEmail + ">"
-		}
-	} else {
-		// No --from specified: best-effort look up the primary account's display name.
-		displayName := ""
-		if sendAsListErr == nil {
-			displayName = primaryDisplayNameFromSendAsList(sendAsList, account)
-		}
-		if displayName != "" {
-			fromAddr = displayName + " <" + account + ">"
-		}
-		// If lookup fails, we just use the plain email address (no error)
+	fromAddr, sendingEmail, err := resolveSendFrom(ctx, svc, account, c.From)
+	if err != nil {
+		return err
 	}
 
 	// Fetch reply info (includes recipient headers for reply-all, and body for quoting)
@@ -192,7 +169,7 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return err
 	}
 
-	body, htmlBody := applyQuoteToBodies(body, c.BodyHTML, c.Quote, replyInfo)
+	body, htmlBody := applyQuoteToBodies(body, bodyHTML, c.Quote, replyInfo)
 
 	// Determine recipients
 	var toRecipients, ccRecipients []string
@@ -238,6 +215,7 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 		BodyHTML:    htmlBody,
 		ReplyInfo:   replyInfo,
 		Attachments: atts,
+		Inline:      inline,
 		Track:       c.Track,
 		TrackingCfg: trackingCfg,
 	}, batches)
@@ -248,6 +226,70 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 	return writeSendResults(ctx, u, fromAddr, results)
 }
 
+// runRaw uploads a .eml file unchanged; only --thread-id may accompany it.
+func (c *GmailSendCmd) runRaw(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	if err := rejectWithRaw(map[string]bool{
+		"to":                  strings.TrimSpace(c.To) != "",
+		"cc":                  strings.TrimSpace(c.Cc) != "",
+		"bcc":                 strings.TrimSpace(c.Bcc) != "",
+		"subject":             strings.TrimSpace(c.Subject) != "",
+		"body":                strings.TrimSpace(c.Body) != "",
+		"body-file":           strings.TrimSpace(c.BodyFile) != "",
+		"body-html":           strings.TrimSpace(c.BodyHTML) != "",
+		"body-markdown":       strings.TrimSpace(c.BodyMarkdown) != "",
+		"body-md-file":        strings.TrimSpace(c.BodyMarkdownFile) != "",
+		"reply-to-message-id": strings.TrimSpace(c.ReplyToMessageID) != "",
+		"reply-all":           c.ReplyAll,
+		"reply-to":            strings.TrimSpace(c.ReplyTo) != "",
+		"attach":              len(c.Attach) > 0,
+		"inline":              len(c.Inline) > 0,
+		"from":                strings.TrimSpace(c.From) != "",
+		"track":               c.Track,
+		"track-split":         c.TrackSplit,
+		"quote":               c.Quote,
+	}); err != nil {
+		return err
+	}
+
+	raw, err := rawMessageFor(ctx, c.Raw, true)
+	if err != nil {
+		return err
+	}
+	threadID := normalizeGmailThreadID(c.ThreadID)
+
+	if dryRunErr := dryRunExit(ctx, flags, "gmail.send", map[string]any{
+		"raw":       raw.Path,
+		"size":      len(raw.Data),
+		"to":        raw.To,
+		"cc":        raw.Cc,
+		"bcc":       raw.Bcc,
+		"subject":   raw.Subject,
+		"thread_id": threadID,
+	}); dryRunErr != nil {
+		return dryRunErr
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	msg := &gmail.Message{Raw: base64.RawURLEncoding.EncodeToString(raw.Data), ThreadId: threadID}
+	sent, err := svc.Users.Messages.Send("me", msg).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return writeSendResults(ctx, u, firstNonEmpty(raw.From, account), []sendResult{{MessageID: sent.Id, ThreadID: sent.ThreadId}})
+}
+
 func (c *GmailSendCmd) resolveTrackingConfig(account string, toRecipients, ccRecipients, bccRecipients []string, htmlBody string) (*tracking.Config, error) {
 	totalRecipients := len(toRecipients) + len(ccRecipients) + len(bccRecipients)
 	if totalRecipients != 1 && !c.TrackSplit {
@@ -269,6 +311,56 @@ func (c *GmailSendCmd) resolveTrackingConfig(account string, toRecipients, ccRec
 	return trackingCfg, nil
 }
 
+// resolveSendFrom returns the From header (with display name when known) and the
+// bare sending address. A non-empty from must be a verified send-as alias.
+func resolveSendFrom(ctx context.Context, svc *gmail.Service, account, from string) (string, string, error) {
+	sendAsList, sendAsListErr := listSendAs(ctx, svc)
+
+	// Determine the From address
+	fromAddr := account
+	sendingEmail := account // The email we're sending from (without display name)
+	if fromEmail := strings.TrimSpace(from); fromEmail != "" {
+		// Validate that this is a configured and verified send-as alias.
+		var sa *gmail.SendAs
+		if sendAsListErr == nil {
+			sa = findSendAsByEmail(sendAsList, fromEmail)
+			if sa == nil {
+				return "", "", fmt.Errorf("invalid --from address %q: not found in send-as settings", fromEmail)
+			}
+		} else {
+			// Fallback: preserve legacy behavior if we cannot list settings.
+			var getErr error
+			sa, getErr = svc.Users.Settings.SendAs.Get("me", fromEmail).Context(ctx).Do()
+			if getErr != nil {
+				return "", "", fmt.Errorf("invalid --from address %q: %w", fromEmail, getErr)
+			}
+		}
+
+		if sa.VerificationStatus != gmailVerificationAccepted {
+			return "", "", fmt.Errorf("--from address %q is not verified (status: %s)", fromEmail, sa.VerificationStatus)
+		}
+
+		sendingEmail = fromEmail
+		fromAddr = fromEmail
+
+		if displayName := strings.TrimSpace(sa.DisplayName); displayName != "" {
+			fromAddr = displayName + " <" + fromEmail + ">"
+		}
+	} else {
+		// No --from specified: best-effort look up the primary account's display name.
+		displayName := ""
+		if sendAsListErr == nil {
+			displayName = primaryDisplayNameFromSendAsList(sendAsList, account)
+		}
+		if displayName != "" {
+			fromAddr = displayName + " <" + account + ">"
+		}
+		// If lookup fails, we just use the plain email address (no error)
+	}
+
+	return fromAddr, sendingEmail, nil
+}
+
 func listSendAs(ctx context.Context, svc *gmail.Service) ([]*gmail.SendAs, error) {
 	if svc == nil {
 		return nil, nil
@@ -384,6 +476,7 @@ func sendGmailBatches(ctx context.Context, svc *gmail.Service, opts sendMessageO
 			InReplyTo:   reply.InReplyTo,
 			References:  reply.References,
 			Attachments: opts.Attachments,
+			Inline:      opts.Inline,
 		}, nil)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,569 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/timeparse"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	statsFormatTable = "table"
+	statsFormatJSON  = "json"
+	statsFormatCSV   = "csv"
+)
+
+// GmailStatsCmd reports mailbox analytics for the messages matching a query.
+type GmailStatsCmd struct {
+	Query       string `name:"query" short:"q" help:"Gmail search query to analyze (default: all mail)"`
+	Since       string `name:"since" help:"Only messages newer than this: 90d, 12w, 6m, 1y, a duration like 72h, or a date; 'all' for no limit" default:"30d"`
+	Max         int64  `name:"max" aliases:"limit" help:"Max messages to analyze" default:"5000"`
+	All         bool   `name:"all" help:"Analyze every matching message (ignores --max)"`
+	Top         int    `name:"top" help:"Rows per ranking (senders, domains, labels, response times)" default:"10"`
+	Format      string `name:"format" help:"Output format: table|json|csv (default: table, or json with --json)"`
+	Concurrency int    `name:"concurrency" help:"Parallel thread fetches" default:"8"`
+	Timezone    string `name:"timezone" short:"z" help:"Timezone for day/week buckets (IANA name). Default: local"`
+	Local       bool   `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+}
+
+type statsCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type statsVolume struct {
+	Period   string `json:"period"`
+	Received int    `json:"received"`
+	Sent     int    `json:"sent"`
+}
+
+type statsThreadLengths struct {
+	Average float64      `json:"average"`
+	Median  float64      `json:"median"`
+	Max     int          `json:"max"`
+	Buckets []statsCount `json:"buckets"`
+}
+
+type statsAttachments struct {
+	Messages int   `json:"messages"`
+	Files    int   `json:"files"`
+	Bytes    int64 `json:"bytes"`
+}
+
+type statsResponseTime struct {
+	Correspondent string  `json:"correspondent"`
+	Replies       int     `json:"replies"`
+	MedianSeconds float64 `json:"medianSeconds"`
+	Median        string  `json:"median"`
+}
+
+type gmailStats struct {
+	Query         string              `json:"query"`
+	Messages      int                 `json:"messages"`
+	Received      int                 `json:"received"`
+	Sent          int                 `json:"sent"`
+	Threads       int                 `json:"threads"`
+	Senders       []statsCount        `json:"senders"`
+	Domains       []statsCount        `json:"domains"`
+	Labels        []statsCount        `json:"labels"`
+	Daily         []statsVolume       `json:"daily"`
+	Weekly        []statsVolume       `json:"weekly"`
+	ThreadLengths statsThreadLengths  `json:"threadLengths"`
+	Attachments   statsAttachments    `json:"attachments"`
+	ResponseTimes []statsResponseTime `json:"responseTimes"`
+}
+
+type messageRef struct {
+	ID       string
+	ThreadID string
+}
+
+func (c *GmailStatsCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	format := strings.ToLower(strings.TrimSpace(c.Format))
+	if format == "" {
+		format = statsFormatTable
+		if outfmt.IsJSON(ctx) {
+			format = statsFormatJSON
+		}
+	}
+	switch format {
+	case statsFormatTable, statsFormatJSON, statsFormatCSV:
+	default:
+		return usagef("invalid --format %q (use table|json|csv)", c.Format)
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be at least 1")
+	}
+	loc, err := resolveOutputLocation(c.Timezone, c.Local)
+	if err != nil {
+		return err
+	}
+	sinceTerm, err := statsSinceQuery(c.Since, time.Now())
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(strings.Join([]string{strings.TrimSpace(c.Query), sinceTerm}, " "))
+	limit := c.Max
+	if c.All || limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	refs, err := searchMessageRefs(ctx, svc, query, limit)
+	if err != nil {
+		return err
+	}
+	threads, err := fetchStatsThreads(ctx, svc, refs, c.Concurrency)
+	if err != nil {
+		return err
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+
+	stats := computeGmailStats(refs, threads, idToName, loc, c.Top)
+	stats.Query = query
+
+	switch format {
+	case statsFormatJSON:
+		return outfmt.WriteJSON(ctx, os.Stdout, stats)
+	case statsFormatCSV:
+		return writeGmailStatsCSV(stats)
+	}
+	printGmailStats(ctx, u, stats)
+	return nil
+}
+
+var statsRelativeSinceRe = regexp.MustCompile(`^(\d+)\s*([dwmy])$`)
+
+// statsSinceQuery turns --since into a Gmail search term. Day, month and year
+// counts map to newer_than:, everything else to after:<unix seconds>.
+func statsSinceQuery(raw string, now time.Time) (string, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" || raw == "all" {
+		return "", nil
+	}
+	if m := statsRelativeSinceRe.FindStringSubmatch(raw); m != nil {
+		if m[2] == "w" {
+			n, _ := strconv.Atoi(m[1])
+			return fmt.Sprintf("newer_than:%dd", n*7), nil
+		}
+		return "newer_than:" + m[1] + m[2], nil
+	}
+	since, err := timeparse.ParseSince(raw, now, time.Local)
+	if err != nil {
+		return "", usagef("invalid --since %q (use 90d, 12w, 6m, 1y, 72h, YYYY-MM-DD or all)", raw)
+	}
+	return "after:" + strconv.FormatInt(since.Time.Unix(), 10), nil
+}
+
+// searchMessageRefs is searchMessageIDs, keeping thread IDs.
+func searchMessageRefs(ctx context.Context, svc *gmail.Service, query string, limit int64) ([]messageRef, error) {
+	var refs []messageRef
+	pageToken := ""
+	remaining := limit
+	for {
+		call := svc.Users.Messages.List("me").
+			Q(query).
+			MaxResults(min(remaining, 500)).
+			Fields("messages(id,threadId),nextPageToken").
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Messages {
+			if m != nil && m.Id != "" {
+				refs = append(refs, messageRef{ID: m.Id, ThreadID: m.ThreadId})
+			}
+		}
+		remaining -= int64(len(resp.Messages))
+		if resp.NextPageToken == "" || remaining <= 0 {
+			return refs, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// statsThreadFields keeps headers and part sizes but drops message bodies, so
+// full-format fetches cost little more than metadata ones.
+const statsThreadFields = "id,messages(id,threadId,labelIds,internalDate,payload(headers,filename,body/size," +
+	"parts(filename,body/size,parts(filename,body/size,parts(filename,body/size)))))"
+
+func fetchStatsThreads(ctx context.Context, svc *gmail.Service, refs []messageRef, concurrency int) (map[string]*gmail.Thread, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, concurrency)
+		threads  = map[string]*gmail.Thread{}
+	)
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if ref.ThreadID == "" || seen[ref.ThreadID] {
+			continue
+		}
+		seen[ref.ThreadID] = true
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			thread, err := svc.Users.Threads.Get("me", id).Format("full").Fields(statsThreadFields).Context(ctx).Do()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("thread %s: %w", id, err)
+					cancel()
+				}
+				return
+			}
+			threads[id] = thread
+		}(ref.ThreadID)
+	}
+	wg.Wait()
+	return threads, firstErr
+}
+
+func computeGmailStats(refs []messageRef, threads map[string]*gmail.Thread, idToName map[string]string, loc *time.Location, top int) gmailStats {
+	matched := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		matched[ref.ID] = true
+	}
+
+	var (
+		stats     gmailStats
+		senders   = map[string]int{}
+		domains   = map[string]int{}
+		labels    = map[string]int{}
+		daily     = map[string]*statsVolume{}
+		weekly    = map[string]*statsVolume{}
+		lengths   []int
+		responses = map[string][]time.Duration{}
+	)
+	for _, thread := range threads {
+		msgs := append([]*gmail.Message(nil), thread.Messages...)
+		sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].InternalDate < msgs[j].InternalDate })
+		lengths = append(lengths, len(msgs))
+
+		// The first unanswered incoming message waits for my next reply.
+		var waiting *gmail.Message
+		for _, msg := range msgs {
+			mine := statsIsSent(msg)
+			if mine && waiting != nil {
+				who := unsubscribeSender(headerValue(waiting.Payload, "From"))
+				responses[strings.ToLower(who)] = append(responses[strings.ToLower(who)],
+					time.Duration(msg.InternalDate-waiting.InternalDate)*time.Millisecond)
+				waiting = nil
+			} else if !mine && waiting == nil && matched[msg.Id] {
+				waiting = msg
+			}
+
+			if !matched[msg.Id] {
+				continue
+			}
+			stats.Messages++
+			when := time.UnixMilli(msg.InternalDate).In(loc)
+			year, week := when.ISOWeek()
+			day := statsBucket(daily, when.Format("2006-01-02"))
+			wk := statsBucket(weekly, fmt.Sprintf("%d-W%02d", year, week))
+			if mine {
+				stats.Sent++
+				day.Sent++
+				wk.Sent++
+			} else {
+				stats.Received++
+				day.Received++
+				wk.Received++
+				from := strings.ToLower(unsubscribeSender(headerValue(msg.Payload, "From")))
+				senders[from]++
+				if at := strings.LastIndex(from, "@"); at >= 0 {
+					domains[from[at+1:]]++
+				}
+			}
+			for _, id := range msg.LabelIds {
+				labels[firstNonEmpty(idToName[id], id)]++
+			}
+			if files, size := statsAttachmentSize(msg.Payload); files > 0 {
+				stats.Attachments.Messages++
+				stats.Attachments.Files += files
+				stats.Attachments.Bytes += size
+			}
+		}
+	}
+
+	stats.Threads = len(threads)
+	stats.Senders = topStatsCounts(senders, top)
+	stats.Domains = topStatsCounts(domains, top)
+	stats.Labels = topStatsCounts(labels, top)
+	stats.Daily = sortedStatsVolume(daily)
+	stats.Weekly = sortedStatsVolume(weekly)
+	stats.ThreadLengths = summarizeThreadLengths(lengths)
+	stats.ResponseTimes = summarizeResponseTimes(responses, top)
+	return stats
+}
+
+func statsIsSent(msg *gmail.Message) bool {
+	for _, id := range msg.LabelIds {
+		if id == "SENT" {
+			return true
+		}
+	}
+	return false
+}
+
+func statsBucket(m map[string]*statsVolume, period string) *statsVolume {
+	v := m[period]
+	if v == nil {
+		v = &statsVolume{Period: period}
+		m[period] = v
+	}
+	return v
+}
+
+func statsAttachmentSize(p *gmail.MessagePart) (int, int64) {
+	if p == nil {
+		return 0, 0
+	}
+	files, size := 0, int64(0)
+	if p.Filename != "" && p.Body != nil {
+		files, size = 1, p.Body.Size
+	}
+	for _, child := range p.Parts {
+		n, s := statsAttachmentSize(child)
+		files += n
+		size += s
+	}
+	return files, size
+}
+
+func topStatsCounts(m map[string]int, top int) []statsCount {
+	out := make([]statsCount, 0, len(m))
+	for name, count := range m {
+		out = append(out, statsCount{Name: name, Count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	if top > 0 && len(out) > top {
+		out = out[:top]
+	}
+	return out
+}
+
+func sortedStatsVolume(m map[string]*statsVolume) []statsVolume {
+	out := make([]statsVolume, 0, len(m))
+	for _, v := range m {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Period < out[j].Period })
+	return out
+}
+
+var threadLengthBuckets = []struct {
+	name     string
+	min, max int
+}{
+	{"1", 1, 1},
+	{"2", 2, 2},
+	{"3-5", 3, 5},
+	{"6-10", 6, 10},
+	{"11+", 11, math.MaxInt},
+}
+
+func summarizeThreadLengths(lengths []int) statsThreadLengths {
+	out := statsThreadLengths{Buckets: make([]statsCount, len(threadLengthBuckets))}
+	for i, b := range threadLengthBuckets {
+		out.Buckets[i].Name = b.name
+	}
+	if len(lengths) == 0 {
+		return out
+	}
+	sort.Ints(lengths)
+	total := 0
+	for _, n := range lengths {
+		total += n
+		for i, b := range threadLengthBuckets {
+			if n >= b.min && n <= b.max {
+				out.Buckets[i].Count++
+			}
+		}
+	}
+	out.Average = math.Round(float64(total)/float64(len(lengths))*10) / 10
+	out.Max = lengths[len(lengths)-1]
+	mid := len(lengths) / 2
+	out.Median = float64(lengths[mid])
+	if len(lengths)%2 == 0 {
+		out.Median = float64(lengths[mid-1]+lengths[mid]) / 2
+	}
+	return out
+}
+
+func summarizeResponseTimes(responses map[string][]time.Duration, top int) []statsResponseTime {
+	out := make([]statsResponseTime, 0, len(responses))
+	for who, times := range responses {
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		median := times[len(times)/2]
+		if len(times)%2 == 0 {
+			median = (times[len(times)/2-1] + times[len(times)/2]) / 2
+		}
+		out = append(out, statsResponseTime{
+			Correspondent: who,
+			Replies:       len(times),
+			MedianSeconds: math.Round(median.Seconds()),
+			Median:        formatResponseDuration(median),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Replies != out[j].Replies {
+			return out[i].Replies > out[j].Replies
+		}
+		return out[i].Correspondent < out[j].Correspondent
+	})
+	if top > 0 && len(out) > top {
+		out = out[:top]
+	}
+	return out
+}
+
+func formatResponseDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+func printGmailStats(ctx context.Context, u *ui.UI, s gmailStats) {
+	u.Out().Printf("Messages: %d (%d received, %d sent) in %d threads", s.Messages, s.Received, s.Sent, s.Threads)
+	u.Out().Printf("Attachments: %d files in %d messages, %s", s.Attachments.Files, s.Attachments.Messages, formatBytes(s.Attachments.Bytes))
+	u.Out().Printf("Thread length: avg %.1f, median %g, max %d", s.ThreadLengths.Average, s.ThreadLengths.Median, s.ThreadLengths.Max)
+
+	section := func(header string, rows [][]string) {
+		if len(rows) == 0 {
+			return
+		}
+		u.Out().Println("")
+		w, flush := tableWriter(ctx)
+		fmt.Fprintln(w, header)
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		flush()
+	}
+	counts := func(items []statsCount) [][]string {
+		rows := make([][]string, 0, len(items))
+		for _, it := range items {
+			rows = append(rows, []string{sanitizeTab(it.Name), strconv.Itoa(it.Count)})
+		}
+		return rows
+	}
+	volume := func(items []statsVolume) [][]string {
+		rows := make([][]string, 0, len(items))
+		for _, it := range items {
+			rows = append(rows, []string{it.Period, strconv.Itoa(it.Received), strconv.Itoa(it.Sent)})
+		}
+		return rows
+	}
+
+	section("SENDER\tMESSAGES", counts(s.Senders))
+	section("DOMAIN\tMESSAGES", counts(s.Domains))
+	section("LABEL\tMESSAGES", counts(s.Labels))
+	section("THREAD LENGTH\tTHREADS", counts(s.ThreadLengths.Buckets))
+	section("WEEK\tRECEIVED\tSENT", volume(s.Weekly))
+	section("DAY\tRECEIVED\tSENT", volume(s.Daily))
+	var replies [][]string
+	for _, r := range s.ResponseTimes {
+		replies = append(replies, []string{sanitizeTab(r.Correspondent), strconv.Itoa(r.Replies), r.Median})
+	}
+	section("CORRESPONDENT\tREPLIES\tMEDIAN RESPONSE", replies)
+}
+
+// writeGmailStatsCSV writes every report as rows of one sheet, keyed by a
+// section column so it can be pivoted in a spreadsheet.
+func writeGmailStatsCSV(s gmailStats) error {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"section", "key", "count", "received", "sent", "median_seconds"})
+	row := func(section, key string, count int, extra ...string) {
+		r := append([]string{section, key, strconv.Itoa(count)}, extra...)
+		for len(r) < 6 {
+			r = append(r, "")
+		}
+		_ = w.Write(r)
+	}
+	row("summary", "messages", s.Messages, strconv.Itoa(s.Received), strconv.Itoa(s.Sent))
+	row("summary", "threads", s.Threads)
+	row("summary", "attachment_files", s.Attachments.Files)
+	row("summary", "attachment_bytes", int(s.Attachments.Bytes))
+	for _, it := range s.Senders {
+		row("sender", it.Name, it.Count)
+	}
+	for _, it := range s.Domains {
+		row("domain", it.Name, it.Count)
+	}
+	for _, it := range s.Labels {
+		row("label", it.Name, it.Count)
+	}
+	for _, it := range s.ThreadLengths.Buckets {
+		row("thread_length", it.Name, it.Count)
+	}
+	for _, it := range s.Daily {
+		row("day", it.Period, it.Received+it.Sent, strconv.Itoa(it.Received), strconv.Itoa(it.Sent))
+	}
+	for _, it := range s.Weekly {
+		row("week", it.Period, it.Received+it.Sent, strconv.Itoa(it.Received), strconv.Itoa(it.Sent))
+	}
+	for _, it := range s.ResponseTimes {
+		row("response_time", it.Correspondent, it.Replies, "", "", strconv.FormatFloat(it.MedianSeconds, 'f', 0, 64))
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestStatsSinceQuery(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"90d": "newer_than:90d",
+		"2w":  "newer_than:14d",
+		"6m":  "newer_than:6m",
+		"all": "",
+		"72h": "after:1772064000",
+	}
+	for in, want := range cases {
+		got, err := statsSinceQuery(in, now)
+		if err != nil || got != want {
+			t.Fatalf("statsSinceQuery(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := statsSinceQuery("soon", now); err == nil {
+		t.Fatalf("expected error for invalid --since")
+	}
+}
+
+func TestGmailStats(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC).UnixMilli() // Monday
+	msg := func(id, thread, from string, offset time.Duration, labels ...string) map[string]any {
+		return map[string]any{
+			"id": id, "threadId": thread, "labelIds": labels,
+			"internalDate": strconv.FormatInt(base+offset.Milliseconds(), 10),
+			"payload":      map[string]any{"headers": []map[string]any{{"name": "From", "value": from}}},
+		}
+	}
+	threads := map[string][]map[string]any{
+		"t1": {
+			msg("a1", "t1", "Ann <ann@acme.com>", 0, "INBOX", "Label_1"),
+			msg("a2", "t1", "me@example.com", 2*time.Hour, "SENT"),
+			msg("a3", "t1", "ann@acme.com", 3*time.Hour, "INBOX"),
+			msg("a4", "t1", "me@example.com", 7*time.Hour, "SENT"),
+		},
+		"t2": {
+			msg("b1", "t2", "Bob <bob@acme.com>", 24*time.Hour, "INBOX"),
+		},
+		"t3": {
+			func() map[string]any {
+				m := msg("c1", "t3", "news@shop.example", 8*24*time.Hour, "CATEGORY_PROMOTIONS")
+				m["payload"].(map[string]any)["parts"] = []map[string]any{
+					{"filename": "", "body": map[string]any{"size": 100}},
+					{"filename": "flyer.pdf", "body": map[string]any{"size": 2048}},
+				}
+				return m
+			}(),
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case path == "/messages":
+			if q := r.URL.Query().Get("q"); q != "-in:chats newer_than:90d" {
+				t.Errorf("unexpected query %q", q)
+			}
+			var refs []map[string]any
+			for _, id := range []string{"t1", "t2", "t3"} {
+				for _, m := range threads[id] {
+					refs = append(refs, map[string]any{"id": m["id"], "threadId": id})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": refs})
+		case strings.HasPrefix(path, "/threads/"):
+			id := strings.TrimPrefix(path, "/threads/")
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "messages": threads[id]})
+		case path == "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{
+				{"id": "INBOX", "name": "INBOX"}, {"id": "Label_1", "name": "Clients"}, {"id": "SENT", "name": "SENT"},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	args := []string{"--account", "me@example.com", "gmail", "stats", "--query=-in:chats", "--since", "90d", "--timezone", "UTC"}
+	out := captureStdout(t, func() {
+		if execErr := Execute(append([]string{"--json"}, args...)); execErr != nil {
+			t.Fatalf("stats: %v", execErr)
+		}
+	})
+	var stats gmailStats
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	if stats.Messages != 6 || stats.Received != 4 || stats.Sent != 2 || stats.Threads != 3 {
+		t.Fatalf("unexpected totals: %+v", stats)
+	}
+	if len(stats.Senders) == 0 || stats.Senders[0] != (statsCount{Name: "ann@acme.com", Count: 2}) {
+		t.Fatalf("unexpected senders: %+v", stats.Senders)
+	}
+	if len(stats.Domains) == 0 || stats.Domains[0] != (statsCount{Name: "acme.com", Count: 3}) {
+		t.Fatalf("unexpected domains: %+v", stats.Domains)
+	}
+	if stats.Labels[0] != (statsCount{Name: "INBOX", Count: 3}) {
+		t.Fatalf("unexpected labels: %+v", stats.Labels)
+	}
+	if len(stats.Weekly) != 2 || stats.Weekly[0] != (statsVolume{Period: "2026-W02", Received: 3, Sent: 2}) {
+		t.Fatalf("unexpected weekly volume: %+v", stats.Weekly)
+	}
+	if stats.Attachments != (statsAttachments{Messages: 1, Files: 1, Bytes: 2048}) {
+		t.Fatalf("unexpected attachments: %+v", stats.Attachments)
+	}
+	if stats.ThreadLengths.Max != 4 || stats.ThreadLengths.Median != 1 {
+		t.Fatalf("unexpected thread lengths: %+v", stats.ThreadLengths)
+	}
+	// Replies after 2h and 4h: median 3h.
+	if len(stats.ResponseTimes) != 1 || stats.ResponseTimes[0].Correspondent != "ann@acme.com" ||
+		stats.ResponseTimes[0].Replies != 2 || stats.ResponseTimes[0].Median != "3h00m" {
+		t.Fatalf("unexpected response times: %+v", stats.ResponseTimes)
+	}
+
+	out = captureStdout(t, func() {
+		if execErr := Execute(append(args, "--format", "csv")); execErr != nil {
+			t.Fatalf("stats csv: %v", execErr)
+		}
+	})
+	if !strings.HasPrefix(out, "section,key,count,received,sent,median_seconds\n") ||
+		!strings.Contains(out, "response_time,ann@acme.com,2,,,10800\n") {
+		t.Fatalf("unexpected csv:\n%s", out)
+	}
+
+	out = captureStdout(t, func() {
+		if execErr := Execute(args); execErr != nil {
+			t.Fatalf("stats table: %v", execErr)
+		}
+	})
+	if !strings.HasPrefix(out, "Messages: 6 (4 received, 2 sent) in 3 threads\n") || !strings.Contains(out, "ann@acme.com   2        3h00m") {
+		t.Fatalf("unexpected table:\n%s", out)
+	}
+}
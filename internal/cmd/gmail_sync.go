@@ -0,0 +1,608 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/maildir"
+	"github.com/steipete/gogcli/internal/mailindex"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	gmailSyncModeFull        = "full"
+	gmailSyncModeIncremental = "incremental"
+
+	// gmailSyncSaveEvery bounds how much work an interrupted full pull loses.
+	gmailSyncSaveEvery = 200
+)
+
+// GmailSyncCmd mirrors a mailbox into a local Maildir, one way.
+type GmailSyncCmd struct {
+	Maildir          string `name:"maildir" required:"" help:"Local Maildir root to mirror into (created if missing)"`
+	Full             bool   `name:"full" help:"Reconcile against the full message list instead of replaying history"`
+	Query            string `name:"query" short:"q" help:"Only pull messages matching this Gmail query on the initial full sync"`
+	Max              int64  `name:"max" aliases:"limit" help:"Max messages to pull on the initial full sync (0 = all)"`
+	IncludeSpamTrash bool   `name:"include-spam-trash" help:"Mirror Spam and Trash too (default: messages moved there are removed locally)"`
+	Concurrency      int    `name:"concurrency" help:"Parallel message downloads" default:"4"`
+	NoIndex          bool   `name:"no-index" help:"Skip updating the local-search index (it catches up on the next local-search)"`
+}
+
+type gmailSyncer struct {
+	cmd   *GmailSyncCmd
+	svc   *gmail.Service
+	store *gmailSyncStore
+	root  string
+
+	mu      sync.Mutex
+	added   int
+	updated int
+	deleted int
+	done    int
+}
+
+func (c *GmailSyncCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	root, err := config.ExpandPath(strings.TrimSpace(c.Maildir))
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		return usage("required: --maildir")
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return err
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be at least 1")
+	}
+	if c.Max < 0 {
+		return usage("--max must be >= 0")
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	store, err := loadGmailSyncStore(account, root)
+	if err != nil {
+		return err
+	}
+	if err := maildir.Create(root); err != nil {
+		return err
+	}
+
+	labelNames, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+	if err := store.Update(func(st *gmailSyncState) { st.LabelNames = labelNames }); err != nil {
+		return err
+	}
+
+	s := &gmailSyncer{cmd: c, svc: svc, store: store, root: root}
+	mode := gmailSyncModeIncremental
+	if c.Full || store.state.HistoryID == "" {
+		mode = gmailSyncModeFull
+	}
+
+	if mode == gmailSyncModeIncremental {
+		err = s.incremental(ctx, u)
+		if err != nil && isStaleHistoryError(err) {
+			u.Err().Println("History ID expired; running a full reconcile")
+			mode = gmailSyncModeFull
+			err = nil
+		}
+		if err != nil {
+			_ = store.Save()
+			return err
+		}
+	}
+	if mode == gmailSyncModeFull {
+		if err := s.full(ctx, u); err != nil {
+			_ = store.Save()
+			return err
+		}
+	}
+
+	if err := store.Update(func(st *gmailSyncState) { st.UpdatedAtMs = time.Now().UnixMilli() }); err != nil {
+		return err
+	}
+	if !c.NoIndex {
+		if err := s.index(); err != nil {
+			return err
+		}
+	}
+
+	st := store.state
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"maildir":   root,
+			"mode":      mode,
+			"historyId": st.HistoryID,
+			"added":     s.added,
+			"updated":   s.updated,
+			"deleted":   s.deleted,
+			"messages":  len(st.Messages),
+			"state":     store.path,
+		})
+	}
+
+	u.Out().Printf("maildir\t%s", root)
+	u.Out().Printf("mode\t%s", mode)
+	u.Out().Printf("history_id\t%s", st.HistoryID)
+	u.Out().Printf("added\t%d", s.added)
+	u.Out().Printf("updated\t%d", s.updated)
+	u.Out().Printf("deleted\t%d", s.deleted)
+	u.Out().Printf("messages\t%d", len(st.Messages))
+	return nil
+}
+
+// full pulls every message not yet mirrored. The history ID is captured
+// before listing so changes made during a long pull are replayed next time.
+func (s *gmailSyncer) full(ctx context.Context, u *ui.UI) error {
+	profile, err := s.svc.Users.GetProfile("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if err := s.store.Update(func(st *gmailSyncState) {
+		// Keep an older pending ID from an interrupted pull: replaying extra
+		// history is harmless, skipping some is not.
+		if st.PendingHistoryID == "" {
+			st.PendingHistoryID = formatHistoryID(profile.HistoryId)
+		}
+	}); err != nil {
+		return err
+	}
+
+	remote, err := s.listMessageIDs(ctx)
+	if err != nil {
+		return err
+	}
+	remoteSet := make(map[string]struct{}, len(remote))
+	var fetch, refresh []string
+	for _, id := range remote {
+		remoteSet[id] = struct{}{}
+		if _, ok := s.store.Get(id); ok {
+			refresh = append(refresh, id)
+		} else {
+			fetch = append(fetch, id)
+		}
+	}
+
+	// Only a complete listing can prove a local message is gone.
+	if strings.TrimSpace(s.cmd.Query) == "" && s.cmd.Max == 0 {
+		for _, id := range s.store.IDs() {
+			if _, ok := remoteSet[id]; !ok {
+				if err := s.remove(id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	total := len(fetch) + len(refresh)
+	if err := s.parallel(ctx, u, fetch, total, s.fetch); err != nil {
+		return err
+	}
+	if err := s.parallel(ctx, u, refresh, total, func(ctx context.Context, id string) error {
+		return s.relabel(ctx, id, nil)
+	}); err != nil {
+		return err
+	}
+
+	return s.store.Update(func(st *gmailSyncState) {
+		if shouldUpdate, _ := shouldUpdateHistoryID(st.HistoryID, st.PendingHistoryID); shouldUpdate || st.HistoryID == "" {
+			st.HistoryID = st.PendingHistoryID
+		}
+		st.PendingHistoryID = ""
+		st.LastFullSyncMs = time.Now().UnixMilli()
+	})
+}
+
+// gmailSyncChange is the net effect of history records on one message.
+type gmailSyncChange struct {
+	deleted bool
+	labels  []string // latest label set seen in history; empty when none was sent
+}
+
+func (s *gmailSyncer) incremental(ctx context.Context, u *ui.UI) error {
+	startID, err := parseHistoryID(s.store.state.HistoryID)
+	if err != nil {
+		return err
+	}
+
+	changes := map[string]*gmailSyncChange{}
+	var order []string
+	note := func(msg *gmail.Message) *gmailSyncChange {
+		if msg == nil || strings.TrimSpace(msg.Id) == "" {
+			return nil
+		}
+		ch, ok := changes[msg.Id]
+		if !ok {
+			ch = &gmailSyncChange{}
+			changes[msg.Id] = ch
+			order = append(order, msg.Id)
+		}
+		return ch
+	}
+	// Label records carry the label set after the change. An omitted labelIds
+	// and an empty set look the same on the wire, so an empty one is resolved
+	// with a minimal get in relabel rather than trusted.
+	noteLabels := func(msg *gmail.Message) {
+		if ch := note(msg); ch != nil {
+			ch.labels = msg.LabelIds
+		}
+	}
+
+	latest := uint64(0)
+	pageToken := ""
+	for {
+		call := s.svc.Users.History.List("me").StartHistoryId(startID).MaxResults(500).HistoryTypes(gmailHistoryTypes...)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		latest = resp.HistoryId
+		for _, h := range resp.History {
+			if h == nil {
+				continue
+			}
+			for _, a := range h.MessagesAdded {
+				if a != nil {
+					noteLabels(a.Message)
+				}
+			}
+			for _, a := range h.LabelsAdded {
+				if a != nil {
+					noteLabels(a.Message)
+				}
+			}
+			for _, r := range h.LabelsRemoved {
+				if r != nil {
+					noteLabels(r.Message)
+				}
+			}
+			for _, d := range h.MessagesDeleted {
+				if d == nil {
+					continue
+				}
+				if ch := note(d.Message); ch != nil {
+					ch.deleted = true
+				}
+			}
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	if err := s.parallel(ctx, u, order, len(order), func(ctx context.Context, id string) error {
+		ch := changes[id]
+		if ch.deleted {
+			return s.remove(id)
+		}
+		if _, ok := s.store.Get(id); ok {
+			labels := ch.labels
+			if len(labels) == 0 {
+				labels = nil
+			}
+			return s.relabel(ctx, id, labels)
+		}
+		return s.fetch(ctx, id)
+	}); err != nil {
+		return err
+	}
+
+	return s.store.Update(func(st *gmailSyncState) {
+		if latest > 0 {
+			st.HistoryID = formatHistoryID(latest)
+		}
+	})
+}
+
+// index applies this run's changes to the local-search index.
+func (s *gmailSyncer) index() error {
+	idx, err := mailindex.Open(gmailSyncIndexPath(s.store.path))
+	if err != nil {
+		return err
+	}
+	if _, err := updateGmailSyncIndex(idx, &s.store.state, s.root); err != nil {
+		return err
+	}
+	return idx.Save()
+}
+
+func (s *gmailSyncer) listMessageIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	pageToken := ""
+	for {
+		batch := int64(500)
+		if s.cmd.Max > 0 && s.cmd.Max-int64(len(ids)) < batch {
+			batch = s.cmd.Max - int64(len(ids))
+		}
+		call := s.svc.Users.Messages.List("me").
+			MaxResults(batch).
+			IncludeSpamTrash(s.cmd.IncludeSpamTrash).
+			Fields("messages(id),nextPageToken").
+			Context(ctx)
+		if q := strings.TrimSpace(s.cmd.Query); q != "" {
+			call = call.Q(q)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Messages {
+			if m != nil && m.Id != "" {
+				ids = append(ids, m.Id)
+			}
+		}
+		if resp.NextPageToken == "" || (s.cmd.Max > 0 && int64(len(ids)) >= s.cmd.Max) {
+			return ids, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// fetch downloads a message and files it by its labels.
+func (s *gmailSyncer) fetch(ctx context.Context, id string) error {
+	msg, err := s.svc.Users.Messages.Get("me", id).Format(gmailFormatRaw).Context(ctx).Do()
+	if err != nil {
+		if isNotFoundAPIError(err) {
+			return s.remove(id)
+		}
+		return fmt.Errorf("get message %s: %w", id, err)
+	}
+	if s.excluded(msg.LabelIds) {
+		return s.remove(id)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(msg.Raw, "="))
+	if err != nil {
+		return fmt.Errorf("decode message %s: %w", id, err)
+	}
+
+	folder, flags := gmailSyncPlacement(msg.LabelIds)
+	path, err := maildir.Deliver(maildir.FolderPath(s.root, folder), gmailSyncBaseName(msg.InternalDate, id), flags, raw)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return err
+	}
+	if prev, ok := s.store.Get(id); ok && prev.Path != rel {
+		_ = os.Remove(filepath.Join(s.root, prev.Path))
+	}
+
+	s.store.Put(id, gmailSyncMessage{Path: rel, ThreadID: msg.ThreadId, Labels: msg.LabelIds, InternalDate: msg.InternalDate})
+	s.count(&s.added)
+	return nil
+}
+
+// relabel moves or renames a mirrored message after a label change. labels
+// may be nil, in which case the current labels are fetched.
+func (s *gmailSyncer) relabel(ctx context.Context, id string, labels []string) error {
+	local, ok := s.store.Get(id)
+	if !ok {
+		return s.fetch(ctx, id)
+	}
+	if labels == nil {
+		msg, err := s.svc.Users.Messages.Get("me", id).Format("minimal").Context(ctx).Do()
+		if err != nil {
+			if isNotFoundAPIError(err) {
+				return s.remove(id)
+			}
+			return fmt.Errorf("get message %s: %w", id, err)
+		}
+		labels = msg.LabelIds
+	}
+	if s.excluded(labels) {
+		return s.remove(id)
+	}
+
+	folder, flags := gmailSyncPlacement(labels)
+	dir := maildir.FolderPath(s.root, folder)
+	target := filepath.Join(dir, "cur", maildir.FileName(gmailSyncBaseName(local.InternalDate, id), flags))
+	rel, err := filepath.Rel(s.root, target)
+	if err != nil {
+		return err
+	}
+
+	if rel != local.Path {
+		current := filepath.Join(s.root, local.Path)
+		if _, statErr := os.Stat(current); statErr != nil {
+			// The file was moved by a mail client or removed; pull it again.
+			s.store.Delete(id)
+			return s.fetch(ctx, id)
+		}
+		if err := maildir.Create(dir); err != nil {
+			return err
+		}
+		if err := os.Rename(current, target); err != nil {
+			return err
+		}
+	} else if sameLabels(local.Labels, labels) {
+		return nil
+	}
+
+	local.Path = rel
+	local.Labels = labels
+	s.store.Put(id, local)
+	s.count(&s.updated)
+	return nil
+}
+
+func (s *gmailSyncer) remove(id string) error {
+	local, ok := s.store.Get(id)
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(s.root, local.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.store.Delete(id)
+	s.count(&s.deleted)
+	return nil
+}
+
+func (s *gmailSyncer) excluded(labels []string) bool {
+	if s.cmd.IncludeSpamTrash {
+		return false
+	}
+	for _, l := range labels {
+		if l == "SPAM" || l == "TRASH" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *gmailSyncer) count(n *int) {
+	s.mu.Lock()
+	*n++
+	s.mu.Unlock()
+}
+
+// parallel runs fn over ids with bounded concurrency, stopping at the first
+// error. State is saved periodically so an interrupted run keeps its progress.
+func (s *gmailSyncer) parallel(ctx context.Context, u *ui.UI, ids []string, total int, fn func(context.Context, string) error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, s.cmd.Concurrency)
+	)
+	for _, id := range ids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, id); err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				errMu.Unlock()
+				return
+			}
+
+			s.mu.Lock()
+			s.done++
+			done := s.done
+			s.mu.Unlock()
+			if done%gmailSyncSaveEvery == 0 {
+				_ = s.store.Save()
+				if !outfmt.IsJSON(ctx) {
+					u.Err().Printf("synced %d/%d", done, total)
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// gmailSyncPlacement maps Gmail labels to a Maildir folder and info flags.
+// Each message is stored once; the most specific system label wins.
+func gmailSyncPlacement(labels []string) (string, string) {
+	has := make(map[string]bool, len(labels))
+	for _, l := range labels {
+		has[l] = true
+	}
+
+	folder := "Archive"
+	switch {
+	case has["TRASH"]:
+		folder = "Trash"
+	case has["SPAM"]:
+		folder = "Spam"
+	case has["DRAFT"]:
+		folder = "Drafts"
+	case has["INBOX"]:
+		folder = maildir.Inbox
+	case has["SENT"]:
+		folder = "Sent"
+	}
+
+	var flags strings.Builder
+	if has["DRAFT"] {
+		flags.WriteByte('D')
+	}
+	if has["STARRED"] {
+		flags.WriteByte('F')
+	}
+	if !has["UNREAD"] {
+		flags.WriteByte('S')
+	}
+	if has["TRASH"] {
+		flags.WriteByte('T')
+	}
+	return folder, flags.String()
+}
+
+func gmailSyncBaseName(internalDateMs int64, id string) string {
+	return fmt.Sprintf("%d.%s.gog", internalDateMs/1000, id)
+}
+
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
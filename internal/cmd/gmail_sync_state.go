@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+const gmailSyncStateVersion = 1
+
+// gmailSyncMessage is the local record of one mirrored message.
+type gmailSyncMessage struct {
+	Path         string   `json:"path"` // relative to the maildir root
+	ThreadID     string   `json:"threadId,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	InternalDate int64    `json:"internalDate,omitempty"`
+}
+
+type gmailSyncState struct {
+	Version   int    `json:"version"`
+	Account   string `json:"account"`
+	Maildir   string `json:"maildir"`
+	HistoryID string `json:"historyId,omitempty"`
+	// PendingHistoryID is recorded when a full pull starts; it becomes
+	// HistoryID once every message has been fetched.
+	PendingHistoryID string                      `json:"pendingHistoryId,omitempty"`
+	LabelNames       map[string]string           `json:"labelNames,omitempty"`
+	Messages         map[string]gmailSyncMessage `json:"messages"`
+	UpdatedAtMs      int64                       `json:"updatedAtMs,omitempty"`
+	LastFullSyncMs   int64                       `json:"lastFullSyncMs,omitempty"`
+}
+
+type gmailSyncStore struct {
+	path  string
+	mu    sync.Mutex
+	state gmailSyncState
+}
+
+// gmailSyncStatePath keys the state by account and maildir so one account
+// can be mirrored to several places.
+func gmailSyncStatePath(account, root string) (string, error) {
+	dir, err := config.GmailSyncDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, sanitizeAccountForPath(account)+"-"+hex.EncodeToString(sum[:6])+".json"), nil
+}
+
+// loadGmailSyncStore returns the saved state, or a fresh one for a first sync.
+func loadGmailSyncStore(account, root string) (*gmailSyncStore, error) {
+	path, err := gmailSyncStatePath(account, root)
+	if err != nil {
+		return nil, err
+	}
+	store := &gmailSyncStore{path: path}
+
+	data, err := os.ReadFile(path) //nolint:gosec // state path under the gog config dir
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		store.state = gmailSyncState{Version: gmailSyncStateVersion, Account: account, Maildir: root}
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &store.state); err != nil {
+			return nil, fmt.Errorf("parse sync state %s: %w", path, err)
+		}
+	}
+	if store.state.Messages == nil {
+		store.state.Messages = map[string]gmailSyncMessage{}
+	}
+	return store, nil
+}
+
+func (s *gmailSyncStore) Get(id string) (gmailSyncMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.state.Messages[id]
+	return m, ok
+}
+
+func (s *gmailSyncStore) Put(id string, m gmailSyncMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Messages[id] = m
+}
+
+func (s *gmailSyncStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state.Messages, id)
+}
+
+// IDs returns a snapshot of the mirrored message IDs.
+func (s *gmailSyncStore) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.state.Messages))
+	for id := range s.state.Messages {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (s *gmailSyncStore) Update(fn func(*gmailSyncState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.state)
+	return s.saveLocked()
+}
+
+func (s *gmailSyncStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+// saveLocked writes the state atomically; the message index can be large and
+// a torn write would force a full re-pull.
+func (s *gmailSyncStore) saveLocked() error {
+	if strings.TrimSpace(s.path) == "" {
+		return errors.New("missing sync state path")
+	}
+	payload, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("ensure gmail sync dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(payload, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
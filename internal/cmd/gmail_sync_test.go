@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/maildir"
+)
+
+func TestGmailSync_FullThenIncremental(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var (
+		mu     sync.Mutex
+		labels = map[string][]string{
+			"m1": {"INBOX", "UNREAD"},
+			"m2": {"STARRED"},
+		}
+		history   []map[string]any
+		historyID = "100"
+		stale     bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case path == "/profile":
+			_ = json.NewEncoder(w).Encode(map[string]any{"emailAddress": "me@example.com", "historyId": historyID})
+		case path == "/labels":
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{{"id": "INBOX", "name": "INBOX"}, {"id": "Label_1", "name": "Work"}}})
+		case path == "/messages":
+			var msgs []map[string]any
+			for _, id := range []string{"m1", "m2", "m3"} {
+				if _, ok := labels[id]; ok {
+					msgs = append(msgs, map[string]any{"id": id})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": msgs})
+		case strings.HasPrefix(path, "/messages/"):
+			id := strings.TrimPrefix(path, "/messages/")
+			l, ok := labels[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "Not Found"}})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": id, "threadId": "t-" + id, "labelIds": l, "internalDate": "1700000000000",
+				"raw": base64.RawURLEncoding.EncodeToString([]byte("Subject: " + id + "\r\n\r\nbody " + id + "\r\n")),
+			})
+		case path == "/history":
+			if stale {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "Requested entity was not found."}})
+				return
+			}
+			if r.URL.Query().Get("startHistoryId") != "100" {
+				t.Errorf("unexpected startHistoryId %q", r.URL.Query().Get("startHistoryId"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"history": history, "historyId": historyID})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	root := filepath.Join(t.TempDir(), "Mail")
+	run := func() map[string]any {
+		t.Helper()
+		out := captureStdout(t, func() {
+			if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "sync", "--maildir", root, "--concurrency", "2"}); execErr != nil {
+				t.Fatalf("sync: %v", execErr)
+			}
+		})
+		var doc map[string]any
+		if uErr := json.Unmarshal([]byte(out), &doc); uErr != nil {
+			t.Fatalf("unmarshal: %v (out=%q)", uErr, out)
+		}
+		return doc
+	}
+	exists := func(rel string) bool {
+		_, statErr := os.Stat(filepath.Join(root, rel))
+		return statErr == nil
+	}
+
+	first := run()
+	if first["mode"] != gmailSyncModeFull || first["added"] != float64(2) || first["historyId"] != "100" {
+		t.Fatalf("unexpected first sync: %v", first)
+	}
+	if !exists("cur/"+maildir.FileName("1700000000.m1.gog", "")) || !exists(".Archive/cur/"+maildir.FileName("1700000000.m2.gog", "FS")) {
+		t.Fatalf("unexpected layout after full sync")
+	}
+	statePath, err := gmailSyncStatePath("me@example.com", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, statErr := os.Stat(gmailSyncIndexPath(statePath)); statErr != nil {
+		t.Fatalf("expected local-search index after sync: %v", statErr)
+	}
+
+	mu.Lock()
+	labels["m1"] = []string{"STARRED"} // history 104 omits labelIds, so sync must ask for the real set
+	delete(labels, "m2")
+	labels["m3"] = []string{"SENT"}
+	history = []map[string]any{
+		{"id": "101", "labelsRemoved": []map[string]any{{"message": map[string]any{"id": "m1", "labelIds": []string{"INBOX"}}, "labelIds": []string{"UNREAD"}}}},
+		{"id": "102", "messagesDeleted": []map[string]any{{"message": map[string]any{"id": "m2"}}}},
+		{"id": "103", "messagesAdded": []map[string]any{{"message": map[string]any{"id": "m3", "labelIds": []string{"SENT"}}}}},
+		{"id": "104", "labelsRemoved": []map[string]any{{"message": map[string]any{"id": "m1"}, "labelIds": []string{"INBOX"}}}},
+	}
+	historyID = "110"
+	mu.Unlock()
+
+	second := run()
+	if second["mode"] != gmailSyncModeIncremental || second["added"] != float64(1) || second["updated"] != float64(1) || second["deleted"] != float64(1) || second["historyId"] != "110" {
+		t.Fatalf("unexpected incremental sync: %v", second)
+	}
+	if !exists(".Archive/cur/"+maildir.FileName("1700000000.m1.gog", "FS")) || exists(".Archive/cur/"+maildir.FileName("1700000000.m2.gog", "FS")) || !exists(".Sent/cur/"+maildir.FileName("1700000000.m3.gog", "S")) {
+		t.Fatalf("unexpected layout after incremental sync")
+	}
+
+	mu.Lock()
+	stale = true
+	labels["m1"] = []string{"TRASH"}
+	historyID = "200"
+	mu.Unlock()
+
+	third := run()
+	if third["mode"] != gmailSyncModeFull || third["deleted"] != float64(1) || third["messages"] != float64(1) || third["historyId"] != "200" {
+		t.Fatalf("unexpected reconcile after stale history: %v", third)
+	}
+	if exists(".Archive/cur/" + maildir.FileName("1700000000.m1.gog", "FS")) {
+		t.Fatalf("trashed message should be removed locally")
+	}
+}
+
+func TestGmailSyncPlacement(t *testing.T) {
+	cases := []struct {
+		labels []string
+		folder string
+		flags  string
+	}{
+		{[]string{"INBOX", "UNREAD"}, "INBOX", ""},
+		{[]string{"INBOX", "STARRED", "Label_1"}, "INBOX", "FS"},
+		{[]string{"SENT"}, "Sent", "S"},
+		{[]string{"DRAFT"}, "Drafts", "DS"},
+		{[]string{"TRASH", "INBOX"}, "Trash", "ST"},
+		{[]string{"Label_1"}, "Archive", "S"},
+	}
+	for _, tc := range cases {
+		folder, flags := gmailSyncPlacement(tc.labels)
+		if folder != tc.folder || flags != tc.flags {
+			t.Fatalf("%v: got %s %q, want %s %q", tc.labels, folder, flags, tc.folder, tc.flags)
+		}
+	}
+}
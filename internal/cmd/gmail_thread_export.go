@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	transcriptFormatMarkdown = "md"
+	transcriptFormatJSON     = "json"
+	transcriptFormatHTML     = "html"
+)
+
+// GmailThreadExportCmd renders a thread as a compact transcript.
+type GmailThreadExportCmd struct {
+	ThreadID   string `arg:"" name:"threadId" help:"Thread ID"`
+	Format     string `name:"format" help:"Transcript format: md|json|html (default: md, or json with --json)"`
+	Out        string `name:"out" aliases:"output" help:"Write the transcript to a file instead of stdout"`
+	KeepQuotes bool   `name:"keep-quotes" help:"Keep quoted replies and signatures in message bodies"`
+	Timezone   string `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
+	Local      bool   `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+}
+
+type threadTranscript struct {
+	ThreadID     string              `json:"threadId"`
+	Subject      string              `json:"subject"`
+	Participants []string            `json:"participants"`
+	MessageCount int                 `json:"messageCount"`
+	Messages     []transcriptMessage `json:"messages"`
+}
+
+type transcriptMessage struct {
+	ID           string                 `json:"id"`
+	From         string                 `json:"from"`
+	To           string                 `json:"to,omitempty"`
+	Cc           string                 `json:"cc,omitempty"`
+	Subject      string                 `json:"subject,omitempty"` // only when it differs from the thread subject
+	Date         string                 `json:"date"`
+	Timestamp    string                 `json:"timestamp,omitempty"`
+	Body         string                 `json:"body"`
+	Attachments  []transcriptAttachment `json:"attachments,omitempty"`
+	InlineImages []string               `json:"inlineImages,omitempty"`
+}
+
+type transcriptAttachment struct {
+	Filename     string `json:"filename"`
+	Size         int64  `json:"size"`
+	SizeHuman    string `json:"sizeHuman"`
+	MimeType     string `json:"mimeType,omitempty"`
+	AttachmentID string `json:"attachmentId,omitempty"`
+}
+
+func (c *GmailThreadExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	format := strings.ToLower(strings.TrimSpace(c.Format))
+	switch format {
+	case "":
+		format = transcriptFormatMarkdown
+		if outfmt.IsJSON(ctx) {
+			format = transcriptFormatJSON
+		}
+	case transcriptFormatMarkdown, "markdown":
+		format = transcriptFormatMarkdown
+	case transcriptFormatJSON, transcriptFormatHTML:
+	default:
+		return usagef("invalid --format %q (expected md, json or html)", c.Format)
+	}
+	loc, err := resolveOutputLocation(c.Timezone, c.Local)
+	if err != nil {
+		return err
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	threadID := normalizeGmailThreadID(strings.TrimSpace(c.ThreadID))
+	if threadID == "" {
+		return usage("empty threadId")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	thread, err := svc.Users.Threads.Get("me", threadID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	transcript := buildThreadTranscript(thread, loc, !c.KeepQuotes)
+
+	out := strings.TrimSpace(c.Out)
+	if out == "" && format == transcriptFormatJSON {
+		return outfmt.WriteJSON(ctx, os.Stdout, transcript)
+	}
+
+	var rendered []byte
+	switch format {
+	case transcriptFormatJSON:
+		rendered, err = json.MarshalIndent(transcript, "", "  ")
+		rendered = append(rendered, '\n')
+	case transcriptFormatHTML:
+		rendered, err = renderTranscriptHTML(transcript)
+	default:
+		rendered = []byte(renderTranscriptMarkdown(transcript))
+	}
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+				"threadId":     transcript.ThreadID,
+				"format":       format,
+				"messageCount": transcript.MessageCount,
+				"transcript":   string(rendered),
+			})
+		}
+		_, err = os.Stdout.Write(rendered)
+		return err
+	}
+
+	path, err := config.ExpandPath(out)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, rendered, 0o600); err != nil { //nolint:gosec // user-provided path
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"threadId":     transcript.ThreadID,
+			"format":       format,
+			"messageCount": transcript.MessageCount,
+			"path":         path,
+			"bytes":        len(rendered),
+		})
+	}
+	u.Out().Printf("thread_id\t%s", transcript.ThreadID)
+	u.Out().Printf("format\t%s", format)
+	u.Out().Printf("messages\t%d", transcript.MessageCount)
+	u.Out().Printf("path\t%s", path)
+	return nil
+}
+
+// buildThreadTranscript orders the messages by date and reduces each to what
+// its sender wrote.
+func buildThreadTranscript(thread *gmail.Thread, loc *time.Location, dequote bool) threadTranscript {
+	t := threadTranscript{Participants: []string{}, Messages: []transcriptMessage{}}
+	if thread == nil {
+		return t
+	}
+	t.ThreadID = thread.Id
+
+	msgs := make([]*gmail.Message, 0, len(thread.Messages))
+	for _, m := range thread.Messages {
+		if m != nil {
+			msgs = append(msgs, m)
+		}
+	}
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].InternalDate < msgs[j].InternalDate })
+
+	seen := map[string]bool{}
+	for _, m := range msgs {
+		subject := strings.TrimSpace(headerValue(m.Payload, "Subject"))
+		if t.Subject == "" {
+			t.Subject = subject
+		}
+		from := strings.TrimSpace(headerValue(m.Payload, "From"))
+		if key := strings.ToLower(from); from != "" && !seen[key] {
+			seen[key] = true
+			t.Participants = append(t.Participants, from)
+		}
+
+		attachments, inline := transcriptParts(m.Payload)
+		cidNames := map[string]string{}
+		item := transcriptMessage{
+			ID:   m.Id,
+			From: from,
+			To:   strings.TrimSpace(headerValue(m.Payload, "To")),
+			Cc:   strings.TrimSpace(headerValue(m.Payload, "Cc")),
+		}
+		if normalizeReplySubject(subject) != normalizeReplySubject(t.Subject) {
+			item.Subject = subject
+		}
+		if m.InternalDate > 0 {
+			when := time.UnixMilli(m.InternalDate).In(loc)
+			item.Date = when.Format("2006-01-02 15:04")
+			item.Timestamp = when.Format(time.RFC3339)
+		} else {
+			item.Date = formatGmailDateInLocation(headerValue(m.Payload, "Date"), loc)
+		}
+		for _, img := range inline {
+			cidNames[img.cid] = img.name
+			item.InlineImages = append(item.InlineImages, img.name)
+		}
+		for _, a := range attachments {
+			item.Attachments = append(item.Attachments, transcriptAttachment{
+				Filename:     a.Filename,
+				Size:         a.Size,
+				SizeHuman:    formatBytes(a.Size),
+				MimeType:     a.MimeType,
+				AttachmentID: a.AttachmentID,
+			})
+		}
+
+		body, isHTML := bestBodyForDisplay(m.Payload)
+		switch {
+		case isHTML:
+			item.Body = htmlToTranscriptText(body, dequote, cidNames)
+		case dequote:
+			item.Body = dequoteText(body)
+		default:
+			item.Body = tidyTranscriptText(body)
+		}
+		t.Messages = append(t.Messages, item)
+	}
+	t.MessageCount = len(t.Messages)
+	return t
+}
+
+type transcriptInlineImage struct {
+	cid  string
+	name string
+}
+
+// transcriptParts splits a message's file parts into attachments and inline
+// images (image parts with a Content-ID that are not marked as attachments).
+func transcriptParts(p *gmail.MessagePart) ([]attachmentInfo, []transcriptInlineImage) {
+	if p == nil {
+		return nil, nil
+	}
+	var (
+		attachments []attachmentInfo
+		inline      []transcriptInlineImage
+	)
+	isFile := p.Filename != "" || (p.Body != nil && p.Body.AttachmentId != "")
+	if isFile && len(p.Parts) == 0 {
+		cid := strings.Trim(strings.TrimSpace(headerValue(p, "Content-ID")), "<>")
+		disposition := strings.ToLower(strings.TrimSpace(headerValue(p, "Content-Disposition")))
+		name := strings.TrimSpace(p.Filename)
+		if cid != "" && strings.HasPrefix(normalizeMimeType(p.MimeType), "image/") && !strings.HasPrefix(disposition, "attachment") {
+			if name == "" {
+				name = cid
+			}
+			inline = append(inline, transcriptInlineImage{cid: cid, name: name})
+		} else {
+			if name == "" {
+				name = "attachment"
+			}
+			a := attachmentInfo{Filename: name, MimeType: p.MimeType}
+			if p.Body != nil {
+				a.Size = p.Body.Size
+				a.AttachmentID = p.Body.AttachmentId
+			}
+			attachments = append(attachments, a)
+		}
+	}
+	for _, part := range p.Parts {
+		a, i := transcriptParts(part)
+		attachments = append(attachments, a...)
+		inline = append(inline, i...)
+	}
+	return attachments, inline
+}
+
+// normalizeReplySubject drops reply/forward prefixes so "Re: X" counts as X.
+func normalizeReplySubject(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		lower := strings.ToLower(s)
+		trimmed := false
+		for _, prefix := range []string{"re:", "fwd:", "fw:", "aw:", "wg:", "tr:"} {
+			if strings.HasPrefix(lower, prefix) {
+				s = strings.TrimSpace(s[len(prefix):])
+				trimmed = true
+				break
+			}
+		}
+		if !trimmed {
+			return strings.ToLower(s)
+		}
+	}
+}
+
+func renderTranscriptMarkdown(t threadTranscript) string {
+	var b strings.Builder
+	subject := t.Subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", subject)
+	fmt.Fprintf(&b, "- Thread: `%s`\n", t.ThreadID)
+	fmt.Fprintf(&b, "- Messages: %d\n", t.MessageCount)
+	if len(t.Participants) > 0 {
+		fmt.Fprintf(&b, "- Participants: %s\n", strings.Join(t.Participants, "; "))
+	}
+
+	for i, m := range t.Messages {
+		fmt.Fprintf(&b, "\n## %d. %s — %s\n\n", i+1, firstNonEmpty(m.From, "(unknown sender)"), m.Date)
+		if m.To != "" {
+			fmt.Fprintf(&b, "To: %s  \n", m.To)
+		}
+		if m.Cc != "" {
+			fmt.Fprintf(&b, "Cc: %s  \n", m.Cc)
+		}
+		if m.Subject != "" {
+			fmt.Fprintf(&b, "Subject: %s  \n", m.Subject)
+		}
+		b.WriteString("\n")
+		if m.Body != "" {
+			b.WriteString(m.Body)
+			b.WriteString("\n")
+		}
+		if len(m.Attachments) > 0 {
+			b.WriteString("\nAttachments:\n")
+			for _, a := range m.Attachments {
+				fmt.Fprintf(&b, "- %s (%s, %s)\n", a.Filename, a.SizeHuman, firstNonEmpty(a.MimeType, "unknown type"))
+			}
+		}
+	}
+	return b.String()
+}
+
+var transcriptHTMLTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Subject}}{{.Subject}}{{else}}(no subject){{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+article { border-top: 1px solid #ddd; padding: 1rem 0; }
+header { color: #555; font-size: 0.9rem; }
+pre { white-space: pre-wrap; font-family: inherit; }
+</style>
+</head>
+<body>
+<h1>{{if .Subject}}{{.Subject}}{{else}}(no subject){{end}}</h1>
+<p>{{.MessageCount}} messages{{if .Participants}} · {{range $i, $p := .Participants}}{{if $i}}; {{end}}{{$p}}{{end}}{{end}}</p>
+{{range .Messages}}<article id="{{.ID}}">
+<header><strong>{{.From}}</strong> · <time{{if .Timestamp}} datetime="{{.Timestamp}}"{{end}}>{{.Date}}</time>{{if .To}}<br>To: {{.To}}{{end}}{{if .Cc}}<br>Cc: {{.Cc}}{{end}}{{if .Subject}}<br>Subject: {{.Subject}}{{end}}</header>
+<pre>{{.Body}}</pre>
+{{if .Attachments}}<ul>{{range .Attachments}}<li>{{.Filename}} ({{.SizeHuman}})</li>{{end}}</ul>
+{{end}}</article>
+{{end}}</body>
+</html>
+`))
+
+func renderTranscriptHTML(t threadTranscript) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := transcriptHTMLTemplate.Execute(&buf, t); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
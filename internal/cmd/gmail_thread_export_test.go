@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestDequoteText(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{
+			name: "gmail reply with wrapped attribution",
+			in:   "Sounds good, ship it.\r\n\r\nOn Mon, Jan 1, 2024 at 10:00 AM Ann Lee <\r\nann@example.com> wrote:\r\n\r\n> Can we ship?\r\n> -- \r\n> Ann\r\n",
+			want: "Sounds good, ship it.",
+		},
+		{
+			name: "interleaved answers and signature",
+			in:   "On Tue, Bob wrote:\n> First?\nYes.\n> Second?\nNo.\n\n-- \nBob\nACME Corp\n",
+			want: "Yes.\nNo.",
+		},
+		{
+			name: "outlook original message",
+			in:   "Thanks!\n\nSent from my iPhone\n\n-----Original Message-----\nFrom: Ann\nSubject: Hi\n\nHello\n",
+			want: "Thanks!",
+		},
+		{
+			name: "prose starting with On is kept",
+			in:   "On Monday the team wrote:\nthe plan below.\n\n    indented code\n",
+			want: "On Monday the team wrote:\nthe plan below.\n\n    indented code",
+		},
+		{
+			name: "all quote falls back to the original",
+			in:   "> only quoted\n",
+			want: "> only quoted",
+		},
+	}
+	for _, tc := range cases {
+		if got := dequoteText(tc.in); got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHTMLToTranscriptText(t *testing.T) {
+	src := `<html><head><title>ignored</title></head><body><div dir="ltr">Hi team,<br>See the chart:<br><img src="cid:ii_abc" alt="chart"><ul><li>one</li><li>two</li></ul>` +
+		`<p>Tom &amp; Jerry</p></div><br><div class="gmail_quote"><div class="gmail_attr">On Mon, Ann wrote:</div><blockquote>old text</blockquote></div></body></html>`
+
+	got := htmlToTranscriptText(src, true, map[string]string{"ii_abc": "chart.png"})
+	want := "Hi team,\nSee the chart:\n[image: chart.png]\n- one\n- two\nTom & Jerry"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if kept := htmlToTranscriptText(src, false, nil); !strings.Contains(kept, "old text") || !strings.Contains(kept, "[image: ii_abc]") {
+		t.Fatalf("expected quote and raw cid placeholder kept: %q", kept)
+	}
+
+	// A blockquote the sender wrote stays; Apple Mail's cite quote goes.
+	apple := `<div>As the docs say:<blockquote>Measure twice.</blockquote>Agreed?</div><blockquote type="cite">On Mon, Ann wrote:<br>old</blockquote>`
+	if got, want := htmlToTranscriptText(apple, true, nil), "As the docs say:\nMeasure twice.\nAgreed?"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestGmailThreadExport(t *testing.T) {
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	threadResp := map[string]any{
+		"id": "t1",
+		"messages": []map[string]any{
+			{
+				"id":           "m2",
+				"internalDate": "1704103200000", // 2024-01-01 10:00 UTC
+				"payload": map[string]any{
+					"mimeType": "multipart/related",
+					"headers": []map[string]any{
+						{"name": "From", "value": "Bob <bob@example.com>"},
+						{"name": "To", "value": "ann@example.com"},
+						{"name": "Subject", "value": "Re: Launch"},
+					},
+					"parts": []map[string]any{
+						{"mimeType": "text/html", "body": map[string]any{"data": enc(`<div>Looks great <img src="cid:logo@x"></div><div class="gmail_quote">On Mon, Ann wrote:<blockquote>Launch?</blockquote></div>`)}},
+						{
+							"mimeType": "image/png", "filename": "logo.png",
+							"headers": []map[string]any{{"name": "Content-ID", "value": "<logo@x>"}, {"name": "Content-Disposition", "value": "inline"}},
+							"body":    map[string]any{"attachmentId": "img1", "size": 100},
+						},
+						{
+							"mimeType": "application/pdf", "filename": "plan.pdf",
+							"headers": []map[string]any{{"name": "Content-Disposition", "value": "attachment; filename=plan.pdf"}},
+							"body":    map[string]any{"attachmentId": "att1", "size": 2048},
+						},
+					},
+				},
+			},
+			{
+				"id":           "m1",
+				"internalDate": "1704099600000", // 2024-01-01 09:00 UTC
+				"payload": map[string]any{
+					"mimeType": "text/plain",
+					"headers": []map[string]any{
+						{"name": "From", "value": "Ann <ann@example.com>"},
+						{"name": "To", "value": "bob@example.com"},
+						{"name": "Subject", "value": "Launch"},
+					},
+					"body": map[string]any{"data": enc("Ready to launch?\n\n-- \nAnn\n")},
+				},
+			},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/users/me/threads/t1") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(threadResp)
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "thread", "export", "t1", "--timezone", "UTC"}); execErr != nil {
+			t.Fatalf("export: %v", execErr)
+		}
+	})
+	var transcript threadTranscript
+	if err := json.Unmarshal([]byte(out), &transcript); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	if transcript.Subject != "Launch" || transcript.MessageCount != 2 || len(transcript.Participants) != 2 {
+		t.Fatalf("unexpected transcript: %+v", transcript)
+	}
+	first, second := transcript.Messages[0], transcript.Messages[1]
+	if first.ID != "m1" || first.Body != "Ready to launch?" || first.Date != "2024-01-01 09:00" || first.Subject != "" {
+		t.Fatalf("unexpected first message: %+v", first)
+	}
+	if second.Body != "Looks great [image: logo.png]" || len(second.Attachments) != 1 || second.Attachments[0].Filename != "plan.pdf" ||
+		len(second.InlineImages) != 1 || second.InlineImages[0] != "logo.png" {
+		t.Fatalf("unexpected second message: %+v", second)
+	}
+
+	path := filepath.Join(t.TempDir(), "launch.md")
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "thread", "export", "t1", "--format", "md", "--out", path, "--timezone", "UTC"}); execErr != nil {
+			t.Fatalf("export md: %v", execErr)
+		}
+	})
+	md, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read md: %v", err)
+	}
+	for _, want := range []string{"# Launch\n", "## 1. Ann <ann@example.com> — 2024-01-01 09:00", "## 2. Bob <bob@example.com> — 2024-01-01 10:00", "- plan.pdf (2.0 KB, application/pdf)"} {
+		if !strings.Contains(string(md), want) {
+			t.Fatalf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(string(md), "Launch?") {
+		t.Fatalf("quoted text leaked into markdown:\n%s", md)
+	}
+
+	out = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "thread", "export", "t1", "--format", "html"}); execErr != nil {
+			t.Fatalf("export html: %v", execErr)
+		}
+	})
+	if !strings.Contains(out, "<title>Launch</title>") || !strings.Contains(out, "Bob &lt;bob@example.com&gt;") {
+		t.Fatalf("unexpected html:\n%s", out)
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Patterns for trimming replies down to what the sender actually wrote.
+var (
+	// "On Mon, Jan 1, 2024 at 10:00 AM Ann <ann@example.com> wrote:", which
+	// Gmail wraps onto two lines for long names; a few common locales too.
+	quoteAttributionRe = regexp.MustCompile(`(?m)^[ \t]*(On|Am|Le|El|Op|Il)\b[^\n]*(\n[^\n]*)?\b(wrote|schrieb|a écrit|escribió|schreef|ha scritto)\s*:[ \t]*$`)
+	// Outlook and older clients put the original below a separator line.
+	quoteSeparatorRe  = regexp.MustCompile(`(?m)^[ \t]*(-{2,}\s*Original Message\s*-{2,}|_{10,})[ \t]*$`)
+	mobileSignatureRe = regexp.MustCompile(`(?mi)^[ \t]*(Sent from my \w+|Get Outlook for \w+)[^\n]*$`)
+	blankLinesRe      = regexp.MustCompile(`\n{3,}`)
+	lineSpaceRe       = regexp.MustCompile(`[ \t\x{00a0}]+`)
+
+	// HTML quote containers used by Gmail, Yahoo, Apple Mail and Outlook. A
+	// plain <blockquote> is often part of what the sender wrote, so only
+	// these reply containers start the quote.
+	htmlHeadRe       = regexp.MustCompile(`(?is)<head\b.*?</head>`)
+	htmlQuoteStartRe = regexp.MustCompile(`(?i)<(div|blockquote)\b[^>]*class="[^"]*(gmail_quote|yahoo_quoted)[^"]*"|<blockquote\b[^>]*type="cite"|<div\b[^>]*id="(appendonsend|divRplyFwdMsg)"`)
+	htmlBreakRe      = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|h[1-6]|table|ul|ol|blockquote)>|<(hr|blockquote)\b[^>]*>`)
+	htmlListItemRe   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlCidImageRe   = regexp.MustCompile(`(?i)<img\b[^>]*\bsrc\s*=\s*["']cid:([^"']+)["'][^>]*>`)
+)
+
+// dequoteText strips quoted replies, reply attributions and signatures from a
+// plain-text body. If nothing is left the body was all quote (for example a
+// bare forward) and it is returned unchanged.
+func dequoteText(body string) string {
+	text := strings.ReplaceAll(body, "\r\n", "\n")
+	if loc := quoteSeparatorRe.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+
+	// Only drop attributions that introduce a quote, not prose that happens
+	// to start with "On".
+	matches := quoteAttributionRe.FindAllStringIndex(text, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		start, end := matches[i][0], matches[i][1]
+		rest := strings.TrimLeft(text[end:], " \t\n")
+		if rest == "" || strings.HasPrefix(rest, ">") {
+			text = text[:start] + text[end:]
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if strings.HasPrefix(strings.TrimLeft(trimmed, " \t"), ">") {
+			continue
+		}
+		// RFC 3676 signature delimiter; everything after it is signature.
+		if trimmed == "--" {
+			break
+		}
+		if mobileSignatureRe.MatchString(trimmed) {
+			continue
+		}
+		kept = append(kept, trimmed)
+	}
+
+	out := tidyTranscriptText(strings.Join(kept, "\n"))
+	if out == "" {
+		return tidyTranscriptText(body)
+	}
+	return out
+}
+
+// htmlToTranscriptText renders an HTML body as plain text with line breaks
+// kept, cid images replaced by placeholders and, when dequote is set, the
+// quoted part of a reply removed.
+func htmlToTranscriptText(src string, dequote bool, cidNames map[string]string) string {
+	if dequote {
+		if loc := htmlQuoteStartRe.FindStringIndex(src); loc != nil && loc[0] > 0 {
+			src = src[:loc[0]]
+		}
+	}
+	src = htmlCidImageRe.ReplaceAllStringFunc(src, func(tag string) string {
+		cid := htmlCidImageRe.FindStringSubmatch(tag)[1]
+		name := cidNames[strings.Trim(cid, "<>")]
+		if name == "" {
+			name = cid
+		}
+		return "[image: " + name + "]"
+	})
+	src = htmlHeadRe.ReplaceAllString(src, "")
+	src = scriptPattern.ReplaceAllString(src, "")
+	src = stylePattern.ReplaceAllString(src, "")
+	src = htmlListItemRe.ReplaceAllString(src, "\n- ")
+	src = htmlBreakRe.ReplaceAllString(src, "\n")
+	src = htmlTagPattern.ReplaceAllString(src, "")
+	lines := strings.Split(html.UnescapeString(src), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(lineSpaceRe.ReplaceAllString(line, " "))
+	}
+	text := tidyTranscriptText(strings.Join(lines, "\n"))
+	if dequote {
+		return dequoteText(text)
+	}
+	return text
+}
+
+// tidyTranscriptText drops trailing spaces and collapses runs of blank lines;
+// indentation is kept for code and tables in plain-text mail.
+func tidyTranscriptText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,369 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	unsubscribeOneClick = "one-click"
+	unsubscribeMailto   = "mailto"
+	unsubscribeManual   = "manual" // only a web link that needs a browser
+	unsubscribeNone     = "none"
+
+	unsubscribeStatusDone    = "unsubscribed"
+	unsubscribeStatusSent    = "sent"
+	unsubscribeStatusSkipped = "skipped"
+	unsubscribeStatusFailed  = "failed"
+)
+
+var listUnsubscribeEntryRe = regexp.MustCompile(`<([^>]+)>`)
+
+// unsubscribeHTTPClient performs the RFC 8058 POST. Redirects are not
+// followed: the RFC forbids them and a redirected POST would become a GET.
+var unsubscribeHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// GmailUnsubscribeCmd unsubscribes from mailing lists using List-Unsubscribe.
+type GmailUnsubscribeCmd struct {
+	MessageIDs []string `arg:"" optional:"" name:"messageId" help:"Message IDs from the senders to unsubscribe from"`
+	Query      string   `name:"query" short:"q" help:"Unsubscribe from the senders of all messages matching this query"`
+	Max        int64    `name:"max" aliases:"limit" help:"Max messages to inspect (with --query)" default:"50"`
+	NoMailto   bool     `name:"no-mailto" help:"Never send an unsubscribe email; only use one-click links"`
+	Archive    bool     `name:"archive" help:"Also archive inbox mail from each sender"`
+	Filter     bool     `name:"filter" help:"Also create a filter that skips the inbox for future mail from each sender"`
+}
+
+// unsubscribeTarget is one sender (or list) and how to leave it.
+type unsubscribeTarget struct {
+	Sender     string   `json:"sender"`
+	MessageIDs []string `json:"messageIds"`
+	Method     string   `json:"method"`
+	URL        string   `json:"url,omitempty"`
+	Mailto     string   `json:"mailto,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Archived   int      `json:"archived,omitempty"`
+	FilterID   string   `json:"filterId,omitempty"`
+}
+
+func (c *GmailUnsubscribeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	var ids []string
+	for _, id := range c.MessageIDs {
+		if id = normalizeGmailMessageID(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	query := strings.TrimSpace(c.Query)
+	if len(ids) == 0 && query == "" {
+		return usage("provide message IDs or --query")
+	}
+	if c.Max <= 0 {
+		return usage("--max must be > 0")
+	}
+
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	if query != "" {
+		found, searchErr := searchMessageIDs(ctx, svc, query, c.Max)
+		if searchErr != nil {
+			return searchErr
+		}
+		ids = append(found, ids...)
+	}
+	targets, err := c.plan(ctx, svc, ids)
+	if err != nil {
+		return err
+	}
+
+	// The plan needs the message headers, so dry-run stops after reading them.
+	if err := dryRunExit(ctx, flags, "gmail.unsubscribe", map[string]any{
+		"targets": targets,
+		"archive": c.Archive,
+		"filter":  c.Filter,
+	}); err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range targets {
+		t := &targets[i]
+		c.unsubscribe(ctx, svc, account, t)
+		if t.Status == unsubscribeStatusFailed {
+			failed++
+			continue
+		}
+		if err := c.followUp(ctx, svc, t); err != nil {
+			t.Error = err.Error()
+			t.Status = unsubscribeStatusFailed
+			failed++
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"results": targets,
+			"count":   len(targets),
+			"failed":  failed,
+		}); err != nil {
+			return err
+		}
+	} else if len(targets) == 0 {
+		u.Err().Println("No messages found")
+	} else {
+		w, flush := tableWriter(ctx)
+		fmt.Fprintln(w, "SENDER\tMETHOD\tSTATUS\tDETAIL")
+		for _, t := range targets {
+			detail := firstNonEmpty(t.Error, t.URL, t.Mailto)
+			switch {
+			case t.Error != "":
+			case t.Method == unsubscribeManual:
+				detail = "open " + t.URL
+			case t.Method == unsubscribeNone:
+				detail = "no List-Unsubscribe header"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Sender, t.Method, t.Status, detail)
+		}
+		flush()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d unsubscribe requests failed", failed, len(targets))
+	}
+	return nil
+}
+
+// plan reads the List-Unsubscribe headers and groups messages by sender so
+// each list is only contacted once.
+func (c *GmailUnsubscribeCmd) plan(ctx context.Context, svc *gmail.Service, ids []string) ([]unsubscribeTarget, error) {
+	var (
+		targets []unsubscribeTarget
+		index   = map[string]int{}
+		seen    = map[string]bool{}
+	)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		msg, err := svc.Users.Messages.Get("me", id).
+			Format("metadata").
+			MetadataHeaders("From", "List-Unsubscribe", "List-Unsubscribe-Post").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, fmt.Errorf("get message %s: %w", id, err)
+		}
+		sender := unsubscribeSender(headerValue(msg.Payload, "From"))
+		key := strings.ToLower(sender)
+		if i, ok := index[key]; ok {
+			targets[i].MessageIDs = append(targets[i].MessageIDs, id)
+			// A later message may carry a better option (e.g. one-click).
+			if next := chooseUnsubscribe(msg.Payload, c.NoMailto); unsubscribeRank(next.Method) > unsubscribeRank(targets[i].Method) {
+				next.Sender, next.MessageIDs = targets[i].Sender, targets[i].MessageIDs
+				targets[i] = next
+			}
+			continue
+		}
+		t := chooseUnsubscribe(msg.Payload, c.NoMailto)
+		t.Sender = sender
+		t.MessageIDs = []string{id}
+		index[key] = len(targets)
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// chooseUnsubscribe picks the best method a message offers: the RFC 8058
+// one-click POST, then a mailto address, then a web link for the user.
+func chooseUnsubscribe(p *gmail.MessagePart, noMailto bool) unsubscribeTarget {
+	var httpsURL, webURL, mailto string
+	for _, m := range listUnsubscribeEntryRe.FindAllStringSubmatch(headerValue(p, "List-Unsubscribe"), -1) {
+		entry := strings.Join(strings.Fields(m[1]), "")
+		switch lower := strings.ToLower(entry); {
+		case strings.HasPrefix(lower, "https://") && httpsURL == "":
+			httpsURL = entry
+		case strings.HasPrefix(lower, "http://") && webURL == "":
+			webURL = entry
+		case strings.HasPrefix(lower, "mailto:") && mailto == "":
+			mailto = entry
+		}
+	}
+	oneClick := strings.Contains(strings.ToLower(strings.ReplaceAll(headerValue(p, "List-Unsubscribe-Post"), " ", "")), "list-unsubscribe=one-click")
+
+	t := unsubscribeTarget{URL: firstNonEmpty(httpsURL, webURL)}
+	if !noMailto {
+		t.Mailto = mailto
+	}
+	switch {
+	case oneClick && httpsURL != "":
+		t.Method = unsubscribeOneClick
+	case t.Mailto != "":
+		t.Method = unsubscribeMailto
+	case t.URL != "":
+		t.Method = unsubscribeManual
+	default:
+		t.Method = unsubscribeNone
+	}
+	return t
+}
+
+func unsubscribeRank(method string) int {
+	switch method {
+	case unsubscribeOneClick:
+		return 3
+	case unsubscribeMailto:
+		return 2
+	case unsubscribeManual:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// unsubscribe performs the chosen method, falling back from a failed
+// one-click POST to the mailto address when there is one.
+func (c *GmailUnsubscribeCmd) unsubscribe(ctx context.Context, svc *gmail.Service, account string, t *unsubscribeTarget) {
+	switch t.Method {
+	case unsubscribeOneClick:
+		err := postOneClickUnsubscribe(ctx, t.URL)
+		if err == nil {
+			t.Status = unsubscribeStatusDone
+			return
+		}
+		if t.Mailto == "" {
+			t.Status, t.Error = unsubscribeStatusFailed, err.Error()
+			return
+		}
+		t.Method = unsubscribeMailto
+		fallthrough
+	case unsubscribeMailto:
+		if err := sendMailtoUnsubscribe(ctx, svc, account, t.Mailto); err != nil {
+			t.Status, t.Error = unsubscribeStatusFailed, err.Error()
+			return
+		}
+		t.Status = unsubscribeStatusSent
+	default:
+		t.Status = unsubscribeStatusSkipped
+	}
+}
+
+// followUp archives and filters the sender's mail when asked.
+func (c *GmailUnsubscribeCmd) followUp(ctx context.Context, svc *gmail.Service, t *unsubscribeTarget) error {
+	if !strings.Contains(t.Sender, "@") {
+		return nil
+	}
+	if c.Archive {
+		ids, err := searchMessageIDs(ctx, svc, "from:"+t.Sender+" in:inbox", 1000)
+		if err != nil {
+			return fmt.Errorf("find mail from %s: %w", t.Sender, err)
+		}
+		if len(ids) > 0 {
+			if err := svc.Users.Messages.BatchModify("me", &gmail.BatchModifyMessagesRequest{
+				Ids:            ids,
+				RemoveLabelIds: []string{"INBOX"},
+			}).Context(ctx).Do(); err != nil {
+				return fmt.Errorf("archive mail from %s: %w", t.Sender, err)
+			}
+		}
+		t.Archived = len(ids)
+	}
+	if c.Filter {
+		created, err := svc.Users.Settings.Filters.Create("me", &gmail.Filter{
+			Criteria: &gmail.FilterCriteria{From: t.Sender},
+			Action:   &gmail.FilterAction{RemoveLabelIds: []string{"INBOX"}},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("create filter for %s: %w", t.Sender, err)
+		}
+		t.FilterID = created.Id
+	}
+	return nil
+}
+
+func postOneClickUnsubscribe(ctx context.Context, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := unsubscribeHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("one-click unsubscribe: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("one-click unsubscribe: %s", resp.Status)
+	}
+	return nil
+}
+
+// sendMailtoUnsubscribe sends the message a mailto: link describes, using the
+// subject and body it carries (RFC 6068).
+func sendMailtoUnsubscribe(ctx context.Context, svc *gmail.Service, account, link string) error {
+	u, err := url.Parse(link)
+	if err != nil || !strings.EqualFold(u.Scheme, "mailto") {
+		return fmt.Errorf("invalid mailto link %q", link)
+	}
+	addr, err := url.PathUnescape(firstNonEmpty(u.Opaque, u.Path))
+	if err != nil {
+		return fmt.Errorf("invalid mailto link %q: %w", link, err)
+	}
+	to := splitCSV(addr)
+	if len(to) == 0 {
+		return fmt.Errorf("mailto link %q has no address", link)
+	}
+	q := u.Query()
+	subject := firstNonEmpty(strings.TrimSpace(q.Get("subject")), "unsubscribe")
+	body := firstNonEmpty(q.Get("body"), "unsubscribe")
+
+	_, err = sendGmailBatches(ctx, svc, sendMessageOptions{
+		FromAddr: account,
+		Subject:  subject,
+		Body:     body,
+	}, []sendBatch{{To: to}})
+	if err != nil {
+		return fmt.Errorf("send unsubscribe email: %w", err)
+	}
+	return nil
+}
+
+// unsubscribeSender returns the bare address of a From header, or the header
+// itself when it does not parse.
+func unsubscribeSender(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return addr.Address
+	}
+	if from = strings.TrimSpace(from); from != "" {
+		return from
+	}
+	return "(unknown sender)"
+}
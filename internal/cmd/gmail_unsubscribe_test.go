@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestGmailUnsubscribe(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var (
+		mu       sync.Mutex
+		posts    []string
+		sent     []string
+		archived []string
+		filters  []string
+	)
+	web := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		posts = append(posts, r.Method+" "+r.URL.Path+" "+string(body))
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer web.Close()
+	origClient := unsubscribeHTTPClient
+	t.Cleanup(func() { unsubscribeHTTPClient = origClient })
+	unsubscribeHTTPClient = web.Client()
+
+	headers := map[string][][2]string{
+		"m1": {{"From", "News <news@a.com>"}, {"List-Unsubscribe", "<mailto:leave@a.com>, <" + web.URL + "/u/1>"}, {"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"}},
+		"m2": {{"From", "news@a.com"}, {"List-Unsubscribe", "<mailto:leave@a.com>"}},
+		"m3": {{"From", "List <list@b.com>"}, {"List-Unsubscribe", "<mailto:unsub@b.com?subject=remove%20me>"}},
+		"m4": {{"From", "c@c.com"}, {"List-Unsubscribe", "<http://c.com/unsubscribe?id=1>"}},
+		"m5": {{"From", "d@d.com"}, {"List-Unsubscribe", "<" + web.URL + "/broken>,\r\n <mailto:bye@d.com>"}, {"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && path == "/messages":
+			switch q := r.URL.Query().Get("q"); {
+			case q == "from:news@a.com in:inbox":
+				_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}, {"id": "m2"}}})
+				return
+			case strings.HasPrefix(q, "from:"):
+				_ = json.NewEncoder(w).Encode(map[string]any{})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}, {"id": "m2"}, {"id": "m3"}, {"id": "m4"}, {"id": "m5"}}})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/messages/"):
+			id := strings.TrimPrefix(path, "/messages/")
+			var hs []map[string]any
+			for _, h := range headers[id] {
+				hs = append(hs, map[string]any{"name": h[0], "value": h[1]})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "payload": map[string]any{"headers": hs}})
+		case r.Method == http.MethodPost && path == "/messages/send":
+			var msg gmail.Message
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			raw, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+			sent = append(sent, string(raw))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "s1"})
+		case r.Method == http.MethodPost && path == "/messages/batchModify":
+			var req gmail.BatchModifyMessagesRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			archived = append(archived, req.Ids...)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && path == "/settings/filters":
+			var f gmail.Filter
+			_ = json.NewDecoder(r.Body).Decode(&f)
+			filters = append(filters, f.Criteria.From)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "f-" + f.Criteria.From})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	// Dry run reads headers but performs nothing.
+	out := captureStdout(t, func() {
+		_ = Execute([]string{"--json", "--dry-run", "--account", "me@example.com", "gmail", "unsubscribe", "--query", "category:promotions"})
+	})
+	var dry struct {
+		Request struct {
+			Targets []unsubscribeTarget `json:"targets"`
+		} `json:"request"`
+	}
+	if err := json.Unmarshal([]byte(out), &dry); err != nil {
+		t.Fatalf("unmarshal dry run: %v (out=%q)", err, out)
+	}
+	methods := map[string]string{}
+	for _, target := range dry.Request.Targets {
+		methods[target.Sender] = target.Method
+	}
+	if len(dry.Request.Targets) != 4 || methods["news@a.com"] != unsubscribeOneClick || methods["list@b.com"] != unsubscribeMailto ||
+		methods["c@c.com"] != unsubscribeManual || methods["d@d.com"] != unsubscribeOneClick {
+		t.Fatalf("unexpected plan: %+v", dry.Request.Targets)
+	}
+	if len(posts) != 0 || len(sent) != 0 {
+		t.Fatalf("dry run performed actions: posts=%v sent=%v", posts, sent)
+	}
+
+	out = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "unsubscribe", "--query", "category:promotions", "--archive", "--filter"}); execErr != nil {
+			t.Fatalf("unsubscribe: %v", execErr)
+		}
+	})
+	var result struct {
+		Results []unsubscribeTarget `json:"results"`
+		Failed  int                 `json:"failed"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	status := map[string]unsubscribeTarget{}
+	for _, r := range result.Results {
+		status[r.Sender] = r
+	}
+	if a := status["news@a.com"]; a.Status != unsubscribeStatusDone || len(a.MessageIDs) != 2 || a.Archived != 2 || a.FilterID != "f-news@a.com" {
+		t.Fatalf("unexpected one-click result: %+v", a)
+	}
+	if b := status["list@b.com"]; b.Status != unsubscribeStatusSent {
+		t.Fatalf("unexpected mailto result: %+v", b)
+	}
+	if c := status["c@c.com"]; c.Status != unsubscribeStatusSkipped || c.URL != "http://c.com/unsubscribe?id=1" {
+		t.Fatalf("unexpected manual result: %+v", c)
+	}
+	if d := status["d@d.com"]; d.Status != unsubscribeStatusSent || d.Method != unsubscribeMailto {
+		t.Fatalf("expected mailto fallback after failed one-click: %+v", d)
+	}
+
+	if len(posts) != 2 || posts[0] != "POST /u/1 List-Unsubscribe=One-Click" {
+		t.Fatalf("unexpected one-click posts: %v", posts)
+	}
+	if len(sent) != 2 || !strings.Contains(sent[0], "To: unsub@b.com") || !strings.Contains(sent[0], "Subject: remove me") || !strings.Contains(sent[1], "To: bye@d.com") {
+		t.Fatalf("unexpected unsubscribe emails: %q", sent)
+	}
+	if len(archived) != 2 || len(filters) != 4 {
+		t.Fatalf("unexpected follow-up: archived=%v filters=%v", archived, filters)
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/idtoken"
 
+	"github.com/steipete/gogcli/internal/config"
 	"github.com/steipete/gogcli/internal/outfmt"
 	"github.com/steipete/gogcli/internal/ui"
 )
@@ -30,16 +32,22 @@ type GmailWatchCmd struct {
 	Renew  GmailWatchRenewCmd  `cmd:"" name:"renew" aliases:"update" help:"Renew Gmail watch using stored config"`
 	Stop   GmailWatchStopCmd   `cmd:"" name:"stop" aliases:"rm,delete" help:"Stop Gmail watch and clear stored state"`
 	Serve  GmailWatchServeCmd  `cmd:"" name:"serve" help:"Run Pub/Sub push handler"`
+	Poll   GmailWatchPollCmd   `cmd:"" name:"poll" help:"Poll Gmail history and deliver changes without Pub/Sub"`
+	Queue  GmailWatchQueueCmd  `cmd:"" name:"queue" aliases:"outbox" help:"Inspect and manage undelivered hook payloads"`
+	Sinks  GmailWatchSinksCmd  `cmd:"" name:"sinks" aliases:"sink" help:"Manage delivery sinks (HTTP, exec, file, unix socket)"`
+	Verify GmailWatchVerifyCmd `cmd:"" name:"verify" help:"Verify the HMAC signature of a received hook request"`
 }
 
 type GmailWatchStartCmd struct {
-	Topic       string   `name:"topic" help:"Pub/Sub topic (projects/.../topics/...)"`
-	Labels      []string `name:"label" help:"Label IDs or names (repeatable, comma-separated)"`
-	TTL         string   `name:"ttl" help:"Renew after duration (seconds or Go duration)"`
-	HookURL     string   `name:"hook-url" help:"Webhook URL to forward messages"`
-	HookToken   string   `name:"hook-token" help:"Webhook bearer token"`
-	IncludeBody bool     `name:"include-body" help:"Include text/plain body in hook payload"`
-	MaxBytes    int      `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
+	Topic          string   `name:"topic" help:"Pub/Sub topic (projects/.../topics/...)"`
+	Labels         []string `name:"label" help:"Label IDs or names (repeatable, comma-separated)"`
+	TTL            string   `name:"ttl" help:"Renew after duration (seconds or Go duration)"`
+	HookURL        string   `name:"hook-url" help:"Webhook URL to forward messages"`
+	HookToken      string   `name:"hook-token" help:"Webhook bearer token"`
+	HookSecret     string   `name:"hook-secret" help:"Sign webhook requests with HMAC-SHA256 using this secret (X-Gog-Signature; prefer --hook-secret-file or GOG_HOOK_SECRET)"`
+	HookSecretFile string   `name:"hook-secret-file" help:"Read the hook signing secret from this file (default: GOG_HOOK_SECRET env when --hook-url is set)"`
+	IncludeBody    bool     `name:"include-body" help:"Include text/plain body in hook payload"`
+	MaxBytes       int      `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
 }
 
 func (c *GmailWatchStartCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
@@ -50,8 +58,12 @@ func (c *GmailWatchStartCmd) Run(ctx context.Context, kctx *kong.Context, flags
 	if err != nil {
 		return err
 	}
+	hookSecret, err := secretFromSources(c.HookSecret, c.HookSecretFile, "hook-secret", "hook-secret-file", "")
+	if err != nil {
+		return err
+	}
 	maxChanged := flagProvided(kctx, "max-bytes")
-	hook, err := hookFromFlags(c.HookURL, c.HookToken, c.IncludeBody, c.MaxBytes, maxChanged, false)
+	hook, err := hookFromFlags(c.HookURL, c.HookToken, hookSecretOrEnv(hookSecret, c.HookURL), c.IncludeBody, c.MaxBytes, maxChanged, false)
 	if err != nil {
 		if errors.Is(err, errNoHookConfigured) {
 			hook = nil
@@ -65,7 +77,7 @@ func (c *GmailWatchStartCmd) Run(ctx context.Context, kctx *kong.Context, flags
 		"labels":  c.Labels,
 		"ttl_raw": strings.TrimSpace(c.TTL),
 		"ttl":     ttl.String(),
-		"hook":    hook,
+		"hook":    hook.redacted(),
 	}); dryRunErr != nil {
 		return dryRunErr
 	}
@@ -149,7 +161,7 @@ func (c *GmailWatchRenewCmd) Run(ctx context.Context, flags *RootFlags) error {
 		"labels":  state.Labels,
 		"ttl_raw": strings.TrimSpace(c.TTL),
 		"ttl":     ttl.String(),
-		"hook":    state.Hook,
+		"hook":    state.Hook.redacted(),
 	}); dryRunErr != nil {
 		return dryRunErr
 	}
@@ -213,27 +225,40 @@ func (c *GmailWatchStopCmd) Run(ctx context.Context, flags *RootFlags) error {
 }
 
 type GmailWatchServeCmd struct {
-	Bind          string   `name:"bind" help:"Bind address" default:"127.0.0.1"`
-	Port          int      `name:"port" help:"Listen port" default:"8788"`
-	Path          string   `name:"path" help:"Push handler path" default:"/gmail-pubsub"`
-	Timezone      string   `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
-	Local         bool     `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
-	VerifyOIDC    bool     `name:"verify-oidc" help:"Verify Pub/Sub OIDC tokens"`
-	OIDCEmail     string   `name:"oidc-email" help:"Expected service account email"`
-	OIDCAudience  string   `name:"oidc-audience" help:"Expected OIDC audience"`
-	SharedToken   string   `name:"token" help:"Shared token for x-gog-token or ?token="`
-	HookURL       string   `name:"hook-url" help:"Webhook URL to forward messages"`
-	HookToken     string   `name:"hook-token" help:"Webhook bearer token"`
-	IncludeBody   bool     `name:"include-body" help:"Include text/plain body in hook payload"`
-	MaxBytes      int      `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
-	HistoryTypes  []string `name:"history-types" help:"History types to include (repeatable, comma-separated: messageAdded,messageDeleted,labelAdded,labelRemoved). Default: messageAdded"`
-	ExcludeLabels string   `name:"exclude-labels" help:"List of Gmail label IDs to exclude from hook payload (e.g. SPAM,TRASH,Label_123). Set to empty string to disable." default:"SPAM,TRASH"`
-	SaveHook      bool     `name:"save-hook" help:"Persist hook settings to watch state"`
+	Bind         string                  `name:"bind" help:"Bind address" default:"127.0.0.1"`
+	Port         int                     `name:"port" help:"Listen port" default:"8788"`
+	Path         string                  `name:"path" help:"Push handler path" default:"/gmail-pubsub"`
+	Timezone     string                  `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
+	Local        bool                    `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+	VerifyOIDC   bool                    `name:"verify-oidc" help:"Verify Pub/Sub OIDC tokens"`
+	OIDCEmail    string                  `name:"oidc-email" help:"Expected service account email"`
+	OIDCAudience string                  `name:"oidc-audience" help:"Expected OIDC audience"`
+	SharedToken  string                  `name:"token" help:"Shared token for x-gog-token or ?token="`
+	Accounts     []string                `name:"accounts" help:"Serve several accounts on one endpoint, routing pushes by emailAddress (comma-separated)"`
+	AllAccounts  bool                    `name:"all-accounts" help:"Serve every account with stored watch state"`
+	Delivery     GmailWatchDeliveryFlags `embed:""`
+}
+
+// GmailWatchDeliveryFlags are the hook and delivery flags shared by serve and
+// poll.
+type GmailWatchDeliveryFlags struct {
+	HookURL        string   `name:"hook-url" help:"Webhook URL to forward messages"`
+	HookToken      string   `name:"hook-token" help:"Webhook bearer token"`
+	HookSecret     string   `name:"hook-secret" help:"Sign webhook requests with HMAC-SHA256 using this secret (X-Gog-Signature; prefer --hook-secret-file or GOG_HOOK_SECRET)"`
+	HookSecretFile string   `name:"hook-secret-file" help:"Read the hook signing secret from this file (default: GOG_HOOK_SECRET env when a hook URL is set)"`
+	IncludeBody    bool     `name:"include-body" help:"Include text/plain body in hook payload"`
+	MaxBytes       int      `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
+	HistoryTypes   []string `name:"history-types" help:"History types to include (repeatable, comma-separated: messageAdded,messageDeleted,labelAdded,labelRemoved). Default: messageAdded"`
+	ExcludeLabels  string   `name:"exclude-labels" help:"List of Gmail label IDs to exclude from hook payload (e.g. SPAM,TRASH,Label_123). Set to empty string to disable." default:"SPAM,TRASH"`
+	SaveHook       bool     `name:"save-hook" help:"Persist hook settings to watch state"`
+	RetryMaxAge    string   `name:"retry-max-age" help:"Retry failed hook deliveries for this long before dead-lettering them (seconds or Go duration)" default:"24h"`
+	NoQueue        bool     `name:"no-queue" help:"Deliver each hook once, without the on-disk retry queue"`
+	Rules          string   `name:"rules" help:"Rules file (YAML) to run on new messages; preview with 'gog gmail rules test'"`
 }
 
 func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
 	u := ui.FromContext(ctx)
-	account, err := requireAccount(flags)
+	accounts, err := c.serveAccounts(flags)
 	if err != nil {
 		return err
 	}
@@ -258,27 +283,129 @@ func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags
 		return err
 	}
 
-	historyTypes, err := parseHistoryTypes(c.HistoryTypes)
+	validator := (*idtoken.Validator)(nil)
+	if c.VerifyOIDC {
+		validator, err = newOIDCValidator(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	queueCtx, stopQueues := context.WithCancel(ctx)
+	defer stopQueues()
+	servers := make([]*gmailWatchServer, 0, len(accounts))
+	for _, account := range accounts {
+		store, err := loadGmailWatchStore(account)
+		if err != nil {
+			if len(accounts) > 1 {
+				return fmt.Errorf("%s: %w", account, err)
+			}
+			return err
+		}
+		server, err := c.Delivery.newServer(kctx, flags, u, account, store, loc)
+		if err != nil {
+			return err
+		}
+		server.validator = validator
+		server.cfg.Bind = c.Bind
+		server.cfg.Port = c.Port
+		server.cfg.Path = c.Path
+		server.cfg.VerifyOIDC = c.VerifyOIDC
+		server.cfg.OIDCEmail = c.OIDCEmail
+		server.cfg.OIDCAudience = c.OIDCAudience
+		server.cfg.SharedToken = c.SharedToken
+		if len(accounts) > 1 {
+			prefix := "[" + account + "] "
+			server.logf = func(format string, args ...any) { u.Err().Printf(prefix+format, args...) }
+			server.warnf = server.logf
+		}
+		server.startQueue(queueCtx)
+		server.startRules(queueCtx)
+		servers = append(servers, server)
+	}
+
+	var handler http.Handler = servers[0]
+	if len(servers) > 1 {
+		handler = newGmailWatchRouter(servers)
+	}
+
+	addr := net.JoinHostPort(c.Bind, strconv.Itoa(c.Port))
+	if len(servers) > 1 {
+		u.Err().Printf("watch: listening on %s%s for %d accounts", addr, c.Path, len(servers))
+	} else {
+		u.Err().Printf("watch: listening on %s%s", addr, c.Path)
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return listenAndServe(httpServer)
+}
+
+// serveAccounts returns the accounts to serve: --accounts, every account with
+// watch state for --all-accounts, or the selected account.
+func (c *GmailWatchServeCmd) serveAccounts(flags *RootFlags) ([]string, error) {
+	if len(c.Accounts) > 0 && c.AllAccounts {
+		return nil, usage("use either --accounts or --all-accounts")
+	}
+	var accounts []string
+	switch {
+	case c.AllAccounts:
+		listed, err := listWatchedAccounts()
+		if err != nil {
+			return nil, err
+		}
+		if len(listed) == 0 {
+			return nil, usage("no watch state found; run gmail watch start for each account")
+		}
+		return listed, nil
+	case len(c.Accounts) > 0:
+		for _, a := range c.Accounts {
+			if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+				accounts = appendUnique(accounts, a)
+			}
+		}
+		if len(accounts) == 0 {
+			return nil, usage("--accounts is empty")
+		}
+		return accounts, nil
+	}
+	account, err := requireAccount(flags)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return []string{account}, nil
+}
 
-	store, err := loadGmailWatchStore(account)
+// newServer resolves the hook settings (flags first, then the stored hook)
+// and builds a server with its sinks. Listener and auth settings are left to
+// the caller.
+func (f *GmailWatchDeliveryFlags) newServer(kctx *kong.Context, flags *RootFlags, u *ui.UI, account string, store *gmailWatchStore, loc *time.Location) (*gmailWatchServer, error) {
+	historyTypes, err := parseHistoryTypes(f.HistoryTypes)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	state := store.Get()
 
-	hookURL := c.HookURL
-	hookToken := c.HookToken
-	includeBody := c.IncludeBody
-	maxBytes := c.MaxBytes
+	hookURL := f.HookURL
+	hookToken := f.HookToken
+	hookSecret, err := secretFromSources(f.HookSecret, f.HookSecretFile, "hook-secret", "hook-secret-file", "")
+	if err != nil {
+		return nil, err
+	}
+	includeBody := f.IncludeBody
+	maxBytes := f.MaxBytes
 
 	if hookURL == "" && state.Hook != nil {
 		hookURL = state.Hook.URL
 		if !flagProvided(kctx, "hook-token") {
 			hookToken = state.Hook.Token
 		}
+		if !flagProvided(kctx, "hook-secret") && !flagProvided(kctx, "hook-secret-file") {
+			hookSecret = state.Hook.Secret
+		}
 		if !flagProvided(kctx, "include-body") {
 			includeBody = state.Hook.IncludeBody
 		}
@@ -288,55 +415,61 @@ func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags
 	}
 
 	maxChanged := flagProvided(kctx, "max-bytes")
-	hook, err := hookFromFlags(hookURL, hookToken, includeBody, maxBytes, maxChanged, true)
+	hook, err := hookFromFlags(hookURL, hookToken, hookSecretOrEnv(hookSecret, hookURL), includeBody, maxBytes, maxChanged, true)
 	if err != nil {
 		if errors.Is(err, errNoHookConfigured) {
 			hook = nil
 		} else {
-			return err
+			return nil, err
 		}
 	}
-	if c.SaveHook && hook != nil {
+	if f.SaveHook && hook != nil {
 		if updateErr := store.Update(func(s *gmailWatchState) error {
 			s.Hook = hook
 			s.UpdatedAtMs = time.Now().UnixMilli()
 			return nil
 		}); updateErr != nil {
-			return updateErr
+			return nil, updateErr
 		}
 	}
 
-	validator := (*idtoken.Validator)(nil)
-	if c.VerifyOIDC {
-		validator, err = newOIDCValidator(ctx)
-		if err != nil {
-			return err
+	retryMaxAge, err := parseDurationSeconds(f.RetryMaxAge)
+	if err != nil {
+		return nil, usagef("invalid --retry-max-age: %v", err)
+	}
+	var rules []*gmailRule
+	if strings.TrimSpace(f.Rules) != "" {
+		if rules, err = loadGmailRules(f.Rules); err != nil {
+			return nil, err
 		}
 	}
 
 	cfg := gmailWatchServeConfig{
-		Account:       account,
-		Bind:          c.Bind,
-		Port:          c.Port,
-		Path:          c.Path,
-		VerifyOIDC:    c.VerifyOIDC,
-		OIDCEmail:     c.OIDCEmail,
-		OIDCAudience:  c.OIDCAudience,
-		SharedToken:   c.SharedToken,
-		HookTimeout:   defaultHookRequestTimeoutSec * time.Second,
-		HistoryMax:    defaultHistoryMaxResults,
-		ResyncMax:     defaultHistoryResyncMax,
-		HistoryTypes:  historyTypes,
-		AllowNoHook:   hook == nil,
-		IncludeBody:   includeBody,
-		MaxBodyBytes:  maxBytes,
-		DateLocation:  loc,
-		ExcludeLabels: splitCommaList(c.ExcludeLabels),
-		VerboseOutput: flags.Verbose,
+		Account:         account,
+		HookTimeout:     defaultHookRequestTimeoutSec * time.Second,
+		HistoryMax:      defaultHistoryMaxResults,
+		ResyncMax:       defaultHistoryResyncMax,
+		HistoryTypes:    historyTypes,
+		AllowNoHook:     hook == nil && len(state.Sinks) == 0,
+		IncludeBody:     includeBody,
+		MaxBodyBytes:    maxBytes,
+		DateLocation:    loc,
+		ExcludeLabels:   splitCommaList(f.ExcludeLabels),
+		VerboseOutput:   flags.Verbose,
+		DryRun:          flags.DryRun,
+		RequireApproval: flags.RequireApproval,
+	}
+	if len(rules) > 0 && strings.TrimSpace(flags.Policy) != "" {
+		policy, policyErr := config.ReadPolicy(strings.TrimSpace(flags.Policy))
+		if policyErr != nil {
+			return nil, &ExitError{Code: exitCodeConfig, Err: policyErr}
+		}
+		cfg.Policy = &policy
 	}
 	if hook != nil {
 		cfg.HookURL = hook.URL
 		cfg.HookToken = hook.Token
+		cfg.HookSecret = hook.Secret
 		cfg.IncludeBody = hook.IncludeBody
 		cfg.MaxBodyBytes = hook.MaxBytes
 	}
@@ -346,29 +479,45 @@ func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags
 	}
 
 	hookClient := &http.Client{Timeout: cfg.HookTimeout}
-	server := &gmailWatchServer{
+	var sinks []*watchSinkRunner
+	if hook != nil {
+		sinks = append(sinks, &watchSinkRunner{
+			sink: gmailWatchSink{Name: watchHookSinkName, Target: hook.URL, Token: hook.Token, Secret: hook.Secret},
+			deliver: func(ctx context.Context, d gmailWatchDelivery) error {
+				return postWatchHook(ctx, hookClient, hook.URL, hook.Token, hook.Secret, d)
+			},
+		})
+	}
+	for _, sink := range state.Sinks {
+		runner, sinkErr := newWatchSinkRunner(sink, hookClient, cfg.HookTimeout)
+		if sinkErr != nil {
+			return nil, fmt.Errorf("sink %s: %w", sink.Name, sinkErr)
+		}
+		sinks = append(sinks, runner)
+	}
+	if !f.NoQueue {
+		for _, runner := range sinks {
+			if runner.queue, err = openGmailWatchQueue(account, runner.sink.Name, retryMaxAge); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &gmailWatchServer{
 		cfg:             cfg,
 		store:           store,
-		validator:       validator,
 		newService:      newGmailService,
 		hookClient:      hookClient,
+		sinks:           sinks,
+		rules:           rules,
 		excludeLabelIDs: stringSet(cfg.ExcludeLabels),
 		logf:            u.Err().Printf,
 		warnf:           u.Err().Printf,
-	}
-
-	addr := net.JoinHostPort(c.Bind, strconv.Itoa(c.Port))
-	u.Err().Printf("watch: listening on %s%s", addr, c.Path)
-
-	httpServer := &http.Server{
-		Addr:              addr,
-		Handler:           server,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
-	return listenAndServe(httpServer)
+	}, nil
 }
 
 func writeWatchState(ctx context.Context, state gmailWatchState) error {
+	state = state.redacted()
 	if outfmt.IsJSON(ctx) {
 		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"watch": state})
 	}
@@ -402,6 +551,9 @@ func writeWatchState(ctx context.Context, state gmailWatchState) error {
 		if state.Hook.Token != "" {
 			u.Out().Printf("hook_token\t%s", state.Hook.Token)
 		}
+		if state.Hook.Secret != "" {
+			u.Out().Printf("hook_signed\ttrue")
+		}
 	}
 	if state.LastDeliveryStatus != "" {
 		u.Out().Printf("last_delivery_status\t%s", state.LastDeliveryStatus)
@@ -451,11 +603,24 @@ func requestGmailWatch(ctx context.Context, svc *gmail.Service, topic string, la
 	return svc.Users.Watch("me", req).Context(ctx).Do()
 }
 
-func hookFromFlags(url, token string, includeBody bool, maxBytes int, maxBytesChanged bool, allowNoHook bool) (*gmailWatchHook, error) {
+// hookSecretOrEnv falls back to GOG_HOOK_SECRET when a hook URL is set but no
+// secret was given; without a hook URL the env var (eg. exported for verify)
+// is ignored rather than tripping the --hook-url check.
+func hookSecretOrEnv(secret, url string) string {
+	if secret == "" && strings.TrimSpace(url) != "" {
+		return os.Getenv(hookSecretEnv)
+	}
+	return secret
+}
+
+func hookFromFlags(url, token, secret string, includeBody bool, maxBytes int, maxBytesChanged bool, allowNoHook bool) (*gmailWatchHook, error) {
 	if strings.TrimSpace(url) == "" {
 		if token != "" {
 			return nil, usage("--hook-url required when using --hook-token")
 		}
+		if secret != "" {
+			return nil, usage("--hook-url required when using --hook-secret")
+		}
 		if !allowNoHook && (includeBody || maxBytesChanged) {
 			return nil, usage("--hook-url required when setting hook options")
 		}
@@ -471,6 +636,7 @@ func hookFromFlags(url, token string, includeBody bool, maxBytes int, maxBytesCh
 	return &gmailWatchHook{
 		URL:         url,
 		Token:       token,
+		Secret:      secret,
 		IncludeBody: includeBody,
 		MaxBytes:    maxBytes,
 	}, nil
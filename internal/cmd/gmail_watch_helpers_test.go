@@ -71,23 +71,29 @@ func TestWriteWatchState_TextAndJSON(t *testing.T) {
 	if parsed.Watch.Hook == nil || parsed.Watch.Hook.URL == "" {
 		t.Fatalf("expected hook in json")
 	}
+	if parsed.Watch.Hook.Token != watchSecretMask || strings.Contains(jsonOut, `"tok"`) || strings.Contains(textOut, "\ttok") {
+		t.Fatalf("expected hook token masked: %s", jsonOut)
+	}
+	if state.Hook.Token != "tok" {
+		t.Fatalf("output must not modify the stored hook")
+	}
 }
 
 func TestHookFromFlags(t *testing.T) {
 	t.Run("missing url with token", func(t *testing.T) {
-		if _, err := hookFromFlags("", "tok", false, 0, false, false); err == nil {
+		if _, err := hookFromFlags("", "tok", "", false, 0, false, false); err == nil {
 			t.Fatalf("expected error")
 		}
 	})
 
 	t.Run("missing url with hook opts", func(t *testing.T) {
-		if _, err := hookFromFlags("", "", true, 0, true, false); err == nil {
+		if _, err := hookFromFlags("", "", "", true, 0, true, false); err == nil {
 			t.Fatalf("expected error")
 		}
 	})
 
 	t.Run("allow no hook", func(t *testing.T) {
-		hook, err := hookFromFlags("", "", false, 0, false, true)
+		hook, err := hookFromFlags("", "", "", false, 0, false, true)
 		if err == nil || !errors.Is(err, errNoHookConfigured) {
 			t.Fatalf("expected no hook error, got: %v", err)
 		}
@@ -97,7 +103,7 @@ func TestHookFromFlags(t *testing.T) {
 	})
 
 	t.Run("defaults max bytes", func(t *testing.T) {
-		hook, err := hookFromFlags("http://example.com", "", true, 0, false, false)
+		hook, err := hookFromFlags("http://example.com", "", "", true, 0, false, false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -107,7 +113,7 @@ func TestHookFromFlags(t *testing.T) {
 	})
 
 	t.Run("invalid max bytes", func(t *testing.T) {
-		if _, err := hookFromFlags("http://example.com", "", false, 0, true, false); err == nil {
+		if _, err := hookFromFlags("http://example.com", "", "", false, 0, true, false); err == nil {
 			t.Fatalf("expected error")
 		}
 	})
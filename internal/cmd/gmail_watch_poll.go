@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const defaultWatchPollInterval = 30 * time.Second
+
+// GmailWatchPollCmd follows users.history.list from the stored historyId, for
+// setups without Pub/Sub or an inbound endpoint. Payloads go to the same hook
+// and sinks as serve, or to stdout as NDJSON when none is configured.
+type GmailWatchPollCmd struct {
+	Interval string                  `name:"interval" help:"Time between polls (seconds or Go duration)" default:"30s"`
+	Once     bool                    `name:"once" help:"Poll once and exit (for cron)"`
+	Timezone string                  `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
+	Local    bool                    `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+	Delivery GmailWatchDeliveryFlags `embed:""`
+}
+
+func (c *GmailWatchPollCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	interval, err := parseDurationSeconds(c.Interval)
+	if err != nil {
+		return usagef("invalid --interval: %v", err)
+	}
+	if interval < time.Second {
+		return usage("--interval must be at least 1s")
+	}
+	loc, err := resolveOutputLocation(c.Timezone, c.Local)
+	if err != nil {
+		return err
+	}
+
+	store, err := loadGmailWatchStore(account)
+	if errors.Is(err, errWatchStateNotFound) {
+		store, err = newGmailWatchStore(account)
+	}
+	if err != nil {
+		return err
+	}
+	server, err := c.Delivery.newServer(kctx, flags, u, account, store, loc)
+	if err != nil {
+		return err
+	}
+	if err := server.initPollState(ctx); err != nil {
+		return err
+	}
+
+	if c.Once {
+		err := server.pollOnce(ctx)
+		// There is no queue loop, so deliver what this poll queued (and earlier
+		// deliveries that are due) before exiting.
+		server.drainSinks(ctx)
+		return err
+	}
+
+	queueCtx, stopQueue := context.WithCancel(ctx)
+	defer stopQueue()
+	server.startQueue(queueCtx)
+	server.startRules(queueCtx)
+	u.Err().Printf("watch: polling history every %s", interval)
+	for {
+		if err := server.pollOnce(ctx); err != nil {
+			server.warnf("watch: poll failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// initPollState seeds the historyId from the mailbox profile when there is no
+// watch state yet, so polling starts with new changes only.
+func (s *gmailWatchServer) initPollState(ctx context.Context) error {
+	if s.store.Get().HistoryID != "" {
+		return nil
+	}
+	svc, err := s.newService(ctx, s.cfg.Account)
+	if err != nil {
+		return err
+	}
+	profile, err := svc.Users.GetProfile("me").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("get profile: %w", err)
+	}
+	return s.store.Update(func(state *gmailWatchState) error {
+		state.Account = s.cfg.Account
+		state.HistoryID = formatHistoryID(profile.HistoryId)
+		state.UpdatedAtMs = time.Now().UnixMilli()
+		return nil
+	})
+}
+
+// pollOnce runs one history.list from the stored historyId and delivers the
+// resulting payload.
+func (s *gmailWatchServer) pollOnce(ctx context.Context) error {
+	result, err := s.handlePush(ctx, gmailPushPayload{})
+	if errors.Is(err, errNoNewMessages) || (err == nil && result == nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(s.sinks) > 0 {
+		s.dispatch(ctx, result)
+		return nil
+	}
+	return outfmt.WriteJSONLine(ctx, os.Stdout, result)
+}
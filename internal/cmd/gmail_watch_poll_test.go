@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestGmailWatchPollOnce(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var stale atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case path == "/profile":
+			_ = json.NewEncoder(w).Encode(map[string]any{"emailAddress": "me@example.com", "historyId": "300"})
+		case path == "/history":
+			if stale.Load() {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "Requested entity was not found."}})
+				return
+			}
+			if r.URL.Query().Get("startHistoryId") == "300" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"historyId": "300"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"historyId": "200",
+				"history": []map[string]any{
+					{"messagesAdded": []map[string]any{{"message": map[string]any{"id": "m1"}}}},
+				},
+			})
+		case path == "/messages":
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}}})
+		case path == "/messages/m1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "m1", "threadId": "t1", "snippet": "hi", "labelIds": []string{"INBOX"},
+				"payload": map[string]any{"headers": []map[string]any{{"name": "Subject", "value": "Hello"}}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	poll := []string{"--account", "me@example.com", "gmail", "watch", "poll", "--once"}
+
+	// Without watch state, the first poll seeds the historyId from the profile.
+	out := captureStdout(t, func() {
+		if execErr := Execute(poll); execErr != nil {
+			t.Fatalf("seed poll: %v", execErr)
+		}
+	})
+	if out != "" {
+		t.Fatalf("expected no payload on the first poll, got %q", out)
+	}
+	store, err := loadGmailWatchStore("me@example.com")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := store.Get().HistoryID; got != "300" {
+		t.Fatalf("unexpected seeded historyId %q", got)
+	}
+	if err := store.Update(func(s *gmailWatchState) error {
+		s.HistoryID = "100"
+		return nil
+	}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	// No hook or sink: payloads go to stdout as NDJSON, masked under --redact.
+	out = captureStdout(t, func() {
+		if execErr := Execute(append([]string{"--json", "--redact"}, poll...)); execErr != nil {
+			t.Fatalf("poll: %v", execErr)
+		}
+	})
+	var payload gmailHookPayload
+	if err := json.Unmarshal([]byte(out), &payload); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, out)
+	}
+	if strings.Contains(out, "me@example.com") || payload.Account != "[EMAIL_1]" {
+		t.Fatalf("expected redacted NDJSON, got %q", out)
+	}
+	if payload.HistoryID != "200" || len(payload.Messages) != 1 || payload.Messages[0].Subject != "Hello" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	if store, _ = loadGmailWatchStore("me@example.com"); store.Get().HistoryID != "200" {
+		t.Fatalf("expected historyId 200, got %q", store.Get().HistoryID)
+	}
+
+	// With a sink, a stale historyId resyncs and restarts at the profile's historyId.
+	sinkPath := filepath.Join(t.TempDir(), "events.ndjson")
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--account", "me@example.com", "gmail", "watch", "sinks", "add", "audit", "file:" + sinkPath}); execErr != nil {
+			t.Fatalf("sinks add: %v", execErr)
+		}
+	})
+	stale.Store(true)
+	out = captureStdout(t, func() {
+		if execErr := Execute(poll); execErr != nil {
+			t.Fatalf("stale poll: %v", execErr)
+		}
+	})
+	if out != "" {
+		t.Fatalf("expected no stdout with a sink, got %q", out)
+	}
+	data, err := os.ReadFile(sinkPath)
+	if err != nil || !strings.Contains(string(data), `"historyId":"300"`) || !strings.Contains(string(data), `"id":"m1"`) {
+		t.Fatalf("unexpected sink contents: %q (%v)", data, err)
+	}
+	if store, _ = loadGmailWatchStore("me@example.com"); store.Get().HistoryID != "300" {
+		t.Fatalf("expected historyId 300 after resync, got %q", store.Get().HistoryID)
+	}
+
+	if err := Execute([]string{"--account", "me@example.com", "gmail", "watch", "poll", "--interval", "0"}); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for --interval 0, got %v", err)
+	}
+}
+
+func TestGmailWatchPollPagesHistory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var failPage2 atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case path == "/history" && r.URL.Query().Get("pageToken") == "":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"historyId":     "150",
+				"nextPageToken": "p2",
+				"history":       []map[string]any{{"id": "101", "messagesAdded": []map[string]any{{"message": map[string]any{"id": "m1"}}}}},
+			})
+		case path == "/history":
+			if failPage2.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 500, "message": "backend error"}})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"historyId": "150",
+				"history":   []map[string]any{{"id": "102", "messagesAdded": []map[string]any{{"message": map[string]any{"id": "m2"}}}}},
+			})
+		case strings.HasPrefix(path, "/messages/"):
+			id := strings.TrimPrefix(path, "/messages/")
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "threadId": "t-" + id, "labelIds": []string{"INBOX"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	store, err := newGmailWatchStore("me@example.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	poll := func(wantIDs []string, wantHistoryID string) {
+		t.Helper()
+		if err := store.Update(func(s *gmailWatchState) error {
+			s.Account = "me@example.com"
+			s.HistoryID = "100"
+			return nil
+		}); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		out := captureStdout(t, func() {
+			if execErr := Execute([]string{"--account", "me@example.com", "gmail", "watch", "poll", "--once"}); execErr != nil {
+				t.Fatalf("poll: %v", execErr)
+			}
+		})
+		var payload gmailHookPayload
+		if err := json.Unmarshal([]byte(out), &payload); err != nil {
+			t.Fatalf("unmarshal: %v (out=%q)", err, out)
+		}
+		var ids []string
+		for _, m := range payload.Messages {
+			ids = append(ids, m.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(wantIDs, ",") || payload.HistoryID != wantHistoryID {
+			t.Fatalf("got messages %v historyId %s, want %v %s", ids, payload.HistoryID, wantIDs, wantHistoryID)
+		}
+		reloaded, _ := loadGmailWatchStore("me@example.com")
+		if got := reloaded.Get().HistoryID; got != wantHistoryID {
+			t.Fatalf("stored historyId %q, want %q", got, wantHistoryID)
+		}
+	}
+
+	poll([]string{"m1", "m2"}, "150")
+	// A failed second page keeps the first page and resumes after its last record.
+	failPage2.Store(true)
+	poll([]string{"m1"}, "101")
+}
@@ -0,0 +1,615 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	defaultWatchQueueMaxAge      = 24 * time.Hour
+	defaultWatchQueueBackoff     = 5 * time.Second
+	defaultWatchQueueMaxBackoff  = 10 * time.Minute
+	defaultWatchQueuePollSeconds = 1
+)
+
+// gmailWatchDelivery is one hook payload waiting in the outbox (or parked in
+// the dead-letter directory). Payload holds the exact request body.
+type gmailWatchDelivery struct {
+	ID              string          `json:"id"`
+	Account         string          `json:"account"`
+	Sink            string          `json:"sink"`
+	CreatedAtMs     int64           `json:"createdAtMs"`
+	Attempts        int             `json:"attempts"`
+	NextAttemptAtMs int64           `json:"nextAttemptAtMs,omitempty"`
+	LastAttemptAtMs int64           `json:"lastAttemptAtMs,omitempty"`
+	LastError       string          `json:"lastError,omitempty"`
+	Payload         json.RawMessage `json:"payload"`
+}
+
+// gmailWatchQueue is a durable outbox for one sink of one account. Each
+// delivery is a JSON file named so that lexical order is enqueue order; the
+// head of the queue blocks later deliveries, which keeps hooks ordered.
+// Deliveries that exceed MaxAge or fail permanently move to the dead-letter
+// directory.
+type gmailWatchQueue struct {
+	sink       string
+	dir        string
+	deadDir    string
+	maxAge     time.Duration
+	backoff    time.Duration
+	maxBackoff time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex // guards the queue files
+	drainMu sync.Mutex // serializes Drain so one attempt is in flight
+}
+
+func gmailWatchQueueDirs(account string) (string, string, error) {
+	dir, err := config.EnsureGmailWatchDir()
+	if err != nil {
+		return "", "", err
+	}
+	name := sanitizeAccountForPath(account)
+	return filepath.Join(dir, "outbox", name), filepath.Join(dir, "dead-letter", name), nil
+}
+
+func openGmailWatchQueue(account, sink string, maxAge time.Duration) (*gmailWatchQueue, error) {
+	dir, deadDir, err := gmailWatchQueueDirs(account)
+	if err != nil {
+		return nil, err
+	}
+	dir, deadDir = filepath.Join(dir, sink), filepath.Join(deadDir, sink)
+	for _, d := range []string{dir, deadDir} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return nil, fmt.Errorf("ensure watch queue dir: %w", err)
+		}
+	}
+	if maxAge <= 0 {
+		maxAge = defaultWatchQueueMaxAge
+	}
+	return &gmailWatchQueue{
+		sink:       sink,
+		dir:        dir,
+		deadDir:    deadDir,
+		maxAge:     maxAge,
+		backoff:    defaultWatchQueueBackoff,
+		maxBackoff: defaultWatchQueueMaxBackoff,
+		now:        time.Now,
+	}, nil
+}
+
+func (q *gmailWatchQueue) Enqueue(account string, payload []byte) (gmailWatchDelivery, error) {
+	now := q.now()
+	d := gmailWatchDelivery{
+		ID:          newWatchDeliveryID(now),
+		Account:     account,
+		Sink:        q.sink,
+		CreatedAtMs: now.UnixMilli(),
+		Payload:     payload,
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return d, writeWatchDelivery(q.dir, d)
+}
+
+// Drain delivers due entries in order until the queue is empty or the head
+// entry is waiting for its next attempt. It returns the number delivered.
+// Only one drain runs at a time, and mu is held just around the file reads and
+// writes, so Enqueue on the push path never waits for a slow sink.
+func (q *gmailWatchQueue) Drain(ctx context.Context, deliver func(context.Context, gmailWatchDelivery) error) (int, error) {
+	q.drainMu.Lock()
+	defer q.drainMu.Unlock()
+
+	q.mu.Lock()
+	pending, err := readWatchDeliveries(q.dir)
+	q.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, d := range pending {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		d, ok, err := q.due(d)
+		if err != nil {
+			return delivered, err
+		}
+		if !ok {
+			continue
+		}
+		now := q.now()
+		if d.NextAttemptAtMs > now.UnixMilli() {
+			return delivered, nil
+		}
+
+		deliverErr := deliver(ctx, d)
+		d.Attempts++
+		d.LastAttemptAtMs = now.UnixMilli()
+		retry, err := q.settle(d, now, deliverErr)
+		if err != nil {
+			return delivered, err
+		}
+		if retry {
+			return delivered, nil
+		}
+		if deliverErr == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// due re-reads a snapshotted entry under the lock, since queue retry or purge
+// may have changed or removed it, and dead-letters it once it has expired.
+// ok is false when the entry is gone.
+func (q *gmailWatchQueue) due(d gmailWatchDelivery) (gmailWatchDelivery, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cur, err := readWatchDelivery(q.dir, d.ID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return d, false, nil
+		}
+		return d, false, err
+	}
+	if q.now().Sub(time.UnixMilli(cur.CreatedAtMs)) > q.maxAge {
+		cur.LastError = firstNonEmpty(cur.LastError, "expired before first attempt")
+		return cur, false, q.moveToDead(cur)
+	}
+	return cur, true, nil
+}
+
+// settle records the outcome of one attempt: it removes delivered entries,
+// dead-letters permanent failures and schedules the next attempt otherwise.
+// retry reports that the head is now waiting, which stops the drain.
+func (q *gmailWatchQueue) settle(d gmailWatchDelivery, now time.Time, deliverErr error) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	path := filepath.Join(q.dir, d.ID+".json")
+	if deliverErr == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+		return false, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		// Purged while the attempt was in flight.
+		return false, nil
+	}
+	d.LastError = deliverErr.Error()
+	if isPermanentHookError(deliverErr) {
+		return false, q.moveToDead(d)
+	}
+	d.NextAttemptAtMs = now.Add(q.backoffFor(d.Attempts)).UnixMilli()
+	return true, writeWatchDelivery(q.dir, d)
+}
+
+func (q *gmailWatchQueue) backoffFor(attempts int) time.Duration {
+	wait := q.backoff
+	for i := 1; i < attempts && wait < q.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, q.maxBackoff)
+}
+
+func (q *gmailWatchQueue) moveToDead(d gmailWatchDelivery) error {
+	if err := writeWatchDelivery(q.deadDir, d); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(q.dir, d.ID+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (q *gmailWatchQueue) Pending() ([]gmailWatchDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return readWatchDeliveries(q.dir)
+}
+
+func (q *gmailWatchQueue) Dead() ([]gmailWatchDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return readWatchDeliveries(q.deadDir)
+}
+
+// Depth counts pending and dead-lettered entries from the directory listings
+// alone. It takes no lock and parses nothing, so health probes stay cheap and
+// never wait on a drain.
+func (q *gmailWatchQueue) Depth() (int, int, error) {
+	pending, err := countWatchDeliveries(q.dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	dead, err := countWatchDeliveries(q.deadDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	return pending, dead, nil
+}
+
+// Retry moves dead letters back into the outbox and makes pending entries due
+// now. Empty ids means every entry. Requeued dead letters keep their place in
+// the original order, so they are delivered before newer payloads.
+func (q *gmailWatchQueue) Retry(ids []string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	want := stringSet(ids)
+	count := 0
+	dead, err := readWatchDeliveries(q.deadDir)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := readWatchDeliveries(q.dir)
+	if err != nil {
+		return 0, err
+	}
+	for dir, entries := range map[string][]gmailWatchDelivery{q.deadDir: dead, q.dir: pending} {
+		for _, d := range entries {
+			if want != nil {
+				if _, ok := want[d.ID]; !ok {
+					continue
+				}
+			}
+			d.NextAttemptAtMs = 0
+			if dir == q.deadDir {
+				// Give the entry a fresh max-age window.
+				d.CreatedAtMs = q.now().UnixMilli()
+				d.Attempts = 0
+			}
+			if err := writeWatchDelivery(q.dir, d); err != nil {
+				return count, err
+			}
+			if dir == q.deadDir {
+				if err := os.Remove(filepath.Join(q.deadDir, d.ID+".json")); err != nil {
+					return count, err
+				}
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Purge deletes dead letters, and pending deliveries too when pending is set.
+func (q *gmailWatchQueue) Purge(ids []string, pending bool) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dirs := []string{q.deadDir}
+	if pending {
+		dirs = append(dirs, q.dir)
+	}
+	want := stringSet(ids)
+	count := 0
+	for _, dir := range dirs {
+		entries, err := readWatchDeliveries(dir)
+		if err != nil {
+			return count, err
+		}
+		for _, d := range entries {
+			if want != nil {
+				if _, ok := want[d.ID]; !ok {
+					continue
+				}
+			}
+			if err := os.Remove(filepath.Join(dir, d.ID+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func writeWatchDelivery(dir string, d gmailWatchDelivery) error {
+	// Compact encoding keeps Payload byte-for-byte what the hook receives.
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, d.ID+".json"), append(data, '\n'))
+}
+
+func countWatchDeliveries(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func readWatchDelivery(dir, id string) (gmailWatchDelivery, error) {
+	data, err := os.ReadFile(filepath.Join(dir, id+".json")) //nolint:gosec // watch state dir
+	if err != nil {
+		return gmailWatchDelivery{}, err
+	}
+	var d gmailWatchDelivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return gmailWatchDelivery{}, fmt.Errorf("read %s.json: %w", id, err)
+	}
+	if d.ID == "" {
+		d.ID = id
+	}
+	return d, nil
+}
+
+func readWatchDeliveries(dir string) ([]gmailWatchDelivery, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := make([]gmailWatchDelivery, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		d, err := readWatchDelivery(dir, strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// openGmailWatchQueues opens the queue of the named sink, or of every sink
+// that has an outbox or dead-letter directory when sink is empty.
+func openGmailWatchQueues(account, sink string) ([]*gmailWatchQueue, error) {
+	if sink = strings.ToLower(strings.TrimSpace(sink)); sink != "" {
+		if err := checkGmailWatchQueueSink(account, sink); err != nil {
+			return nil, err
+		}
+		q, err := openGmailWatchQueue(account, sink, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []*gmailWatchQueue{q}, nil
+	}
+	dir, deadDir, err := gmailWatchQueueDirs(account)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, d := range []string{dir, deadDir} {
+		entries, err := os.ReadDir(d)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				names = appendUnique(names, e.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	queues := make([]*gmailWatchQueue, 0, len(names))
+	for _, name := range names {
+		q, err := openGmailWatchQueue(account, name, 0)
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, q)
+	}
+	return queues, nil
+}
+
+// checkGmailWatchQueueSink rejects a --sink that is not a valid sink name, so
+// it can't reach outside the queue directory, or that names no known queue:
+// the --hook-url queue, a configured sink, or one with queued deliveries left.
+func checkGmailWatchQueueSink(account, sink string) error {
+	if !watchSinkNamePattern.MatchString(sink) {
+		return usagef("invalid sink name %q (lowercase letters, digits, - and _)", sink)
+	}
+	if sink == watchHookSinkName {
+		return nil
+	}
+	store, err := loadGmailWatchStore(account)
+	if err != nil && !errors.Is(err, errWatchStateNotFound) {
+		return err
+	}
+	if store != nil {
+		for _, configured := range store.Get().Sinks {
+			if configured.Name == sink {
+				return nil
+			}
+		}
+	}
+	dir, deadDir, err := gmailWatchQueueDirs(account)
+	if err != nil {
+		return err
+	}
+	for _, d := range []string{dir, deadDir} {
+		if st, statErr := os.Stat(filepath.Join(d, sink)); statErr == nil && st.IsDir() {
+			return nil
+		}
+	}
+	return usagef("unknown sink %q (see gmail watch sinks list)", sink)
+}
+
+// hookStatusError is a non-2xx hook response.
+type hookStatusError struct {
+	StatusCode int
+}
+
+func (e *hookStatusError) Error() string {
+	return fmt.Sprintf("hook status %d", e.StatusCode)
+}
+
+// isPermanentHookError reports client errors that a retry won't fix; timeouts
+// and rate limits are retried like server errors.
+func isPermanentHookError(err error) bool {
+	var statusErr *hookStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	code := statusErr.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+type GmailWatchQueueCmd struct {
+	List  GmailWatchQueueListCmd  `cmd:"" name:"list" aliases:"ls" help:"List pending and dead-lettered hook deliveries"`
+	Retry GmailWatchQueueRetryCmd `cmd:"" name:"retry" help:"Requeue dead letters and make pending deliveries due now"`
+	Purge GmailWatchQueuePurgeCmd `cmd:"" name:"purge" help:"Delete dead letters (and pending deliveries with --pending)"`
+}
+
+type GmailWatchQueueListCmd struct {
+	Sink    string `name:"sink" help:"Only this sink's deliveries"`
+	Dead    bool   `name:"dead" help:"Only dead letters"`
+	Pending bool   `name:"pending" help:"Only pending deliveries"`
+}
+
+type watchQueueItem struct {
+	gmailWatchDelivery
+	State string `json:"state"`
+}
+
+func (c *GmailWatchQueueListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	queues, err := openGmailWatchQueues(account, c.Sink)
+	if err != nil {
+		return err
+	}
+	var items []watchQueueItem
+	for _, queue := range queues {
+		if !c.Dead {
+			pending, err := queue.Pending()
+			if err != nil {
+				return err
+			}
+			for _, d := range pending {
+				items = append(items, watchQueueItem{gmailWatchDelivery: d, State: "pending"})
+			}
+		}
+		if !c.Pending {
+			dead, err := queue.Dead()
+			if err != nil {
+				return err
+			}
+			for _, d := range dead {
+				items = append(items, watchQueueItem{gmailWatchDelivery: d, State: "dead"})
+			}
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if items == nil {
+			items = []watchQueueItem{}
+		}
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"deliveries": items})
+	}
+	if len(items) == 0 {
+		ui.FromContext(ctx).Err().Println("No queued deliveries")
+		return nil
+	}
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "ID\tSINK\tSTATE\tATTEMPTS\tCREATED\tNEXT ATTEMPT\tLAST ERROR")
+	for _, it := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", it.ID, it.Sink, it.State, it.Attempts,
+			formatUnixMillis(it.CreatedAtMs), formatUnixMillis(it.NextAttemptAtMs), sanitizeTab(it.LastError))
+	}
+	return nil
+}
+
+type GmailWatchQueueRetryCmd struct {
+	IDs  []string `arg:"" name:"id" optional:"" help:"Delivery IDs"`
+	All  bool     `name:"all" help:"Retry every dead letter and pending delivery"`
+	Sink string   `name:"sink" help:"Only this sink's deliveries"`
+}
+
+func (c *GmailWatchQueueRetryCmd) Run(ctx context.Context, flags *RootFlags) error {
+	if len(c.IDs) == 0 && !c.All {
+		return usage("pass delivery IDs or --all")
+	}
+	if err := dryRunExit(ctx, flags, "gmail.watch.queue.retry", map[string]any{"ids": c.IDs, "all": c.All}); err != nil {
+		return err
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	queues, err := openGmailWatchQueues(account, c.Sink)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, queue := range queues {
+		count, err := queue.Retry(c.IDs)
+		n += count
+		if err != nil {
+			return err
+		}
+	}
+	if len(c.IDs) > 0 && n == 0 {
+		return usage("no matching deliveries")
+	}
+	return writeResult(ctx, ui.FromContext(ctx), kv("requeued", n))
+}
+
+type GmailWatchQueuePurgeCmd struct {
+	IDs     []string `arg:"" name:"id" optional:"" help:"Delivery IDs (default: all dead letters)"`
+	Pending bool     `name:"pending" help:"Also delete pending deliveries (they will never be sent)"`
+	Sink    string   `name:"sink" help:"Only this sink's deliveries"`
+}
+
+func (c *GmailWatchQueuePurgeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	action := "delete dead-lettered gmail watch deliveries"
+	if c.Pending {
+		action = "delete pending and dead-lettered gmail watch deliveries"
+	}
+	if err := confirmDestructive(ctx, flags, action); err != nil {
+		return err
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	queues, err := openGmailWatchQueues(account, c.Sink)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, queue := range queues {
+		count, err := queue.Purge(c.IDs, c.Pending)
+		n += count
+		if err != nil {
+			return err
+		}
+	}
+	return writeResult(ctx, ui.FromContext(ctx), kv("purged", n))
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGmailWatchQueueOrderingBackoffAndDeadLetter(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	q, err := openGmailWatchQueue("a@b.com", watchHookSinkName, time.Hour)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	for _, body := range []string{`{"n":1}`, `{"n":2}`} {
+		if _, err := q.Enqueue("a@b.com", []byte(body)); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	var sent []string
+	fail := true
+	deliver := func(_ context.Context, d gmailWatchDelivery) error {
+		if fail {
+			return &hookStatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		sent = append(sent, string(d.Payload))
+		return nil
+	}
+
+	// The failing head blocks the second delivery.
+	if n, err := q.Drain(context.Background(), deliver); err != nil || n != 0 {
+		t.Fatalf("drain: n=%d err=%v", n, err)
+	}
+	pending, _ := q.Pending()
+	if len(pending) != 2 || pending[0].Attempts != 1 || pending[1].Attempts != 0 {
+		t.Fatalf("unexpected pending: %+v", pending)
+	}
+	if got := time.UnixMilli(pending[0].NextAttemptAtMs).Sub(now); got != defaultWatchQueueBackoff {
+		t.Fatalf("unexpected backoff %s", got)
+	}
+
+	// Not due yet: nothing is attempted.
+	fail = false
+	if n, _ := q.Drain(context.Background(), deliver); n != 0 || len(sent) != 0 {
+		t.Fatalf("delivered before backoff elapsed")
+	}
+	now = now.Add(time.Minute)
+	if n, err := q.Drain(context.Background(), deliver); err != nil || n != 2 {
+		t.Fatalf("drain after backoff: n=%d err=%v", n, err)
+	}
+	if strings.Join(sent, ",") != `{"n":1},{"n":2}` {
+		t.Fatalf("unexpected order: %v", sent)
+	}
+
+	// Client errors are dead-lettered right away; old entries expire.
+	if _, err := q.Enqueue("a@b.com", []byte(`{"n":3}`)); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := q.Drain(context.Background(), func(context.Context, gmailWatchDelivery) error {
+		return &hookStatusError{StatusCode: http.StatusBadRequest}
+	}); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if _, err := q.Enqueue("a@b.com", []byte(`{"n":4}`)); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := q.Drain(context.Background(), deliver); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	dead, _ := q.Dead()
+	if len(dead) != 2 || dead[0].LastError != "hook status 400" || dead[1].LastError != "expired before first attempt" {
+		t.Fatalf("unexpected dead letters: %+v", dead)
+	}
+
+	if n, err := q.Retry([]string{dead[0].ID}); err != nil || n != 1 {
+		t.Fatalf("retry: n=%d err=%v", n, err)
+	}
+	if n, err := q.Drain(context.Background(), deliver); err != nil || n != 1 || sent[len(sent)-1] != `{"n":3}` {
+		t.Fatalf("retried delivery: n=%d err=%v sent=%v", n, err, sent)
+	}
+	if n, err := q.Purge(nil, false); err != nil || n != 1 {
+		t.Fatalf("purge: n=%d err=%v", n, err)
+	}
+}
+
+func TestGmailWatchQueueEnqueueDuringDrain(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	q, err := openGmailWatchQueue("a@b.com", watchHookSinkName, time.Hour)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := q.Enqueue("a@b.com", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int)
+	go func() {
+		n, _ := q.Drain(context.Background(), func(context.Context, gmailWatchDelivery) error {
+			close(started)
+			<-release
+			return nil
+		})
+		done <- n
+	}()
+	<-started
+
+	// A slow sink must not hold up the push path or status reads.
+	enqueued := make(chan error)
+	go func() {
+		_, err := q.Enqueue("a@b.com", []byte(`{"n":2}`))
+		if err == nil {
+			_, err = q.Pending()
+		}
+		enqueued <- err
+	}()
+	select {
+	case err := <-enqueued:
+		if err != nil {
+			t.Fatalf("enqueue during drain: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("enqueue blocked behind an in-flight delivery")
+	}
+	close(release)
+	if n := <-done; n != 1 {
+		t.Fatalf("expected the snapshotted entry to be delivered, got %d", n)
+	}
+	if pending, _ := q.Pending(); len(pending) != 1 || string(pending[0].Payload) != `{"n":2}` {
+		t.Fatalf("unexpected pending after drain: %+v", pending)
+	}
+}
+
+func TestGmailWatchServerQueuesFailedHooks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var (
+		mu     sync.Mutex
+		status = http.StatusBadGateway
+		bodies []string
+	)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if status == http.StatusOK {
+			bodies = append(bodies, string(b))
+		}
+		w.WriteHeader(status)
+	}))
+	defer hookSrv.Close()
+
+	store, err := newGmailWatchStore("a@b.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	q, err := openGmailWatchQueue("a@b.com", watchHookSinkName, 0)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "a@b.com", HookURL: hookSrv.URL},
+		store:      store,
+		hookClient: hookSrv.Client(),
+		logf:       func(string, ...any) {},
+		warnf:      func(string, ...any) {},
+	}
+	server.sinks = []*watchSinkRunner{{
+		sink: gmailWatchSink{Name: watchHookSinkName, Target: hookSrv.URL},
+		deliver: func(ctx context.Context, d gmailWatchDelivery) error {
+			return postWatchHook(ctx, server.hookClient, hookSrv.URL, "", "", d)
+		},
+		queue: q,
+	}}
+
+	server.dispatch(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "7"})
+	if pending, _ := q.Pending(); len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Fatalf("dispatch should only enqueue: %+v", pending)
+	}
+	server.drainSinks(context.Background())
+	pending, _ := q.Pending()
+	if len(pending) != 1 || store.Get().LastDeliveryStatus != gmailWatchStatusHTTPError {
+		t.Fatalf("expected queued delivery after hook failure: %+v", pending)
+	}
+
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+	q.now = func() time.Time { return time.Now().Add(time.Minute) }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.runQueue(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if pending, _ = q.Pending(); len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue never drained")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	var payload gmailHookPayload
+	if len(bodies) != 1 || json.Unmarshal([]byte(bodies[0]), &payload) != nil || payload.HistoryID != "7" {
+		t.Fatalf("unexpected redelivery: %v", bodies)
+	}
+	if store.Get().LastDeliveryStatus != "ok" {
+		t.Fatalf("expected ok status, got %q", store.Get().LastDeliveryStatus)
+	}
+}
+
+func TestGmailWatchQueueCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	q, err := openGmailWatchQueue("me@example.com", watchHookSinkName, 0)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	d, err := q.Enqueue("me@example.com", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	d.LastError = "hook status 404"
+	if err := q.moveToDead(d); err != nil {
+		t.Fatalf("dead: %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "watch", "queue", "list"}); execErr != nil {
+			t.Fatalf("list: %v", execErr)
+		}
+	})
+	var listed struct {
+		Deliveries []watchQueueItem `json:"deliveries"`
+	}
+	if err := json.Unmarshal([]byte(out), &listed); err != nil || len(listed.Deliveries) != 1 || listed.Deliveries[0].State != "dead" {
+		t.Fatalf("unexpected list: %v %s", err, out)
+	}
+
+	// --sink must name a known queue; anything else is rejected before a directory is created.
+	for _, sink := range []string{"../../x", "nosuch"} {
+		if err := Execute([]string{"--account", "me@example.com", "gmail", "watch", "queue", "list", "--sink", sink}); ExitCode(err) != 2 {
+			t.Fatalf("expected usage error for --sink %q, got %v", sink, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(q.dir), "nosuch")); !os.IsNotExist(err) {
+		t.Fatalf("unknown sink created a queue dir: %v", err)
+	}
+
+	if err := Execute([]string{"--account", "me@example.com", "gmail", "watch", "queue", "retry"}); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error without ids, got %v", err)
+	}
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--account", "me@example.com", "gmail", "watch", "queue", "retry", d.ID}); execErr != nil {
+			t.Fatalf("retry: %v", execErr)
+		}
+	})
+	if pending, _ := q.Pending(); len(pending) != 1 || pending[0].ID != d.ID {
+		t.Fatalf("retry did not requeue: %+v", pending)
+	}
+
+	err = Execute([]string{"--account", "me@example.com", "--no-input", "gmail", "watch", "queue", "purge", "--pending"})
+	if ExitCode(err) != 2 {
+		t.Fatalf("expected refusal without --force, got %v", err)
+	}
+	_ = captureStdout(t, func() {
+		if execErr := Execute([]string{"--json", "--force", "--account", "me@example.com", "gmail", "watch", "queue", "purge", "--pending"}); execErr != nil {
+			t.Fatalf("purge: %v", execErr)
+		}
+	})
+	if pending, _ := q.Pending(); len(pending) != 0 {
+		t.Fatalf("purge left pending: %+v", pending)
+	}
+}
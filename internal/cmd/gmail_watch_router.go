@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+const watchHealthPath = "/healthz"
+
+// gmailWatchStats counts activity since the server started.
+type gmailWatchStats struct {
+	pushes       atomic.Int64
+	delivered    atomic.Int64
+	failed       atomic.Int64
+	lastPushAtMs atomic.Int64
+}
+
+// gmailWatchRouter serves pushes for several accounts on one endpoint,
+// routing each push by its emailAddress. Every server shares the same path
+// and auth settings.
+type gmailWatchRouter struct {
+	servers   []*gmailWatchServer
+	byAccount map[string]*gmailWatchServer
+}
+
+func newGmailWatchRouter(servers []*gmailWatchServer) *gmailWatchRouter {
+	rt := &gmailWatchRouter{servers: servers, byAccount: make(map[string]*gmailWatchServer, len(servers))}
+	for _, s := range servers {
+		rt.byAccount[strings.ToLower(s.cfg.Account)] = s
+	}
+	return rt
+}
+
+func (rt *gmailWatchRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	front := rt.servers[0]
+	if isWatchHealthRequest(r) {
+		front.serveHealth(w, r, rt.servers)
+		return
+	}
+	payload, ok := front.readPush(w, r)
+	if !ok {
+		return
+	}
+	s := rt.byAccount[strings.ToLower(strings.TrimSpace(payload.EmailAddress))]
+	if s == nil {
+		front.warnf("watch: ignoring push for unknown account %q", payload.EmailAddress)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	s.servePush(w, r, payload)
+}
+
+func isWatchHealthRequest(r *http.Request) bool {
+	return r.URL.Path == watchHealthPath && (r.Method == http.MethodGet || r.Method == http.MethodHead)
+}
+
+type watchAccountHealth struct {
+	Account                string `json:"account"`
+	HistoryID              string `json:"historyId,omitempty"`
+	WatchExpiration        string `json:"watchExpiration,omitempty"`
+	WatchExpired           bool   `json:"watchExpired,omitempty"`
+	LastPushAt             string `json:"lastPushAt,omitempty"`
+	LastDeliveryStatus     string `json:"lastDeliveryStatus,omitempty"`
+	LastDeliveryAt         string `json:"lastDeliveryAt,omitempty"`
+	LastDeliveryStatusNote string `json:"lastDeliveryStatusNote,omitempty"`
+	Pushes                 int64  `json:"pushes"`
+	Delivered              int64  `json:"delivered"`
+	Failed                 int64  `json:"failed"`
+	Pending                int    `json:"pending"`
+	Dead                   int    `json:"dead"`
+}
+
+// health summarizes the account's stored state, counters since startup and
+// queue depth across its sinks.
+func (s *gmailWatchServer) health(now time.Time) watchAccountHealth {
+	state := s.store.Get()
+	h := watchAccountHealth{
+		Account:                s.cfg.Account,
+		HistoryID:              state.HistoryID,
+		WatchExpiration:        formatUnixMillis(state.ExpirationMs),
+		WatchExpired:           state.ExpirationMs > 0 && now.UnixMilli() > state.ExpirationMs,
+		LastPushAt:             formatUnixMillis(s.stats.lastPushAtMs.Load()),
+		LastDeliveryStatus:     state.LastDeliveryStatus,
+		LastDeliveryAt:         formatUnixMillis(state.LastDeliveryAtMs),
+		LastDeliveryStatusNote: state.LastDeliveryStatusNote,
+		Pushes:                 s.stats.pushes.Load(),
+		Delivered:              s.stats.delivered.Load(),
+		Failed:                 s.stats.failed.Load(),
+	}
+	for _, r := range s.sinks {
+		if r.queue == nil {
+			continue
+		}
+		if pending, dead, err := r.queue.Depth(); err == nil {
+			h.Pending += pending
+			h.Dead += dead
+		}
+	}
+	return h
+}
+
+// serveHealth reports every account. The overall status is "degraded" when a
+// watch has expired or an account's last delivery failed; the response code
+// stays 200 so probes only fail when the process is down. Requests must pass
+// the same OIDC or --token check as pushes; without either configured, only
+// the overall status is reported, since account details would be public.
+func (s *gmailWatchServer) serveHealth(w http.ResponseWriter, r *http.Request, servers []*gmailWatchServer) {
+	if !s.authorize(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	detailed := s.cfg.VerifyOIDC || s.cfg.SharedToken != ""
+	now := time.Now()
+	status := "ok"
+	accounts := make([]watchAccountHealth, 0, len(servers))
+	for _, srv := range servers {
+		h := srv.health(now)
+		if h.WatchExpired || (h.LastDeliveryStatus != "" && h.LastDeliveryStatus != "ok") {
+			status = "degraded"
+		}
+		accounts = append(accounts, h)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if !detailed {
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": status})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "accounts": accounts})
+}
+
+// listWatchedAccounts returns the accounts that have stored watch state.
+func listWatchedAccounts() ([]string, error) {
+	dir, err := config.GmailWatchDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var accounts []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path) //nolint:gosec // path is inside the gog state dir
+		if err != nil {
+			return nil, err
+		}
+		var state gmailWatchState
+		if err := json.Unmarshal(data, &state); err != nil || strings.TrimSpace(state.Account) == "" {
+			continue
+		}
+		accounts = appendUnique(accounts, strings.ToLower(strings.TrimSpace(state.Account)))
+	}
+	sort.Strings(accounts)
+	return accounts, nil
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestGmailWatchServeMultiAccount(t *testing.T) {
+	origListen := listenAndServe
+	t.Cleanup(func() { listenAndServe = origListen })
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	sinkPath := filepath.Join(t.TempDir(), "c.ndjson")
+	for account, sinks := range map[string][]gmailWatchSink{
+		"a@b.com": nil,
+		"c@d.com": {{Name: "audit", Target: "file:" + sinkPath}},
+	} {
+		store, err := newGmailWatchStore(account)
+		if err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		if err := store.Update(func(s *gmailWatchState) error {
+			s.Account = account
+			s.HistoryID = "100"
+			s.Sinks = sinks
+			return nil
+		}); err != nil {
+			t.Fatalf("seed %s: %v", account, err)
+		}
+	}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+		w.Header().Set("Content-Type", "application/json")
+		switch path {
+		case "/history":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"historyId": "200",
+				"history": []map[string]any{
+					{"messagesAdded": []map[string]any{{"message": map[string]any{"id": "m1"}}}},
+				},
+			})
+		case "/messages/m1":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1", "labelIds": []string{"INBOX"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(api.Client()),
+		option.WithEndpoint(api.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	var (
+		mu           sync.Mutex
+		serviceCalls []string
+	)
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(_ context.Context, account string) (*gmail.Service, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		serviceCalls = append(serviceCalls, account)
+		return svc, nil
+	}
+
+	var handler http.Handler
+	listenAndServe = func(srv *http.Server) error {
+		handler = srv.Handler
+		return nil
+	}
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := ui.WithUI(context.Background(), u)
+	if err := runKong(t, &GmailWatchServeCmd{}, []string{"--all-accounts", "--path", "/push", "--token", "tok"}, ctx, &RootFlags{}); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	router, ok := handler.(*gmailWatchRouter)
+	if !ok || len(router.servers) != 2 {
+		t.Fatalf("expected a router for two accounts, got %T", handler)
+	}
+
+	push := func(email string) int {
+		data := base64.StdEncoding.EncodeToString([]byte(`{"emailAddress":"` + email + `","historyId":"200"}`))
+		body := `{"message":{"data":"` + data + `","messageId":"p-` + email + `"}}`
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/push?token=tok", bytes.NewBufferString(body)))
+		return rec.Code
+	}
+	if code := push("C@D.com"); code != http.StatusOK {
+		t.Fatalf("push for c@d.com: status %d", code)
+	}
+	if code := push("x@y.com"); code != http.StatusAccepted {
+		t.Fatalf("push for unknown account: status %d", code)
+	}
+	// Pushes only enqueue; deliver as the queue loop would.
+	for _, s := range router.servers {
+		s.drainSinks(context.Background())
+	}
+
+	if data, err := os.ReadFile(sinkPath); err != nil || !strings.Contains(string(data), `"account":"c@d.com"`) {
+		t.Fatalf("unexpected sink contents: %q (%v)", data, err)
+	}
+	mu.Lock()
+	if len(serviceCalls) != 1 || serviceCalls[0] != "c@d.com" {
+		t.Fatalf("expected one API client for c@d.com, got %v", serviceCalls)
+	}
+	mu.Unlock()
+	for account, want := range map[string]string{"a@b.com": "100", "c@d.com": "200"} {
+		store, err := loadGmailWatchStore(account)
+		if err != nil || store.Get().HistoryID != want {
+			t.Fatalf("%s: expected historyId %s, got %+v (%v)", account, want, store.Get(), err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for health without token, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz?token=tok", nil))
+	var health struct {
+		Status   string               `json:"status"`
+		Accounts []watchAccountHealth `json:"accounts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("health: %v (%s)", err, rec.Body.String())
+	}
+	if health.Status != "ok" || len(health.Accounts) != 2 {
+		t.Fatalf("unexpected health: %+v", health)
+	}
+	c := health.Accounts[1]
+	if c.Account != "c@d.com" || c.Pushes != 1 || c.Delivered != 1 || c.LastDeliveryStatus != "ok" || c.LastPushAt == "" || c.HistoryID != "200" {
+		t.Fatalf("unexpected account health: %+v", c)
+	}
+	if a := health.Accounts[0]; a.Pushes != 0 || a.LastDeliveryStatus != "" {
+		t.Fatalf("unexpected account health: %+v", a)
+	}
+
+	if err := runKong(t, &GmailWatchServeCmd{}, []string{"--all-accounts", "--accounts", "a@b.com"}, ctx, &RootFlags{}); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestGmailWatchServerHealthRequiresAuth(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	store, err := newGmailWatchStore("a@b.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	server := &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "a@b.com", Path: "/", SharedToken: "tok"},
+		store: store,
+		logf:  func(string, ...any) {},
+		warnf: func(string, ...any) {},
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz?token=tok", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"account":"a@b.com"`) {
+		t.Fatalf("unexpected health response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// OIDC alone also guards health; with no auth at all only the status is public.
+	server.cfg.SharedToken = ""
+	server.cfg.VerifyOIDC = true
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an OIDC token, got %d", rec.Code)
+	}
+	server.cfg.VerifyOIDC = false
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"status":"ok"}` {
+		t.Fatalf("unexpected unauthenticated health response %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGmailWatchServerHealthDuringDrain(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	store, err := newGmailWatchStore("a@b.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	q, err := openGmailWatchQueue("a@b.com", watchHookSinkName, 0)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	for _, body := range []string{`{"n":1}`, `{"n":2}`} {
+		if _, err := q.Enqueue("a@b.com", []byte(body)); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	server := &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "a@b.com", Path: "/", SharedToken: "tok"},
+		store: store,
+		sinks: []*watchSinkRunner{{sink: gmailWatchSink{Name: watchHookSinkName}, queue: q}},
+		logf:  func(string, ...any) {},
+		warnf: func(string, ...any) {},
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	drained := make(chan struct{})
+	defer func() {
+		close(release)
+		<-drained
+	}()
+	go func() {
+		defer close(drained)
+		_, _ = q.Drain(context.Background(), func(context.Context, gmailWatchDelivery) error {
+			select {
+			case <-started:
+			default:
+				close(started)
+			}
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// The probe must answer while the hook target is hanging.
+	got := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz?token=tok", nil))
+		got <- rec
+	}()
+	select {
+	case rec := <-got:
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"pending":2`) {
+			t.Fatalf("unexpected health response %d: %s", rec.Code, rec.Body.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("/healthz blocked behind an in-flight delivery")
+	}
+}
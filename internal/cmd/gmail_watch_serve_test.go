@@ -4,6 +4,8 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -183,6 +185,11 @@ func TestGmailWatchServeCmd_SaveHookAndOIDC(t *testing.T) {
 		t.Fatalf("seed: %v", updateErr)
 	}
 
+	secretPath := filepath.Join(t.TempDir(), "hook-secret")
+	if writeErr := os.WriteFile(secretPath, []byte("s3cret\n"), 0o600); writeErr != nil {
+		t.Fatalf("write secret: %v", writeErr)
+	}
+
 	flags := &RootFlags{Account: "a@b.com"}
 	var got *gmailWatchServer
 	listenAndServe = func(srv *http.Server) error {
@@ -205,6 +212,7 @@ func TestGmailWatchServeCmd_SaveHookAndOIDC(t *testing.T) {
 		"--verify-oidc",
 		"--hook-url", "http://example.com/hook",
 		"--hook-token", "tok",
+		"--hook-secret-file", secretPath,
 		"--include-body",
 		"--max-bytes", "10",
 		"--save-hook",
@@ -219,6 +227,9 @@ func TestGmailWatchServeCmd_SaveHookAndOIDC(t *testing.T) {
 	if err != nil {
 		t.Fatalf("load: %v", err)
 	}
+	if got.cfg.HookSecret != "s3cret" {
+		t.Fatalf("expected the secret from --hook-secret-file, got %q", got.cfg.HookSecret)
+	}
 	if loaded.Get().Hook == nil || loaded.Get().Hook.URL != "http://example.com/hook" {
 		t.Fatalf("expected hook saved, got %#v", loaded.Get().Hook)
 	}
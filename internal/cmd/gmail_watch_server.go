@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
 	"crypto/subtle"
 	"encoding/base64"
@@ -11,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/api/gmail/v1"
@@ -31,44 +31,71 @@ type gmailWatchServer struct {
 	validator       *idtoken.Validator
 	newService      func(context.Context, string) (*gmail.Service, error)
 	hookClient      *http.Client
+	sinks           []*watchSinkRunner
+	queueWake       chan struct{} // set by startQueue; wakes runQueue for new deliveries
+	rules           []*gmailRule
+	ruleMu          sync.Mutex
+	rulePending     []string      // added message IDs waiting for the rules worker
+	ruleWake        chan struct{} // set by startRules
+	stats           gmailWatchStats
 	excludeLabelIDs map[string]struct{}
 	logf            func(string, ...any)
 	warnf           func(string, ...any)
 }
 
 func (s *gmailWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWatchHealthRequest(r) {
+		s.serveHealth(w, r, []*gmailWatchServer{s})
+		return
+	}
+	payload, ok := s.readPush(w, r)
+	if !ok {
+		return
+	}
+	if payload.EmailAddress != "" && !strings.EqualFold(payload.EmailAddress, s.cfg.Account) {
+		s.warnf("watch: ignoring push for %s", payload.EmailAddress)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	s.servePush(w, r, payload)
+}
+
+// readPush checks path, method and auth and decodes the Pub/Sub push. It
+// writes the error response and returns false when the request is rejected.
+func (s *gmailWatchServer) readPush(w http.ResponseWriter, r *http.Request) (gmailPushPayload, bool) {
 	if !pathMatches(s.cfg.Path, r.URL.Path) {
 		w.WriteHeader(http.StatusNotFound)
-		return
+		return gmailPushPayload{}, false
 	}
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", http.MethodPost)
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
+		return gmailPushPayload{}, false
 	}
 	if ok := s.authorize(r); !ok {
 		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return gmailPushPayload{}, false
 	}
 
 	push, err := parsePubSubPush(r)
 	if err != nil {
 		s.warnf("watch: invalid push payload: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
-		return
+		return gmailPushPayload{}, false
 	}
 	payload, err := decodeGmailPushPayload(push)
 	if err != nil {
 		s.warnf("watch: invalid push data: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	if payload.EmailAddress != "" && !strings.EqualFold(payload.EmailAddress, s.cfg.Account) {
-		s.warnf("watch: ignoring push for %s", payload.EmailAddress)
-		w.WriteHeader(http.StatusAccepted)
-		return
+		return gmailPushPayload{}, false
 	}
+	return payload, true
+}
 
+// servePush processes a push addressed to this server's account.
+func (s *gmailWatchServer) servePush(w http.ResponseWriter, r *http.Request, payload gmailPushPayload) {
+	s.stats.pushes.Add(1)
+	s.stats.lastPushAtMs.Store(time.Now().UnixMilli())
 	result, err := s.handlePush(r.Context(), payload)
 	if err != nil {
 		if errors.Is(err, errNoNewMessages) {
@@ -84,6 +111,11 @@ func (s *gmailWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(s.sinks) > 0 {
+		s.dispatch(r.Context(), result)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 	if s.cfg.HookURL == "" {
 		if s.cfg.AllowNoHook {
 			_ = json.NewEncoder(w).Encode(result)
@@ -177,12 +209,7 @@ func (s *gmailWatchServer) handlePush(ctx context.Context, payload gmailPushPayl
 		return nil, err
 	}
 
-	historyCall := svc.Users.History.List("me").StartHistoryId(startID).MaxResults(s.cfg.HistoryMax)
-	if len(s.cfg.HistoryTypes) > 0 {
-		historyCall.HistoryTypes(s.cfg.HistoryTypes...)
-	}
-
-	historyResp, err := historyCall.Do()
+	historyResp, err := s.listHistory(ctx, svc, startID)
 	if err != nil {
 		if isStaleHistoryError(err) {
 			return s.resyncHistory(ctx, svc, payload.HistoryID, payload.MessageID)
@@ -213,6 +240,7 @@ func (s *gmailWatchServer) handlePush(ctx context.Context, payload gmailPushPayl
 	}); err != nil {
 		s.warnf("watch: failed to update state: %v", err)
 	}
+	s.queueRules(ctx, svc, historyIDs.AddedIDs)
 
 	if excluded > 0 && len(msgs) == 0 {
 		if s.cfg.VerboseOutput {
@@ -230,7 +258,52 @@ func (s *gmailWatchServer) handlePush(ctx context.Context, payload gmailPushPayl
 	}, nil
 }
 
+// listHistory reads every history page after startID into one response.
+// If a later page fails, the records read so far are kept and HistoryId is
+// the last record consumed, so the next push or poll resumes after it.
+func (s *gmailWatchServer) listHistory(ctx context.Context, svc *gmail.Service, startID uint64) (*gmail.ListHistoryResponse, error) {
+	merged := &gmail.ListHistoryResponse{}
+	pageToken := ""
+	for {
+		call := svc.Users.History.List("me").StartHistoryId(startID).MaxResults(s.cfg.HistoryMax)
+		if len(s.cfg.HistoryTypes) > 0 {
+			call = call.HistoryTypes(s.cfg.HistoryTypes...)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Context(ctx).Do()
+		if err != nil {
+			if pageToken == "" {
+				return nil, err
+			}
+			s.warnf("watch: history page failed, resuming after the records read so far: %v", err)
+			merged.HistoryId = startID
+			for _, h := range merged.History {
+				if h != nil && h.Id > merged.HistoryId {
+					merged.HistoryId = h.Id
+				}
+			}
+			return merged, nil
+		}
+		merged.History = append(merged.History, resp.History...)
+		if resp.NextPageToken == "" {
+			merged.HistoryId = resp.HistoryId
+			return merged, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
 func (s *gmailWatchServer) resyncHistory(ctx context.Context, svc *gmail.Service, historyID string, messageID string) (*gmailHookPayload, error) {
+	if historyID == "" {
+		// Polling has no push historyId to resume from; restart at the mailbox's current one.
+		profile, err := svc.Users.GetProfile("me").Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		historyID = formatHistoryID(profile.HistoryId)
+	}
 	list, err := svc.Users.Messages.List("me").MaxResults(s.cfg.ResyncMax).Do()
 	if err != nil {
 		return nil, err
@@ -339,41 +412,14 @@ func (s *gmailWatchServer) sendHook(ctx context.Context, payload *gmailHookPaylo
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.HookURL, bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if s.cfg.HookToken != "" {
-		req.Header.Set("Authorization", "Bearer "+s.cfg.HookToken)
-	}
-	resp, err := s.hookClient.Do(req) //nolint:gosec // hook URL is explicit user configuration
-	if err != nil {
-		_ = s.store.Update(func(state *gmailWatchState) error {
-			state.LastDeliveryStatus = "error"
-			state.LastDeliveryAtMs = time.Now().UnixMilli()
-			state.LastDeliveryStatusNote = err.Error()
-			return nil
-		})
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		_ = s.store.Update(func(state *gmailWatchState) error {
-			state.LastDeliveryStatus = gmailWatchStatusHTTPError
-			state.LastDeliveryAtMs = time.Now().UnixMilli()
-			state.LastDeliveryStatusNote = fmt.Sprintf("status %d", resp.StatusCode)
-			return nil
-		})
-		return fmt.Errorf("hook status %d", resp.StatusCode)
-	}
-	_ = s.store.Update(func(state *gmailWatchState) error {
-		state.LastDeliveryStatus = "ok"
-		state.LastDeliveryAtMs = time.Now().UnixMilli()
-		state.LastDeliveryStatusNote = ""
-		return nil
-	})
-	return nil
+	return s.postHook(ctx, data)
+}
+
+func (s *gmailWatchServer) postHook(ctx context.Context, data []byte) error {
+	d := gmailWatchDelivery{ID: newWatchDeliveryID(time.Now()), Payload: data}
+	err := postWatchHook(ctx, s.hookClient, s.cfg.HookURL, s.cfg.HookToken, s.cfg.HookSecret, d)
+	s.recordDelivery(watchHookSinkName, err)
+	return err
 }
 
 func parsePubSubPush(r *http.Request) (*pubsubPushEnvelope, error) {
@@ -521,9 +567,11 @@ func isNotFoundAPIError(err error) bool {
 // historyMessageIDs holds the result of collecting message IDs from history.
 // FetchIDs contains messages that should be fetched (added, label changes, etc.).
 // DeletedIDs contains messages that were deleted and cannot be fetched.
+// AddedIDs is the subset of FetchIDs that arrived as new messages.
 type historyMessageIDs struct {
 	FetchIDs   []string
 	DeletedIDs []string
+	AddedIDs   []string
 }
 
 func collectHistoryMessageIDs(resp *gmail.ListHistoryResponse) historyMessageIDs {
@@ -532,6 +580,7 @@ func collectHistoryMessageIDs(resp *gmail.ListHistoryResponse) historyMessageIDs
 	}
 	fetchIdx := make(map[string]int)
 	seenDeleted := make(map[string]struct{})
+	seenAdded := make(map[string]struct{})
 	var result historyMessageIDs
 
 	addFetch := func(id string) {
@@ -582,6 +631,7 @@ func collectHistoryMessageIDs(resp *gmail.ListHistoryResponse) historyMessageIDs
 				continue
 			}
 			addFetch(messageID(added.Message))
+			seenAdded[messageID(added.Message)] = struct{}{}
 		}
 		for _, deleted := range h.MessagesDeleted {
 			if deleted == nil {
@@ -614,5 +664,10 @@ func collectHistoryMessageIDs(resp *gmail.ListHistoryResponse) historyMessageIDs
 		}
 		result.FetchIDs = compacted
 	}
+	for _, id := range result.FetchIDs {
+		if _, ok := seenAdded[id]; ok {
+			result.AddedIDs = append(result.AddedIDs, id)
+		}
+	}
 	return result
 }
@@ -96,6 +96,11 @@ func TestCollectHistoryMessageIDs(t *testing.T) {
 	if len(result.DeletedIDs) != 1 || result.DeletedIDs[0] != "m4" {
 		t.Fatalf("expected deleted ids [m4], got: %v", result.DeletedIDs)
 	}
+
+	// Only messagesAdded count as new messages
+	if len(result.AddedIDs) != 1 || result.AddedIDs[0] != "m1" {
+		t.Fatalf("expected added ids [m1], got: %v", result.AddedIDs)
+	}
 }
 
 func TestCollectHistoryMessageIDs_DeletedRemovesFromFetch(t *testing.T) {
@@ -134,6 +139,9 @@ func TestCollectHistoryMessageIDs_DeletedRemovesFromFetch(t *testing.T) {
 	if !found {
 		t.Fatalf("deleted message m1 should be in deleted ids: %v", result.DeletedIDs)
 	}
+	if len(result.AddedIDs) != 0 {
+		t.Fatalf("deleted message m1 should not be in added ids: %v", result.AddedIDs)
+	}
 }
 
 func TestCollectHistoryMessageIDs_EmptyResponse(t *testing.T) {
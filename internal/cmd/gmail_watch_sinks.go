@@ -0,0 +1,497 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/hooksig"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	watchSinkHTTP = "http"
+	watchSinkExec = "exec"
+	watchSinkFile = "file"
+	watchSinkUnix = "unix"
+
+	// watchHookSinkName is the sink built from --hook-url / the stored hook.
+	watchHookSinkName = "hook"
+)
+
+var watchSinkNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// parseWatchSinkTarget splits a sink target into its kind and address.
+func parseWatchSinkTarget(target string) (string, string, error) {
+	target = strings.TrimSpace(target)
+	lower := strings.ToLower(target)
+	switch {
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return watchSinkHTTP, target, nil
+	case strings.HasPrefix(lower, "exec:"):
+		if cmd := strings.TrimSpace(target[len("exec:"):]); cmd != "" {
+			return watchSinkExec, cmd, nil
+		}
+	case strings.HasPrefix(lower, "file:"):
+		if path := strings.TrimSpace(target[len("file:"):]); path != "" {
+			expanded, err := config.ExpandPath(path)
+			return watchSinkFile, expanded, err
+		}
+	case strings.HasPrefix(lower, "unix:"):
+		if path := strings.TrimSpace(target[len("unix:"):]); path != "" {
+			expanded, err := config.ExpandPath(path)
+			return watchSinkUnix, expanded, err
+		}
+	}
+	return "", "", usagef("invalid sink target %q (use http(s)://..., exec:<command>, file:<path> or unix:<socket>)", target)
+}
+
+// watchSinkRunner delivers payloads to one sink, through its outbox unless
+// queueing is disabled.
+type watchSinkRunner struct {
+	sink    gmailWatchSink
+	labels  map[string]struct{}
+	deliver func(context.Context, gmailWatchDelivery) error
+	queue   *gmailWatchQueue
+}
+
+func newWatchSinkRunner(sink gmailWatchSink, client *http.Client, timeout time.Duration) (*watchSinkRunner, error) {
+	kind, addr, err := parseWatchSinkTarget(sink.Target)
+	if err != nil {
+		return nil, err
+	}
+	r := &watchSinkRunner{sink: sink, labels: stringSet(sink.Labels)}
+	switch kind {
+	case watchSinkHTTP:
+		r.deliver = func(ctx context.Context, d gmailWatchDelivery) error {
+			return postWatchHook(ctx, client, addr, sink.Token, sink.Secret, d)
+		}
+	case watchSinkExec:
+		r.deliver = func(ctx context.Context, d gmailWatchDelivery) error {
+			return runWatchExecSink(ctx, addr, d, timeout)
+		}
+	case watchSinkFile:
+		r.deliver = func(_ context.Context, d gmailWatchDelivery) error {
+			return appendWatchFileSink(addr, d.Payload)
+		}
+	case watchSinkUnix:
+		r.deliver = func(ctx context.Context, d gmailWatchDelivery) error {
+			return writeWatchUnixSink(ctx, addr, d.Payload, timeout)
+		}
+	}
+	return r, nil
+}
+
+// postWatchHook POSTs the payload. With a secret, the body is signed on every
+// attempt so retries carry a fresh timestamp; the delivery ID stays the same.
+func postWatchHook(ctx context.Context, client *http.Client, url, token, secret string, d gmailWatchDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if d.ID != "" {
+		req.Header.Set(hooksig.DeliveryHeader, d.ID)
+	}
+	if secret != "" {
+		req.Header.Set(hooksig.SignatureHeader, hooksig.Sign(secret, time.Now(), d.ID, d.Payload))
+	}
+	resp, err := client.Do(req) //nolint:gosec // hook URL is explicit user configuration
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &hookStatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// runWatchExecSink runs the command through the shell with the payload on
+// stdin. A non-zero exit is a failed delivery.
+func runWatchExecSink(ctx context.Context, command string, d gmailWatchDelivery, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = bytes.NewReader(d.Payload)
+	cmd.Env = append(os.Environ(),
+		"GOG_ACCOUNT="+d.Account,
+		"GOG_SINK="+d.Sink,
+		"GOG_DELIVERY_ID="+d.ID,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, firstLine(msg))
+		}
+		return err
+	}
+	return nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command) //nolint:gosec // command is explicit user configuration
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec // command is explicit user configuration
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// appendWatchFileSink appends one NDJSON line. Named pipes are opened without
+// blocking, so a missing reader is a retryable failure instead of a hang.
+func appendWatchFileSink(path string, payload []byte) error {
+	flag := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if st, err := os.Stat(path); err == nil && st.Mode()&os.ModeNamedPipe != 0 {
+		flag = os.O_WRONLY | fifoNonblockFlag
+	}
+	f, err := os.OpenFile(path, flag, 0o600) //nolint:gosec // sink path is explicit user configuration
+	if err != nil {
+		return err
+	}
+	line := append(bytes.TrimRight(payload, "\n"), '\n')
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeWatchUnixSink(ctx context.Context, path string, payload []byte, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err = conn.Write(append(bytes.TrimRight(payload, "\n"), '\n'))
+	return err
+}
+
+// filterHookPayload keeps the messages carrying one of the sink's labels. It
+// returns nil when nothing is left for the sink. Deleted message IDs carry no
+// labels, so only unfiltered sinks receive them.
+func filterHookPayload(p *gmailHookPayload, labels map[string]struct{}) *gmailHookPayload {
+	if len(labels) == 0 {
+		return p
+	}
+	out := *p
+	out.Messages = nil
+	out.DeletedMessageIDs = nil
+	for _, msg := range p.Messages {
+		for _, id := range msg.Labels {
+			if _, ok := labels[id]; ok {
+				out.Messages = append(out.Messages, msg)
+				break
+			}
+		}
+	}
+	if len(out.Messages) == 0 {
+		return nil
+	}
+	return &out
+}
+
+var watchDeliverySeq atomic.Uint64
+
+// newWatchDeliveryID returns an ID that sorts in creation order.
+func newWatchDeliveryID(now time.Time) string {
+	return fmt.Sprintf("%019d-%04d", now.UnixNano(), watchDeliverySeq.Add(1)%10000)
+}
+
+// dispatch hands the payload to every sink. Queued sinks only store it and
+// wake runQueue, which makes the first attempt off the request path and
+// retries failures instead of dropping them.
+func (s *gmailWatchServer) dispatch(ctx context.Context, payload *gmailHookPayload) {
+	for _, r := range s.sinks {
+		p := filterHookPayload(payload, r.labels)
+		if p == nil {
+			continue
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			s.warnf("watch: encode payload for sink %s: %v", r.sink.Name, err)
+			continue
+		}
+		if r.queue != nil {
+			_, err := r.queue.Enqueue(p.Account, data)
+			if err == nil {
+				s.wakeQueue()
+				continue
+			}
+			s.warnf("watch: enqueue for sink %s failed, delivering once: %v", r.sink.Name, err)
+		}
+		_ = s.deliverToSink(ctx, r, gmailWatchDelivery{
+			ID:          newWatchDeliveryID(time.Now()),
+			Account:     p.Account,
+			Sink:        r.sink.Name,
+			CreatedAtMs: time.Now().UnixMilli(),
+			Payload:     data,
+		})
+	}
+}
+
+func (s *gmailWatchServer) deliverToSink(ctx context.Context, r *watchSinkRunner, d gmailWatchDelivery) error {
+	err := r.deliver(ctx, d)
+	s.recordDelivery(r.sink.Name, err)
+	if err != nil {
+		s.warnf("watch: sink %s delivery %s failed (attempt %d): %v", r.sink.Name, d.ID, d.Attempts+1, err)
+	}
+	return err
+}
+
+func (s *gmailWatchServer) drainSink(ctx context.Context, r *watchSinkRunner) {
+	if _, err := r.queue.Drain(ctx, func(ctx context.Context, d gmailWatchDelivery) error {
+		return s.deliverToSink(ctx, r, d)
+	}); err != nil {
+		s.warnf("watch: sink %s queue: %v", r.sink.Name, err)
+	}
+}
+
+// runQueue delivers queued payloads until ctx is done: right away when
+// dispatch enqueues, and every interval for retries.
+func (s *gmailWatchServer) runQueue(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.queueWake:
+		}
+		s.drainSinks(ctx)
+	}
+}
+
+// wakeQueue nudges runQueue without blocking; a pending wake already covers
+// the new delivery.
+func (s *gmailWatchServer) wakeQueue() {
+	if s.queueWake == nil {
+		return
+	}
+	select {
+	case s.queueWake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *gmailWatchServer) drainSinks(ctx context.Context) {
+	for _, r := range s.sinks {
+		if r.queue != nil {
+			s.drainSink(ctx, r)
+		}
+	}
+}
+
+// startQueue runs the redelivery loop in the background until ctx is done,
+// when any sink is queued.
+func (s *gmailWatchServer) startQueue(ctx context.Context) {
+	for _, r := range s.sinks {
+		if r.queue != nil {
+			s.queueWake = make(chan struct{}, 1)
+			go s.runQueue(ctx, defaultWatchQueuePollSeconds*time.Second)
+			return
+		}
+	}
+}
+
+// recordDelivery stores the outcome of the latest delivery attempt. Notes
+// from named sinks are prefixed with the sink name.
+func (s *gmailWatchServer) recordDelivery(sink string, err error) {
+	if err == nil {
+		s.stats.delivered.Add(1)
+	} else {
+		s.stats.failed.Add(1)
+	}
+	_ = s.store.Update(func(state *gmailWatchState) error {
+		state.LastDeliveryAtMs = time.Now().UnixMilli()
+		var statusErr *hookStatusError
+		switch {
+		case err == nil:
+			state.LastDeliveryStatus = "ok"
+			state.LastDeliveryStatusNote = ""
+			return nil
+		case errors.As(err, &statusErr):
+			state.LastDeliveryStatus = gmailWatchStatusHTTPError
+			state.LastDeliveryStatusNote = fmt.Sprintf("status %d", statusErr.StatusCode)
+		default:
+			state.LastDeliveryStatus = "error"
+			state.LastDeliveryStatusNote = err.Error()
+		}
+		if sink != "" && sink != watchHookSinkName {
+			state.LastDeliveryStatusNote = sink + ": " + state.LastDeliveryStatusNote
+		}
+		return nil
+	})
+}
+
+type GmailWatchSinksCmd struct {
+	List   GmailWatchSinksListCmd   `cmd:"" name:"list" aliases:"ls" help:"List delivery sinks stored in the watch state"`
+	Add    GmailWatchSinksAddCmd    `cmd:"" name:"add" aliases:"set" help:"Add or replace a delivery sink"`
+	Remove GmailWatchSinksRemoveCmd `cmd:"" name:"remove" aliases:"rm,delete" help:"Remove a delivery sink"`
+}
+
+type GmailWatchSinksListCmd struct{}
+
+func (c *GmailWatchSinksListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	store, err := loadGmailWatchStore(account)
+	if err != nil {
+		return err
+	}
+	sinks := store.Get().Sinks
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"sinks": redactWatchSinks(sinks)})
+	}
+	if len(sinks) == 0 {
+		ui.FromContext(ctx).Err().Println("No sinks")
+		return nil
+	}
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "NAME\tTARGET\tLABELS")
+	for _, sink := range sinks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", sink.Name, sanitizeTab(sink.Target), strings.Join(sink.Labels, ","))
+	}
+	return nil
+}
+
+type GmailWatchSinksAddCmd struct {
+	Name       string   `arg:"" name:"name" help:"Sink name (lowercase letters, digits, - and _)"`
+	Target     string   `arg:"" name:"target" help:"http(s)://URL, exec:<command>, file:<path> (NDJSON file or named pipe) or unix:<socket path>"`
+	Token      string   `name:"token" help:"Bearer token for HTTP sinks (visible in shell history and ps; prefer --token-file)"`
+	TokenFile  string   `name:"token-file" help:"Read the bearer token for HTTP sinks from this file"`
+	Secret     string   `name:"secret" help:"HMAC-SHA256 signing secret for HTTP sinks (X-Gog-Signature; prefer --secret-file)"`
+	SecretFile string   `name:"secret-file" help:"Read the HMAC-SHA256 signing secret for HTTP sinks from this file"`
+	Labels     []string `name:"label" help:"Only forward messages with one of these labels (IDs or names; repeatable, comma-separated)"`
+}
+
+func (c *GmailWatchSinksAddCmd) Run(ctx context.Context, flags *RootFlags) error {
+	name := strings.ToLower(strings.TrimSpace(c.Name))
+	if !watchSinkNamePattern.MatchString(name) {
+		return usagef("invalid sink name %q (lowercase letters, digits, - and _)", c.Name)
+	}
+	if name == watchHookSinkName {
+		return usagef("sink name %q is reserved for --hook-url", name)
+	}
+	kind, _, err := parseWatchSinkTarget(c.Target)
+	if err != nil {
+		return err
+	}
+	token, err := secretFromSources(c.Token, c.TokenFile, "token", "token-file", "")
+	if err != nil {
+		return err
+	}
+	secret, err := secretFromSources(c.Secret, c.SecretFile, "secret", "secret-file", "")
+	if err != nil {
+		return err
+	}
+	if (token != "" || secret != "") && kind != watchSinkHTTP {
+		return usage("--token and --secret only apply to http(s) sinks")
+	}
+	labels := c.Labels
+	sink := gmailWatchSink{Name: name, Target: strings.TrimSpace(c.Target), Token: token, Secret: secret}
+
+	if err := dryRunExit(ctx, flags, "gmail.watch.sinks.add", map[string]any{"sink": sink.redacted(), "labels": labels}); err != nil {
+		return err
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	store, err := loadGmailWatchStore(account)
+	if err != nil {
+		return err
+	}
+	if len(labels) > 0 {
+		svc, err := newGmailService(ctx, account)
+		if err != nil {
+			return err
+		}
+		if sink.Labels, err = resolveLabelIDsWithService(svc, labels); err != nil {
+			return err
+		}
+	}
+	if err := store.Update(func(s *gmailWatchState) error {
+		for i := range s.Sinks {
+			if s.Sinks[i].Name == name {
+				s.Sinks[i] = sink
+				return nil
+			}
+		}
+		s.Sinks = append(s.Sinks, sink)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"sink": sink.redacted()})
+	}
+	ui.FromContext(ctx).Out().Printf("sink\t%s\t%s", sink.Name, sink.Target)
+	return nil
+}
+
+type GmailWatchSinksRemoveCmd struct {
+	Name string `arg:"" name:"name" help:"Sink name"`
+}
+
+func (c *GmailWatchSinksRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	name := strings.ToLower(strings.TrimSpace(c.Name))
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("remove gmail watch sink %s", name)); err != nil {
+		return err
+	}
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	store, err := loadGmailWatchStore(account)
+	if err != nil {
+		return err
+	}
+	found := false
+	if err := store.Update(func(s *gmailWatchState) error {
+		kept := s.Sinks[:0]
+		for _, sink := range s.Sinks {
+			if sink.Name == name {
+				found = true
+				continue
+			}
+			kept = append(kept, sink)
+		}
+		s.Sinks = kept
+		return nil
+	}); err != nil {
+		return err
+	}
+	if !found {
+		return usagef("no sink named %q", name)
+	}
+	return writeResult(ctx, ui.FromContext(ctx), kv("removed", name))
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWatchSinkTarget(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/hook": watchSinkHTTP,
+		"exec:jq .":                watchSinkExec,
+		"file:/tmp/events.ndjson":  watchSinkFile,
+		"unix:/tmp/gog.sock":       watchSinkUnix,
+	}
+	for target, want := range cases {
+		kind, _, err := parseWatchSinkTarget(target)
+		if err != nil || kind != want {
+			t.Fatalf("parseWatchSinkTarget(%q) = %q, %v; want %q", target, kind, err, want)
+		}
+	}
+	for _, bad := range []string{"", "exec:", "ftp://x", "/tmp/file"} {
+		if _, _, err := parseWatchSinkTarget(bad); ExitCode(err) != 2 {
+			t.Fatalf("expected usage error for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestFilterHookPayload(t *testing.T) {
+	p := &gmailHookPayload{
+		Account: "a@b.com",
+		Messages: []gmailHookMessage{
+			{ID: "m1", Labels: []string{"INBOX", "Label_1"}},
+			{ID: "m2", Labels: []string{"INBOX"}},
+		},
+		DeletedMessageIDs: []string{"m3"},
+	}
+	if got := filterHookPayload(p, nil); got != p {
+		t.Fatalf("unfiltered sink should get the payload as is")
+	}
+	got := filterHookPayload(p, stringSet([]string{"Label_1"}))
+	if got == nil || len(got.Messages) != 1 || got.Messages[0].ID != "m1" || got.DeletedMessageIDs != nil {
+		t.Fatalf("unexpected filtered payload: %+v", got)
+	}
+	if filterHookPayload(p, stringSet([]string{"Label_2"})) != nil {
+		t.Fatalf("expected nothing for a sink without matching labels")
+	}
+}
+
+func TestWatchSinksDeliverToFileExecAndUnix(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "events.ndjson")
+	execOut := filepath.Join(dir, "exec.out")
+
+	sinks := []gmailWatchSink{
+		{Name: "archive", Target: "file:" + filePath},
+		{Name: "clients", Target: "file:" + filepath.Join(dir, "clients.ndjson"), Labels: []string{"Label_1"}},
+	}
+	if runtime.GOOS != "windows" {
+		sinks = append(sinks, gmailWatchSink{
+			Name:   "script",
+			Target: `exec:{ echo "$GOG_ACCOUNT $GOG_SINK"; cat; } > ` + execOut,
+		})
+	}
+
+	var lines chan string
+	if runtime.GOOS != "windows" {
+		sock := filepath.Join(dir, "gog.sock")
+		ln, err := net.Listen("unix", sock)
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer ln.Close()
+		lines = make(chan string, 1)
+		go func() {
+			conn, acceptErr := ln.Accept()
+			if acceptErr != nil {
+				return
+			}
+			defer conn.Close()
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			lines <- line
+		}()
+		sinks = append(sinks, gmailWatchSink{Name: "socket", Target: "unix:" + sock})
+	}
+
+	store, err := newGmailWatchStore("a@b.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	server := &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "a@b.com"},
+		store: store,
+		logf:  func(string, ...any) {},
+		warnf: func(format string, args ...any) { t.Errorf(format, args...) },
+	}
+	for _, sink := range sinks {
+		r, runErr := newWatchSinkRunner(sink, nil, 5*time.Second)
+		if runErr != nil {
+			t.Fatalf("runner %s: %v", sink.Name, runErr)
+		}
+		if r.queue, runErr = openGmailWatchQueue("a@b.com", sink.Name, 0); runErr != nil {
+			t.Fatalf("queue %s: %v", sink.Name, runErr)
+		}
+		server.sinks = append(server.sinks, r)
+	}
+
+	payload := &gmailHookPayload{
+		Source:    "gmail",
+		Account:   "a@b.com",
+		HistoryID: "42",
+		Messages:  []gmailHookMessage{{ID: "m1", Labels: []string{"INBOX"}}},
+	}
+	server.dispatch(context.Background(), payload)
+	payload.HistoryID = "43"
+	server.dispatch(context.Background(), payload)
+	server.drainSinks(context.Background())
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read file sink: %v", err)
+	}
+	got := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var first gmailHookPayload
+	if len(got) != 2 || json.Unmarshal([]byte(got[0]), &first) != nil || first.HistoryID != "42" {
+		t.Fatalf("unexpected file sink contents: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "clients.ndjson")); !os.IsNotExist(err) {
+		t.Fatalf("label-filtered sink should not receive unmatched messages: %v", err)
+	}
+	for _, r := range server.sinks {
+		if pending, _ := r.queue.Pending(); len(pending) != 0 {
+			t.Fatalf("sink %s left pending deliveries: %+v", r.sink.Name, pending)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	out, err := os.ReadFile(execOut)
+	if err != nil || !strings.HasPrefix(string(out), "a@b.com script\n{") || !strings.Contains(string(out), `"historyId":"43"`) {
+		t.Fatalf("unexpected exec sink output: %q (%v)", out, err)
+	}
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, `"historyId":"42"`) {
+			t.Fatalf("unexpected socket line: %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("socket sink never received a payload")
+	}
+}
+
+func TestWatchExecSinkFailureIsQueued(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	store, err := newGmailWatchStore("a@b.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	r, err := newWatchSinkRunner(gmailWatchSink{Name: "broken", Target: "exec:echo nope >&2; exit 3"}, nil, 5*time.Second)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	if r.queue, err = openGmailWatchQueue("a@b.com", "broken", 0); err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	server := &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "a@b.com"},
+		store: store,
+		sinks: []*watchSinkRunner{r},
+		logf:  func(string, ...any) {},
+		warnf: func(string, ...any) {},
+	}
+	server.dispatch(context.Background(), &gmailHookPayload{Account: "a@b.com", HistoryID: "1"})
+	server.drainSinks(context.Background())
+
+	pending, _ := r.queue.Pending()
+	if len(pending) != 1 || pending[0].Sink != "broken" || !strings.Contains(pending[0].LastError, "nope") {
+		t.Fatalf("expected queued failure: %+v", pending)
+	}
+	state := store.Get()
+	if state.LastDeliveryStatus != "error" || !strings.HasPrefix(state.LastDeliveryStatusNote, "broken: ") {
+		t.Fatalf("unexpected delivery status: %q %q", state.LastDeliveryStatus, state.LastDeliveryStatusNote)
+	}
+}
+
+func TestGmailWatchSinksCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	store, err := newGmailWatchStore("me@example.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if err := store.Update(func(s *gmailWatchState) error {
+		s.Account = "me@example.com"
+		s.HistoryID = "1"
+		return nil
+	}); err != nil {
+		t.Fatalf("seed state: %v", err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		return captureStdout(t, func() {
+			if execErr := Execute(append([]string{"--json", "--force", "--account", "me@example.com", "gmail", "watch", "sinks"}, args...)); execErr != nil {
+				t.Fatalf("%v: %v", args, execErr)
+			}
+		})
+	}
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+
+	run("add", "audit", "file:~/gog-events.ndjson")
+	run("add", "notify", "https://example.com/hook", "--token-file", tokenPath)
+	run("add", "audit", "exec:logger -t gog")
+
+	var listed struct {
+		Sinks []gmailWatchSink `json:"sinks"`
+	}
+	if err := json.Unmarshal([]byte(run("list")), &listed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(listed.Sinks) != 2 || listed.Sinks[0].Name != "audit" || listed.Sinks[0].Target != "exec:logger -t gog" || listed.Sinks[1].Token != watchSecretMask {
+		t.Fatalf("unexpected sinks: %+v", listed.Sinks)
+	}
+	if reloaded, _ := loadGmailWatchStore("me@example.com"); reloaded.Get().Sinks[1].Token != "secret" {
+		t.Fatalf("stored sink token should be kept: %+v", reloaded.Get().Sinks)
+	}
+	if out := run("add", "signed", "https://example.com/hook", "--secret", "s3cret", "--dry-run"); strings.Contains(out, "s3cret") {
+		t.Fatalf("dry-run leaked the secret: %s", out)
+	}
+
+	for _, args := range [][]string{
+		{"add", "hook", "https://example.com"},
+		{"add", "Bad Name", "https://example.com"},
+		{"add", "x", "exec:true", "--token", "t"},
+		{"add", "x", "exec:true", "--secret-file", tokenPath},
+		{"add", "x", "https://example.com", "--token", "t", "--token-file", tokenPath},
+		{"add", "x", "gopher://example.com"},
+		{"remove", "missing"},
+	} {
+		err := Execute(append([]string{"--force", "--account", "me@example.com", "gmail", "watch", "sinks"}, args...))
+		if ExitCode(err) != 2 {
+			t.Fatalf("expected usage error for %v, got %v", args, err)
+		}
+	}
+
+	run("remove", "audit")
+	loaded, err := loadGmailWatchStore("me@example.com")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if sinks := loaded.Get().Sinks; len(sinks) != 1 || sinks[0].Name != "notify" {
+		t.Fatalf("unexpected sinks after remove: %+v", sinks)
+	}
+}
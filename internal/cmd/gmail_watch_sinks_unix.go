@@ -0,0 +1,7 @@
+//go:build !windows
+
+package cmd
+
+import "syscall"
+
+const fifoNonblockFlag = syscall.O_NONBLOCK
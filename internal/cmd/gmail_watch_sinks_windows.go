@@ -0,0 +1,6 @@
+//go:build windows
+
+package cmd
+
+// Windows has no named pipes in the filesystem namespace.
+const fifoNonblockFlag = 0
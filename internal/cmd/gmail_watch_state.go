@@ -62,6 +62,8 @@ func newGmailWatchStore(account string) (*gmailWatchStore, error) {
 	return &gmailWatchStore{path: path}, nil
 }
 
+var errWatchStateNotFound = errors.New("watch state not found; run gmail watch start")
+
 func loadGmailWatchStore(account string) (*gmailWatchStore, error) {
 	store, err := newGmailWatchStore(account)
 	if err != nil {
@@ -70,7 +72,7 @@ func loadGmailWatchStore(account string) (*gmailWatchStore, error) {
 	data, err := os.ReadFile(store.path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, errors.New("watch state not found; run gmail watch start")
+			return nil, errWatchStateNotFound
 		}
 		return nil, err
 	}
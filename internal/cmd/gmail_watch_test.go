@@ -68,6 +68,7 @@ func TestGmailWatchStartCmd_JSON(t *testing.T) {
 	}
 	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
 
+	t.Setenv("GOG_HOOK_SECRET", "from-env")
 	flags := &RootFlags{Account: "a@b.com"}
 	out := captureStdout(t, func() {
 		u, uiErr := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
@@ -120,6 +121,9 @@ func TestGmailWatchStartCmd_JSON(t *testing.T) {
 	if store.Get().HistoryID != "123" {
 		t.Fatalf("store missing history: %#v", store.Get())
 	}
+	if hook := store.Get().Hook; hook == nil || hook.Secret != "from-env" {
+		t.Fatalf("expected the hook secret from GOG_HOOK_SECRET, got %#v", hook)
+	}
 }
 
 func TestGmailWatchServerServeHTTP_TruncateBody(t *testing.T) {
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"time"
+
+	"github.com/steipete/gogcli/internal/config"
 )
 
 const (
@@ -19,24 +21,82 @@ const (
 type gmailWatchHook struct {
 	URL         string `json:"url"`
 	Token       string `json:"token,omitempty"`
+	Secret      string `json:"secret,omitempty"`
 	IncludeBody bool   `json:"includeBody,omitempty"`
 	MaxBytes    int    `json:"maxBytes,omitempty"`
 }
 
+// gmailWatchSink is a delivery target declared in the watch state. Target is
+// an http(s) URL, exec:<command>, file:<path> or unix:<socket path>. With
+// Labels set, only messages carrying one of those label IDs are forwarded.
+type gmailWatchSink struct {
+	Name   string   `json:"name"`
+	Target string   `json:"target"`
+	Token  string   `json:"token,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// watchSecretMask replaces tokens and signing secrets in printed output.
+const watchSecretMask = "***"
+
+func maskWatchSecret(v string) string {
+	if v == "" {
+		return ""
+	}
+	return watchSecretMask
+}
+
+// redacted returns a copy with Token and Secret masked, for output.
+func (h *gmailWatchHook) redacted() *gmailWatchHook {
+	if h == nil {
+		return nil
+	}
+	out := *h
+	out.Token = maskWatchSecret(out.Token)
+	out.Secret = maskWatchSecret(out.Secret)
+	return &out
+}
+
+// redacted returns a copy with Token and Secret masked, for output.
+func (s gmailWatchSink) redacted() gmailWatchSink {
+	s.Token = maskWatchSecret(s.Token)
+	s.Secret = maskWatchSecret(s.Secret)
+	return s
+}
+
+func redactWatchSinks(sinks []gmailWatchSink) []gmailWatchSink {
+	out := make([]gmailWatchSink, len(sinks))
+	for i, s := range sinks {
+		out[i] = s.redacted()
+	}
+	return out
+}
+
 type gmailWatchState struct {
-	Account                string          `json:"account"`
-	Topic                  string          `json:"topic"`
-	Labels                 []string        `json:"labels,omitempty"`
-	HistoryID              string          `json:"historyId"`
-	ExpirationMs           int64           `json:"expirationMs,omitempty"`
-	ProviderExpirationMs   int64           `json:"providerExpirationMs,omitempty"`
-	RenewAfterMs           int64           `json:"renewAfterMs,omitempty"`
-	UpdatedAtMs            int64           `json:"updatedAtMs,omitempty"`
-	Hook                   *gmailWatchHook `json:"hook,omitempty"`
-	LastDeliveryStatus     string          `json:"lastDeliveryStatus,omitempty"`
-	LastDeliveryAtMs       int64           `json:"lastDeliveryAtMs,omitempty"`
-	LastDeliveryStatusNote string          `json:"lastDeliveryStatusNote,omitempty"`
-	LastPushMessageID      string          `json:"lastPushMessageId,omitempty"`
+	Account                string           `json:"account"`
+	Topic                  string           `json:"topic"`
+	Labels                 []string         `json:"labels,omitempty"`
+	HistoryID              string           `json:"historyId"`
+	ExpirationMs           int64            `json:"expirationMs,omitempty"`
+	ProviderExpirationMs   int64            `json:"providerExpirationMs,omitempty"`
+	RenewAfterMs           int64            `json:"renewAfterMs,omitempty"`
+	UpdatedAtMs            int64            `json:"updatedAtMs,omitempty"`
+	Hook                   *gmailWatchHook  `json:"hook,omitempty"`
+	Sinks                  []gmailWatchSink `json:"sinks,omitempty"`
+	LastDeliveryStatus     string           `json:"lastDeliveryStatus,omitempty"`
+	LastDeliveryAtMs       int64            `json:"lastDeliveryAtMs,omitempty"`
+	LastDeliveryStatusNote string           `json:"lastDeliveryStatusNote,omitempty"`
+	LastPushMessageID      string           `json:"lastPushMessageId,omitempty"`
+}
+
+// redacted returns a copy with hook and sink credentials masked, for output.
+func (s gmailWatchState) redacted() gmailWatchState {
+	s.Hook = s.Hook.redacted()
+	if s.Sinks != nil {
+		s.Sinks = redactWatchSinks(s.Sinks)
+	}
+	return s
 }
 
 type gmailWatchServeConfig struct {
@@ -50,6 +110,7 @@ type gmailWatchServeConfig struct {
 	SharedToken   string
 	HookURL       string
 	HookToken     string
+	HookSecret    string
 	IncludeBody   bool
 	MaxBodyBytes  int
 	ExcludeLabels []string
@@ -61,6 +122,12 @@ type gmailWatchServeConfig struct {
 	PersistHook   bool
 	AllowNoHook   bool
 	VerboseOutput bool
+	// Rule actions follow the global flags: --policy applies to the
+	// recipients of forward and reply, --dry-run only logs the actions, and
+	// --require-approval holds actions that send mail or run commands.
+	Policy          *config.Policy
+	DryRun          bool
+	RequireApproval bool
 }
 
 var gmailHistoryTypes = []string{
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/gogcli/internal/hooksig"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// GmailWatchVerifyCmd checks a signed hook request on the receiving side, e.g.
+// from a shell-based receiver that saved the body and headers.
+type GmailWatchVerifyCmd struct {
+	Secret     string `name:"secret" help:"Hook signing secret (visible in shell history and ps; prefer --secret-file or GOG_HOOK_SECRET)"`
+	SecretFile string `name:"secret-file" help:"Read the hook signing secret from this file (default: GOG_HOOK_SECRET env)"`
+	Signature  string `name:"signature" help:"X-Gog-Signature header value"`
+	Delivery   string `name:"delivery" help:"X-Gog-Delivery header value"`
+	Body       string `name:"body" help:"Raw request body file (- for stdin)" default:"-"`
+	Tolerance  string `name:"tolerance" help:"Maximum signature age (seconds or Go duration; 0 disables the check)" default:"5m"`
+}
+
+func (c *GmailWatchVerifyCmd) Run(ctx context.Context) error {
+	secret, err := c.secret()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.Signature) == "" {
+		return usage("--signature is required")
+	}
+	tolerance, err := parseDurationSeconds(c.Tolerance)
+	if err != nil {
+		return usagef("invalid --tolerance: %v", err)
+	}
+	body, err := readTextInput(c.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if err := hooksig.Verify(secret, c.Signature, c.Delivery, body, time.Now(), tolerance); err != nil {
+		return fmt.Errorf("invalid hook signature: %w", err)
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"valid": true, "delivery": c.Delivery})
+	}
+	ui.FromContext(ctx).Out().Printf("valid\ttrue")
+	return nil
+}
+
+// hookSecretEnv holds the hook signing secret for start, serve, poll and
+// verify, so it stays out of shell history and ps.
+const hookSecretEnv = "GOG_HOOK_SECRET"
+
+// secret resolves the signing secret from --secret, --secret-file or
+// GOG_HOOK_SECRET, in that order.
+func (c *GmailWatchVerifyCmd) secret() (string, error) {
+	secret, err := secretFromSources(c.Secret, c.SecretFile, "secret", "secret-file", hookSecretEnv)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", usage("--secret-file, GOG_HOOK_SECRET or --secret is required")
+	}
+	return secret, nil
+}
+
+// secretFromSources resolves a secret from its flag, a file flag or an
+// environment variable (if envVar is set), in that order, and returns "" when
+// none is set. The file must not be stdin or empty; trailing newlines are
+// trimmed.
+func secretFromSources(value, path, flagName, fileFlag, envVar string) (string, error) {
+	path = strings.TrimSpace(path)
+	switch {
+	case value != "" && path != "":
+		return "", usagef("use either --%s or --%s", flagName, fileFlag)
+	case value != "":
+		return value, nil
+	case path != "":
+		if path == "-" {
+			return "", usagef("--%s must be a file, not stdin", fileFlag)
+		}
+		data, err := readTextInput(path)
+		if err != nil {
+			return "", fmt.Errorf("read --%s: %w", fileFlag, err)
+		}
+		secret := strings.TrimRight(string(data), "\r\n")
+		if secret == "" {
+			return "", usagef("--%s %s is empty", fileFlag, path)
+		}
+		return secret, nil
+	}
+	if envVar != "" {
+		return os.Getenv(envVar), nil
+	}
+	return "", nil
+}
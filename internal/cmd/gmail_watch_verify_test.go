@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/steipete/gogcli/internal/hooksig"
+)
+
+func TestGmailWatchSignedHookAndVerify(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	type request struct {
+		body                []byte
+		signature, delivery string
+		auth                string
+	}
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request{
+			body:      b,
+			signature: r.Header.Get(hooksig.SignatureHeader),
+			delivery:  r.Header.Get(hooksig.DeliveryHeader),
+			auth:      r.Header.Get("Authorization"),
+		})
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer hookSrv.Close()
+
+	store, err := newGmailWatchStore("a@b.com")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	r, err := newWatchSinkRunner(gmailWatchSink{Name: "signed", Target: hookSrv.URL, Secret: "s3cret"}, hookSrv.Client(), 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	if r.queue, err = openGmailWatchQueue("a@b.com", "signed", 0); err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	server := &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "a@b.com"},
+		store: store,
+		sinks: []*watchSinkRunner{r},
+		logf:  func(string, ...any) {},
+		warnf: func(string, ...any) {},
+	}
+	server.dispatch(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "9"})
+	server.drainSink(context.Background(), r)
+	if _, err := r.queue.Retry(nil); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	server.drainSink(context.Background(), r)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("expected a failed attempt and a retry, got %d", len(requests))
+	}
+	first, retry := requests[0], requests[1]
+	if first.delivery == "" || first.delivery != retry.delivery || first.auth != "" {
+		t.Fatalf("unexpected headers: %+v %+v", first, retry)
+	}
+	if !strings.HasPrefix(retry.signature, "t=") || !strings.Contains(retry.signature, ",v1=") {
+		t.Fatalf("unexpected signature %q", retry.signature)
+	}
+
+	bodyPath := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyPath, retry.body, 0o600); err != nil {
+		t.Fatalf("write body: %v", err)
+	}
+	args := []string{"gmail", "watch", "verify", "--secret", "s3cret", "--signature", retry.signature, "--delivery", retry.delivery, "--body", bodyPath}
+	out := captureStdout(t, func() {
+		if execErr := Execute(args); execErr != nil {
+			t.Fatalf("verify: %v", execErr)
+		}
+	})
+	if strings.TrimSpace(out) != "valid\ttrue" {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	if err := Execute(append(args, "--secret", "wrong")); err == nil || !strings.Contains(err.Error(), "signature mismatch") {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+	if err := Execute([]string{"gmail", "watch", "verify", "--secret", "s3cret"}); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error without --signature, got %v", err)
+	}
+
+	// The secret can stay off the command line: a file, or GOG_HOOK_SECRET.
+	secretPath := filepath.Join(t.TempDir(), "hook-secret")
+	if err := os.WriteFile(secretPath, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	headers := []string{"--signature", retry.signature, "--delivery", retry.delivery, "--body", bodyPath}
+	_ = captureStdout(t, func() {
+		if execErr := Execute(append([]string{"gmail", "watch", "verify", "--secret-file", secretPath}, headers...)); execErr != nil {
+			t.Fatalf("verify with --secret-file: %v", execErr)
+		}
+		t.Setenv("GOG_HOOK_SECRET", "s3cret")
+		if execErr := Execute(append([]string{"gmail", "watch", "verify"}, headers...)); execErr != nil {
+			t.Fatalf("verify with GOG_HOOK_SECRET: %v", execErr)
+		}
+	})
+	if err := Execute(append([]string{"gmail", "watch", "verify", "--secret", "s3cret", "--secret-file", secretPath}, headers...)); ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for --secret with --secret-file, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+)
+
+// newOutputBudget builds the --max-output-bytes/--max-items/--continue budget.
+// The command line (minus the budget flags) is fingerprinted so a token cannot
+// resume a different command.
+func newOutputBudget(flags *RootFlags, args []string, jsonMode bool) (*outfmt.Budget, error) {
+	if flags.MaxOutputBytes < 0 || flags.MaxItems < 0 {
+		return nil, usage("--max-output-bytes and --max-items must be >= 0")
+	}
+
+	b := &outfmt.Budget{
+		MaxBytes:    flags.MaxOutputBytes,
+		MaxItems:    flags.MaxItems,
+		Fingerprint: commandFingerprint(args),
+	}
+
+	if token := strings.TrimSpace(flags.Continue); token != "" {
+		cursor, err := outfmt.DecodeCursor(token)
+		if err != nil {
+			return nil, usagef("%v", err)
+		}
+		b.Cursor = &cursor
+	}
+
+	if err := b.CheckMode(jsonMode); err != nil {
+		return nil, usagef("%v", err)
+	}
+
+	return b, nil
+}
+
+func commandFingerprint(args []string) string {
+	kept := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			kept = append(kept, args[i:]...)
+			break
+		}
+		switch {
+		case a == "--continue" || a == "--max-output-bytes" || a == "--max-items":
+			i++
+			continue
+		case strings.HasPrefix(a, "--continue="),
+			strings.HasPrefix(a, "--max-output-bytes="),
+			strings.HasPrefix(a, "--max-items="):
+			continue
+		}
+		kept = append(kept, a)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(kept, "\x00")))
+
+	return hex.EncodeToString(sum[:8])
+}
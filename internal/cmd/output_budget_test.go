@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+func TestExecute_OutputBudgetContinue(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	for _, alias := range []string{"a", "b", "c"} {
+		if err := config.SetAccountAlias(alias, alias+"@example.com"); err != nil {
+			t.Fatalf("SetAccountAlias: %v", err)
+		}
+	}
+
+	var errOut string
+	out := captureStdout(t, func() {
+		errOut = captureStderr(t, func() {
+			if err := Execute([]string{"--plain", "--max-items", "2", "auth", "alias", "list"}); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+		})
+	})
+	if out != "ALIAS\tEMAIL\na\ta@example.com\n" {
+		t.Fatalf("unexpected first page: %q", out)
+	}
+	_, token, ok := strings.Cut(strings.TrimSpace(errOut), "--continue ")
+	if !ok || token == "" {
+		t.Fatalf("expected continuation hint, got %q", errOut)
+	}
+
+	out = captureStdout(t, func() {
+		errOut = captureStderr(t, func() {
+			if err := Execute([]string{"--plain", "--max-items", "2", "--continue", token, "auth", "alias", "list"}); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+		})
+	})
+	if out != "b\tb@example.com\nc\tc@example.com\n" || errOut != "" {
+		t.Fatalf("unexpected second page: %q (stderr %q)", out, errOut)
+	}
+
+	_ = captureStderr(t, func() {
+		if err := Execute([]string{"--continue", token, "auth", "alias", "list", "--json"}); ExitCode(err) != 2 {
+			t.Fatalf("expected token/mode mismatch usage error, got %v", err)
+		}
+	})
+
+	jsonOut := captureStdout(t, func() {
+		if err := Execute([]string{"--json", "--max-items", "1", "config", "keys"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	})
+	var doc struct {
+		Keys      []string `json:"keys"`
+		Truncated bool     `json:"truncated"`
+		Continue  string   `json:"continue"`
+	}
+	if err := json.Unmarshal([]byte(jsonOut), &doc); err != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", err, jsonOut)
+	}
+	if len(doc.Keys) != 1 || !doc.Truncated || doc.Continue == "" {
+		t.Fatalf("unexpected json budget output: %#v", doc)
+	}
+}
+
+func TestExecute_OutputBudgetInvalidContinue(t *testing.T) {
+	var err error
+	errOut := captureStderr(t, func() {
+		err = Execute([]string{"--continue", "bogus", "version"})
+	})
+	if ExitCode(err) != 2 || strings.TrimSpace(errOut) == "" {
+		t.Fatalf("expected printed usage error, got %v (stderr=%q)", err, errOut)
+	}
+
+	errOut = captureStderr(t, func() {
+		err = Execute([]string{"--json", "--continue", "bogus", "version"})
+	})
+	if ExitCode(err) != 2 {
+		t.Fatalf("expected usage exit code, got %v", err)
+	}
+	var doc struct {
+		Error errorEnvelope `json:"error"`
+	}
+	if uErr := json.Unmarshal([]byte(errOut), &doc); uErr != nil {
+		t.Fatalf("unmarshal: %v (stderr=%q)", uErr, errOut)
+	}
+	if doc.Error.Class != errorClassUsage || doc.Error.Message == "" {
+		t.Fatalf("unexpected envelope: %#v", doc.Error)
+	}
+}
@@ -55,3 +55,30 @@ func printNextPageHint(u *ui.UI, nextPageToken string) {
 	}
 	u.Err().Printf("# Next page: --page %s", nextPageToken)
 }
+
+// redirectStdout swaps os.Stdout for a pipe so output from any printer (ui,
+// tabwriter, fmt) passes through wrap. The returned func restores stdout and
+// waits until everything was flushed.
+func redirectStdout(wrap func(io.Writer) io.WriteCloser) (func(), error) {
+	orig := os.Stdout
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	os.Stdout = pw
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w := wrap(orig)
+		_, _ = io.Copy(w, pr)
+		_ = w.Close()
+		_ = pr.Close()
+	}()
+
+	return func() {
+		os.Stdout = orig
+		_ = pw.Close()
+		<-done
+	}, nil
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"fmt"
+	"net/mail"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+// PolicyError reports a command or argument rejected by the --policy file.
+type PolicyError struct {
+	Command string
+	Rule    string
+	Arg     string
+	Value   string
+	Reason  string
+}
+
+func (e *PolicyError) Error() string {
+	if e == nil {
+		return ""
+	}
+	msg := fmt.Sprintf("policy denied %q", e.Command)
+	if e.Arg != "" {
+		msg += fmt.Sprintf(" (%s", e.Arg)
+		if e.Value != "" {
+			msg += fmt.Sprintf("=%s", e.Value)
+		}
+		msg += ")"
+	}
+	return msg + ": " + e.Reason
+}
+
+// rootShortcutCommands maps hidden top-level desire paths to the command they alias,
+// so policies written against "gmail send" also cover "gog send".
+var rootShortcutCommands = map[string][]string{
+	"send":       {"gmail", "send"},
+	"ls":         {"drive", "ls"},
+	"search":     {"drive", "search"},
+	"download":   {"drive", "download"},
+	"upload":     {"drive", "upload"},
+	"login":      {"auth", "add"},
+	"logout":     {"auth", "remove"},
+	"status":     {"auth", "status"},
+	"me":         {"people", "me"},
+	"whoami":     {"people", "me"},
+	"exit-codes": {"agent", "exit-codes"},
+}
+
+// enforcePolicy checks the selected command against the --policy file. It
+// returns the --raw message it checked, if any, so the command sends exactly
+// those bytes instead of reading the file a second time.
+func enforcePolicy(kctx *kong.Context, policyPath string) (*rawMessage, error) {
+	policyPath = strings.TrimSpace(policyPath)
+	if policyPath == "" {
+		return nil, nil
+	}
+	policy, err := config.ReadPolicy(policyPath)
+	if err != nil {
+		return nil, &ExitError{Code: exitCodeConfig, Err: err}
+	}
+	cmdPath := policyCommandPath(kctx)
+	values := policyArgValues(kctx)
+	raw, err := addRawMessageValues(cmdPath, policyRawPath(kctx), values)
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluatePolicy(policy, cmdPath, values); err != nil {
+		return nil, &ExitError{Code: exitCodePolicyDenied, Err: err}
+	}
+	return raw, nil
+}
+
+// rawMessageCommands take their recipients from the --raw .eml instead of flags.
+var rawMessageCommands = map[string]bool{
+	"gmail send":          true,
+	"gmail drafts create": true,
+}
+
+// rawMessageArgs are the headers of a --raw message checked as if they were flags.
+var rawMessageArgs = []string{"to", "cc", "bcc", "from"}
+
+func policyRawPath(kctx *kong.Context) string {
+	for _, flag := range kctx.Flags() {
+		if flag == nil || flag.Name != "raw" || flag.Target.Kind() != reflect.String {
+			continue
+		}
+		return strings.TrimSpace(flag.Target.String())
+	}
+	return ""
+}
+
+// addRawMessageValues fills in To/Cc/Bcc/From from a --raw file so recipient
+// rules apply to it. Stdin can't be read twice; evaluatePolicy denies it when
+// any of those arguments is constrained.
+func addRawMessageValues(cmdPath []string, rawPath string, values map[string][]string) (*rawMessage, error) {
+	if rawPath == "" || rawPath == "-" || !rawMessageCommands[strings.Join(cmdPath, " ")] {
+		return nil, nil
+	}
+	raw, err := loadRawMessage(rawPath, false)
+	if err != nil {
+		return nil, err
+	}
+	for arg, addrs := range map[string][]string{"to": raw.To, "cc": raw.Cc, "bcc": raw.Bcc} {
+		values[arg] = append(values[arg], addrs...)
+	}
+	if raw.From != "" {
+		values["from"] = append(values["from"], raw.From)
+	}
+	return &raw, nil
+}
+
+func evaluatePolicy(policy config.Policy, cmdPath []string, values map[string][]string) error {
+	command := strings.Join(cmdPath, " ")
+	rule, ok := matchPolicyRule(policy.Rules, cmdPath)
+	if !ok {
+		if strings.EqualFold(strings.TrimSpace(policy.Default), config.PolicyDeny) {
+			return &PolicyError{Command: command, Reason: "no rule allows this command (default: deny)"}
+		}
+		return nil
+	}
+
+	ruleName := strings.TrimSpace(rule.Command)
+	if strings.EqualFold(strings.TrimSpace(rule.Action), config.PolicyDeny) {
+		return &PolicyError{Command: command, Rule: ruleName, Reason: "command is denied"}
+	}
+
+	names := make([]string, 0, len(rule.Args))
+	for name := range rule.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rawStdin := rawMessageCommands[command] && len(values["raw"]) == 1 && values["raw"][0] == "-"
+	for _, name := range names {
+		constraint := rule.Args[name]
+		arg := strings.TrimPrefix(strings.TrimSpace(name), "--")
+		vals := values[arg]
+
+		if rawStdin && slices.Contains(rawMessageArgs, arg) {
+			return &PolicyError{Command: command, Rule: ruleName, Arg: "raw", Value: "-", Reason: fmt.Sprintf("--%s is constrained and can't be checked on a message read from stdin; pass the .eml as a file", arg)}
+		}
+
+		if constraint.Required && len(vals) == 0 {
+			return &PolicyError{Command: command, Rule: ruleName, Arg: arg, Reason: "argument is required"}
+		}
+		if constraint.MaxItems > 0 && len(vals) > constraint.MaxItems {
+			return &PolicyError{
+				Command: command,
+				Rule:    ruleName,
+				Arg:     arg,
+				Value:   strconv.Itoa(len(vals)),
+				Reason:  fmt.Sprintf("at most %d item(s) allowed", constraint.MaxItems),
+			}
+		}
+		for _, v := range vals {
+			if pattern, hit := matchPolicyPattern(constraint.Deny, v); hit {
+				return &PolicyError{Command: command, Rule: ruleName, Arg: arg, Value: v, Reason: fmt.Sprintf("value matches deny pattern %q", pattern)}
+			}
+			if len(constraint.Allow) > 0 {
+				if _, hit := matchPolicyPattern(constraint.Allow, v); !hit {
+					return &PolicyError{Command: command, Rule: ruleName, Arg: arg, Value: v, Reason: "value does not match any allow pattern"}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchPolicyRule picks the most specific rule (longest command prefix); ties go to the first rule.
+func matchPolicyRule(rules []config.PolicyRule, cmdPath []string) (config.PolicyRule, bool) {
+	best := -1
+	bestLen := -1
+	for i, r := range rules {
+		words := strings.Fields(strings.ToLower(r.Command))
+		if len(words) == 1 && words[0] == "*" {
+			if bestLen < 0 {
+				best, bestLen = i, 0
+			}
+			continue
+		}
+		if len(words) == 0 || len(words) > len(cmdPath) {
+			continue
+		}
+		matched := true
+		for j, w := range words {
+			if w != cmdPath[j] {
+				matched = false
+				break
+			}
+		}
+		if matched && len(words) > bestLen {
+			best, bestLen = i, len(words)
+		}
+	}
+	if best < 0 {
+		return config.PolicyRule{}, false
+	}
+	return rules[best], true
+}
+
+func matchPolicyPattern(patterns []string, value string) (string, bool) {
+	value = strings.ToLower(value)
+	for _, p := range patterns {
+		pattern := strings.ToLower(strings.TrimSpace(p))
+		if pattern == "" {
+			continue
+		}
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func policyCommandPath(kctx *kong.Context) []string {
+	out := make([]string, 0, 4)
+	for _, trace := range kctx.Path {
+		if trace.Command != nil {
+			out = append(out, strings.ToLower(trace.Command.Name))
+		}
+	}
+	if len(out) > 0 {
+		if alias, ok := rootShortcutCommands[out[0]]; ok {
+			out = append(append([]string{}, alias...), out[1:]...)
+		}
+	}
+	return out
+}
+
+// policyArgValues collects flag (by long name) and positional values for the selected command.
+// Comma-separated strings are split so each recipient/item is checked on its own.
+func policyArgValues(kctx *kong.Context) map[string][]string {
+	out := map[string][]string{}
+	for _, flag := range kctx.Flags() {
+		if flag == nil || flag.Value == nil {
+			continue
+		}
+		out[flag.Name] = policyValueStrings(flag.Target)
+	}
+	if node := kctx.Selected(); node != nil {
+		for _, pos := range node.Positional {
+			out[pos.Name] = policyValueStrings(pos.Target)
+		}
+	}
+	return out
+}
+
+func policyValueStrings(v reflect.Value) []string {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return policyValueStrings(v.Elem())
+	case reflect.Slice, reflect.Array:
+		out := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, policyValueStrings(v.Index(i))...)
+		}
+		return out
+	case reflect.String:
+		out := []string{}
+		for _, part := range strings.Split(v.String(), ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if addr, err := mail.ParseAddress(part); err == nil && addr.Address != "" {
+				part = addr.Address
+			}
+			out = append(out, part)
+		}
+		return out
+	case reflect.Bool:
+		return []string{strconv.FormatBool(v.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(v.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(v.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(v.Float(), 'f', -1, 64)}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicy = `{
+  // JSON5: comments + trailing commas
+  default: "allow",
+  rules: [
+    { command: "gmail send", args: { to: { allow: ["*@ourco.com"] } } },
+    { command: "drive share", args: { to: { deny: ["anyone"] } } },
+    { command: "drive delete", args: { permanent: { deny: ["true"] } } },
+    { command: "gmail batch delete", args: { messageId: { max_items: 2 } } },
+    { command: "calendar create", args: { "send-updates": { allow: ["none"] } } },
+    { command: "tasks", action: "deny" },
+  ],
+}`
+
+func writeTestPolicy(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json5")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	return path
+}
+
+func policyCheck(t *testing.T, policyPath string, args ...string) error {
+	t.Helper()
+	parser, _, err := newParser("test parser")
+	if err != nil {
+		t.Fatalf("newParser: %v", err)
+	}
+	kctx, err := parser.Parse(args)
+	if err != nil {
+		t.Fatalf("parse %v: %v", args, err)
+	}
+	_, err = enforcePolicy(kctx, policyPath)
+	return err
+}
+
+func TestEnforcePolicy_Arguments(t *testing.T) {
+	policyPath := writeTestPolicy(t, testPolicy)
+
+	cases := []struct {
+		name    string
+		args    []string
+		denied  bool
+		wantArg string
+	}{
+		{name: "send allowed", args: []string{"gmail", "send", "--to", "a@ourco.com, B <b@OurCo.com>"}},
+		{name: "send denied", args: []string{"gmail", "send", "--to", "a@ourco.com,x@evil.com"}, denied: true, wantArg: "to"},
+		{name: "send shortcut denied", args: []string{"send", "--to", "x@evil.com"}, denied: true, wantArg: "to"},
+		{name: "share user", args: []string{"drive", "share", "f1", "--to", "user", "--email", "a@b.com"}},
+		{name: "share anyone", args: []string{"drive", "share", "f1", "--to=anyone"}, denied: true, wantArg: "to"},
+		{name: "trash", args: []string{"drive", "delete", "f1"}},
+		{name: "permanent", args: []string{"drive", "delete", "f1", "--permanent"}, denied: true, wantArg: "permanent"},
+		{name: "batch small", args: []string{"gmail", "batch", "delete", "m1", "m2"}},
+		{name: "batch large", args: []string{"gmail", "batch", "delete", "m1", "m2", "m3"}, denied: true, wantArg: "messageId"},
+		{name: "create default", args: []string{"calendar", "create", "primary"}},
+		{name: "create none", args: []string{"calendar", "create", "primary", "--send-updates", "none"}},
+		{name: "create all", args: []string{"calendar", "create", "primary", "--send-updates", "all"}, denied: true, wantArg: "send-updates"},
+		{name: "denied group", args: []string{"tasks", "lists", "list"}, denied: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policyCheck(t, policyPath, tc.args...)
+			if !tc.denied {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if got := ExitCode(err); got != exitCodePolicyDenied {
+				t.Fatalf("expected exit code %d, got %d (%v)", exitCodePolicyDenied, got, err)
+			}
+			var perr *PolicyError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected PolicyError, got %T", err)
+			}
+			if perr.Arg != tc.wantArg {
+				t.Fatalf("expected arg %q, got %q (%v)", tc.wantArg, perr.Arg, perr)
+			}
+		})
+	}
+}
+
+func TestEnforcePolicy_RawMessageRecipients(t *testing.T) {
+	policyPath := writeTestPolicy(t, testPolicy)
+	dir := t.TempDir()
+	writeEML := func(name, to string) string {
+		path := filepath.Join(dir, name)
+		body := "From: me@ourco.com\r\nTo: " + to + "\r\nSubject: hi\r\n\r\nbody\r\n"
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write eml: %v", err)
+		}
+		return path
+	}
+	internal := writeEML("internal.eml", "a@ourco.com")
+	external := writeEML("external.eml", "a@ourco.com, X <x@evil.com>")
+
+	if err := policyCheck(t, policyPath, "gmail", "send", "--raw", internal); err != nil {
+		t.Fatalf("expected internal raw send allowed: %v", err)
+	}
+	for _, args := range [][]string{
+		{"gmail", "send", "--raw", external},
+		{"gmail", "send", "--raw", "-"},
+	} {
+		err := policyCheck(t, policyPath, args...)
+		var perr *PolicyError
+		if ExitCode(err) != exitCodePolicyDenied || !errors.As(err, &perr) {
+			t.Fatalf("%v: expected policy denial, got %v", args, err)
+		}
+	}
+	// Drafts are not constrained by this policy, so stdin stays usable there.
+	if err := policyCheck(t, policyPath, "gmail", "drafts", "create", "--raw", "-"); err != nil {
+		t.Fatalf("expected unconstrained raw draft allowed: %v", err)
+	}
+}
+
+func TestEnforcePolicy_DefaultDeny(t *testing.T) {
+	policyPath := writeTestPolicy(t, `{default: "deny", rules: [{command: "drive ls"}]}`)
+
+	if err := policyCheck(t, policyPath, "drive", "ls"); err != nil {
+		t.Fatalf("expected drive ls allowed: %v", err)
+	}
+	if err := policyCheck(t, policyPath, "drive", "delete", "f1"); ExitCode(err) != exitCodePolicyDenied {
+		t.Fatalf("expected drive delete denied, got %v", err)
+	}
+}
+
+func TestEnforcePolicy_InvalidFile(t *testing.T) {
+	policyPath := writeTestPolicy(t, `{default: "maybe"}`)
+	err := policyCheck(t, policyPath, "drive", "ls")
+	if ExitCode(err) != exitCodeConfig {
+		t.Fatalf("expected config exit code, got %v", err)
+	}
+}
+
+func TestExecute_PolicyJSONError(t *testing.T) {
+	policyPath := writeTestPolicy(t, testPolicy)
+
+	var err error
+	errOut := captureStderr(t, func() {
+		err = Execute([]string{"--json", "--policy", policyPath, "drive", "delete", "f1", "--permanent"})
+	})
+	if ExitCode(err) != exitCodePolicyDenied {
+		t.Fatalf("expected policy exit code, got %v", err)
+	}
+
+	var doc struct {
+		Error struct {
+			Code     string `json:"code"`
+			ExitCode int    `json:"exit_code"`
+			Command  string `json:"command"`
+			Arg      string `json:"arg"`
+		} `json:"error"`
+	}
+	if uErr := json.Unmarshal([]byte(errOut), &doc); uErr != nil {
+		t.Fatalf("unmarshal: %v (out=%q)", uErr, errOut)
+	}
+	if doc.Error.Code != "policy_denied" || doc.Error.ExitCode != exitCodePolicyDenied {
+		t.Fatalf("unexpected error doc: %#v", doc.Error)
+	}
+	if doc.Error.Command != "drive delete" || doc.Error.Arg != "permanent" {
+		t.Fatalf("unexpected error doc: %#v", doc.Error)
+	}
+}
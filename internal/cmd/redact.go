@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+)
+
+// newRedactor builds the --redact masker from flags plus config.json redact_patterns.
+func newRedactor(flags *RootFlags) (*outfmt.Redactor, error) {
+	patterns := append([]string{}, flags.RedactPattern...)
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, cfg.RedactPatterns...)
+
+	mapPath, err := config.ExpandPath(strings.TrimSpace(flags.RedactMap))
+	if err != nil {
+		return nil, err
+	}
+
+	return outfmt.NewRedactor(outfmt.RedactOptions{
+		Patterns: patterns,
+		MapPath:  mapPath,
+	})
+}
+
+type AgentUnredactCmd struct {
+	Map   string `name:"map" help:"Redaction map file written by --redact-map" default:"${redact_map}"`
+	Input string `arg:"" name:"file" optional:"" help:"Input file ('-' or omitted for stdin)"`
+}
+
+func (c *AgentUnredactCmd) Run(_ context.Context, flags *RootFlags) error {
+	if flags != nil && flags.Redact {
+		return usage("agent unredact cannot be combined with --redact")
+	}
+	mapPath, err := config.ExpandPath(strings.TrimSpace(c.Map))
+	if err != nil {
+		return err
+	}
+	if mapPath == "" {
+		return usage("missing --map (or GOG_REDACT_MAP)")
+	}
+	tokens, err := outfmt.LoadRedactionMap(mapPath)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if path := strings.TrimSpace(c.Input); path != "" && path != "-" {
+		path, err = config.ExpandPath(path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path) //nolint:gosec // user-provided input path
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	return outfmt.UnredactLines(os.Stdout, in, tokens)
+}
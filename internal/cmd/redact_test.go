@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+func TestExecute_RedactTextAndJSON(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	if err := config.SetAccountAlias("work", "alice@example.com"); err != nil {
+		t.Fatalf("SetAccountAlias: %v", err)
+	}
+	mapPath := filepath.Join(home, "redact-map.json")
+
+	text := captureStdout(t, func() {
+		if err := Execute([]string{"--redact", "--redact-map", mapPath, "auth", "alias", "list"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	})
+	if strings.Contains(text, "alice@example.com") || !strings.Contains(text, "[EMAIL_1]") {
+		t.Fatalf("expected redacted text output, got %q", text)
+	}
+
+	jsonOut := captureStdout(t, func() {
+		if err := Execute([]string{"--json", "--redact", "--redact-map", mapPath, "auth", "alias", "list"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	})
+	if strings.Contains(jsonOut, "alice@example.com") || !strings.Contains(jsonOut, `"work": "[EMAIL_1]"`) {
+		t.Fatalf("expected redacted json output, got %q", jsonOut)
+	}
+
+	in := filepath.Join(home, "in.txt")
+	if err := os.WriteFile(in, []byte("reply to [EMAIL_1]\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	restored := captureStdout(t, func() {
+		if err := Execute([]string{"agent", "unredact", "--map", mapPath, in}); err != nil {
+			t.Fatalf("unredact: %v", err)
+		}
+	})
+	if restored != "reply to alice@example.com\n" {
+		t.Fatalf("unexpected unredact output: %q", restored)
+	}
+}
+
+func TestExecute_RedactErrors(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	var err error
+	errOut := captureStderr(t, func() {
+		err = Execute([]string{"--redact", "--redact-pattern", "(", "version"})
+	})
+	if ExitCode(err) != 2 || !strings.Contains(errOut, "invalid redact pattern") {
+		t.Fatalf("expected printed usage error, got %v (stderr=%q)", err, errOut)
+	}
+
+	policyPath := writeTestPolicy(t, testPolicy)
+	for _, jsonMode := range []bool{true, false} {
+		args := []string{"--redact", "--policy", policyPath, "gmail", "send", "--to", "bob@example.com", "--subject", "s", "--body", "b"}
+		if jsonMode {
+			args = append([]string{"--json"}, args...)
+		}
+		errOut = captureStderr(t, func() {
+			err = Execute(args)
+		})
+		if ExitCode(err) != exitCodePolicyDenied {
+			t.Fatalf("expected policy denial, got %v", err)
+		}
+		if strings.Contains(errOut, "bob@example.com") || !strings.Contains(errOut, "[EMAIL_1]") {
+			t.Fatalf("expected redacted policy error (json=%v), got %q", jsonMode, errOut)
+		}
+	}
+}
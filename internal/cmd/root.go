@@ -28,18 +28,26 @@ const (
 )
 
 type RootFlags struct {
-	Color          string `help:"Color output: auto|always|never" default:"${color}"`
-	Account        string `help:"Account email for API commands (gmail/calendar/chat/classroom/drive/docs/slides/contacts/tasks/people/sheets/forms/appscript)" aliases:"acct" short:"a"`
-	Client         string `help:"OAuth client name (selects stored credentials + token bucket)" default:"${client}"`
-	EnableCommands string `help:"Comma-separated list of enabled top-level commands (restricts CLI)" default:"${enabled_commands}"`
-	JSON           bool   `help:"Output JSON to stdout (best for scripting)" default:"${json}" aliases:"machine" short:"j"`
-	Plain          bool   `help:"Output stable, parseable text to stdout (TSV; no colors)" default:"${plain}" aliases:"tsv" short:"p"`
-	ResultsOnly    bool   `name:"results-only" help:"In JSON mode, emit only the primary result (drops envelope fields like nextPageToken)"`
-	Select         string `name:"select" aliases:"pick,project" help:"In JSON mode, select comma-separated fields (best-effort; supports dot paths). Desire path: use --fields for most commands."`
-	DryRun         bool   `help:"Do not make changes; print intended actions and exit successfully" aliases:"noop,preview,dryrun" short:"n"`
-	Force          bool   `help:"Skip confirmations for destructive commands" aliases:"yes,assume-yes" short:"y"`
-	NoInput        bool   `help:"Never prompt; fail instead (useful for CI)" aliases:"non-interactive,noninteractive"`
-	Verbose        bool   `help:"Enable verbose logging" short:"v"`
+	Color           string   `help:"Color output: auto|always|never" default:"${color}"`
+	Account         string   `help:"Account email for API commands (gmail/calendar/chat/classroom/drive/docs/slides/contacts/tasks/people/sheets/forms/appscript)" aliases:"acct" short:"a"`
+	Client          string   `help:"OAuth client name (selects stored credentials + token bucket)" default:"${client}"`
+	EnableCommands  string   `help:"Comma-separated list of enabled top-level commands (restricts CLI)" default:"${enabled_commands}"`
+	Policy          string   `help:"Policy file (JSON5) allowing/denying subcommands and argument values" default:"${policy}"`
+	JSON            bool     `help:"Output JSON to stdout (best for scripting)" default:"${json}" aliases:"machine" short:"j"`
+	Plain           bool     `help:"Output stable, parseable text to stdout (TSV; no colors)" default:"${plain}" aliases:"tsv" short:"p"`
+	ResultsOnly     bool     `name:"results-only" help:"In JSON mode, emit only the primary result (drops envelope fields like nextPageToken)"`
+	Redact          bool     `help:"Mask emails, phone numbers, street addresses and --redact-pattern matches in output" default:"${redact}"`
+	RedactPattern   []string `name:"redact-pattern" sep:"none" help:"Extra regex to mask with --redact (repeatable; also config redact_patterns)"`
+	MaxOutputBytes  int      `name:"max-output-bytes" help:"Cut output at a clean item/paragraph/row boundary after N bytes (adds a --continue token)" default:"${max_output_bytes}"`
+	MaxItems        int      `name:"max-items" help:"Cut output after N items/rows/lines (adds a --continue token)" default:"${max_items}"`
+	Continue        string   `name:"continue" help:"Resume truncated output from a continuation token"`
+	RedactMap       string   `name:"redact-map" help:"File that stores token -> value mappings so --redact output can be un-redacted" default:"${redact_map}"`
+	Select          string   `name:"select" aliases:"pick,project" help:"In JSON mode, select comma-separated fields (best-effort; supports dot paths). Desire path: use --fields for most commands."`
+	DryRun          bool     `help:"Do not make changes; print intended actions and exit successfully" aliases:"noop,preview,dryrun" short:"n"`
+	Force           bool     `help:"Skip confirmations for destructive commands" aliases:"yes,assume-yes" short:"y"`
+	RequireApproval bool     `name:"require-approval" help:"Queue destructive commands for human approval instead of running them (see 'gog approvals')" default:"${require_approval}"`
+	NoInput         bool     `help:"Never prompt; fail instead (useful for CI)" aliases:"non-interactive,noninteractive"`
+	Verbose         bool     `help:"Enable verbose logging" short:"v"`
 }
 
 type CLI struct {
@@ -80,6 +88,7 @@ type CLI struct {
 	Config     ConfigCmd             `cmd:"" hidden:"" help:"Manage configuration"`
 	ExitCodes  AgentExitCodesCmd     `cmd:"" name:"exit-codes" aliases:"exitcodes" hidden:"" help:"Print stable exit codes (alias for 'agent exit-codes')"`
 	Agent      AgentCmd              `cmd:"" hidden:"" help:"Agent-friendly helpers"`
+	Approvals  ApprovalsCmd          `cmd:"" aliases:"approval" hidden:"" help:"Review destructive requests queued by --require-approval"`
 	Schema     SchemaCmd             `cmd:"" hidden:"" help:"Machine-readable command/flag schema" aliases:"help-json,helpjson"`
 	VersionCmd VersionCmd            `cmd:"" name:"version" hidden:"" help:"Print version"`
 	Completion CompletionCmd         `cmd:"" hidden:"" help:"Generate shell completion scripts"`
@@ -88,7 +97,12 @@ type CLI struct {
 
 type exitPanic struct{ code int }
 
-func Execute(args []string) (err error) {
+func Execute(args []string) error {
+	return execute(context.Background(), args)
+}
+
+// execute runs one command line; base may carry an approved request being replayed.
+func execute(base context.Context, args []string) (err error) {
 	args = rewriteDesirePathArgs(args)
 
 	parser, cli, err := newParser(helpDescription())
@@ -113,12 +127,35 @@ func Execute(args []string) (err error) {
 	kctx, err := parser.Parse(args)
 	if err != nil {
 		parsedErr := wrapParseError(err)
-		_, _ = fmt.Fprintln(os.Stderr, errfmt.Format(parsedErr))
+		writeError(os.Stderr, cli.JSON || outfmt.FromEnv().JSON || argsRequestJSON(args), cli.Account, nil, parsedErr)
 		return parsedErr
 	}
 
+	// Build the redactor before any error can be printed, so policy denials and
+	// other stderr messages are masked too.
+	var redactor *outfmt.Redactor
+	if cli.Redact {
+		redactor, err = newRedactor(&cli.RootFlags)
+		if err != nil {
+			err = newUsageError(err)
+			writeError(os.Stderr, cli.JSON || outfmt.FromEnv().JSON, cli.Account, nil, err)
+			return err
+		}
+		defer func() {
+			if saveErr := redactor.Save(); saveErr != nil {
+				slog.Warn("save redaction map", "err", saveErr)
+			}
+		}()
+	}
+
 	if err = enforceEnabledCommands(kctx, cli.EnableCommands); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, errfmt.Format(err))
+		writeError(os.Stderr, cli.JSON || outfmt.FromEnv().JSON, cli.Account, redactor, err)
+		return err
+	}
+
+	policyRaw, err := enforcePolicy(kctx, cli.Policy)
+	if err != nil {
+		writeError(os.Stderr, cli.JSON || outfmt.FromEnv().JSON, cli.Account, redactor, err)
 		return err
 	}
 
@@ -141,7 +178,14 @@ func Execute(args []string) (err error) {
 		return newUsageError(err)
 	}
 
-	ctx := context.Background()
+	ctx := base
+	if st := invocationFromContext(ctx); st != nil {
+		st.args = args
+		st.target = commandTargetArgs(kctx)
+		st.raw = policyRaw
+	} else {
+		ctx = withInvocation(ctx, &invocationState{args: args, target: commandTargetArgs(kctx), raw: policyRaw})
+	}
 	ctx = outfmt.WithMode(ctx, mode)
 	ctx = outfmt.WithJSONTransform(ctx, outfmt.JSONTransform{
 		ResultsOnly: cli.ResultsOnly,
@@ -149,6 +193,43 @@ func Execute(args []string) (err error) {
 	})
 	ctx = authclient.WithClient(ctx, cli.Client)
 
+	if redactor != nil {
+		ctx = outfmt.WithRedactor(ctx, redactor)
+		// JSON written through outfmt is already masked; the stdout filter also
+		// catches commands that stream to stdout directly, in either mode.
+		restore, redirectErr := redirectStdout(redactor.Writer)
+		if redirectErr != nil {
+			writeError(os.Stderr, mode.JSON, cli.Account, redactor, redirectErr)
+			return redirectErr
+		}
+		defer restore()
+	}
+
+	budget, err := newOutputBudget(&cli.RootFlags, args, mode.JSON)
+	if err != nil {
+		writeError(os.Stderr, mode.JSON, cli.Account, redactor, err)
+		return err
+	}
+	if budget.Active() {
+		ctx = outfmt.WithBudget(ctx, budget)
+		restore := func() {}
+		if !mode.JSON {
+			var redirectErr error
+			restore, redirectErr = redirectStdout(budget.TextWriter)
+			if redirectErr != nil {
+				writeError(os.Stderr, mode.JSON, cli.Account, redactor, redirectErr)
+				return redirectErr
+			}
+		}
+		// Text output, and JSON without an object to carry the cursor, report it here.
+		defer func() {
+			restore()
+			if budget.Truncated && (!mode.JSON || budget.OutOfBand) {
+				_, _ = fmt.Fprintf(os.Stderr, "# Truncated: --continue %s\n", budget.Next)
+			}
+		}()
+	}
+
 	uiColor := cli.Color
 	if outfmt.IsJSON(ctx) || outfmt.IsPlain(ctx) {
 		uiColor = colorNever
@@ -177,14 +258,18 @@ func Execute(args []string) (err error) {
 	}
 	err = stableExitCode(err)
 
+	if outfmt.IsJSON(ctx) {
+		writeError(os.Stderr, true, cli.Account, redactor, err)
+		return err
+	}
 	if u := ui.FromContext(ctx); u != nil {
-		msg := strings.TrimSpace(errfmt.Format(err))
+		msg := strings.TrimSpace(redactor.String(errfmt.Format(err)))
 		if msg != "" {
 			u.Err().Error(msg)
 		}
 		return err
 	}
-	msg := strings.TrimSpace(errfmt.Format(err))
+	msg := strings.TrimSpace(redactor.String(errfmt.Format(err)))
 	if msg != "" {
 		_, _ = fmt.Fprintln(os.Stderr, msg)
 	}
@@ -254,7 +339,7 @@ func isCalendarEventsCommand(args []string) bool {
 
 func globalFlagTakesValue(flag string) bool {
 	switch flag {
-	case "--color", "--account", "--acct", "--client", "--enable-commands", "--select", "--pick", "--project", "-a":
+	case "--color", "--account", "--acct", "--client", "--enable-commands", "--policy", "--redact-pattern", "--redact-map", "--max-output-bytes", "--max-items", "--continue", "--select", "--pick", "--project", "-a":
 		return true
 	default:
 		return false
@@ -304,6 +389,12 @@ func newParser(description string) (*kong.Kong, *CLI, error) {
 		"calendar_weekday": envOr("GOG_CALENDAR_WEEKDAY", "false"),
 		"client":           envOr("GOG_CLIENT", ""),
 		"enabled_commands": envOr("GOG_ENABLE_COMMANDS", ""),
+		"policy":           envOr("GOG_POLICY", ""),
+		"require_approval": boolString(envBool("GOG_REQUIRE_APPROVAL")),
+		"redact":           boolString(envBool("GOG_REDACT")),
+		"redact_map":       envOr("GOG_REDACT_MAP", ""),
+		"max_output_bytes": envOr("GOG_MAX_OUTPUT_BYTES", "0"),
+		"max_items":        envOr("GOG_MAX_ITEMS", "0"),
 		"json":             boolString(envMode.JSON),
 		"plain":            boolString(envMode.Plain),
 		"version":          VersionString(),
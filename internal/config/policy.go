@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yosuke-furukawa/json5/encoding/json5"
+)
+
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+)
+
+var errInvalidPolicy = errors.New("invalid policy")
+
+// Policy is a declarative allow/deny list for commands and their arguments.
+// It is stored as JSON5 (same as the config file) and loaded via --policy / GOG_POLICY.
+type Policy struct {
+	// Default decides commands that no rule matches: "allow" (default) or "deny".
+	Default string       `json:"default,omitempty"`
+	Rules   []PolicyRule `json:"rules,omitempty"`
+}
+
+// PolicyRule matches a command path prefix (e.g. "gmail", "gmail send", "*").
+type PolicyRule struct {
+	Command string                    `json:"command"`
+	Action  string                    `json:"action,omitempty"`
+	Args    map[string]ArgumentPolicy `json:"args,omitempty"`
+}
+
+// ArgumentPolicy constrains the values of a flag or positional argument.
+// Patterns are case-insensitive globs (path.Match syntax).
+type ArgumentPolicy struct {
+	Allow    []string `json:"allow,omitempty"`
+	Deny     []string `json:"deny,omitempty"`
+	MaxItems int      `json:"max_items,omitempty"`
+	Required bool     `json:"required,omitempty"`
+}
+
+func ReadPolicy(path string) (Policy, error) {
+	expanded, err := ExpandPath(strings.TrimSpace(path))
+	if err != nil {
+		return Policy{}, err
+	}
+
+	b, err := os.ReadFile(expanded) //nolint:gosec // user-provided policy path
+	if err != nil {
+		return Policy{}, fmt.Errorf("read policy: %w", err)
+	}
+
+	var p Policy
+	if err := json5.Unmarshal(b, &p); err != nil {
+		return Policy{}, fmt.Errorf("parse policy %s: %w", expanded, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return Policy{}, fmt.Errorf("policy %s: %w", expanded, err)
+	}
+
+	return p, nil
+}
+
+func (p Policy) Validate() error {
+	if !validPolicyAction(p.Default) {
+		return fmt.Errorf("%w: default must be allow|deny, got %q", errInvalidPolicy, p.Default)
+	}
+
+	for i, r := range p.Rules {
+		if strings.TrimSpace(r.Command) == "" {
+			return fmt.Errorf("%w: rules[%d]: missing command", errInvalidPolicy, i)
+		}
+
+		if !validPolicyAction(r.Action) {
+			return fmt.Errorf("%w: rules[%d]: action must be allow|deny, got %q", errInvalidPolicy, i, r.Action)
+		}
+
+		for name, a := range r.Args {
+			if a.MaxItems < 0 {
+				return fmt.Errorf("%w: rules[%d].args.%s: max_items must be >= 0", errInvalidPolicy, i, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validPolicyAction(action string) bool {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case "", PolicyAllow, PolicyDeny:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+// Package hooksig signs and verifies webhook requests sent by the gmail watch
+// server.
+//
+// A signed request carries two headers:
+//
+//	X-Gog-Delivery:  <delivery id>
+//	X-Gog-Signature: t=<unix seconds>,v1=<hex hmac>
+//
+// The HMAC is SHA-256 over "<t>.<delivery id>.<raw body>" keyed with the hook
+// secret. Receivers recompute it and reject requests whose timestamp is outside
+// the tolerance window, which bounds replays; the delivery ID is signed too, so
+// it can be used to drop duplicates inside the window.
+package hooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the timestamp and signature.
+	SignatureHeader = "X-Gog-Signature"
+	// DeliveryHeader holds the delivery ID. It stays the same across retries.
+	DeliveryHeader = "X-Gog-Delivery"
+	// DefaultTolerance is the accepted clock skew / replay window.
+	DefaultTolerance = 5 * time.Minute
+
+	schemeV1 = "v1"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrMalformed        = errors.New("malformed signature header")
+	ErrTimestamp        = errors.New("signature timestamp outside tolerance")
+	ErrMismatch         = errors.New("signature mismatch")
+)
+
+// Sign returns the X-Gog-Signature header value for body.
+func Sign(secret string, ts time.Time, deliveryID string, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + "," + schemeV1 + "=" + hex.EncodeToString(mac(secret, t, deliveryID, body))
+}
+
+// Verify checks header against body. Several v1 entries are accepted so a
+// sender can sign with an old and a new secret while rotating. A tolerance of
+// zero or less disables the timestamp check.
+func Verify(secret, header, deliveryID string, body []byte, now time.Time, tolerance time.Duration) error {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return ErrMissingSignature
+	}
+	var (
+		t    string
+		sigs [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformed
+		}
+		switch key {
+		case "t":
+			t = value
+		case schemeV1:
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrMalformed
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrMalformed
+	}
+	if tolerance > 0 {
+		skew := now.Sub(time.Unix(unix, 0))
+		if skew > tolerance || skew < -tolerance {
+			return ErrTimestamp
+		}
+	}
+	want := mac(secret, t, deliveryID, body)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+func mac(secret, t, deliveryID string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte{'.'})
+	h.Write([]byte(deliveryID))
+	h.Write([]byte{'.'})
+	h.Write(body)
+	return h.Sum(nil)
+}
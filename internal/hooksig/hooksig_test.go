@@ -0,0 +1,52 @@
+package hooksig
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1767225600, 0)
+	body := []byte(`{"historyId":"1"}`)
+	header := Sign("s3cret", now, "d1", body)
+	// HMAC-SHA256("s3cret", `1767225600.d1.{"historyId":"1"}`)
+	if header != "t=1767225600,v1=aeeea0b453d7ab2b4ece98a2c3a8fde6a1ab49186c4f36c30c76a95949e4f096" {
+		t.Fatalf("unexpected header %q", header)
+	}
+
+	if err := Verify("s3cret", header, "d1", body, now.Add(time.Minute), DefaultTolerance); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	cases := []struct {
+		name     string
+		secret   string
+		header   string
+		delivery string
+		body     string
+		now      time.Time
+		want     error
+	}{
+		{"wrong secret", "other", header, "d1", string(body), now, ErrMismatch},
+		{"tampered body", "s3cret", header, "d1", `{"historyId":"2"}`, now, ErrMismatch},
+		{"swapped delivery", "s3cret", header, "d2", string(body), now, ErrMismatch},
+		{"replayed", "s3cret", header, "d1", string(body), now.Add(10 * time.Minute), ErrTimestamp},
+		{"missing", "s3cret", "", "d1", string(body), now, ErrMissingSignature},
+		{"no timestamp", "s3cret", "v1=00", "d1", string(body), now, ErrMalformed},
+		{"bad hex", "s3cret", "t=1,v1=zz", "d1", string(body), now, ErrMalformed},
+	}
+	for _, tc := range cases {
+		if err := Verify(tc.secret, tc.header, tc.delivery, []byte(tc.body), tc.now, DefaultTolerance); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	// Rotation: either signature may match.
+	rotated := Sign("old", now, "d1", body) + ",v1=" + Sign("s3cret", now, "d1", body)[len("t=1767225600,v1="):]
+	if err := Verify("s3cret", rotated, "d1", body, now, DefaultTolerance); err != nil {
+		t.Fatalf("rotated verify: %v", err)
+	}
+	if err := Verify("s3cret", header, "d1", body, now.Add(time.Hour), 0); err != nil {
+		t.Fatalf("tolerance disabled: %v", err)
+	}
+}
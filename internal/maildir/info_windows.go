@@ -0,0 +1,7 @@
+//go:build windows
+
+package maildir
+
+// Windows file names cannot contain ':', so the info suffix uses the
+// common '!' substitute (Mutt, isync).
+const infoSep = "!2,"
@@ -0,0 +1,140 @@
+// Package maildir reads and writes Maildir and Maildir++ mail stores.
+package maildir
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Inbox is the folder name of the Maildir root.
+const Inbox = "INBOX"
+
+var errNotMaildir = errors.New("not a Maildir")
+
+// Folder is one maildir (a directory with cur/new/tmp) inside a store.
+type Folder struct {
+	Name string // "INBOX" for the root, "Archive/2019" for ".Archive.2019" or Archive/2019
+	Path string
+}
+
+// Message is one message file with its Maildir info flags.
+type Message struct {
+	Path  string
+	Flags string // sorted info flags after ":2,", eg. "FS"
+}
+
+func (m Message) Seen() bool    { return strings.Contains(m.Flags, "S") }
+func (m Message) Flagged() bool { return strings.Contains(m.Flags, "F") }
+func (m Message) Trashed() bool { return strings.Contains(m.Flags, "T") }
+
+// IsMaildir reports whether dir holds a cur or new subdirectory.
+func IsMaildir(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if st, err := os.Stat(filepath.Join(dir, sub)); err == nil && st.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// Folders lists every maildir under root, root first, then by name. Both the
+// Maildir++ layout (".Sent", ".Archive.2019") and nested directories are
+// recognized.
+func Folders(root string) ([]Folder, error) {
+	var out []Folder
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		switch d.Name() {
+		case "cur", "new", "tmp":
+			if path != root {
+				return filepath.SkipDir
+			}
+		}
+		if !IsMaildir(path) {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return relErr
+		}
+		out = append(out, Folder{Name: folderName(rel), Path: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNotMaildir, root)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if (out[i].Name == Inbox) != (out[j].Name == Inbox) {
+			return out[i].Name == Inbox
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func folderName(rel string) string {
+	if rel == "." {
+		return Inbox
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	names := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if strings.HasPrefix(p, ".") {
+			// Maildir++: ".Archive.2019" is Archive/2019.
+			names = append(names, strings.Split(strings.TrimPrefix(p, "."), ".")...)
+			continue
+		}
+		names = append(names, p)
+	}
+	return strings.Join(names, "/")
+}
+
+// Messages lists the messages in new and cur, sorted by file name (delivery
+// order for standard unique names).
+func Messages(dir string) ([]Message, error) {
+	var out []Message
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			out = append(out, Message{Path: filepath.Join(dir, sub, e.Name()), Flags: parseFlags(e.Name())})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return filepath.Base(out[i].Path) < filepath.Base(out[j].Path) })
+	return out, nil
+}
+
+func parseFlags(name string) string {
+	_, info, ok := strings.Cut(name, ":2,")
+	if !ok {
+		// Some filesystems cannot store ':' and use '!' instead.
+		if _, info, ok = strings.Cut(name, "!2,"); !ok {
+			return ""
+		}
+	}
+	flags := []rune(info)
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+	return string(flags)
+}
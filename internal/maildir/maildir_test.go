@@ -0,0 +1,75 @@
+package maildir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFoldersAndMessages(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"cur", "new", "tmp", ".Sent/cur", ".Archive.2019/new", "Projects/Alpha/cur"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"new/2.host", "cur/1.host:2,SF", "cur/3.host!2,S", "cur/.hidden"} {
+		if err := os.WriteFile(filepath.Join(root, f), []byte("Subject: x\n\nx\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	folders, err := Folders(root)
+	if err != nil {
+		t.Fatalf("folders: %v", err)
+	}
+	var names []string
+	for _, f := range folders {
+		names = append(names, f.Name)
+	}
+	want := []string{"INBOX", "Archive/2019", "Projects/Alpha", "Sent"}
+	if len(names) != len(want) {
+		t.Fatalf("folders = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("folders = %v, want %v", names, want)
+		}
+	}
+
+	msgs, err := Messages(root)
+	if err != nil {
+		t.Fatalf("messages: %v", err)
+	}
+	if len(msgs) != 3 || msgs[0].Flags != "FS" || !msgs[0].Seen() || !msgs[0].Flagged() || msgs[1].Seen() || msgs[2].Flags != "S" {
+		t.Fatalf("unexpected messages: %#v", msgs)
+	}
+
+	if _, err := Folders(t.TempDir()); err == nil {
+		t.Fatalf("expected error for empty dir")
+	}
+}
+
+func TestDeliverIntoFolder(t *testing.T) {
+	root := t.TempDir()
+	dir := FolderPath(root, "Archive/2019")
+	if filepath.Base(dir) != ".Archive.2019" || FolderPath(root, Inbox) != root {
+		t.Fatalf("unexpected folder paths: %s", dir)
+	}
+
+	path, err := Deliver(dir, "1700000000.abc.gog", "FS", []byte("Subject: x\n\nx\n"))
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if path != filepath.Join(dir, "cur", "1700000000.abc.gog"+infoSep+"FS") {
+		t.Fatalf("unexpected path %s", path)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(entries) != 0 {
+		t.Fatalf("tmp not empty: %v", entries)
+	}
+
+	folders, err := Folders(root)
+	if err != nil || len(folders) != 1 || folders[0].Name != "Archive/2019" {
+		t.Fatalf("folders = %v (%v)", folders, err)
+	}
+}
@@ -0,0 +1,51 @@
+package maildir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FolderPath returns the directory of folder name inside a Maildir++ store:
+// the root for INBOX, ".A.B" for "A/B".
+func FolderPath(root, name string) string {
+	if name == "" || strings.EqualFold(name, Inbox) {
+		return root
+	}
+	return filepath.Join(root, "."+strings.ReplaceAll(strings.Trim(name, "/"), "/", "."))
+}
+
+// Create makes dir a maildir (cur, new and tmp subdirectories).
+func Create(dir string) error {
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return fmt.Errorf("create maildir %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// FileName appends the info suffix for flags to a unique base name, using
+// "!2," instead of ":2," on Windows.
+func FileName(base, flags string) string {
+	return base + infoSep + flags
+}
+
+// Deliver writes data to tmp and renames it into cur under base with flags,
+// so readers never see a partial message. It returns the final path.
+func Deliver(dir, base, flags string, data []byte) (string, error) {
+	if err := Create(dir); err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(dir, "tmp", base)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", err
+	}
+	final := filepath.Join(dir, "cur", FileName(base, flags))
+	if err := os.Rename(tmp, final); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return final, nil
+}
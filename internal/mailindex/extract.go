@@ -0,0 +1,134 @@
+package mailindex
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// maxPartText bounds how much of a single text part is indexed; the tail of a
+// huge message adds little to search quality.
+const maxPartText = 1 << 20
+
+var (
+	htmlDropRe = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlTagRe  = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// extracted is the searchable content of one RFC 822 message.
+type extracted struct {
+	From       string
+	To         string // To, Cc and Bcc
+	Subject    string
+	Date       string
+	Body       string
+	Attachment bool
+}
+
+func extract(raw []byte) (*extracted, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	dec := &mime.WordDecoder{}
+	header := func(name string) string {
+		v := msg.Header.Get(name)
+		if d, decErr := dec.DecodeHeader(v); decErr == nil {
+			return d
+		}
+		return v
+	}
+
+	out := &extracted{
+		From:    header("From"),
+		Subject: header("Subject"),
+		Date:    msg.Header.Get("Date"),
+	}
+	var to []string
+	for _, name := range []string{"To", "Cc", "Bcc"} {
+		if v := header(name); v != "" {
+			to = append(to, v)
+		}
+	}
+	out.To = strings.Join(to, ", ")
+
+	var body strings.Builder
+	walkPart(textproto.MIMEHeader(msg.Header), msg.Body, out, &body, 0)
+	out.Body = body.String()
+	return out, nil
+}
+
+// walkPart collects text from text/plain and text/html parts and notes
+// attachments. Malformed parts are skipped rather than failing the message.
+func walkPart(h textproto.MIMEHeader, r io.Reader, out *extracted, body *strings.Builder, depth int) {
+	if depth > 10 {
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, partErr := mr.NextPart()
+			if partErr != nil {
+				return
+			}
+			walkPart(part.Header, part, out, body, depth+1)
+		}
+	}
+
+	if disposition == "attachment" || dparams["filename"] != "" || params["name"] != "" {
+		out.Attachment = true
+		return
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return
+	}
+
+	// multipart.Part decodes quoted-printable itself and drops the header, so
+	// the header is only still present on a single-part message body.
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxPartText))
+	if err != nil && len(data) == 0 {
+		return
+	}
+	text := decodeCharset(data, params["charset"])
+	if mediaType == "text/html" {
+		text = htmlDropRe.ReplaceAllString(text, " ")
+		text = html.UnescapeString(htmlTagRe.ReplaceAllString(text, " "))
+	}
+	body.WriteString(text)
+	body.WriteByte('\n')
+}
+
+// decodeCharset handles the single-byte Latin charsets; anything else is
+// treated as UTF-8, where stray bytes simply become token separators.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(data)
+	}
+}
@@ -0,0 +1,371 @@
+// Package mailindex is an embedded inverted index over locally mirrored mail,
+// searchable with a subset of Gmail query syntax.
+package mailindex
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const version = 1
+
+// Fields a term can be indexed under. Free text matches any of them.
+const (
+	fieldFrom    = "from"
+	fieldTo      = "to"
+	fieldSubject = "subject"
+	fieldBody    = "body"
+)
+
+var textFields = []string{fieldFrom, fieldTo, fieldSubject, fieldBody}
+
+// maxTokenLen skips base64 runs and other noise that nobody searches for.
+const maxTokenLen = 64
+
+// Doc is the stored metadata of one indexed message.
+type Doc struct {
+	ThreadID   string
+	Path       string // message file, relative to the mirror root
+	Time       int64  // Gmail internal date, unix ms
+	Date       string // raw Date header, for display
+	From       string
+	Subject    string
+	Labels     []string // Gmail label IDs
+	Attachment bool
+	Terms      []string // posting keys, kept so the doc can be removed
+}
+
+// Meta is what the caller knows about a message besides its content.
+type Meta struct {
+	ThreadID string
+	Path     string
+	Time     int64
+	Labels   []string
+}
+
+// Index maps "field:token" terms to message IDs. It is stored with gob: the
+// posting table of a large mailbox is big and has to load instantly.
+type Index struct {
+	Version    int
+	LabelNames map[string]string // label ID -> display name
+	Docs       map[string]*Doc
+	Postings   map[string][]string
+
+	path  string
+	dirty bool
+}
+
+// Open loads the index at path, or returns an empty one if it does not exist
+// or was written by another version.
+func Open(path string) (*Index, error) {
+	idx := &Index{path: path}
+	f, err := os.Open(path) //nolint:gosec // index path under the gog config dir
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		decodeErr := gob.NewDecoder(f).Decode(idx)
+		_ = f.Close()
+		if decodeErr != nil || idx.Version != version {
+			idx = &Index{path: path, dirty: true}
+		}
+	}
+	idx.Version = version
+	if idx.Docs == nil {
+		idx.Docs = map[string]*Doc{}
+	}
+	if idx.Postings == nil {
+		idx.Postings = map[string][]string{}
+	}
+	return idx, nil
+}
+
+// Path returns the file the index is stored in.
+func (x *Index) Path() string { return x.path }
+
+// Save writes the index atomically if it changed since it was opened.
+func (x *Index) Save() error {
+	if !x.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0o700); err != nil {
+		return fmt.Errorf("ensure index dir: %w", err)
+	}
+	tmp := x.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) //nolint:gosec // index path under the gog config dir
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(x); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		return err
+	}
+	x.dirty = false
+	return nil
+}
+
+// Len returns the number of indexed messages.
+func (x *Index) Len() int { return len(x.Docs) }
+
+// Doc returns the stored metadata of message id.
+func (x *Index) Doc(id string) (*Doc, bool) {
+	d, ok := x.Docs[id]
+	return d, ok
+}
+
+// IDs returns the indexed message IDs.
+func (x *Index) IDs() []string {
+	ids := make([]string, 0, len(x.Docs))
+	for id := range x.Docs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SetLabelNames records the display names used by label: queries.
+func (x *Index) SetLabelNames(names map[string]string) {
+	x.LabelNames = names
+	x.dirty = true
+}
+
+// Add indexes raw (an RFC 822 message) under id, replacing any earlier entry.
+func (x *Index) Add(id string, meta Meta, raw []byte) error {
+	content, err := extract(raw)
+	if err != nil {
+		return fmt.Errorf("index message %s: %w", id, err)
+	}
+	x.Remove(id)
+
+	seen := map[string]struct{}{}
+	var terms []string
+	add := func(field, text string) {
+		for _, tok := range tokenize(text) {
+			key := field + ":" + tok
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			terms = append(terms, key)
+			x.Postings[key] = append(x.Postings[key], id)
+		}
+	}
+	add(fieldFrom, content.From)
+	add(fieldTo, content.To)
+	add(fieldSubject, content.Subject)
+	add(fieldBody, content.Body)
+
+	x.Docs[id] = &Doc{
+		ThreadID:   meta.ThreadID,
+		Path:       meta.Path,
+		Time:       meta.Time,
+		Date:       content.Date,
+		From:       content.From,
+		Subject:    content.Subject,
+		Labels:     meta.Labels,
+		Attachment: content.Attachment,
+		Terms:      terms,
+	}
+	x.dirty = true
+	return nil
+}
+
+// Update refreshes the metadata of an indexed message without re-reading it.
+func (x *Index) Update(id string, meta Meta) {
+	d, ok := x.Docs[id]
+	if !ok {
+		return
+	}
+	d.ThreadID = meta.ThreadID
+	d.Path = meta.Path
+	d.Time = meta.Time
+	d.Labels = meta.Labels
+	x.dirty = true
+}
+
+// Remove drops id and its postings.
+func (x *Index) Remove(id string) {
+	d, ok := x.Docs[id]
+	if !ok {
+		return
+	}
+	for _, key := range d.Terms {
+		ids := x.Postings[key]
+		for i, v := range ids {
+			if v == id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(x.Postings, key)
+		} else {
+			x.Postings[key] = ids
+		}
+	}
+	delete(x.Docs, id)
+	x.dirty = true
+}
+
+// Search returns the IDs of messages matching q, newest first.
+func (x *Index) Search(q *Query) []string {
+	var candidates map[string]struct{}
+	for _, c := range q.clauses {
+		if c.neg || c.kind != clauseText {
+			continue
+		}
+		set := x.match(c)
+		if candidates == nil {
+			candidates = set
+			continue
+		}
+		for id := range candidates {
+			if _, ok := set[id]; !ok {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	// Negated text clauses are resolved once, not per message.
+	excluded := map[int]map[string]struct{}{}
+	for i, c := range q.clauses {
+		if c.neg && c.kind == clauseText {
+			excluded[i] = x.match(c)
+		}
+	}
+
+	var ids []string
+	consider := func(id string) {
+		if x.filter(id, q, excluded) {
+			ids = append(ids, id)
+		}
+	}
+	if candidates == nil {
+		for id := range x.Docs {
+			consider(id)
+		}
+	} else {
+		for id := range candidates {
+			consider(id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := x.Docs[ids[i]], x.Docs[ids[j]]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// match returns the messages containing every token of a text clause.
+func (x *Index) match(c clause) map[string]struct{} {
+	fields := textFields
+	if c.field != "" {
+		fields = []string{c.field}
+	}
+	var out map[string]struct{}
+	for _, tok := range c.tokens {
+		set := map[string]struct{}{}
+		for _, f := range fields {
+			for _, id := range x.Postings[f+":"+tok] {
+				set[id] = struct{}{}
+			}
+		}
+		if out == nil {
+			out = set
+			continue
+		}
+		for id := range out {
+			if _, ok := set[id]; !ok {
+				delete(out, id)
+			}
+		}
+	}
+	if out == nil {
+		out = map[string]struct{}{}
+	}
+	return out
+}
+
+// filter applies the non-posting clauses and negations to one message.
+func (x *Index) filter(id string, q *Query, excluded map[int]map[string]struct{}) bool {
+	d, ok := x.Docs[id]
+	if !ok {
+		return false
+	}
+	if !q.spamTrash && (x.hasLabel(d, "spam") || x.hasLabel(d, "trash")) {
+		return false
+	}
+	for i, c := range q.clauses {
+		var hit bool
+		switch c.kind {
+		case clauseText:
+			if !c.neg {
+				continue // already applied through the postings
+			}
+			_, hit = excluded[i][id]
+		case clauseLabel:
+			hit = x.hasLabel(d, c.value)
+		case clauseAfter:
+			hit = d.Time >= c.time.UnixMilli()
+		case clauseBefore:
+			hit = d.Time < c.time.UnixMilli()
+		case clauseAttachment:
+			hit = d.Attachment
+		}
+		if hit == c.neg {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLabel matches label IDs and display names the way Gmail does: case
+// insensitive, with spaces and slashes interchangeable with dashes.
+func (x *Index) hasLabel(d *Doc, want string) bool {
+	want = normalizeLabel(want)
+	for _, id := range d.Labels {
+		if normalizeLabel(id) == want {
+			return true
+		}
+		if name, ok := x.LabelNames[id]; ok && normalizeLabel(name) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeLabel(s string) string {
+	return strings.NewReplacer(" ", "-", "/", "-").Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// tokenize lowercases s and splits it on anything that is not a letter or
+// digit, so "ann@example.com" yields ann, example and com.
+func tokenize(s string) []string {
+	var out []string
+	for _, tok := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(tok) <= maxTokenLen {
+			out = append(out, tok)
+		}
+	}
+	return out
+}
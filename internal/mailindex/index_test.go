@@ -0,0 +1,141 @@
+package mailindex
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const multipartMessage = "From: Ann Lee <ann@example.com>\r\n" +
+	"To: bob@example.org\r\n" +
+	"Cc: =?UTF-8?Q?Zo=C3=AB?= <zoe@example.net>\r\n" +
+	"Subject: Quarterly report\r\n" +
+	"Date: Tue, 14 Nov 2023 22:13:20 +0000\r\n" +
+	"Content-Type: multipart/mixed; boundary=b1\r\n\r\n" +
+	"--b1\r\n" +
+	"Content-Type: multipart/alternative; boundary=b2\r\n\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n\r\n" +
+	"UmV2ZW51ZSBpcyB1cCB0aGlzIHF1YXJ0ZXIu\r\n" + // "Revenue is up this quarter."
+	"--b2\r\n" +
+	"Content-Type: text/html\r\n\r\n" +
+	"<html><style>.hidden{}</style><p>Margins &amp; forecasts</p></html>\r\n" +
+	"--b2--\r\n" +
+	"--b1\r\n" +
+	"Content-Type: application/pdf; name=report.pdf\r\n" +
+	"Content-Disposition: attachment; filename=report.pdf\r\n\r\n" +
+	"JVBERi0=\r\n" +
+	"--b1--\r\n"
+
+func TestExtract(t *testing.T) {
+	got, err := extract([]byte(multipartMessage))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !got.Attachment || got.Subject != "Quarterly report" || !strings.Contains(got.To, "Zoë") {
+		t.Fatalf("unexpected headers: %#v", got)
+	}
+	if !strings.Contains(got.Body, "Revenue is up") || !strings.Contains(got.Body, "Margins & forecasts") || strings.Contains(got.Body, "hidden") {
+		t.Fatalf("unexpected body: %q", got.Body)
+	}
+
+	qp, err := extract([]byte("Subject: qp\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\ncaf=C3=A9 soft=\r\nbreak\r\n"))
+	if err != nil || !strings.Contains(qp.Body, "café softbreak") || qp.Attachment {
+		t.Fatalf("quoted-printable body: %#v (%v)", qp, err)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx, err := Open(filepath.Join(t.TempDir(), "mail.index"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	idx.SetLabelNames(map[string]string{"Label_1": "Clients/Acme Corp"})
+
+	day := func(d int) int64 { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC).UnixMilli() }
+	add := func(id string, meta Meta, raw string) {
+		t.Helper()
+		if addErr := idx.Add(id, meta, []byte(raw)); addErr != nil {
+			t.Fatalf("Add %s: %v", id, addErr)
+		}
+	}
+	add("m1", Meta{ThreadID: "t1", Time: day(1), Labels: []string{"INBOX", "UNREAD", "Label_1"}}, multipartMessage)
+	add("m2", Meta{ThreadID: "t1", Time: day(5), Labels: []string{"SENT"}}, "From: bob@example.org\r\nTo: ann@example.com\r\nSubject: Re: Quarterly report\r\n\r\nThanks, looks good.\r\n")
+	add("m3", Meta{ThreadID: "t3", Time: day(9), Labels: []string{"TRASH"}}, "From: spam@example.com\r\nSubject: report\r\n\r\nold\r\n")
+
+	search := func(query string) []string {
+		t.Helper()
+		q, parseErr := Parse(query, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+		if parseErr != nil {
+			t.Fatalf("Parse %q: %v", query, parseErr)
+		}
+		return idx.Search(q)
+	}
+	cases := map[string][]string{
+		"report":                             {"m2", "m1"},
+		"report in:anywhere":                 {"m3", "m2", "m1"},
+		"from:ann":                           {"m1"},
+		"to:zoe":                             {"m1"},
+		"subject:\"quarterly report\"":       {"m2", "m1"},
+		"revenue has:attachment":             {"m1"},
+		"label:clients-acme-corp is:unread":  {"m1"},
+		"in:inbox":                           {"m1"},
+		"report -from:ann":                   {"m2"},
+		"after:2024/03/02 before:2024-03-06": {"m2"},
+		"newer_than:7d in:anywhere":          {"m3", "m2"},
+		"is:read":                            {"m2"},
+		"label:trash":                        {"m3"},
+		"margins forecasts":                  {"m1"},
+		"nothing-here":                       nil,
+	}
+	for query, want := range cases {
+		if got := search(query); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q: got %v, want %v", query, got, want)
+		}
+	}
+
+	idx.Remove("m1")
+	idx.Update("m2", Meta{ThreadID: "t1", Time: day(5), Labels: []string{"INBOX"}})
+	if got := search("report"); !reflect.DeepEqual(got, []string{"m2"}) {
+		t.Fatalf("after remove: %v", got)
+	}
+	if _, ok := idx.Postings["from:ann"]; ok {
+		t.Fatalf("postings of removed message left behind")
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reopened, err := Open(idx.Path())
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.Len() != 2 || reopened.LabelNames["Label_1"] != "Clients/Acme Corp" {
+		t.Fatalf("unexpected reopened index: %d docs", reopened.Len())
+	}
+	q, _ := Parse("in:inbox looks", time.Now())
+	if got := reopened.Search(q); !reflect.DeepEqual(got, []string{"m2"}) {
+		t.Fatalf("reopened search: %v", got)
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	for _, query := range []string{"cc:ann", "a OR b", "is:muted", "has:drive", "(a b)", "larger:5M"} {
+		_, err := Parse(query, time.Now())
+		var unsupported *UnsupportedError
+		if !errors.As(err, &unsupported) {
+			t.Fatalf("%q: expected UnsupportedError, got %v", query, err)
+		}
+	}
+	if _, err := Parse("after:yesterday", time.Now()); err == nil {
+		t.Fatalf("expected invalid date error")
+	}
+	// Unknown prefixes are plain text, as in Gmail.
+	if _, err := Parse("see https://example.com re:", time.Now()); err != nil {
+		t.Fatalf("free text with colons: %v", err)
+	}
+}
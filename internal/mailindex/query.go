@@ -0,0 +1,220 @@
+package mailindex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type clauseKind int
+
+const (
+	clauseText clauseKind = iota
+	clauseLabel
+	clauseAfter
+	clauseBefore
+	clauseAttachment
+)
+
+type clause struct {
+	kind   clauseKind
+	neg    bool
+	field  string   // clauseText: "" matches any field
+	tokens []string // clauseText
+	value  string   // clauseLabel
+	time   time.Time
+}
+
+// Query is a parsed search. All clauses must hold (implicit AND).
+type Query struct {
+	clauses   []clause
+	spamTrash bool // the query names spam or trash, so they are not hidden
+}
+
+// UnsupportedError reports Gmail syntax the local index cannot answer; callers
+// can fall back to a remote search.
+type UnsupportedError struct {
+	Term string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported in local search: %s", e.Term)
+}
+
+// Gmail operators the index does not implement. Anything else followed by a
+// colon is treated as free text (URLs, times, "re:").
+var unsupportedOperators = map[string]bool{
+	"cc": true, "bcc": true, "filename": true, "larger": true, "smaller": true,
+	"size": true, "category": true, "list": true, "deliveredto": true,
+	"rfc822msgid": true, "around": true,
+}
+
+// isLabels maps is: keywords onto the system labels that carry them.
+var isLabels = map[string]string{
+	"unread":    "UNREAD",
+	"starred":   "STARRED",
+	"important": "IMPORTANT",
+	"sent":      "SENT",
+	"draft":     "DRAFT",
+	"snoozed":   "SNOOZED",
+}
+
+// Parse reads a Gmail-style query: from:, to:, subject:, label:, in:, is:,
+// after:, before:, older_than:, newer_than:, has:attachment, "phrases", bare
+// words and a leading "-" for negation. Dates without a zone use now's
+// location. Quoted phrases match when every word is present.
+func Parse(query string, now time.Time) (*Query, error) {
+	q := &Query{}
+	for _, term := range splitTerms(query) {
+		neg := false
+		if strings.HasPrefix(term, "-") && len(term) > 1 {
+			neg = true
+			term = term[1:]
+		}
+		if term == "OR" || term == "AND" || strings.ContainsAny(term[:1], "({") {
+			return nil, &UnsupportedError{Term: term}
+		}
+
+		op, value, hasOp := strings.Cut(term, ":")
+		op = strings.ToLower(op)
+		value = unquote(value)
+		if !hasOp || value == "" || strings.HasPrefix(op, `"`) {
+			q.addText(neg, "", term)
+			continue
+		}
+
+		switch op {
+		case fieldFrom, fieldTo, fieldSubject:
+			q.addText(neg, op, value)
+		case "label", "in":
+			v := strings.ToLower(value)
+			switch v {
+			case "anywhere":
+				q.spamTrash = true
+				continue
+			case "spam", "trash":
+				q.spamTrash = true
+			}
+			q.clauses = append(q.clauses, clause{kind: clauseLabel, neg: neg, value: value})
+		case "is":
+			switch v := strings.ToLower(value); v {
+			case "read":
+				q.clauses = append(q.clauses, clause{kind: clauseLabel, neg: !neg, value: "UNREAD"})
+			default:
+				label, ok := isLabels[v]
+				if !ok {
+					return nil, &UnsupportedError{Term: term}
+				}
+				q.clauses = append(q.clauses, clause{kind: clauseLabel, neg: neg, value: label})
+			}
+		case "has":
+			if !strings.EqualFold(value, "attachment") {
+				return nil, &UnsupportedError{Term: term}
+			}
+			q.clauses = append(q.clauses, clause{kind: clauseAttachment, neg: neg})
+		case "after", "newer", "before", "older":
+			t, err := parseDate(value, now.Location())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", term, err)
+			}
+			kind := clauseAfter
+			if op == "before" || op == "older" {
+				kind = clauseBefore
+			}
+			q.clauses = append(q.clauses, clause{kind: kind, neg: neg, time: t})
+		case "newer_than", "older_than":
+			t, err := relativeDate(value, now)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", term, err)
+			}
+			kind := clauseAfter
+			if op == "older_than" {
+				kind = clauseBefore
+			}
+			q.clauses = append(q.clauses, clause{kind: kind, neg: neg, time: t})
+		default:
+			if unsupportedOperators[op] {
+				return nil, &UnsupportedError{Term: term}
+			}
+			q.addText(neg, "", term)
+		}
+	}
+	return q, nil
+}
+
+func (q *Query) addText(neg bool, field, text string) {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return
+	}
+	q.clauses = append(q.clauses, clause{kind: clauseText, neg: neg, field: field, tokens: tokens})
+}
+
+// splitTerms splits on whitespace outside double quotes, keeping the quotes
+// so op:"two words" and "a phrase" stay single terms.
+func splitTerms(s string) []string {
+	var (
+		terms  []string
+		cur    strings.Builder
+		quoted bool
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			terms = append(terms, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return terms
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+// parseDate accepts Gmail's YYYY/MM/DD (and dashed) dates at local midnight,
+// or unix seconds.
+func parseDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"2006/1/2", "2006-1-2"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY/MM/DD)", value)
+}
+
+// relativeDate resolves Gmail's 7d / 2m / 1y durations back from now.
+func relativeDate(value string, now time.Time) (time.Time, error) {
+	if len(value) < 2 {
+		return time.Time{}, fmt.Errorf("invalid duration %q (use e.g. 7d, 2m, 1y)", value)
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid duration %q (use e.g. 7d, 2m, 1y)", value)
+	}
+	switch strings.ToLower(value[len(value)-1:]) {
+	case "d":
+		return now.AddDate(0, 0, -n), nil
+	case "m":
+		return now.AddDate(0, -n, 0), nil
+	case "y":
+		return now.AddDate(-n, 0, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid duration %q (use e.g. 7d, 2m, 1y)", value)
+	}
+}
@@ -0,0 +1,105 @@
+package mailmerge
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var errNoHeader = errors.New("data has no header row")
+
+// Row is one data record keyed by header name. Number is the 1-based data row
+// (the header row is not counted).
+type Row struct {
+	Number int
+	Values map[string]string
+}
+
+// Get looks up a column by exact name, then case-insensitively.
+func (r Row) Get(name string) (string, bool) {
+	if v, ok := r.Values[name]; ok {
+		return v, true
+	}
+	for k, v := range r.Values {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// ReadCSV parses CSV with a header row. Blank rows are skipped.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+
+	return rowsFromRecords(records)
+}
+
+// RowsFromValues converts Sheets API values (first row = header) to rows.
+func RowsFromValues(values [][]any) ([]Row, error) {
+	records := make([][]string, 0, len(values))
+	for _, raw := range values {
+		rec := make([]string, len(raw))
+		for i, cell := range raw {
+			rec[i] = fmt.Sprint(cell)
+		}
+		records = append(records, rec)
+	}
+
+	return rowsFromRecords(records)
+}
+
+func rowsFromRecords(records [][]string) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, errNoHeader
+	}
+
+	header := make([]string, len(records[0]))
+	for i, h := range records[0] {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+	if strings.Join(header, "") == "" {
+		return nil, errNoHeader
+	}
+
+	rows := make([]Row, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		if isBlankRecord(rec) {
+			continue
+		}
+		values := make(map[string]string, len(header))
+		for j, h := range header {
+			if h == "" {
+				continue
+			}
+			if j < len(rec) {
+				values[h] = strings.TrimSpace(rec[j])
+			} else {
+				values[h] = ""
+			}
+		}
+		rows = append(rows, Row{Number: i + 1, Values: values})
+	}
+
+	return rows, nil
+}
+
+func isBlankRecord(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,76 @@
+package mailmerge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errFrontMatter = errors.New("invalid template front matter")
+
+// frontMatterKeys are the headers a template may set before its body.
+var frontMatterKeys = map[string]struct{}{
+	"subject":  {},
+	"to":       {},
+	"cc":       {},
+	"bcc":      {},
+	"reply-to": {},
+	"attach":   {},
+}
+
+// Document is a template file split into front-matter headers and a body.
+//
+//	---
+//	subject: Your invite, {{name}}
+//	to: {{email}}
+//	attach: tickets/{{id}}.pdf
+//	---
+//	Hi {{name}}, ...
+type Document struct {
+	Headers map[string]string
+	Body    string
+}
+
+// ParseDocument splits optional "---" delimited front matter from the body.
+func ParseDocument(src string) (Document, error) {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+	doc := Document{Headers: map[string]string{}}
+
+	if !strings.HasPrefix(src, "---\n") {
+		doc.Body = src
+		return doc, nil
+	}
+
+	// Prefix the header with a newline so an empty front matter block matches too.
+	rest := "\n" + src[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return Document{}, fmt.Errorf("%w: missing closing ---", errFrontMatter)
+	}
+
+	header := rest[:end]
+	body := rest[end+len("\n---"):]
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = ""
+	}
+	doc.Body = body
+
+	for n, line := range strings.Split(header, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return Document{}, fmt.Errorf("%w: line %d: expected key: value", errFrontMatter, n+1)
+		}
+		key := strings.ToLower(strings.TrimSpace(k))
+		if _, known := frontMatterKeys[key]; !known {
+			return Document{}, fmt.Errorf("%w: line %d: unknown key %q", errFrontMatter, n+1, key)
+		}
+		doc.Headers[key] = strings.TrimSpace(v)
+	}
+
+	return doc, nil
+}
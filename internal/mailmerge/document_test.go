@@ -0,0 +1,33 @@
+package mailmerge
+
+import "testing"
+
+func TestParseDocument(t *testing.T) {
+	doc, err := ParseDocument("---\nsubject: Hi {{name}}\nattach: a.pdf\n---\nBody {{name}}\n")
+	if err != nil {
+		t.Fatalf("ParseDocument: %v", err)
+	}
+	if doc.Headers["subject"] != "Hi {{name}}" || doc.Headers["attach"] != "a.pdf" {
+		t.Fatalf("unexpected headers: %#v", doc.Headers)
+	}
+	if doc.Body != "Body {{name}}\n" {
+		t.Fatalf("unexpected body: %q", doc.Body)
+	}
+
+	doc, err = ParseDocument("---\n---\nJust body")
+	if err != nil || doc.Body != "Just body" || len(doc.Headers) != 0 {
+		t.Fatalf("empty front matter: %#v, %v", doc, err)
+	}
+
+	doc, err = ParseDocument("No front matter")
+	if err != nil || doc.Body != "No front matter" {
+		t.Fatalf("plain body: %#v, %v", doc, err)
+	}
+
+	if _, err := ParseDocument("---\nsubject: x\n"); err == nil {
+		t.Fatalf("expected missing closing delimiter error")
+	}
+	if _, err := ParseDocument("---\nfoo: x\n---\n"); err == nil {
+		t.Fatalf("expected unknown key error")
+	}
+}
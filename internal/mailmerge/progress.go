@@ -0,0 +1,120 @@
+package mailmerge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const progressVersion = 1
+
+// ProgressEntry records one message that was sent (or drafted).
+type ProgressEntry struct {
+	Row       int       `json:"row"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject,omitempty"`
+	MessageID string    `json:"message_id,omitempty"`
+	ThreadID  string    `json:"thread_id,omitempty"`
+	DraftID   string    `json:"draft_id,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Progress is the resumable record of a merge run. It is saved after every
+// message so a rerun skips rows that already went out.
+type Progress struct {
+	mu   sync.Mutex
+	path string
+
+	Version int                      `json:"version"`
+	Source  string                   `json:"source,omitempty"`
+	Done    map[string]ProgressEntry `json:"done"`
+}
+
+// LoadProgress reads path; a missing file yields an empty progress record.
+func LoadProgress(path, source string) (*Progress, error) {
+	p := &Progress{path: path, Version: progressVersion, Source: source, Done: map[string]ProgressEntry{}}
+
+	b, err := os.ReadFile(path) //nolint:gosec // user-provided progress path
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read merge progress: %w", err)
+	}
+
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("parse merge progress %s: %w", path, err)
+	}
+	if p.Done == nil {
+		p.Done = map[string]ProgressEntry{}
+	}
+	p.path = path
+
+	return p, nil
+}
+
+func (p *Progress) Path() string {
+	return p.path
+}
+
+func (p *Progress) Lookup(key string) (ProgressEntry, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.Done[key]
+
+	return e, ok
+}
+
+// Record stores entry under key and writes the file atomically.
+func (p *Progress) Record(key string, entry ProgressEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Done[key] = entry
+
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode merge progress: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o700); err != nil {
+		return fmt.Errorf("ensure merge progress dir: %w", err)
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write merge progress: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("commit merge progress: %w", err)
+	}
+
+	return nil
+}
+
+// MessageKey identifies a rendered message by its recipients and subject, so a
+// rerun skips it even if the data file was reordered or the body was edited.
+func MessageKey(to, cc, bcc []string, subject string) string {
+	norm := func(in []string) string {
+		out := make([]string, 0, len(in))
+		for _, a := range in {
+			if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+				out = append(out, a)
+			}
+		}
+		sort.Strings(out)
+
+		return strings.Join(out, ",")
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{norm(to), norm(cc), norm(bcc), strings.TrimSpace(subject)}, "\x00")))
+
+	return hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,30 @@
+package mailmerge
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProgressRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "progress.json")
+
+	p, err := LoadProgress(path, "csv:x")
+	if err != nil {
+		t.Fatalf("LoadProgress: %v", err)
+	}
+	key := MessageKey([]string{"B@x.com", "a@x.com"}, nil, nil, "Hi")
+	if key != MessageKey([]string{"a@x.com", "b@x.com"}, nil, nil, "Hi ") {
+		t.Fatalf("expected key to ignore order, case and surrounding space")
+	}
+	if err := p.Record(key, ProgressEntry{Row: 1, MessageID: "m1"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	again, err := LoadProgress(path, "csv:x")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if e, ok := again.Lookup(key); !ok || e.MessageID != "m1" {
+		t.Fatalf("expected recorded entry, got %#v %v", e, ok)
+	}
+}
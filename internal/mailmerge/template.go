@@ -0,0 +1,220 @@
+// Package mailmerge renders per-recipient messages from a template and tabular data.
+package mailmerge
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+var (
+	errUnclosedTag   = errors.New("unclosed {{ tag")
+	errUnclosedBlock = errors.New("unclosed block")
+	errUnexpectedTag = errors.New("unexpected tag")
+)
+
+// MissingFieldError reports a placeholder with no matching column.
+type MissingFieldError struct {
+	Field string
+	Row   int
+}
+
+func (e *MissingFieldError) Error() string {
+	if e.Row > 0 {
+		return fmt.Sprintf("row %d: missing value for {{%s}}", e.Row, e.Field)
+	}
+
+	return fmt.Sprintf("missing value for {{%s}}", e.Field)
+}
+
+type nodeKind int
+
+const (
+	nodeText nodeKind = iota
+	nodeField
+	nodeIf
+	nodeUnless
+)
+
+type node struct {
+	kind  nodeKind
+	text  string // literal text or field name
+	then  []node
+	other []node
+}
+
+// Template is a parsed merge template. It supports {{column}} placeholders,
+// {{#if column}}...{{else}}...{{/if}} and {{#unless column}}...{{/unless}}.
+// A column is "truthy" when its trimmed value is non-empty.
+type Template struct {
+	nodes []node
+}
+
+func Parse(src string) (*Template, error) {
+	nodes, rest, closer, err := parseNodes(src)
+	if err != nil {
+		return nil, err
+	}
+	if closer != "" {
+		return nil, fmt.Errorf("%w {{%s}}", errUnexpectedTag, closer)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("%w: %q", errUnexpectedTag, rest)
+	}
+
+	return &Template{nodes: nodes}, nil
+}
+
+// MustParse is Parse for templates known to be valid (tests, constants).
+func MustParse(src string) *Template {
+	t, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// parseNodes consumes src until EOF or a closing tag ({{else}}, {{/if}}, {{/unless}}),
+// returning the closing tag name and the remaining input after it.
+func parseNodes(src string) ([]node, string, string, error) {
+	var nodes []node
+	for src != "" {
+		start := strings.Index(src, "{{")
+		if start < 0 {
+			nodes = append(nodes, node{kind: nodeText, text: src})
+			return nodes, "", "", nil
+		}
+		if start > 0 {
+			nodes = append(nodes, node{kind: nodeText, text: src[:start]})
+		}
+
+		end := strings.Index(src[start:], "}}")
+		if end < 0 {
+			return nil, "", "", errUnclosedTag
+		}
+		tag := strings.TrimSpace(src[start+2 : start+end])
+		src = src[start+end+2:]
+
+		switch {
+		case tag == "else" || tag == "/if" || tag == "/unless":
+			return nodes, src, tag, nil
+		case strings.HasPrefix(tag, "#if ") || strings.HasPrefix(tag, "#unless "):
+			kind, name := nodeIf, strings.TrimSpace(strings.TrimPrefix(tag, "#if "))
+			closing := "/if"
+			if strings.HasPrefix(tag, "#unless ") {
+				kind, name, closing = nodeUnless, strings.TrimSpace(strings.TrimPrefix(tag, "#unless ")), "/unless"
+			}
+			if name == "" {
+				return nil, "", "", fmt.Errorf("%w {{%s}}", errUnexpectedTag, tag)
+			}
+
+			block := node{kind: kind, text: name}
+			var (
+				closer string
+				err    error
+			)
+			block.then, src, closer, err = parseNodes(src)
+			if err != nil {
+				return nil, "", "", err
+			}
+			if closer == "else" {
+				block.other, src, closer, err = parseNodes(src)
+				if err != nil {
+					return nil, "", "", err
+				}
+			}
+			if closer != closing {
+				return nil, "", "", fmt.Errorf("%w {{%s}}", errUnclosedBlock, tag)
+			}
+			nodes = append(nodes, block)
+		case tag == "" || strings.HasPrefix(tag, "#") || strings.HasPrefix(tag, "/"):
+			return nil, "", "", fmt.Errorf("%w {{%s}}", errUnexpectedTag, tag)
+		default:
+			nodes = append(nodes, node{kind: nodeField, text: tag})
+		}
+	}
+
+	return nodes, "", "", nil
+}
+
+// Fields returns every column referenced by the template, in first-use order.
+func (t *Template) Fields() []string {
+	if t == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var out []string
+	var walk func([]node)
+	walk = func(nodes []node) {
+		for _, n := range nodes {
+			if n.kind != nodeText && !seen[n.text] {
+				seen[n.text] = true
+				out = append(out, n.text)
+			}
+			walk(n.then)
+			walk(n.other)
+		}
+	}
+	walk(t.nodes)
+
+	return out
+}
+
+// Render fills the template from row. Unknown placeholders are an error unless
+// allowMissing is set, in which case they render empty. Conditionals never fail.
+func (t *Template) Render(row Row, allowMissing bool) (string, error) {
+	return t.render(row, allowMissing, nil)
+}
+
+// RenderHTML is Render for HTML templates: values are HTML-escaped, so data
+// cannot inject markup or break out of attributes.
+func (t *Template) RenderHTML(row Row, allowMissing bool) (string, error) {
+	return t.render(row, allowMissing, html.EscapeString)
+}
+
+func (t *Template) render(row Row, allowMissing bool, escape func(string) string) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	var sb strings.Builder
+	if err := renderNodes(&sb, t.nodes, row, allowMissing, escape); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func renderNodes(sb *strings.Builder, nodes []node, row Row, allowMissing bool, escape func(string) string) error {
+	for _, n := range nodes {
+		switch n.kind {
+		case nodeText:
+			sb.WriteString(n.text)
+		case nodeField:
+			v, ok := row.Get(n.text)
+			if !ok && !allowMissing {
+				return &MissingFieldError{Field: n.text, Row: row.Number}
+			}
+			if escape != nil {
+				v = escape(v)
+			}
+			sb.WriteString(v)
+		case nodeIf, nodeUnless:
+			v, _ := row.Get(n.text)
+			truthy := strings.TrimSpace(v) != ""
+			if n.kind == nodeUnless {
+				truthy = !truthy
+			}
+			branch := n.other
+			if truthy {
+				branch = n.then
+			}
+			if err := renderNodes(sb, branch, row, allowMissing, escape); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
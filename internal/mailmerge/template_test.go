@@ -0,0 +1,92 @@
+package mailmerge
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateRender(t *testing.T) {
+	row := Row{Number: 2, Values: map[string]string{"Name": "Ada", "company": "", "vip": "yes"}}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"Hi {{name}}!", "Hi Ada!"},
+		{"{{#if company}}at {{company}}{{else}}independent{{/if}}", "independent"},
+		{"{{#if vip}}VIP {{#unless company}}solo{{/unless}}{{/if}}", "VIP solo"},
+		{"{{ Name }}", "Ada"},
+	}
+	for _, tc := range tests {
+		got, err := MustParse(tc.src).Render(row, false)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.src, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%q: got %q, want %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestTemplateRenderHTMLEscapes(t *testing.T) {
+	row := Row{Values: map[string]string{"company": `AT&T`, "title": `"><img src="x`}}
+	got, err := MustParse(`<p title="{{title}}">{{company}}</p>`).RenderHTML(row, false)
+	if err != nil {
+		t.Fatalf("RenderHTML: %v", err)
+	}
+	if want := `<p title="&#34;&gt;&lt;img src=&#34;x">AT&amp;T</p>`; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestTemplateMissingField(t *testing.T) {
+	tmpl := MustParse("Hi {{nickname}}")
+	row := Row{Number: 3, Values: map[string]string{"name": "Ada"}}
+
+	_, err := tmpl.Render(row, false)
+	var missing *MissingFieldError
+	if !errors.As(err, &missing) || missing.Field != "nickname" || missing.Row != 3 {
+		t.Fatalf("expected MissingFieldError, got %v", err)
+	}
+
+	got, err := tmpl.Render(row, true)
+	if err != nil || got != "Hi " {
+		t.Fatalf("allowMissing: got %q, %v", got, err)
+	}
+}
+
+func TestTemplateParseErrors(t *testing.T) {
+	for _, src := range []string{"{{name", "{{#if a}}x", "{{/if}}", "{{#if a}}x{{/unless}}", "{{}}"} {
+		if _, err := Parse(src); err == nil {
+			t.Fatalf("%q: expected parse error", src)
+		}
+	}
+}
+
+func TestTemplateFields(t *testing.T) {
+	got := MustParse("{{a}} {{#if b}}{{c}}{{else}}{{a}}{{/if}}").Fields()
+	if !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected fields: %v", got)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	rows, err := ReadCSV(strings.NewReader("\ufeffemail,name\na@b.com,Ada\n,\nc@d.com\n"))
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %#v", rows)
+	}
+	if v, _ := rows[0].Get("email"); v != "a@b.com" {
+		t.Fatalf("unexpected email: %q", v)
+	}
+	if rows[1].Number != 3 {
+		t.Fatalf("expected blank row to keep numbering, got %d", rows[1].Number)
+	}
+	if v, ok := rows[1].Get("name"); !ok || v != "" {
+		t.Fatalf("expected short row to pad name, got %q %v", v, ok)
+	}
+}
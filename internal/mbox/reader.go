@@ -0,0 +1,96 @@
+package mbox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Reader splits an mbox stream into messages. It accepts mboxrd and mboxo:
+// every line starting with "From " separates messages and one level of
+// ">From " quoting is removed.
+type Reader struct {
+	r      *bufio.Reader
+	offset int64 // bytes consumed so far
+	next   int64 // offset of the pending separator line, -1 before the first
+	done   bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 64*1024), next: -1}
+}
+
+// Next returns the next message and the byte offset of its "From " line,
+// which can be passed to ReadAt to fetch it again. It returns io.EOF after
+// the last message.
+func (r *Reader) Next() ([]byte, int64, error) {
+	if r.done {
+		return nil, 0, io.EOF
+	}
+
+	// Skip anything before the first separator.
+	for r.next < 0 {
+		line, err := r.readLine()
+		if len(line) > 0 && bytes.HasPrefix(line, []byte("From ")) {
+			r.next = r.offset - int64(len(line))
+			break
+		}
+		if err != nil {
+			r.done = true
+			if errors.Is(err, io.EOF) {
+				return nil, 0, io.EOF
+			}
+			return nil, 0, err
+		}
+	}
+
+	start := r.next
+	var msg bytes.Buffer
+	for {
+		line, err := r.readLine()
+		if len(line) > 0 && bytes.HasPrefix(line, []byte("From ")) {
+			r.next = r.offset - int64(len(line))
+			return trimSeparatorGap(msg.Bytes()), start, nil
+		}
+		if isFromLine(line) {
+			line = line[1:]
+		}
+		msg.Write(line)
+		if err != nil {
+			r.done = true
+			if errors.Is(err, io.EOF) {
+				return trimSeparatorGap(msg.Bytes()), start, nil
+			}
+			return nil, 0, err
+		}
+	}
+}
+
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.r.ReadBytes('\n')
+	r.offset += int64(len(line))
+	return line, err
+}
+
+// ReadAt returns the single message whose "From " line starts at offset.
+func ReadAt(f io.ReadSeeker, offset int64) ([]byte, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	msg, _, err := NewReader(f).Next()
+	return msg, err
+}
+
+// trimSeparatorGap drops the blank line the writer adds before the next
+// separator.
+func trimSeparatorGap(b []byte) []byte {
+	switch {
+	case bytes.HasSuffix(b, []byte("\r\n\r\n")):
+		return b[:len(b)-2]
+	case bytes.HasSuffix(b, []byte("\n\n")):
+		return b[:len(b)-1]
+	default:
+		return b
+	}
+}
@@ -0,0 +1,44 @@
+package mbox
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReaderRoundTrip(t *testing.T) {
+	msgs := []string{
+		"From: a@example.com\nSubject: one\n\nFrom here on\n>From quoted\n",
+		"From: b@example.com\nSubject: two\n\nsecond\n",
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, m := range msgs {
+		if err := w.WriteMessage([]byte(m), time.Unix(0, 0)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	var offsets []int64
+	for i, want := range msgs {
+		got, off, err := r.Next()
+		if err != nil {
+			t.Fatalf("next %d: %v", i, err)
+		}
+		if string(got) != want {
+			t.Fatalf("message %d:\n got %q\nwant %q", i, got, want)
+		}
+		offsets = append(offsets, off)
+	}
+	if _, _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	again, err := ReadAt(bytes.NewReader(buf.Bytes()), offsets[1])
+	if err != nil || string(again) != msgs[1] {
+		t.Fatalf("ReadAt: %q (%v)", again, err)
+	}
+}
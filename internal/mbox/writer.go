@@ -0,0 +1,87 @@
+// Package mbox reads and writes mboxrd mailboxes.
+package mbox
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// asctime is the date layout of the "From " separator line.
+const asctime = "Mon Jan _2 15:04:05 2006"
+
+// Writer appends messages to an mboxrd stream.
+type Writer struct {
+	w *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteMessage writes raw (an RFC 822 message) with a "From " separator line.
+// Line endings are normalized to LF and body lines that look like separators
+// are quoted with ">" (mboxrd), so the original is recovered exactly on read
+// apart from CRLF.
+func (w *Writer) WriteMessage(raw []byte, received time.Time) error {
+	if received.IsZero() {
+		received = time.Now()
+	}
+	if _, err := w.w.WriteString("From " + envelopeSender(raw) + " " + received.UTC().Format(asctime) + "\n"); err != nil {
+		return err
+	}
+
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	for len(raw) > 0 {
+		line := raw
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			line, raw = raw[:i], raw[i+1:]
+		} else {
+			raw = nil
+		}
+		if isFromLine(line) {
+			if err := w.w.WriteByte('>'); err != nil {
+				return err
+			}
+		}
+		if _, err := w.w.Write(line); err != nil {
+			return err
+		}
+		if err := w.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	if err := w.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// isFromLine reports whether line matches ^>*From  and so needs quoting.
+func isFromLine(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From "))
+}
+
+func envelopeSender(raw []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "MAILER-DAEMON"
+	}
+	for _, h := range []string{"Return-Path", "Sender", "From"} {
+		v := strings.TrimSpace(msg.Header.Get(h))
+		if v == "" || v == "<>" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(v); err == nil {
+			return addr.Address
+		}
+		if v = strings.Trim(v, "<>"); !strings.ContainsAny(v, " \t") {
+			return v
+		}
+	}
+	return "MAILER-DAEMON"
+}
@@ -0,0 +1,27 @@
+package mbox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterQuotesFromLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	raw := "From: Ann <ann@example.com>\r\nSubject: hi\r\n\r\nFrom here on\r\n>From quoted\r\nok"
+	if err := w.WriteMessage([]byte(raw), time.Date(2024, 3, 5, 9, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	want := "From ann@example.com Tue Mar  5 09:04:05 2024\n" +
+		"From: Ann <ann@example.com>\nSubject: hi\n\n>From here on\n>>From quoted\nok\n\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+	if strings.Contains(buf.String(), "\r") {
+		t.Fatalf("expected LF line endings")
+	}
+}
@@ -0,0 +1,451 @@
+package outfmt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	cursorVersion  = 1
+	cursorModeJSON = "json"
+	cursorModeText = "text"
+
+	// budgetReserve leaves room for the truncated/continue fields added to JSON output.
+	budgetReserve = 160
+)
+
+var errInvalidCursor = errors.New("invalid continuation token")
+
+// Cursor is the decoded form of an opaque --continue token.
+type Cursor struct {
+	Version     int      `json:"v"`
+	Mode        string   `json:"m"`
+	Path        []string `json:"p,omitempty"`
+	Offset      int      `json:"o"`
+	Fingerprint string   `json:"f,omitempty"`
+}
+
+func EncodeCursor(c Cursor) string {
+	c.Version = cursorVersion
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(token string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", errInvalidCursor, err)
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", errInvalidCursor, err)
+	}
+
+	if c.Version != cursorVersion || c.Offset < 0 || (c.Mode != cursorModeJSON && c.Mode != cursorModeText) {
+		return Cursor{}, errInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Budget cuts output at a clean item/paragraph/row boundary and records where to resume.
+type Budget struct {
+	MaxBytes int
+	MaxItems int
+	// Cursor is set when resuming via --continue.
+	Cursor *Cursor
+	// Fingerprint identifies the command line so tokens are not replayed elsewhere.
+	Fingerprint string
+
+	// Truncated and Next are filled in after output was written.
+	Truncated bool
+	Next      string
+	// OutOfBand is set when the output had no object to carry "truncated" and
+	// "continue" (a top-level list or string), so the caller reports them on stderr.
+	OutOfBand bool
+}
+
+func (b *Budget) Active() bool {
+	return b != nil && (b.MaxBytes > 0 || b.MaxItems > 0 || b.Cursor != nil)
+}
+
+// CheckMode validates a resume cursor against the current output mode and command.
+func (b *Budget) CheckMode(jsonMode bool) error {
+	if b == nil || b.Cursor == nil {
+		return nil
+	}
+
+	want := cursorModeText
+	if jsonMode {
+		want = cursorModeJSON
+	}
+
+	if b.Cursor.Mode != want {
+		return fmt.Errorf("%w: token was issued for %s output", errInvalidCursor, b.Cursor.Mode)
+	}
+
+	if b.Cursor.Fingerprint != "" && b.Fingerprint != "" && b.Cursor.Fingerprint != b.Fingerprint {
+		return fmt.Errorf("%w: token belongs to a different command", errInvalidCursor)
+	}
+
+	return nil
+}
+
+type budgetKey struct{}
+
+func WithBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+func BudgetFromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(budgetKey{}).(*Budget)
+	return b
+}
+
+func (b *Budget) offset() int {
+	if b.Cursor == nil {
+		return 0
+	}
+
+	return b.Cursor.Offset
+}
+
+func (b *Budget) maxItems() int {
+	if b.MaxItems > 0 {
+		return b.MaxItems
+	}
+
+	return int(^uint(0) >> 1)
+}
+
+func (b *Budget) byteBudget(overhead int) int {
+	if b.MaxBytes <= 0 {
+		return int(^uint(0) >> 1)
+	}
+
+	return b.MaxBytes - overhead - budgetReserve
+}
+
+// ApplyJSON trims the largest list (or text field) in a generic JSON value and
+// adds "truncated" (plus "continue" when more output is available). A top-level
+// list or string keeps its shape and sets OutOfBand instead.
+func (b *Budget) ApplyJSON(v any) (any, error) {
+	path := budgetTargetPath(v)
+	if b.Cursor != nil {
+		path = b.Cursor.Path
+	}
+
+	target, ok := getPath(v, path)
+	if !ok {
+		if b.Cursor != nil {
+			return nil, fmt.Errorf("%w: output no longer contains %q", errInvalidCursor, strings.Join(path, "."))
+		}
+
+		return v, nil
+	}
+
+	overhead := jsonSize(setPath(v, path, emptyLike(target)), 0)
+	budget := b.byteBudget(overhead)
+	depth := len(path) + 1
+
+	var (
+		kept any
+		next int
+	)
+
+	switch t := target.(type) {
+	case []any:
+		kept, next = b.cutList(t, budget, depth)
+	case string:
+		kept, next = b.cutText(t, budget)
+	default:
+		return v, nil
+	}
+
+	out := setPath(v, path, kept)
+	b.Truncated = next >= 0
+	if b.Truncated {
+		b.Next = EncodeCursor(Cursor{Mode: cursorModeJSON, Path: path, Offset: next, Fingerprint: b.Fingerprint})
+	}
+
+	m, isMap := out.(map[string]any)
+	if !isMap {
+		b.OutOfBand = true
+		return out, nil
+	}
+
+	m["truncated"] = b.Truncated
+	if b.Truncated {
+		m["continue"] = b.Next
+	}
+
+	return m, nil
+}
+
+// cutList returns the kept items and the next offset (-1 when everything was emitted).
+func (b *Budget) cutList(items []any, budget, depth int) ([]any, int) {
+	start := min(b.offset(), len(items))
+	rest := items[start:]
+	limit := b.maxItems()
+
+	used := 0
+	n := 0
+	for _, item := range rest {
+		if n >= limit {
+			break
+		}
+
+		size := jsonSize(item, depth) + 2
+		if n > 0 && used+size > budget {
+			break
+		}
+
+		used += size
+		n++
+	}
+
+	if n == len(rest) {
+		return rest, -1
+	}
+
+	return rest[:n], start + n
+}
+
+// cutText keeps whole paragraphs (blank-line separated) when possible, else whole lines.
+func (b *Budget) cutText(s string, budget int) (string, int) {
+	start := min(b.offset(), len(s))
+	for start > 0 && start < len(s) && !utf8.RuneStart(s[start]) {
+		start--
+	}
+	rest := s[start:]
+
+	end := len(rest)
+	if b.MaxItems > 0 {
+		end = lineLimit(rest, b.MaxItems)
+	}
+
+	if end > budget {
+		end = cleanCut(rest[:end], max(budget, 1))
+	}
+
+	if end >= len(rest) {
+		return rest, -1
+	}
+
+	return rest[:end], start + end
+}
+
+// TextWriter streams text output line by line and stops after MaxBytes/MaxItems
+// lines, skipping lines already shown by a previous --continue token. Lines pass
+// through as they are written, so long-running commands are not held back.
+func (b *Budget) TextWriter(w io.Writer) io.WriteCloser {
+	return &budgetWriter{b: b, w: w, skip: b.offset()}
+}
+
+type budgetWriter struct {
+	b       *Budget
+	w       io.Writer
+	buf     bytes.Buffer
+	skip    int
+	lines   int
+	written int
+	full    bool
+}
+
+func (bw *budgetWriter) Write(p []byte) (int, error) {
+	bw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(bw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		if err := bw.emit(bw.buf.Next(i + 1)); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (bw *budgetWriter) Close() error {
+	if bw.buf.Len() == 0 {
+		return nil
+	}
+	line := bytes.Clone(bw.buf.Bytes())
+	bw.buf.Reset()
+
+	return bw.emit(line)
+}
+
+// emit writes one line unless the budget is spent. The first line of a page is
+// always written so every page makes progress.
+func (bw *budgetWriter) emit(line []byte) error {
+	if bw.full {
+		return nil
+	}
+	if bw.skip > 0 {
+		bw.skip--
+		return nil
+	}
+	if (bw.b.MaxItems > 0 && bw.lines >= bw.b.MaxItems) ||
+		(bw.b.MaxBytes > 0 && bw.lines > 0 && bw.written+len(line) > bw.b.MaxBytes) {
+		bw.full = true
+		bw.b.Truncated = true
+		bw.b.Next = EncodeCursor(Cursor{
+			Mode:        cursorModeText,
+			Offset:      bw.b.offset() + bw.lines,
+			Fingerprint: bw.b.Fingerprint,
+		})
+
+		return nil
+	}
+	bw.lines++
+	bw.written += len(line)
+	_, err := bw.w.Write(line)
+
+	return err
+}
+
+// lineLimit returns the byte length of the first n lines of s.
+func lineLimit(s string, n int) int {
+	pos := 0
+	for i := 0; i < n; i++ {
+		j := strings.IndexByte(s[pos:], '\n')
+		if j < 0 {
+			return len(s)
+		}
+		pos += j + 1
+	}
+
+	return pos
+}
+
+// cleanCut returns a cut position <= limit, preferring a paragraph break, then a line
+// break, and never splitting a UTF-8 sequence. Always makes progress.
+func cleanCut(s string, limit int) int {
+	if limit >= len(s) {
+		return len(s)
+	}
+
+	head := s[:limit]
+	if i := strings.LastIndex(head, "\n\n"); i > 0 {
+		return i + 2
+	}
+
+	if i := strings.LastIndexByte(head, '\n'); i >= 0 {
+		return i + 1
+	}
+
+	// A single line longer than the budget: emit the full first line to make progress.
+	if j := strings.IndexByte(s, '\n'); j >= 0 {
+		return j + 1
+	}
+
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+
+	if limit == 0 {
+		return len(s)
+	}
+
+	return limit
+}
+
+// budgetTargetPath walks maps to find the largest list or string (by encoded size).
+func budgetTargetPath(v any) []string {
+	var (
+		best     []string
+		bestSize = -1
+	)
+
+	var walk func(cur any, path []string)
+	walk = func(cur any, path []string) {
+		switch c := cur.(type) {
+		case []any, string:
+			if size := jsonSize(c, 0); size > bestSize {
+				best, bestSize = append([]string(nil), path...), size
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(c))
+			for k := range c {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				walk(c[k], append(path, k))
+			}
+		}
+	}
+	walk(v, nil)
+
+	return best
+}
+
+func getPath(v any, path []string) (any, bool) {
+	cur := v
+	for _, seg := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		cur, ok = m[seg]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// setPath returns a shallow copy of v with the value at path replaced.
+func setPath(v any, path []string, val any) any {
+	if len(path) == 0 {
+		return val
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+
+	out := make(map[string]any, len(m))
+	for k, item := range m {
+		out[k] = item
+	}
+	out[path[0]] = setPath(m[path[0]], path[1:], val)
+
+	return out
+}
+
+func emptyLike(v any) any {
+	if _, ok := v.(string); ok {
+		return ""
+	}
+
+	return []any{}
+}
+
+func jsonSize(v any, depth int) int {
+	b, err := json.MarshalIndent(v, strings.Repeat("  ", depth), "  ")
+	if err != nil {
+		return 0
+	}
+
+	return len(b)
+}
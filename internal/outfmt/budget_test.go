@@ -0,0 +1,170 @@
+package outfmt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBudget_JSONListContinuation(t *testing.T) {
+	doc := map[string]any{
+		"thread": map[string]any{
+			"id":       "t1",
+			"messages": []any{"m1", "m2", "m3", "m4", "m5"},
+		},
+	}
+
+	var seen []string
+	token := ""
+	for round := 0; round < 5; round++ {
+		b := &Budget{MaxItems: 2, Fingerprint: "fp"}
+		if token != "" {
+			c, err := DecodeCursor(token)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			b.Cursor = &c
+		}
+
+		var buf bytes.Buffer
+		if err := WriteJSON(WithBudget(context.Background(), b), &buf, doc); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+
+		var got struct {
+			Thread struct {
+				Messages []string `json:"messages"`
+			} `json:"thread"`
+			Truncated bool   `json:"truncated"`
+			Continue  string `json:"continue"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		seen = append(seen, got.Thread.Messages...)
+		if !got.Truncated {
+			break
+		}
+		token = got.Continue
+	}
+
+	if strings.Join(seen, ",") != "m1,m2,m3,m4,m5" {
+		t.Fatalf("unexpected items across pages: %v", seen)
+	}
+}
+
+func TestBudget_JSONTextParagraphs(t *testing.T) {
+	text := strings.Repeat("a", 100) + "\n\n" + strings.Repeat("b", 100) + "\n\n" + strings.Repeat("c", 100)
+	b := &Budget{MaxBytes: 400}
+
+	out, err := b.ApplyJSON(map[string]any{"text": text})
+	if err != nil {
+		t.Fatalf("ApplyJSON: %v", err)
+	}
+	m := out.(map[string]any)
+	got := m["text"].(string)
+	if !strings.HasSuffix(got, "\n\n") || strings.Contains(got, "c") {
+		t.Fatalf("expected cut at paragraph boundary, got %q", got)
+	}
+	if m["truncated"] != true || m["continue"] == "" {
+		t.Fatalf("expected truncated envelope, got %#v", m)
+	}
+
+	c, err := DecodeCursor(m["continue"].(string))
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	b2 := &Budget{MaxBytes: 100000, Cursor: &c}
+	out2, err := b2.ApplyJSON(map[string]any{"text": text})
+	if err != nil {
+		t.Fatalf("ApplyJSON: %v", err)
+	}
+	m2 := out2.(map[string]any)
+	if got+m2["text"].(string) != text || m2["truncated"] != false {
+		t.Fatalf("expected remainder on resume, got %#v", m2)
+	}
+}
+
+func TestBudget_TextWriter(t *testing.T) {
+	input := "ID\tNAME\nr1\ta\nr2\tb\nr3\tc\n"
+
+	b := &Budget{MaxItems: 2}
+	var buf bytes.Buffer
+	w := b.TextWriter(&buf)
+	_, _ = w.Write([]byte(input))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if buf.String() != "ID\tNAME\nr1\ta\n" || !b.Truncated {
+		t.Fatalf("unexpected first page: %q truncated=%v", buf.String(), b.Truncated)
+	}
+
+	c, err := DecodeCursor(b.Next)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	b2 := &Budget{MaxItems: 2, Cursor: &c}
+	buf.Reset()
+	w = b2.TextWriter(&buf)
+	_, _ = w.Write([]byte(input))
+	_ = w.Close()
+	if buf.String() != "r2\tb\nr3\tc\n" || b2.Truncated {
+		t.Fatalf("unexpected second page: %q truncated=%v", buf.String(), b2.Truncated)
+	}
+}
+
+func TestBudget_TextWriterStreams(t *testing.T) {
+	b := &Budget{MaxBytes: 12}
+	var buf bytes.Buffer
+	w := b.TextWriter(&buf)
+
+	// Complete lines pass through before Close.
+	_, _ = w.Write([]byte("line1\nli"))
+	if buf.String() != "line1\n" {
+		t.Fatalf("expected the first line to stream, got %q", buf.String())
+	}
+	_, _ = w.Write([]byte("ne2\nline3\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if buf.String() != "line1\nline2\n" || !b.Truncated {
+		t.Fatalf("unexpected output: %q truncated=%v", buf.String(), b.Truncated)
+	}
+	c, err := DecodeCursor(b.Next)
+	if err != nil || c.Offset != 2 {
+		t.Fatalf("unexpected cursor: %+v (%v)", c, err)
+	}
+}
+
+func TestBudget_CheckMode(t *testing.T) {
+	c, err := DecodeCursor(EncodeCursor(Cursor{Mode: "text", Offset: 3, Fingerprint: "a"}))
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if err := (&Budget{Cursor: &c, Fingerprint: "a"}).CheckMode(true); err == nil {
+		t.Fatalf("expected mode mismatch")
+	}
+	if err := (&Budget{Cursor: &c, Fingerprint: "b"}).CheckMode(false); err == nil {
+		t.Fatalf("expected fingerprint mismatch")
+	}
+	if _, err := DecodeCursor("not-a-token"); err == nil {
+		t.Fatalf("expected invalid token error")
+	}
+}
+
+func TestBudget_JSONTopLevelListKeepsShape(t *testing.T) {
+	b := &Budget{MaxItems: 2}
+	var buf bytes.Buffer
+	if err := WriteJSON(WithBudget(context.Background(), b), &buf, []string{"a", "b", "c"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var got []string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("expected a top-level list, got %s (%v)", buf.String(), err)
+	}
+	if strings.Join(got, ",") != "a,b" || !b.Truncated || !b.OutOfBand || b.Next == "" {
+		t.Fatalf("unexpected page %v truncated=%v outOfBand=%v", got, b.Truncated, b.OutOfBand)
+	}
+}
@@ -88,6 +88,25 @@ func WriteJSON(ctx context.Context, w io.Writer, v any) error {
 		v = transformed
 	}
 
+	// Redact before budgeting so byte counts match what is printed.
+	if r := RedactorFromContext(ctx); r != nil {
+		generic, err := toGeneric(v)
+		if err != nil {
+			return fmt.Errorf("redact json: %w", err)
+		}
+		v = r.Value(generic)
+	}
+
+	if b := BudgetFromContext(ctx); b.Active() {
+		generic, err := toGeneric(v)
+		if err != nil {
+			return fmt.Errorf("budget json: %w", err)
+		}
+		if v, err = b.ApplyJSON(generic); err != nil {
+			return err
+		}
+	}
+
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
 	enc.SetIndent("", "  ")
@@ -99,16 +118,30 @@ func WriteJSON(ctx context.Context, w io.Writer, v any) error {
 	return nil
 }
 
-func applyJSONTransform(v any, t JSONTransform) (any, error) {
-	// Convert typed structs into a generic representation so we can manipulate them.
-	b, err := json.Marshal(v)
-	if err != nil {
-		return nil, fmt.Errorf("marshal: %w", err)
+// WriteJSONLine writes v as one compact line (NDJSON) for streaming commands,
+// masking it like WriteJSON when --redact is set.
+func WriteJSONLine(ctx context.Context, w io.Writer, v any) error {
+	if r := RedactorFromContext(ctx); r != nil {
+		generic, err := toGeneric(v)
+		if err != nil {
+			return fmt.Errorf("redact json: %w", err)
+		}
+		v = r.Value(generic)
 	}
 
-	var anyV any
-	if err := json.Unmarshal(b, &anyV); err != nil {
-		return nil, fmt.Errorf("unmarshal: %w", err)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+
+	return nil
+}
+
+func applyJSONTransform(v any, t JSONTransform) (any, error) {
+	anyV, err := toGeneric(v)
+	if err != nil {
+		return nil, err
 	}
 
 	if t.ResultsOnly {
@@ -122,6 +155,21 @@ func applyJSONTransform(v any, t JSONTransform) (any, error) {
 	return anyV, nil
 }
 
+// toGeneric converts typed structs into a generic representation so we can manipulate them.
+func toGeneric(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	var anyV any
+	if err := json.Unmarshal(b, &anyV); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return anyV, nil
+}
+
 func unwrapPrimary(v any) any {
 	m, ok := v.(map[string]any)
 	if !ok {
@@ -0,0 +1,350 @@
+package outfmt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	redactKindEmail   = "EMAIL"
+	redactKindPhone   = "PHONE"
+	redactKindAddress = "ADDRESS"
+	redactKindCustom  = "REDACTED"
+)
+
+var (
+	redactEmailRe = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`)
+	// Phone numbers need a phone-like shape so IDs, timestamps, IPs and amounts are
+	// left alone: a leading + (any separators), an (area code), or space/dash
+	// separated groups. Dots only count as separators after a leading +.
+	redactPhoneRe   = regexp.MustCompile(`\+\d{10,15}\b|\+\d{1,3}[\s.\-]?(?:\(\d{2,4}\)|\d{2,4})[\s.\-]\d{3,4}[\s.\-]\d{3,4}\b|(?:\(\d{2,4}\) ?|\b\d{2,4}[ \-])\d{3,4}[ \-]\d{3,4}\b`)
+	redactAddressRe = regexp.MustCompile(`(?i)\b\d{1,6}\s+(?:[a-z0-9.'\-]+\s+){1,5}(?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|square|sq|terrace|parkway|pkwy|highway|hwy|strasse|straße)\b\.?(?:,?\s*(?:apt|suite|ste|unit|#)\.?\s*[a-z0-9\-]+)?`)
+
+	errRedactMapFormat = errors.New("invalid redaction map")
+)
+
+// redactAddressKeys are structured address fields (People API) that are masked as a whole.
+var redactAddressKeys = map[string]struct{}{
+	"streetAddress":   {},
+	"extendedAddress": {},
+	"poBox":           {},
+	"postalCode":      {},
+}
+
+type RedactOptions struct {
+	// Patterns are extra regular expressions whose matches are masked as REDACTED.
+	Patterns []string
+	// MapPath optionally persists token -> original value so output can be un-redacted later.
+	MapPath string
+}
+
+// Redactor masks PII with stable tokens: the same input yields the same token
+// for the lifetime of the Redactor (and across runs when a map file is used).
+type Redactor struct {
+	mu      sync.Mutex
+	custom  []*regexp.Regexp
+	mapPath string
+	byValue map[string]string
+	byToken map[string]string
+	counts  map[string]int
+	dirty   bool
+}
+
+func NewRedactor(opts RedactOptions) (*Redactor, error) {
+	r := &Redactor{
+		mapPath: strings.TrimSpace(opts.MapPath),
+		byValue: map[string]string{},
+		byToken: map[string]string{},
+		counts:  map[string]int{},
+	}
+
+	for _, p := range opts.Patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.custom = append(r.custom, re)
+	}
+
+	if r.mapPath != "" {
+		tokens, err := LoadRedactionMap(r.mapPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for token, value := range tokens {
+			r.remember(token, value)
+		}
+	}
+
+	return r, nil
+}
+
+type redactorKey struct{}
+
+func WithRedactor(ctx context.Context, r *Redactor) context.Context {
+	return context.WithValue(ctx, redactorKey{}, r)
+}
+
+func RedactorFromContext(ctx context.Context) *Redactor {
+	r, _ := ctx.Value(redactorKey{}).(*Redactor)
+	return r
+}
+
+// String masks custom patterns, street addresses, emails and phone numbers (in that order).
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, re := range r.custom {
+		s = r.replace(s, re, redactKindCustom)
+	}
+	s = r.replace(s, redactAddressRe, redactKindAddress)
+	s = r.replace(s, redactEmailRe, redactKindEmail)
+	s = r.replace(s, redactPhoneRe, redactKindPhone)
+
+	return s
+}
+
+// Value masks every string in a generic JSON value (map[string]any / []any / scalars).
+// Map keys are masked too, since outputs such as per-sender stats are keyed by address.
+func (r *Redactor) Value(v any) any {
+	if r == nil {
+		return v
+	}
+	switch vv := v.(type) {
+	case map[string]any:
+		// Walk keys in order so token numbering is deterministic.
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		out := make(map[string]any, len(vv))
+		for _, k := range keys {
+			item := vv[k]
+			if s, ok := item.(string); ok && s != "" {
+				if _, addr := redactAddressKeys[k]; addr {
+					out[k] = r.token(redactKindAddress, s)
+					continue
+				}
+			}
+			out[r.String(k)] = r.Value(item)
+		}
+
+		return out
+	case []any:
+		out := make([]any, len(vv))
+		for i, item := range vv {
+			out[i] = r.Value(item)
+		}
+
+		return out
+	case string:
+		return r.String(vv)
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) replace(s string, re *regexp.Regexp, kind string) string {
+	return re.ReplaceAllStringFunc(s, func(m string) string {
+		if isRedactToken(m) {
+			return m
+		}
+		return r.token(kind, m)
+	})
+}
+
+func (r *Redactor) token(kind, value string) string {
+	key := value
+	if kind == redactKindEmail {
+		key = strings.ToLower(value)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.byValue[key]; ok {
+		return t
+	}
+	r.counts[kind]++
+	t := fmt.Sprintf("[%s_%d]", kind, r.counts[kind])
+	for r.byToken[t] != "" {
+		r.counts[kind]++
+		t = fmt.Sprintf("[%s_%d]", kind, r.counts[kind])
+	}
+	r.byValue[key] = t
+	r.byToken[t] = value
+	r.dirty = true
+
+	return t
+}
+
+func (r *Redactor) remember(token, value string) {
+	r.byToken[token] = value
+	key := value
+	if strings.HasPrefix(token, "["+redactKindEmail+"_") {
+		key = strings.ToLower(value)
+	}
+	r.byValue[key] = token
+
+	kind, n, ok := parseRedactToken(token)
+	if ok && n > r.counts[kind] {
+		r.counts[kind] = n
+	}
+}
+
+// Save writes the token map (if configured and changed).
+func (r *Redactor) Save() error {
+	if r == nil || r.mapPath == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(map[string]any{"tokens": r.byToken}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode redaction map: %w", err)
+	}
+	tmp := r.mapPath + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write redaction map: %w", err)
+	}
+	if err := os.Rename(tmp, r.mapPath); err != nil {
+		return fmt.Errorf("commit redaction map: %w", err)
+	}
+	r.dirty = false
+
+	return nil
+}
+
+// Writer returns a line-buffered writer that redacts text before forwarding it.
+// Close flushes any trailing partial line.
+func (r *Redactor) Writer(w io.Writer) io.WriteCloser {
+	return &redactWriter{r: r, w: w}
+}
+
+type redactWriter struct {
+	r   *Redactor
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	rw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(rw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := rw.buf.Next(i + 1)
+		if _, err := io.WriteString(rw.w, rw.r.String(string(line))); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (rw *redactWriter) Close() error {
+	if rw.buf.Len() == 0 {
+		return nil
+	}
+	_, err := io.WriteString(rw.w, rw.r.String(rw.buf.String()))
+	rw.buf.Reset()
+
+	return err
+}
+
+// LoadRedactionMap reads a map written by Redactor.Save (token -> original value).
+func LoadRedactionMap(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path) //nolint:gosec // user-provided map path
+	if err != nil {
+		return nil, fmt.Errorf("read redaction map: %w", err)
+	}
+
+	var doc struct {
+		Tokens map[string]string `json:"tokens"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", errRedactMapFormat, path, err)
+	}
+	if doc.Tokens == nil {
+		doc.Tokens = map[string]string{}
+	}
+
+	return doc.Tokens, nil
+}
+
+// Unredact replaces known tokens in text with their original values.
+func Unredact(text string, tokens map[string]string) string {
+	if len(tokens) == 0 {
+		return text
+	}
+	// Tokens are bracketed, so no token is a prefix of another.
+	pairs := make([]string, 0, len(tokens)*2)
+	for token, value := range tokens {
+		pairs = append(pairs, token, value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// UnredactLines copies r to w, restoring tokens line by line.
+func UnredactLines(w io.Writer, r io.Reader, tokens map[string]string) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if _, wErr := io.WriteString(w, Unredact(line, tokens)); wErr != nil {
+				return wErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+var redactTokenRe = regexp.MustCompile(`^\[([A-Z]+)_(\d+)\]$`)
+
+func isRedactToken(s string) bool {
+	return redactTokenRe.MatchString(s)
+}
+
+func parseRedactToken(token string) (string, int, bool) {
+	m := redactTokenRe.FindStringSubmatch(token)
+	if m == nil {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return m[1], n, true
+}
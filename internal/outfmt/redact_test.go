@@ -0,0 +1,149 @@
+package outfmt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactor_StringConsistentTokens(t *testing.T) {
+	r, err := NewRedactor(RedactOptions{Patterns: []string{`ACME-\d+`}})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	got := r.String("From Alice <alice@example.com>, cc ALICE@example.com and bob@example.org; call +1 415-555-1234 re ACME-42")
+	want := "From Alice <[EMAIL_1]>, cc [EMAIL_1] and [EMAIL_2]; call [PHONE_1] re [REDACTED_1]"
+	if got != want {
+		t.Fatalf("unexpected redaction:\n got: %q\nwant: %q", got, want)
+	}
+
+	if got := r.String("Ship to 221 Baker Street, Apt 2B please"); got != "Ship to [ADDRESS_1] please" {
+		t.Fatalf("unexpected address redaction: %q", got)
+	}
+
+	// IDs, dates and timestamps must survive.
+	keep := "id 18c2f0a9b1d4e5f6 historyId 1234567890 at 2026-10-18T10:00:00Z"
+	if got := r.String(keep); got != keep {
+		t.Fatalf("unexpected redaction of ids: %q", got)
+	}
+}
+
+func TestRedactor_PhoneShapes(t *testing.T) {
+	r, err := NewRedactor(RedactOptions{})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	for _, phone := range []string{"+14155551234", "+1 415 555 1234", "+49.30.1234.5678", "(415) 555-1234", "415-555-1234", "030 1234 5678"} {
+		if got := r.String("call " + phone); !strings.HasPrefix(got, "call [PHONE_") {
+			t.Errorf("expected %q to be masked, got %q", phone, got)
+		}
+	}
+	// IPs, dotted amounts and versions are not phone numbers.
+	for _, keep := range []string{"host 192.168.100.200", "total 12.345.678 EUR", "version 10.1234.5678", "paid 1.234,56"} {
+		if got := r.String(keep); got != keep {
+			t.Errorf("unexpected redaction of %q: %q", keep, got)
+		}
+	}
+}
+
+func TestWriteJSON_Redacts(t *testing.T) {
+	r, err := NewRedactor(RedactOptions{})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	ctx := WithRedactor(context.Background(), r)
+
+	var buf bytes.Buffer
+	if err := WriteJSON(ctx, &buf, map[string]any{
+		"attendees": []any{map[string]any{"email": "a@b.com"}},
+		"addresses": []any{map[string]any{"streetAddress": "Main", "postalCode": "10115"}},
+		"from":      "a@b.com",
+		"senders":   map[string]any{"a@b.com": 3},
+	}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var got struct {
+		Attendees []map[string]string `json:"attendees"`
+		Addresses []map[string]string `json:"addresses"`
+		From      string              `json:"from"`
+		Senders   map[string]int      `json:"senders"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.From != "[EMAIL_1]" || got.Attendees[0]["email"] != "[EMAIL_1]" {
+		t.Fatalf("unexpected email redaction: %s", buf.String())
+	}
+	if got.Senders["[EMAIL_1]"] != 3 || len(got.Senders) != 1 {
+		t.Fatalf("expected map keys to be redacted: %s", buf.String())
+	}
+	if got.Addresses[0]["postalCode"] != "[ADDRESS_1]" || got.Addresses[0]["streetAddress"] != "[ADDRESS_2]" {
+		t.Fatalf("unexpected address redaction: %s", buf.String())
+	}
+}
+
+func TestRedactor_MapRoundTrip(t *testing.T) {
+	mapPath := filepath.Join(t.TempDir(), "redact-map.json")
+
+	r, err := NewRedactor(RedactOptions{MapPath: mapPath})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	redacted := r.String("ping a@b.com and c@d.com")
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A second run reuses existing tokens and continues numbering.
+	r2, err := NewRedactor(RedactOptions{MapPath: mapPath})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	if got := r2.String("c@d.com e@f.com"); got != "[EMAIL_2] [EMAIL_3]" {
+		t.Fatalf("unexpected tokens across runs: %q", got)
+	}
+	if err := r2.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	tokens, err := LoadRedactionMap(mapPath)
+	if err != nil {
+		t.Fatalf("LoadRedactionMap: %v", err)
+	}
+	if got := Unredact(redacted, tokens); got != "ping a@b.com and c@d.com" {
+		t.Fatalf("unexpected unredact: %q", got)
+	}
+
+	var out bytes.Buffer
+	if err := UnredactLines(&out, strings.NewReader("x [EMAIL_3]\ny"), tokens); err != nil {
+		t.Fatalf("UnredactLines: %v", err)
+	}
+	if out.String() != "x e@f.com\ny" {
+		t.Fatalf("unexpected UnredactLines output: %q", out.String())
+	}
+}
+
+func TestRedactWriter_LineBuffered(t *testing.T) {
+	r, err := NewRedactor(RedactOptions{})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+	_, _ = w.Write([]byte("to a@"))
+	_, _ = w.Write([]byte("b.com\ntail x@y.com"))
+	if buf.String() != "to [EMAIL_1]\n" {
+		t.Fatalf("expected only complete line flushed, got %q", buf.String())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if buf.String() != "to [EMAIL_1]\ntail [EMAIL_2]" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}